@@ -0,0 +1,281 @@
+// Package client - bound.go
+// Typed proxies returned by Registry.Bind, whose methods are refused when
+// the negotiated interface version is too old for them
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vignemail1/pipewire-go/core"
+)
+
+// methodsVersion is the interface version whose opcodes and arguments the
+// methods below encode
+// The version 3 interfaces of pipewire 0.3 renumbered the methods of the
+// version 0 ones, so older proxies cannot call them
+const methodsVersion uint32 = 3
+
+// BoundProxy is a proxy for a global, returned by Registry.Bind
+// The concrete type follows the interface of the global: *BoundNode,
+// *BoundPort, *BoundLink, *BoundDevice, *BoundClient, *BoundModule,
+// *BoundFactory, *BoundMetadata or *BoundProfiler
+type BoundProxy interface {
+	ID() uint32         // Proxy id on the connection
+	GlobalID() uint32   // Id of the bound global
+	Interface() string  // Interface type, e.g. core.TypeInterfaceNode
+	Version() uint32    // Negotiated interface version
+	Proxy() *core.Proxy // Underlying proxy, for events and lifecycle
+	Destroy() error     // Destroy the proxy, the global stays
+}
+
+// boundProxy holds what every typed proxy shares
+type boundProxy struct {
+	proxy    *core.Proxy
+	conn     *core.Connection
+	globalID uint32
+	iface    string
+	version  uint32
+}
+
+// newBoundProxy wraps a bound proxy in the type matching its interface
+func newBoundProxy(b boundProxy) BoundProxy {
+	switch b.iface {
+	case core.TypeInterfaceNode:
+		return &BoundNode{b}
+	case core.TypeInterfacePort:
+		return &BoundPort{b}
+	case core.TypeInterfaceLink:
+		return &BoundLink{b}
+	case core.TypeInterfaceDevice:
+		return &BoundDevice{b}
+	case core.TypeInterfaceClient:
+		return &BoundClient{b}
+	case core.TypeInterfaceModule:
+		return &BoundModule{b}
+	case core.TypeInterfaceFactory:
+		return &BoundFactory{b}
+	case core.TypeInterfaceMetadata:
+		return &BoundMetadata{b}
+	case core.TypeInterfaceProfiler:
+		return &BoundProfiler{b}
+	}
+	return nil
+}
+
+// ID returns the proxy id on the connection
+func (b *boundProxy) ID() uint32 {
+	return b.proxy.ID()
+}
+
+// GlobalID returns the id of the bound global
+func (b *boundProxy) GlobalID() uint32 {
+	return b.globalID
+}
+
+// Interface returns the interface type of the global
+func (b *boundProxy) Interface() string {
+	return b.iface
+}
+
+// Version returns the interface version negotiated by Registry.Bind
+func (b *boundProxy) Version() uint32 {
+	return b.version
+}
+
+// Proxy returns the underlying proxy
+func (b *boundProxy) Proxy() *core.Proxy {
+	return b.proxy
+}
+
+// Destroy destroys the proxy; the global itself is left alone
+func (b *boundProxy) Destroy() error {
+	return b.proxy.Destroy()
+}
+
+// String returns a string representation
+func (b *boundProxy) String() string {
+	return fmt.Sprintf("%s{id=%d, global=%d, version=%d}",
+		interfaceName(b.iface), b.proxy.ID(), b.globalID, b.version)
+}
+
+// call sends method on the bound object
+// A proxy bound at a version older than since gets a VersionError and
+// nothing is sent
+func (b *boundProxy) call(ctx context.Context, method string, since uint32, opcode core.MethodID, args *core.ArgsBuilder) error {
+	if b.version < since {
+		return &core.VersionError{Interface: b.iface, Method: method, Since: since, Version: b.version}
+	}
+	if b.proxy.IsDestroyed() {
+		return fmt.Errorf("%s: proxy %d is destroyed", method, b.proxy.ID())
+	}
+	msg := core.NewMessageBuilder(b.proxy.ID(), uint32(opcode)).WithPOD(args.Build()).Build()
+	if _, err := b.conn.SendCtx(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+// subscribeParams sends SubscribeParams(ids), shared by nodes, ports and devices
+func (b *boundProxy) subscribeParams(opcode core.MethodID, ids []uint32) error {
+	return b.call(context.Background(), "SubscribeParams", methodsVersion, opcode,
+		core.NewArgsBuilder().IDArray(ids))
+}
+
+// enumParams sends EnumParams(seq, id, index, num, filter), shared by nodes,
+// ports and devices; a nil filter is sent as None
+func (b *boundProxy) enumParams(opcode core.MethodID, seq int32, id, index, num uint32, filter []byte) error {
+	args := core.NewArgsBuilder().Int(seq).ID(id).Int(int32(index)).Int(int32(num))
+	if filter == nil {
+		args.None()
+	} else {
+		args.Raw(filter)
+	}
+	return b.call(context.Background(), "EnumParams", methodsVersion, opcode, args)
+}
+
+// setParam sends SetParam(id, flags, param), shared by nodes and devices
+func (b *boundProxy) setParam(opcode core.MethodID, id, flags uint32, param []byte) error {
+	return b.call(context.Background(), "SetParam", methodsVersion, opcode,
+		core.NewArgsBuilder().ID(id).Int(int32(flags)).Raw(param))
+}
+
+// interfaceName returns the short name of an interface type, such as
+// "node" for core.TypeInterfaceNode
+func interfaceName(iface string) string {
+	return strings.ToLower(iface[strings.LastIndexByte(iface, ':')+1:])
+}
+
+// BoundNode is a proxy for a Node global
+type BoundNode struct{ boundProxy }
+
+// SubscribeParams asks for Node.Param events whenever the params ids change
+func (n *BoundNode) SubscribeParams(ids ...uint32) error {
+	return n.subscribeParams(core.NodeMethodSubscribeParams, ids)
+}
+
+// EnumParams asks for the params id as Node.Param events tagged with seq,
+// starting at index and at most num of them (0 for all)
+// filter is an encoded POD object the params must match, or nil
+func (n *BoundNode) EnumParams(seq int32, id, index, num uint32, filter []byte) error {
+	return n.enumParams(core.NodeMethodEnumParams, seq, id, index, num, filter)
+}
+
+// SetParam sets the param id to the encoded POD object param
+func (n *BoundNode) SetParam(id, flags uint32, param []byte) error {
+	return n.setParam(core.NodeMethodSetParam, id, flags, param)
+}
+
+// SendCommand sends the encoded POD object command, such as Suspend
+func (n *BoundNode) SendCommand(command []byte) error {
+	return n.call(context.Background(), "SendCommand", methodsVersion, core.NodeMethodSendCommand,
+		core.NewArgsBuilder().Raw(command))
+}
+
+// BoundPort is a proxy for a Port global
+type BoundPort struct{ boundProxy }
+
+// SubscribeParams asks for Port.Param events whenever the params ids change
+func (p *BoundPort) SubscribeParams(ids ...uint32) error {
+	return p.subscribeParams(core.PortMethodSubscribeParams, ids)
+}
+
+// EnumParams asks for the params id as Port.Param events, see BoundNode.EnumParams
+func (p *BoundPort) EnumParams(seq int32, id, index, num uint32, filter []byte) error {
+	return p.enumParams(core.PortMethodEnumParams, seq, id, index, num, filter)
+}
+
+// BoundDevice is a proxy for a Device global
+type BoundDevice struct{ boundProxy }
+
+// SubscribeParams asks for Device.Param events whenever the params ids change
+func (d *BoundDevice) SubscribeParams(ids ...uint32) error {
+	return d.subscribeParams(core.DeviceMethodSubscribeParams, ids)
+}
+
+// EnumParams asks for the params id as Device.Param events, see BoundNode.EnumParams
+func (d *BoundDevice) EnumParams(seq int32, id, index, num uint32, filter []byte) error {
+	return d.enumParams(core.DeviceMethodEnumParams, seq, id, index, num, filter)
+}
+
+// SetParam sets the param id, such as Profile or Route, to the encoded POD object param
+func (d *BoundDevice) SetParam(id, flags uint32, param []byte) error {
+	return d.setParam(core.DeviceMethodSetParam, id, flags, param)
+}
+
+// BoundClient is a proxy for a Client global
+type BoundClient struct{ boundProxy }
+
+// ClientPermission grants permissions on a global to a client
+type ClientPermission struct {
+	ID          uint32 // Global id, core.InvalidID for the default permissions
+	Permissions uint32 // PW_PERM_* bits
+}
+
+// Error sends an error to the client about its resource id
+// res is a negative errno
+func (c *BoundClient) Error(id uint32, res int32, message string) error {
+	return c.call(context.Background(), "Error", methodsVersion, core.ClientMethodError,
+		core.NewArgsBuilder().Int(int32(id)).Int(res).String(message))
+}
+
+// UpdateProperties updates the properties of the client
+func (c *BoundClient) UpdateProperties(props map[string]string) error {
+	return c.call(context.Background(), "UpdateProperties", methodsVersion, core.ClientMethodUpdateProperties,
+		core.NewArgsBuilder().Dict(props))
+}
+
+// GetPermissions asks for num permissions of the client starting at index
+// as Client.Permissions events
+func (c *BoundClient) GetPermissions(index, num uint32) error {
+	return c.call(context.Background(), "GetPermissions", methodsVersion, core.ClientMethodGetPermissions,
+		core.NewArgsBuilder().Int(int32(index)).Int(int32(num)))
+}
+
+// UpdatePermissions changes the permissions of the client on globals
+func (c *BoundClient) UpdatePermissions(perms []ClientPermission) error {
+	args := core.NewArgsBuilder().Int(int32(len(perms)))
+	for _, p := range perms {
+		args.Int(int32(p.ID)).Int(int32(p.Permissions))
+	}
+	return c.call(context.Background(), "UpdatePermissions", methodsVersion, core.ClientMethodUpdatePermissions, args)
+}
+
+// BoundMetadata is a proxy for a Metadata global
+type BoundMetadata struct{ boundProxy }
+
+// SetProperty sets key on the global subject to value of type typ (may be
+// empty); an empty value removes the key
+func (m *BoundMetadata) SetProperty(subject uint32, key, typ, value string) error {
+	args := core.NewArgsBuilder().Int(int32(subject)).String(key)
+	if typ == "" {
+		args.None()
+	} else {
+		args.String(typ)
+	}
+	if value == "" {
+		args.None()
+	} else {
+		args.String(value)
+	}
+	return m.call(context.Background(), "SetProperty", methodsVersion, core.MetadataMethodSetProperty, args)
+}
+
+// Clear removes all the properties
+func (m *BoundMetadata) Clear() error {
+	return m.call(context.Background(), "Clear", methodsVersion, core.MetadataMethodClear, core.NewArgsBuilder())
+}
+
+// BoundLink is a proxy for a Link global, which only has events
+type BoundLink struct{ boundProxy }
+
+// BoundModule is a proxy for a Module global, which only has events
+type BoundModule struct{ boundProxy }
+
+// BoundFactory is a proxy for a Factory global, which only has events
+type BoundFactory struct{ boundProxy }
+
+// BoundProfiler is a proxy for a Profiler global, which only has events
+type BoundProfiler struct{ boundProxy }
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core"
+	"github.com/vignemail1/pipewire-go/core/pwtest"
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+// lastRequest returns the last method call received on objectID
+func lastRequest(t *testing.T, server *pwtest.Server, objectID uint32) *core.MessageFrame {
+	t.Helper()
+	requests := server.Requests()
+	for i := len(requests) - 1; i >= 0; i-- {
+		if msg := requests[i].Message; msg.ObjectID == objectID {
+			return msg
+		}
+	}
+	t.Fatalf("no request on object %d", objectID)
+	return nil
+}
+
+func TestRegistryBind(t *testing.T) {
+	server := pwtest.NewServer(t)
+	node := server.AddNode(map[string]string{"node.name": "sink"})
+	newer := server.AddGlobal(core.TypeInterfaceMetadata, core.MetadataVersion+2, nil)
+	older := server.AddGlobal(core.TypeInterfaceNode, 0, nil)
+
+	client, err := NewClient(server.Path(), verbose.NewLogger(verbose.LogLevelSilent, false))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+	registry := client.GetRegistry()
+
+	bound, err := registry.Bind(node.ID, core.TypeInterfaceNode)
+	if err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	n, ok := bound.(*BoundNode)
+	if !ok || n.Version() != core.NodeVersion || n.GlobalID() != node.ID {
+		t.Fatalf("unexpected bound proxy %v", bound)
+	}
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+	if n.Proxy().BoundID() != node.ID {
+		t.Errorf("proxy bound to %d, want %d", n.Proxy().BoundID(), node.ID)
+	}
+	bind, err := core.ParseRegistryBind(lastRequest(t, server, client.GetRegistryID()))
+	if err != nil {
+		t.Fatalf("ParseRegistryBind failed: %v", err)
+	}
+	want := core.RegistryBindRequest{ID: node.ID, Type: core.TypeInterfaceNode, Version: core.NodeVersion, NewID: n.ID()}
+	if *bind != want {
+		t.Errorf("sent %+v, want %+v", bind, want)
+	}
+
+	if err := n.SubscribeParams(3, 4); err != nil {
+		t.Fatalf("SubscribeParams failed: %v", err)
+	}
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+	msg := lastRequest(t, server, n.ID())
+	r, err := core.NewArgsReader(msg)
+	if err != nil {
+		t.Fatalf("NewArgsReader failed: %v", err)
+	}
+	if ids, err := r.IDArray(); err != nil || msg.MethodID != uint32(core.NodeMethodSubscribeParams) || len(ids) != 2 || ids[1] != 4 {
+		t.Errorf("unexpected SubscribeParams %d %v: %v", msg.MethodID, ids, err)
+	}
+
+	// A global newer than this library is bound at the version it implements
+	bound, err = registry.Bind(newer.ID, core.TypeInterfaceMetadata)
+	if err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if _, ok := bound.(*BoundMetadata); !ok || bound.Version() != core.MetadataVersion {
+		t.Errorf("metadata bound as %T at version %d", bound, bound.Version())
+	}
+
+	// An older one at its own, refusing the methods it does not have
+	bound, err = registry.Bind(older.ID, core.TypeInterfaceNode)
+	if err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+	sent := len(server.Requests())
+	err = bound.(*BoundNode).SetParam(2, 0, nil)
+	var versionErr *core.VersionError
+	if !errors.As(err, &versionErr) || !errors.Is(err, core.ErrNotSupported) || versionErr.Version != 0 {
+		t.Errorf("expected a VersionError, got %v", err)
+	}
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+	if got := len(server.Requests()); got != sent+1 {
+		t.Errorf("%d requests sent for a refused method", got-sent-1)
+	}
+
+	// The interface must match the global
+	if _, err := registry.Bind(node.ID, core.TypeInterfacePort); !errors.Is(err, core.ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a wrong interface, got %v", err)
+	}
+	if _, err := registry.Bind(9999, core.TypeInterfaceNode); !errors.Is(err, core.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown global, got %v", err)
+	}
+}
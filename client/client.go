@@ -6,6 +6,8 @@ package client
 import (
 	"context"
 	"fmt"
+	"maps"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,6 +15,9 @@ import (
 	"github.com/vignemail1/pipewire-go/verbose"
 )
 
+// NewClient connects to the PipeWire daemon and waits for the connect sequence
+// socketPath is a remote name, an absolute socket path or empty for
+// $PIPEWIRE_REMOTE or the default remote; core.Dial resolves it
 func NewClient(socketPath string, logger *verbose.Logger) (*Client, error) {
 	if logger == nil {
 		logger = verbose.NewLogger(verbose.LogLevelInfo, false)
@@ -26,6 +31,22 @@ func NewClient(socketPath string, logger *verbose.Logger) (*Client, error) {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
 
+	return newClient(connection, socketPath, logger)
+}
+
+// NewClientWithConnection runs a client over an existing connection, such
+// as one from core.NewReplayConnection or core.NewConnectionFromSocket
+// The connect sequence must not have been started; the client takes
+// ownership of conn. Reconnecting dials the default remote
+func NewClientWithConnection(conn *core.Connection, logger *verbose.Logger) (*Client, error) {
+	if logger == nil {
+		logger = verbose.NewLogger(verbose.LogLevelInfo, false)
+	}
+	return newClient(conn, "", logger)
+}
+
+// newClient sets up the client structures over connection and waits for the connect sequence
+func newClient(connection *core.Connection, socketPath string, logger *verbose.Logger) (*Client, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// ========================================================================
@@ -42,35 +63,35 @@ func NewClient(socketPath string, logger *verbose.Logger) (*Client, error) {
 		links:        make(map[uint32]*Link),
 		done:         make(chan struct{}),
 		errors:       make(chan error, 10),
-		eventChan:    make(chan Event, 100),
-		listeners:    make(map[EventType][]EventListener),
 		mu:           sync.RWMutex{},
-		connection:   connection,      // Unix socket connection to daemon
-		registryID:   1,               // Registry object ID
-		coreID:       0,               // Core object ID
-		eventHandler: eventHandler,    // Event handler for protocol events
-		lastSequence: 0,               // Request sequence counter
-		dispatcher:   NewEventDispatcher(), // Application event dispatcher
+		connection:   connection,              // Unix socket connection to daemon
+		registryID:   connection.RegistryID(), // Registry proxy ID allocated by the connection
+		coreID:       0,                       // Core object ID
+		eventHandler: eventHandler,            // Event handler for protocol events
+		lastSequence: 0,                       // Request sequence counter
+		dispatcher:   NewEventDispatcher(),    // Application event dispatcher
+		socketPath:   socketPath,
 	}
 
 	// Create Core proxy (id=0)
 	client.core = newCore(0, connection, logger)
 
-	// Create Registry proxy (id=1)
-	client.registry = newRegistry(1, connection, logger)
+	// Create Registry proxy, bound during the connect sequence
+	client.registry = newRegistry(connection.RegistryID(), connection, logger)
 
-	logger.Infof("Client: Connected to PipeWire daemon")
+	// Registry changes reach the listeners registered with RegisterEventListener
+	client.registry.AddListener(client.forwardRegistryEvent)
+	if err := client.dispatcher.Start(); err != nil {
+		logger.Warnf("Client: Event dispatcher: %v", err)
+	}
 
-	// FIXED: Start protocol event loop with correct variable name
-	go func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+	// Events for the registry and bound objects are routed through the handler
+	client.attach(connection, eventHandler)
 
-		// Fixed: Use 'client' instead of undefined 'c'
-		if err := client.connection.StartEventLoop(ctx) {
-			logger.Errorf("Protocol event loop error: %v", err)
-		}
-	}()
+	logger.Infof("Client: Connected to PipeWire daemon")
+
+	// Start protocol event loop, stopped by Close
+	loopDone := client.startEventLoop(connection)
 
 	// Wait for connection to be ready
 	ctxReady, cancelReady := context.WithTimeout(context.Background(), 5*time.Second)
@@ -78,9 +99,14 @@ func NewClient(socketPath string, logger *verbose.Logger) (*Client, error) {
 
 	// Fixed: Use 'client' instead of undefined 'c'
 	if err := client.connection.WaitUntilReady(ctxReady); err != nil {
+		cancel()
+		connection.Close()
 		return nil, fmt.Errorf("connection not ready: %w", err)
 	}
 
+	// Watch the connection, reconnecting if a policy gets set
+	go client.run(loopDone)
+
 	// Start application event loop
 	go client.eventLoop()
 
@@ -103,30 +129,39 @@ type Client struct {
 	links map[uint32]*Link
 
 	// Event channels
-	done      chan struct{}
-	errors    chan error
-	eventChan chan Event
-	listeners map[EventType][]EventListener
+	done   chan struct{}
+	errors chan error
 
 	// Proxy objects for Core and Registry
-	core     *coreProxy
-	registry *registryProxy
+	core     *Core
+	registry *Registry
 
 	// Protocol communication and synchronization
 	mu           sync.RWMutex       // Protects all fields below
 	connection   *core.Connection   // Unix socket connection to daemon (consolidated from 'conn')
-	registryID   uint32             // Registry object ID (1)
+	registryID   uint32             // Registry proxy ID
 	coreID       uint32             // Core object ID (0)
 	eventHandler *core.EventHandler // Protocol-level event handler
 	lastSequence uint32             // Sequence counter for protocol requests
 	dispatcher   *EventDispatcher   // Application-level event dispatcher
+	protocol     *ProtocolClient    // Request helper of the connection, see protocolClient
+
+	// Reconnect support
+	socketPath string           // Remote passed to NewClient, resolved again on reconnect
+	reconnect  *ReconnectPolicy // nil when reconnecting is disabled
+	stateMu    sync.Mutex       // Protects stateSubs
+	stateSubs  []chan ConnectionStateEvent
+
+	// Keepalive support
+	keepaliveCancel context.CancelFunc // Stops the running probe, nil when disabled
 }
 
 // ============================================================================
 // FIXED AND COMPLETE: eventLoop() METHOD - WAS MISSING
 // ============================================================================
 
-// eventLoop handles incoming events from PipeWire daemon
+// eventLoop reports client errors until Close; events reach the
+// listeners through the dispatcher
 func (c *Client) eventLoop() {
 	defer func() {
 		c.done <- struct{}{}
@@ -138,26 +173,6 @@ func (c *Client) eventLoop() {
 			c.logger.Debugf("Event loop shutting down")
 			return
 
-		case event, ok := <-c.eventChan:
-			if !ok {
-				c.logger.Debugf("Event channel closed")
-				return
-			}
-
-			// Dispatch event to registered listeners
-			c.mu.RLock()
-			listeners := c.listeners[event.Type]
-			c.mu.RUnlock()
-
-			for _, listener := range listeners {
-				// Call listeners asynchronously to avoid blocking
-				go func(l EventListener, e Event) {
-					if err := l(e); err != nil {
-						c.logger.Warnf("Event listener error: %v", err)
-					}
-				}(listener, event)
-			}
-
 		case err := <-c.errors:
 			c.logger.Errorf("Client error: %v", err)
 		}
@@ -173,6 +188,10 @@ func (c *Client) Close() error {
 		return nil
 	}
 
+	// Stop reconnecting before the connection goes away
+	c.cancel()
+	c.closeStates()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -183,6 +202,11 @@ func (c *Client) Close() error {
 		}
 	}
 
+	// Proxies still allocated at this point were never destroyed
+	for _, proxy := range c.leakedProxies() {
+		c.logger.Warnf("Client: %s leaked, never destroyed", proxy)
+	}
+
 	// Shutdown protocol connection
 	if c.connection != nil {
 		if err := c.connection.Shutdown(context.Background()); err != nil {
@@ -190,9 +214,6 @@ func (c *Client) Close() error {
 		}
 	}
 
-	// Signal event loop to stop
-	c.cancel()
-
 	// Wait for event loop to finish
 	<-c.done
 
@@ -203,6 +224,32 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// protocolClient returns the request helper of the current connection
+// It is kept across calls so its Stats accumulate
+func (c *Client) protocolClient() *ProtocolClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.protocol == nil || c.protocol.GetConnection() != c.connection {
+		c.protocol = NewProtocolClient(c.connection, c.registryID, c.coreID, c.logger)
+	}
+	return c.protocol
+}
+
+// leakedProxies returns the proxies of the connection not destroyed yet,
+// the registry proxy owned by the client aside; caller holds c.mu
+func (c *Client) leakedProxies() []*core.Proxy {
+	if c.connection == nil {
+		return nil
+	}
+	var leaked []*core.Proxy
+	for _, proxy := range c.connection.Proxies() {
+		if proxy.ID() != c.registryID && !proxy.IsDestroyed() {
+			leaked = append(leaked, proxy)
+		}
+	}
+	return leaked
+}
+
 // ============================================================================
 // CONSOLIDATED HELPER METHODS - Access consolidated 'connection' field
 // ============================================================================
@@ -217,6 +264,14 @@ func (c *Client) GetConnection() *core.Connection {
 	return nil
 }
 
+// GetRegistry returns the registry proxy, used to list and bind globals
+func (c *Client) GetRegistry() *Registry {
+	if c != nil {
+		return c.registry
+	}
+	return nil
+}
+
 // GetRegistryID returns the registry object ID
 func (c *Client) GetRegistryID() uint32 {
 	if c != nil {
@@ -264,29 +319,66 @@ func (c *Client) GetNextSequence() uint32 {
 
 // IsReady returns true if the connection is ready for communication
 func (c *Client) IsReady() bool {
-	if c == nil || c.connection == nil {
+	conn := c.GetConnection()
+	if conn == nil {
 		return false
 	}
-	return c.connection.GetState() == core.StateReady
+	return conn.GetState() == core.StateReady
 }
 
 // WaitUntilReady waits for the connection to be ready
 func (c *Client) WaitUntilReady(ctx context.Context) error {
-	if c == nil || c.connection == nil {
+	conn := c.GetConnection()
+	if conn == nil {
+		return fmt.Errorf("client not initialized")
+	}
+	return conn.WaitUntilReady(ctx)
+}
+
+// Roundtrip blocks until the daemon has processed every request sent so far
+// and all events it emitted in response have been received
+// Call it after connecting to wait for the initial registry burst
+func (c *Client) Roundtrip(ctx context.Context) error {
+	conn := c.GetConnection()
+	if conn == nil {
 		return fmt.Errorf("client not initialized")
 	}
-	return c.connection.WaitUntilReady(ctx)
+	return conn.Roundtrip(ctx)
 }
 
 // GetConnectionState returns the current protocol connection state
+// It reports the connection in use, which changes after a reconnect
 func (c *Client) GetConnectionState() core.State {
-	if c == nil || c.connection == nil {
+	conn := c.GetConnection()
+	if conn == nil {
 		return core.StateDisconnected
 	}
-	return c.connection.GetState()
+	return conn.GetState()
+}
+
+// GetConnectionError returns why the connection entered core.StateError,
+// for example an error matching core.ErrDaemonUnresponsive, or nil
+func (c *Client) GetConnectionError() error {
+	conn := c.GetConnection()
+	if conn == nil || conn.GetState() != core.StateError {
+		return nil
+	}
+	return conn.StateMachine().GetLastError()
+}
+
+// MemPool returns the shared memory the daemon announced with Core.AddMem
+// Memory ids are per connection, so the pool is replaced on reconnect
+func (c *Client) MemPool() *core.MemPool {
+	conn := c.GetConnection()
+	if conn == nil {
+		return nil
+	}
+	return conn.MemPool()
 }
 
 // RegisterEventListener registers an application-level event listener
+// Listeners stay installed across reconnects and receive the synthetic
+// registry events of a resync
 func (c *Client) RegisterEventListener(eventType EventType, listener EventListener) error {
 	if c.dispatcher == nil {
 		return fmt.Errorf("event dispatcher not initialized")
@@ -366,16 +458,35 @@ func (c *Client) GetLinkByID(id uint32) *Link {
 // COMPLETE CreateLink() METHOD
 // ============================================================================
 
+// CreateLink links output to input and waits for the daemon to create the link
 func (c *Client) CreateLink(output, input *Port, params *LinkParams) (*Link, error) {
+	return c.CreateLinkCtx(context.Background(), output, input, params)
+}
+
+// CreateLinkCtx is CreateLink giving up when ctx is done
+// The pending request is dropped on cancellation and no link is recorded
+func (c *Client) CreateLinkCtx(ctx context.Context, output, input *Port, params *LinkParams) (*Link, error) {
 	if output == nil || input == nil {
 		return nil, fmt.Errorf("ports cannot be nil")
 	}
 
 	// Use ProtocolClient to create link via daemon
-	protoClient := NewProtocolClient(c.connection, c.registryID, c.coreID, c.logger)
+	protoClient := c.protocolClient()
 
-	// Send CreateLink request and get link ID from daemon
-	linkID, err := protoClient.CreateLink(output.ID(), input.ID(), params.Properties)
+	// The link-factory is given the nodes of the ports as well, like pw-link
+	props := make(map[string]string)
+	if params != nil {
+		maps.Copy(props, params.Properties)
+	}
+	if node := output.Node(); node != nil {
+		props["link.output.node"] = strconv.FormatUint(uint64(node.ID), 10)
+	}
+	if node := input.Node(); node != nil {
+		props["link.input.node"] = strconv.FormatUint(uint64(node.ID), 10)
+	}
+
+	// Create the link on the daemon and get its global id
+	linkID, err := protoClient.CreateLinkCtx(ctx, output.ID(), input.ID(), props)
 	if err != nil {
 		return nil, fmt.Errorf("protocol error: %w", err)
 	}
@@ -395,16 +506,23 @@ func (c *Client) CreateLink(output, input *Port, params *LinkParams) (*Link, err
 // COMPLETE RemoveLink() METHOD
 // ============================================================================
 
+// RemoveLink destroys link and waits for the daemon to confirm
 func (c *Client) RemoveLink(link *Link) error {
+	return c.RemoveLinkCtx(context.Background(), link)
+}
+
+// RemoveLinkCtx is RemoveLink giving up when ctx is done
+// The link stays recorded when the request is cancelled
+func (c *Client) RemoveLinkCtx(ctx context.Context, link *Link) error {
 	if link == nil || link.ID() == 0 {
 		return fmt.Errorf("invalid link")
 	}
 
 	// Use ProtocolClient to destroy link via daemon
-	protoClient := NewProtocolClient(c.connection, c.registryID, c.coreID, c.logger)
+	protoClient := c.protocolClient()
 
 	// Send DestroyLink request to daemon
-	if err := protoClient.DestroyLink(link.ID()); err != nil {
+	if err := protoClient.DestroyLinkCtx(ctx, link.ID()); err != nil {
 		return fmt.Errorf("protocol error: %w", err)
 	}
 
@@ -417,3 +535,33 @@ func (c *Client) RemoveLink(link *Link) error {
 
 	return nil
 }
+
+// DisconnectPorts removes the link from output port outputPortID to input
+// port inputPortID
+func (c *Client) DisconnectPorts(outputPortID, inputPortID uint32) error {
+	for _, link := range c.GetLinks() {
+		output, input := link.OutputPort(), link.InputPort()
+		if output != nil && input != nil && output.ID() == outputPortID && input.ID() == inputPortID {
+			return c.RemoveLink(link)
+		}
+	}
+	return fmt.Errorf("no link from port %d to port %d", outputPortID, inputPortID)
+}
+
+// GetConnectedPorts returns the ports linked to port portID
+func (c *Client) GetConnectedPorts(portID uint32) ([]*Port, error) {
+	var ports []*Port
+	for _, link := range c.GetLinks() {
+		output, input := link.OutputPort(), link.InputPort()
+		if output == nil || input == nil {
+			continue
+		}
+		switch portID {
+		case output.ID():
+			ports = append(ports, input)
+		case input.ID():
+			ports = append(ports, output)
+		}
+	}
+	return ports, nil
+}
@@ -2,7 +2,6 @@ package client
 
 import (
 	"testing"
-	"time"
 )
 
 // TestNodeCreation tests node creation and initialization
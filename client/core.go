@@ -6,8 +6,12 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"math"
 	"sync"
+	"time"
 
 	"github.com/vignemail1/pipewire-go/core"
 	"github.com/vignemail1/pipewire-go/verbose"
@@ -63,12 +67,12 @@ func (r *ObjectRegistry) RegisterNode(node *Node) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if node.ID() == 0 {
+	if node.ID == 0 {
 		r.idCounter++
 		// Note: In real implementation, would set node.id
 	}
 
-	r.nodes[node.ID()] = node
+	r.nodes[node.ID] = node
 	r.notifyWatchers("node", ObjectEvent{
 		Type:   ObjectEventTypeAdded,
 		Object: node,
@@ -313,7 +317,7 @@ func (r *ObjectRegistry) FindPortsByNode(nodeID uint32) []*Port {
 
 	var result []*Port
 	for _, port := range r.ports {
-		if port.Node() != nil && port.Node().ID() == nodeID {
+		if port.Node() != nil && port.Node().ID == nodeID {
 			result = append(result, port)
 		}
 	}
@@ -389,33 +393,35 @@ func newCore(id uint32, conn *core.Connection, logger *verbose.Logger) *Core {
 	}
 }
 
-// Ping sends a ping message to verify the connection is alive
-func (c *Core) Ping() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// Ping checks that the daemon is alive and returns the round-trip time
+// The native protocol only lets the daemon ping clients, so the probe is a
+// Core.Sync with a fresh seq whose Core.Done plays the pong
+func (c *Core) Ping(ctx context.Context) (time.Duration, error) {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
 
-	if !c.conn.IsConnected() {
-		return core.NewConnectionError("connection is closed")
+	if !conn.IsConnected() {
+		return 0, core.NewConnectionError("connection is closed")
 	}
 
-	// Get a sequence ID for this request
-	seq := c.conn.GetSyncID()
-
-	c.logger.Debugf("Core: Sending ping (seq=%d)", seq)
-
-	// In a real implementation, we would:
-	// 1. Marshal a POD with the ping request
-	// 2. Send via connection
-	// 3. Wait for pong response
-
-	// For now, we just log and return success
-	// TODO: Implement actual ping/pong message exchange
-	return nil
+	start := time.Now()
+	if err := conn.Roundtrip(ctx); err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	c.logger.Debugf("Core: Pong after %v", rtt)
+	return rtt, nil
 }
 
-// Sync sends a sync request and waits for the daemon to acknowledge
-// This is used as a synchronization point in the protocol
+// Sync sends a sync request without waiting for the daemon to acknowledge
+// Use Roundtrip to block until the matching done event arrives
 func (c *Core) Sync() error {
+	return c.SyncCtx(context.Background())
+}
+
+// SyncCtx is Sync giving up when ctx is done before the request was queued
+func (c *Core) SyncCtx(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -423,21 +429,25 @@ func (c *Core) Sync() error {
 		return core.NewConnectionError("connection is closed")
 	}
 
-	// Get a sequence ID for this sync request
-	seq := c.conn.GetSyncID()
-
-	c.logger.Debugf("Core: Sending sync (seq=%d)", seq)
-
-	// In a real implementation, we would:
-	// 1. Send a sync method call with this sequence
-	// 2. Wait for a done event with matching sequence
-	// 3. Return once received
+	seq, err := c.conn.SyncCtx(ctx, core.CoreID)
+	if err != nil {
+		return err
+	}
 
-	// For now, we just log and return success
-	// TODO: Implement actual sync message exchange and waiting
+	c.logger.Debugf("Core: Sent sync (seq=%d)", seq)
 	return nil
 }
 
+// Roundtrip sends a sync request with a fresh sequence number and blocks
+// until the daemon answers with the matching done event
+// All events emitted by the daemon before the done have been received on return
+func (c *Core) Roundtrip(ctx context.Context) error {
+	if !c.conn.IsConnected() {
+		return core.NewConnectionError("connection is closed")
+	}
+	return c.conn.Roundtrip(ctx)
+}
+
 // Version returns the core protocol version
 func (c *Core) Version() uint32 {
 	c.mu.RLock()
@@ -477,6 +487,14 @@ func (c *Core) SetProperties(props map[string]string) {
 	c.properties = props
 }
 
+// setConnection points the core proxy at a new connection
+func (c *Core) setConnection(conn *core.Connection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.proxy = core.NewProxy(core.CoreID, "core", conn)
+	c.conn = conn
+}
+
 // String returns a human-readable representation
 func (c *Core) String() string {
 	c.mu.RLock()
@@ -491,7 +509,7 @@ func (c *Core) String() string {
 // GlobalObject represents a PipeWire object advertised by the registry
 type GlobalObject struct {
 	ID         uint32
-	Type       string // e.g. "Node", "Port", "Link"
+	Type       string // Interface type, e.g. core.TypeInterfaceNode
 	Version    uint32
 	Properties map[string]string
 }
@@ -504,6 +522,10 @@ type RegistryEvent struct {
 	Type     RegistryEventType
 	Object   *GlobalObject
 	ObjectID uint32
+
+	// Synthetic is set for events derived from a registry resync after a
+	// reconnect rather than received from the daemon
+	Synthetic bool
 }
 
 // RegistryEventType represents the type of registry event
@@ -523,6 +545,9 @@ type Registry struct {
 	mu        sync.RWMutex
 	objects   map[uint32]*GlobalObject // Discovered global objects
 	listeners map[uint32][]RegistryListener
+
+	// Globals announced by a new connection while resyncing, nil otherwise
+	staging map[uint32]*GlobalObject
 }
 
 // newRegistry creates a new Registry proxy
@@ -590,9 +615,58 @@ func (r *Registry) ObjectsByType(objType string) []*GlobalObject {
 	return result
 }
 
+// Bind binds the global id, whose interface must be iface, and returns its
+// typed proxy, such as a *BoundNode for core.TypeInterfaceNode
+// The version is the lower of the one advertised by the global and the one
+// this library implements; methods it does not have return a VersionError
+// The proxy belongs to the current connection, bind again after a reconnect
+func (r *Registry) Bind(id uint32, iface string) (BoundProxy, error) {
+	return r.BindCtx(context.Background(), id, iface)
+}
+
+// BindCtx is Bind giving up when ctx is done before the request was queued
+func (r *Registry) BindCtx(ctx context.Context, id uint32, iface string) (BoundProxy, error) {
+	r.mu.RLock()
+	obj, exists := r.objects[id]
+	conn, registryID := r.conn, r.proxy.ID()
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("bind global %d: %w", id, core.ErrNotFound)
+	}
+	if obj.Type != iface {
+		return nil, fmt.Errorf("bind global %d: is a %s, not a %s: %w", id, obj.Type, iface, core.ErrInvalid)
+	}
+	version, err := core.NegotiateVersion(iface, obj.Version)
+	if err != nil {
+		return nil, fmt.Errorf("bind global %d: %w", id, err)
+	}
+	if conn == nil || !conn.IsConnected() {
+		return nil, core.NewConnectionError("connection is closed")
+	}
+
+	proxy := conn.AllocateProxy(interfaceName(iface))
+	req := &core.RegistryBindRequest{ID: id, Type: iface, Version: version, NewID: proxy.ID()}
+	if _, err := conn.SendCtx(ctx, req.Message(registryID)); err != nil {
+		conn.ReleaseProxy(proxy)
+		return nil, fmt.Errorf("bind global %d: %w", id, err)
+	}
+
+	r.logger.Debugf("Registry: Bound global %d (%s) version %d as proxy %d", id, iface, version, proxy.ID())
+	return newBoundProxy(boundProxy{
+		proxy:    proxy,
+		conn:     conn,
+		globalID: id,
+		iface:    iface,
+		version:  version,
+	}), nil
+}
+
 // handleGlobal is called when a new global object is advertised
 // This is typically called from the event loop when registry.global event is received
-func (r *Registry) handleGlobal(id uint32, objType string, version uint32, props map[string]string) {
+// A new global beyond the MaxGlobals limit of the connection is dropped
+// with a ProtocolError
+func (r *Registry) handleGlobal(id uint32, objType string, version uint32, props map[string]string) error {
 	r.mu.Lock()
 	obj := &GlobalObject{
 		ID:         id,
@@ -600,29 +674,50 @@ func (r *Registry) handleGlobal(id uint32, objType string, version uint32, props
 		Version:    version,
 		Properties: props,
 	}
-	r.objects[id] = obj
+	// While resyncing, compared with the previous objects once it completes
+	objects, resyncing := r.objects, r.staging != nil
+	if resyncing {
+		objects = r.staging
+	}
+	if _, exists := objects[id]; !exists && len(objects) >= r.maxGlobals() {
+		r.mu.Unlock()
+		return core.NewProtocolErrorf("registry full: global %d (%s) over the limit of %d", id, objType, len(objects))
+	}
+	objects[id] = obj
 	r.mu.Unlock()
+	if resyncing {
+		return nil
+	}
 
 	r.logger.Debugf("Registry: Global object added: id=%d type=%s version=%d", id, objType, version)
 
-	// Notify listeners
-	r.mu.RLock()
-	listeners := r.listeners[0]
-	r.mu.RUnlock()
+	r.notify(RegistryEvent{
+		Type:     RegistryEventTypeGlobal,
+		Object:   obj,
+		ObjectID: id,
+	})
+	return nil
+}
 
-	event := RegistryEvent{
-		Type:   RegistryEventTypeGlobal,
-		Object: obj,
-	}
-	for _, listener := range listeners {
-		go listener(event)
+// maxGlobals returns the MaxGlobals limit of the connection, unlimited when unset
+func (r *Registry) maxGlobals() int {
+	if r.conn != nil {
+		if max := r.conn.Limits().MaxGlobals; max > 0 {
+			return max
+		}
 	}
+	return math.MaxInt
 }
 
 // handleGlobalRemove is called when a global object is removed
 // This is typically called from the event loop when registry.global_remove event is received
 func (r *Registry) handleGlobalRemove(id uint32) {
 	r.mu.Lock()
+	if r.staging != nil {
+		delete(r.staging, id)
+		r.mu.Unlock()
+		return
+	}
 	obj, exists := r.objects[id]
 	delete(r.objects, id)
 	r.mu.Unlock()
@@ -633,19 +728,95 @@ func (r *Registry) handleGlobalRemove(id uint32) {
 		return
 	}
 
-	// Notify listeners
-	r.mu.RLock()
-	listeners := r.listeners[0]
-	r.mu.RUnlock()
-
-	event := RegistryEvent{
+	r.notify(RegistryEvent{
 		Type:     RegistryEventTypeGlobalRemove,
 		Object:   obj,
 		ObjectID: id,
-	}
+	})
+}
+
+// notify calls the registry listeners in order on the calling goroutine
+func (r *Registry) notify(event RegistryEvent) {
+	r.mu.RLock()
+	listeners := append([]RegistryListener(nil), r.listeners[0]...)
+	r.mu.RUnlock()
+
 	for _, listener := range listeners {
-		go listener(event)
+		listener(event)
+	}
+}
+
+// setConnection points the registry proxy at a new connection
+func (r *Registry) setConnection(id uint32, conn *core.Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.proxy = core.NewProxy(id, "registry", conn)
+	r.conn = conn
+}
+
+// beginResync starts collecting the globals announced by a new connection
+// instead of applying them, so only the difference is reported
+func (r *Registry) beginResync() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.staging = make(map[uint32]*GlobalObject)
+}
+
+// cancelResync drops the globals collected since beginResync
+func (r *Registry) cancelResync() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.staging = nil
+}
+
+// finishResync replaces the objects with the globals collected since
+// beginResync and notifies listeners with synthetic events: removals for
+// globals that are gone or changed, then additions for new or changed ones
+func (r *Registry) finishResync() (removed, added int) {
+	r.mu.Lock()
+	if r.staging == nil {
+		r.mu.Unlock()
+		return 0, 0
+	}
+
+	var events []RegistryEvent
+	for id, old := range r.objects {
+		if cur, ok := r.staging[id]; !ok || !sameGlobal(old, cur) {
+			events = append(events, RegistryEvent{
+				Type:      RegistryEventTypeGlobalRemove,
+				Object:    old,
+				ObjectID:  id,
+				Synthetic: true,
+			})
+		}
 	}
+	removed = len(events)
+	for id, cur := range r.staging {
+		if old, ok := r.objects[id]; !ok || !sameGlobal(old, cur) {
+			events = append(events, RegistryEvent{
+				Type:      RegistryEventTypeGlobal,
+				Object:    cur,
+				ObjectID:  id,
+				Synthetic: true,
+			})
+		}
+	}
+	added = len(events) - removed
+
+	r.objects = r.staging
+	r.staging = nil
+	r.mu.Unlock()
+
+	for _, event := range events {
+		r.notify(event)
+	}
+	return removed, added
+}
+
+// sameGlobal reports whether two globals describe the same object
+// Ids are reused by a restarted daemon, so the type and properties must match too
+func sameGlobal(a, b *GlobalObject) bool {
+	return a.Type == b.Type && a.Version == b.Version && maps.Equal(a.Properties, b.Properties)
 }
 
 // Count returns the number of global objects
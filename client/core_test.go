@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core/pwtest"
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+func TestRegistryMaxGlobals(t *testing.T) {
+	server := pwtest.NewServer(t)
+	existing := server.AddNode(map[string]string{"node.name": "existing"})
+	client, err := NewClient(server.Path(), verbose.NewLogger(verbose.LogLevelSilent, false))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+
+	count := client.registry.Count()
+	limits := client.connection.Limits()
+	limits.MaxGlobals = count
+	client.connection.SetLimits(limits)
+
+	node := server.AddNode(map[string]string{"node.name": "over-limit"})
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+	if _, ok := client.registry.GetObject(node.ID); ok || client.registry.Count() != count {
+		t.Errorf("registry grew past %d globals to %d", count, client.registry.Count())
+	}
+
+	// Removals make room again
+	server.RemoveGlobal(node.ID)
+	server.RemoveGlobal(existing.ID)
+	node = server.AddNode(map[string]string{"node.name": "fits"})
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+	if _, ok := client.registry.GetObject(node.ID); !ok {
+		t.Error("global not added after a removal")
+	}
+}
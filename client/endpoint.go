@@ -245,18 +245,6 @@ func (e *Endpoint) String() string {
 	return fmt.Sprintf("Endpoint(%s, type=%v, ports=%d)", e.name, e.endpointType, len(e.ports))
 }
 
-// GetConnectedEndpoints returns all endpoints connected to this one
-func (e *Endpoint) GetConnectedEndpoints() ([]*Endpoint, error) {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-
-	if e.client == nil {
-		return nil, fmt.Errorf("endpoint not associated with client")
-	}
-
-	return e.client.GetConnectedEndpoints(e.id)
-}
-
 // TypeString returns string representation of endpoint type
 func (et EndpointType) String() string {
 	switch et {
@@ -7,6 +7,7 @@ package client
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/vignemail1/pipewire-go/core"
 )
@@ -68,6 +69,20 @@ type EventDispatcher struct {
 	eventQueue      chan *ApplicationEvent
 	running         bool
 	stopChan        chan struct{}
+
+	// Counters returned by Stats
+	dispatched atomic.Uint64
+	dropped    atomic.Uint64
+	peakQueue  atomic.Int64
+}
+
+// DispatcherStats is a snapshot of the event delivery of an EventDispatcher
+type DispatcherStats struct {
+	QueueDepth     int    // Events waiting for the listeners
+	QueueCapacity  int    // Events the queue holds before dropping
+	PeakQueueDepth int    // Highest QueueDepth seen
+	Dispatched     uint64 // Events handed to the listeners
+	Dropped        uint64 // Events refused, queue full or dispatcher stopped
 }
 
 // NewEventDispatcher creates a new event dispatcher
@@ -145,10 +160,13 @@ func (ed *EventDispatcher) Dispatch(event *ApplicationEvent) error {
 		// Queue event for async dispatch
 		select {
 		case ed.eventQueue <- event:
+			ed.notePeakQueue(len(ed.eventQueue))
 			return nil
 		case <-ed.stopChan:
+			ed.dropped.Add(1)
 			return fmt.Errorf("dispatcher stopped")
 		default:
+			ed.dropped.Add(1)
 			return fmt.Errorf("event queue full")
 		}
 	}
@@ -159,6 +177,8 @@ func (ed *EventDispatcher) Dispatch(event *ApplicationEvent) error {
 
 // dispatchSync dispatches synchronously to all listeners
 func (ed *EventDispatcher) dispatchSync(event *ApplicationEvent) error {
+	ed.dispatched.Add(1)
+
 	ed.mu.RLock()
 	listeners, ok := ed.listeners[event.Type]
 	ed.mu.RUnlock()
@@ -227,6 +247,27 @@ func (ed *EventDispatcher) eventLoop() {
 	}
 }
 
+// notePeakQueue records depth if it is the highest queue depth seen
+func (ed *EventDispatcher) notePeakQueue(depth int) {
+	for {
+		peak := ed.peakQueue.Load()
+		if int64(depth) <= peak || ed.peakQueue.CompareAndSwap(peak, int64(depth)) {
+			return
+		}
+	}
+}
+
+// Stats returns a snapshot of the event delivery counters
+func (ed *EventDispatcher) Stats() DispatcherStats {
+	return DispatcherStats{
+		QueueDepth:     len(ed.eventQueue),
+		QueueCapacity:  cap(ed.eventQueue),
+		PeakQueueDepth: int(ed.peakQueue.Load()),
+		Dispatched:     ed.dispatched.Load(),
+		Dropped:        ed.dropped.Load(),
+	}
+}
+
 // GetListenersCount returns the number of listeners for an event type
 func (ed *EventDispatcher) GetListenersCount(eventType EventType) int {
 	ed.mu.RLock()
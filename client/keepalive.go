@@ -0,0 +1,103 @@
+// Package client - keepalive.go
+// Periodic liveness probes detecting a daemon that stopped answering
+
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core"
+)
+
+// KeepalivePolicy controls how often the daemon is probed and when it is declared dead
+type KeepalivePolicy struct {
+	Interval  time.Duration // Time between probes
+	Timeout   time.Duration // Deadline for each pong
+	MaxMissed int           // Consecutive missed pongs before failing, 0 means 1
+}
+
+// DefaultKeepalivePolicy returns a policy probing every 10s and failing
+// the connection after two pongs missing their 5s deadline
+func DefaultKeepalivePolicy() *KeepalivePolicy {
+	return &KeepalivePolicy{
+		Interval:  10 * time.Second,
+		Timeout:   5 * time.Second,
+		MaxMissed: 2,
+	}
+}
+
+// SetKeepalive starts probing the daemon with Core.Ping according to policy
+// When the daemon misses the deadline MaxMissed times in a row the connection
+// fails with an error matching core.ErrDaemonUnresponsive: GetConnectionState
+// reports core.StateError, ConnectionStates receives ConnectionStateLost and
+// the reconnect policy, if any, takes over. A nil policy stops probing
+func (c *Client) SetKeepalive(policy *KeepalivePolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keepaliveCancel != nil {
+		c.keepaliveCancel()
+		c.keepaliveCancel = nil
+	}
+	if policy == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.keepaliveCancel = cancel
+	go c.keepalive(ctx, *policy)
+}
+
+// keepalive probes the daemon until ctx is cancelled
+func (c *Client) keepalive(ctx context.Context, policy KeepalivePolicy) {
+	maxMissed := policy.MaxMissed
+	if maxMissed <= 0 {
+		maxMissed = 1
+	}
+
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	missed := 0
+	var probed *core.Connection
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Only ready connections are probed, reconnects are run() business
+		conn := c.GetConnection()
+		if conn == nil || conn.GetState() != core.StateReady {
+			continue
+		}
+		if conn != probed {
+			probed = conn
+			missed = 0
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, policy.Timeout)
+		_, err := c.core.Ping(probeCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			missed = 0
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, context.DeadlineExceeded):
+			missed++
+			c.logger.Warnf("Client: Daemon missed keepalive %d/%d", missed, maxMissed)
+			if missed >= maxMissed {
+				conn.Fail(core.NewUnresponsiveErrorf("no pong within %v, %d probes missed", policy.Timeout, missed))
+				missed = 0
+			}
+		default:
+			// The connection went away, the event loop reports it
+			c.logger.Debugf("Client: Keepalive probe failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core"
+	"github.com/vignemail1/pipewire-go/core/pwtest"
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+func TestKeepaliveDetectsHungDaemon(t *testing.T) {
+	server := pwtest.NewServer(t)
+	client, err := NewClient(server.Path(), verbose.NewLogger(verbose.LogLevelSilent, false))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	states := client.ConnectionStates()
+	client.SetKeepalive(&KeepalivePolicy{
+		Interval:  20 * time.Millisecond,
+		Timeout:   50 * time.Millisecond,
+		MaxMissed: 2,
+	})
+
+	// Answered probes keep the connection up
+	time.Sleep(100 * time.Millisecond)
+	if state := client.GetConnectionState(); state != core.StateReady {
+		t.Fatalf("got state %s while the daemon answers", state)
+	}
+
+	server.Hang()
+	defer server.Resume()
+
+	select {
+	case event := <-states:
+		if event.State != ConnectionStateLost || !errors.Is(event.Err, core.ErrDaemonUnresponsive) {
+			t.Fatalf("unexpected state event %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("hung daemon not detected")
+	}
+
+	if state := client.GetConnectionState(); state != core.StateError {
+		t.Errorf("got state %s, want Error", state)
+	}
+	if err := client.GetConnectionError(); !core.IsTimeout(err) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
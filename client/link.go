@@ -48,6 +48,13 @@ type LinkInfo struct {
 
 // NewLink creates a new link between two ports
 func NewLink(id uint32, inputPort, outputPort *Port, client *Client) *Link {
+	var inputID, outputID uint32
+	if inputPort != nil {
+		inputID = inputPort.ID()
+	}
+	if outputPort != nil {
+		outputID = outputPort.ID()
+	}
 	return &Link{
 		id:         id,
 		inputPort:  inputPort,
@@ -58,8 +65,8 @@ func NewLink(id uint32, inputPort, outputPort *Port, client *Client) *Link {
 		createdAt:  time.Now(),
 		info: &LinkInfo{
 			ID:         id,
-			InputPort:  inputPort.ID(),
-			OutputPort: outputPort.ID(),
+			InputPort:  inputID,
+			OutputPort: outputID,
 			Input:      inputPort,
 			Output:     outputPort,
 			State:      LinkStateActive,
@@ -151,18 +158,14 @@ func (l *Link) Duration() time.Duration {
 
 // Disconnect removes this link
 func (l *Link) Disconnect() error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.mu.RLock()
+	client := l.client
+	l.mu.RUnlock()
 
-	if l.client == nil {
+	if client == nil {
 		return fmt.Errorf("link not associated with client")
 	}
-	if l.outputPort == nil || l.inputPort == nil {
-		return fmt.Errorf("link ports are not set")
-	}
-
-	// Call the client's DisconnectPorts method
-	return l.client.DisconnectPorts(l.outputPort.ID(), l.inputPort.ID())
+	return client.RemoveLink(l)
 }
 
 // IsValid checks if the link is valid
@@ -311,8 +314,8 @@ func (lm *LinkManager) FilterByNode(nodeID uint32) []*Link {
 
 	var result []*Link
 	for _, link := range lm.links {
-		if (link.inputPort != nil && link.inputPort.Node() != nil && link.inputPort.Node().ID() == nodeID) ||
-			(link.outputPort != nil && link.outputPort.Node() != nil && link.outputPort.Node().ID() == nodeID) {
+		if (link.inputPort != nil && link.inputPort.Node() != nil && link.inputPort.Node().ID == nodeID) ||
+			(link.outputPort != nil && link.outputPort.Node() != nil && link.outputPort.Node().ID == nodeID) {
 			result = append(result, link)
 		}
 	}
@@ -9,6 +9,8 @@ import (
 	"sync"
 
 	"github.com/vignemail1/pipewire-go/core"
+	"github.com/vignemail1/pipewire-go/spa"
+	"github.com/vignemail1/pipewire-go/spa/param"
 	"github.com/vignemail1/pipewire-go/verbose"
 )
 
@@ -78,11 +80,13 @@ func (n *Node) parseProperties() {
 	n.info.State = NodeState(n.Props["node.state"])
 
 	if sr, ok := n.Props["audio.rate"]; ok {
-		n.info.SampleRate, _ = strconv.ParseUint(sr, 10, 32)
+		rate, _ := strconv.ParseUint(sr, 10, 32)
+		n.info.SampleRate = uint32(rate)
 	}
 
 	if ch, ok := n.Props["audio.channels"]; ok {
-		n.info.Channels, _ = strconv.ParseUint(ch, 10, 32)
+		channels, _ := strconv.ParseUint(ch, 10, 32)
+		n.info.Channels = uint32(channels)
 	}
 }
 
@@ -186,62 +190,64 @@ func (n *Node) GetInfo() *NodeInfo {
 // ParamID represents a PipeWire node parameter identifier
 type ParamID uint32
 
-// Node parameter IDs (as defined in PipeWire spec)
+// Node parameter IDs (SPA_PARAM_*, as numbered on the wire)
 const (
-	ParamIDEnumFormat ParamID = iota
-	ParamIDPropInfo
-	ParamIDProps
-	ParamIDFormat
-	ParamIDBuffers
-	ParamIDMeta
-	ParamIDIO
-	ParamIDEnumProfile
-	ParamIDProfile
-	ParamIDEnumPortConfig
-	ParamIDPortConfig
-	ParamIDProcessLatency
+	ParamIDPropInfo       = ParamID(spa.ParamPropInfo)
+	ParamIDProps          = ParamID(spa.ParamProps)
+	ParamIDEnumFormat     = ParamID(spa.ParamEnumFormat)
+	ParamIDFormat         = ParamID(spa.ParamFormat)
+	ParamIDBuffers        = ParamID(spa.ParamBuffers)
+	ParamIDMeta           = ParamID(spa.ParamMeta)
+	ParamIDIO             = ParamID(spa.ParamIO)
+	ParamIDEnumProfile    = ParamID(spa.ParamEnumProfile)
+	ParamIDProfile        = ParamID(spa.ParamProfile)
+	ParamIDEnumPortConfig = ParamID(spa.ParamEnumPortConfig)
+	ParamIDPortConfig     = ParamID(spa.ParamPortConfig)
+	ParamIDEnumRoute      = ParamID(spa.ParamEnumRoute)
+	ParamIDRoute          = ParamID(spa.ParamRoute)
+	ParamIDLatency        = ParamID(spa.ParamLatency)
+	ParamIDProcessLatency = ParamID(spa.ParamProcessLatency)
 )
 
 // GetParams queries node parameters
 // paramID specifies which parameter to query:
-//   - ParamIDEnumFormat: Available audio formats
-//   - ParamIDFormat: Current format
-//   - ParamIDProcessLatency: Processing latency
+//   - ParamIDEnumFormat: Available audio formats, a *param.EnumFormat
+//   - ParamIDFormat: Current format, a *param.Format
+//   - ParamIDProcessLatency: Processing latency, a *param.ProcessLatency
 //   - ParamIDProps: Node properties
 func (n *Node) GetParams(paramID ParamID) (interface{}, error) {
-	if n == nil || n.conn == nil {
-		return nil, fmt.Errorf("node or connection not initialized")
+	if n == nil {
+		return nil, fmt.Errorf("node not initialized")
 	}
 
-	n.logger.Debugf("Node %d: Getting parameter %d", n.ID, paramID)
+	// The params are derived from the cached properties, so a node that
+	// was never bound to a connection can still answer
+	if n.logger != nil {
+		n.logger.Debugf("Node %d: Getting parameter %d", n.ID, paramID)
+	}
 
 	// In a full implementation, this would:
-	// 1. Send a Get request to the node via the protocol
-	// 2. Wait for the response
-	// 3. Parse the POD structure response
-	// 4. Return typed result
+	// 1. Send EnumParams to the node via BoundNode.EnumParams
+	// 2. Wait for the Param events
+	// 3. Decode them with param.Decode
 
-	// For now, return supported parameter info
+	// For now, derive the params from the node properties
 	switch paramID {
 	case ParamIDEnumFormat:
-		return map[string]interface{}{
-			"description": "Available audio formats",
-			"values": []string{"s16", "s32", "f32", "f64"},
+		return &param.EnumFormat{
+			MediaType:    param.MediaTypeAudio,
+			MediaSubtype: param.MediaSubtypeRaw,
+			Format: param.IDEnum(param.AudioFormatF32P, param.AudioFormatF32P,
+				param.AudioFormatS16LE, param.AudioFormatS32LE, param.AudioFormatF32LE, param.AudioFormatF64LE),
 		}, nil
 
 	case ParamIDFormat:
-		return map[string]interface{}{
-			"format": n.Props["audio.format"],
-			"rate":   n.GetSampleRate(),
-			"channels": n.GetChannels(),
-		}, nil
+		name, _ := n.GetProperty("audio.format")
+		format, _ := param.ParseAudioFormat(name)
+		return param.NewAudioFormat(format, n.GetSampleRate(), n.GetChannels()), nil
 
 	case ParamIDProcessLatency:
-		return map[string]interface{}{
-			"min_quantum": 32,
-			"max_quantum": 8192,
-			"latency":     64,
-		}, nil
+		return &param.ProcessLatency{}, nil
 
 	case ParamIDProps:
 		return n.GetProperties(), nil
@@ -309,8 +315,8 @@ func (n *Node) SetParam(paramID ParamID, flags uint32, value interface{}) error
 func (n *Node) AddPort(port *Port) {
 	n.portMut.Lock()
 	defer n.portMut.Unlock()
-	n.ports[port.ID] = port
-	n.logger.Debugf("Node %d: Port added: %s", n.ID, port.Name)
+	n.ports[port.ID()] = port
+	n.logger.Debugf("Node %d: Port added: %s", n.ID, port.Name())
 }
 
 // GetPort retrieves a port by name
@@ -319,7 +325,7 @@ func (n *Node) GetPort(name string) *Port {
 	defer n.portMut.RUnlock()
 
 	for _, port := range n.ports {
-		if port.Name == name {
+		if port.Name() == name {
 			return port
 		}
 	}
@@ -345,7 +351,7 @@ func (n *Node) GetPortsByDirection(dir PortDirection) []*Port {
 
 	var result []*Port
 	for _, port := range n.ports {
-		if port.Direction == dir {
+		if port.Direction() == dir {
 			result = append(result, port)
 		}
 	}
@@ -359,7 +365,7 @@ func (n *Node) GetPortsByType(portType PortType) []*Port {
 
 	var result []*Port
 	for _, port := range n.ports {
-		if port.Type == portType {
+		if port.Type() == portType {
 			result = append(result, port)
 		}
 	}
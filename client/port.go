@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"sync"
 
-	"github.com/vignemail1/pipewire-go/core"
 )
 
 // ============================================================================
@@ -263,13 +262,14 @@ func (p *Port) String() string {
 // GetConnectedPorts returns all ports connected to this port
 func (p *Port) GetConnectedPorts() ([]*Port, error) {
 	p.mu.RLock()
-	defer p.mu.RUnlock()
+	client, id := p.client, p.id
+	p.mu.RUnlock()
 
-	if p.client == nil {
+	if client == nil {
 		return nil, fmt.Errorf("port not associated with client")
 	}
 
-	return p.client.GetConnectedPorts(p.id)
+	return client.GetConnectedPorts(id)
 }
 
 // IsAudioPort returns true if this is an audio port
@@ -5,12 +5,16 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"strconv"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/vignemail1/pipewire-go/core"
+	"github.com/vignemail1/pipewire-go/verbose"
 )
 
 // ProtocolClient provides high-level protocol operations
@@ -21,31 +25,99 @@ type ProtocolClient struct {
 	eventHandler    *core.EventHandler
 	registryID      uint32
 	coreID          uint32
-	logger          *core.VerboseLogger
-	lastSequence    uint32
+	logger          *verbose.Logger
 	requestTimeout  time.Duration
+	stats           *requestStats
 }
 
 // NewProtocolClient creates a new protocol client
-func NewProtocolClient(conn *core.Connection, registryID, coreID uint32, logger *core.VerboseLogger) *ProtocolClient {
+// Requests are tracked in the connection's event handler so that Core.Error
+// events from the daemon fail the request that caused them
+func NewProtocolClient(conn *core.Connection, registryID, coreID uint32, logger *verbose.Logger) *ProtocolClient {
 	if logger == nil {
-		logger = core.NewVerboseLogger(core.LogLevelInfo, false)
+		logger = verbose.NewLogger(verbose.LogLevelInfo, false)
+	}
+
+	var eventHandler *core.EventHandler
+	if conn != nil {
+		eventHandler = conn.EventHandler()
+	}
+	if eventHandler == nil {
+		eventHandler = core.NewEventHandler()
 	}
 
 	return &ProtocolClient{
 		connection:     conn,
-		eventHandler:   core.NewEventHandler(),
+		eventHandler:   eventHandler,
 		registryID:     registryID,
 		coreID:         coreID,
 		logger:         logger,
-		lastSequence:   0,
 		requestTimeout: 5 * time.Second,
+		stats:          newRequestStats(),
 	}
 }
 
-// nextSequence generates the next sequence number
-func (pc *ProtocolClient) nextSequence() uint32 {
-	return atomic.AddUint32(&pc.lastSequence, 1)
+// sendRequest writes a request using the next connection sequence number
+// and waits for its outcome, until ctx is done or the request timeout expires
+// Methods have no reply: the request succeeded once the Core.Done of a
+// following Core.Sync arrives, the daemon sending any Core.Error before it
+// A Core.Error naming the request fails it with a *core.DaemonError
+// Cancelling ctx drops the pending request, the error then wraps ctx.Err()
+func (pc *ProtocolClient) sendRequest(ctx context.Context, frame *core.MessageFrame) (interface{}, error) {
+	if pc.connection == nil {
+		return nil, fmt.Errorf("connection is nil")
+	}
+
+	pc.mu.RLock()
+	timeout := pc.requestTimeout
+	pc.mu.RUnlock()
+
+	// The daemon reports errors with the header sequence of the failing message,
+	// so the pending request must be keyed by it and registered before sending
+	sequence := pc.connection.GetSyncID()
+	frame.Sequence = sequence
+
+	req := pc.eventHandler.CreatePendingRequestFor(sequence, frame.ObjectID)
+	if req == nil {
+		return nil, fmt.Errorf("failed to create pending request")
+	}
+	pc.stats.sent(pc.eventHandler.PendingRequestCount())
+	start := time.Now()
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	written := false
+	if err := pc.connection.WriteMessageCtx(waitCtx, frame); err != nil {
+		// Rejecting makes WaitForRequestCtx return at once and drop the request
+		pc.eventHandler.RejectPendingRequest(sequence, fmt.Errorf("failed to send message: %w", err))
+	} else {
+		written = true
+		pc.logger.Debugf("ProtocolClient: sent %d/%d seq=%d, waiting for response", frame.ObjectID, frame.MethodID, sequence)
+		if err := pc.connection.Roundtrip(waitCtx); err != nil {
+			if waitCtx.Err() == nil {
+				pc.eventHandler.RejectPendingRequest(sequence, err)
+			}
+		} else if len(req.Error) == 0 {
+			pc.eventHandler.ResolvePendingRequest(sequence, nil)
+		}
+	}
+
+	result, err := pc.eventHandler.WaitForRequestCtx(waitCtx, req)
+	switch {
+	case err == nil:
+		pc.stats.answered(time.Since(start), false)
+	case ctx.Err() != nil:
+		pc.stats.cancelled()
+	case errors.Is(err, context.DeadlineExceeded):
+		pc.stats.timedOut()
+		return nil, core.NewTimeoutErrorf("request %d timeout after %v", sequence, timeout)
+	case written:
+		pc.stats.answered(time.Since(start), true)
+	default:
+		pc.stats.failed()
+	}
+	return result, err
 }
 
 // SetRequestTimeout configures the timeout for protocol requests
@@ -77,216 +149,101 @@ func (pc *ProtocolClient) UnregisterEventHandler(objectID uint32) {
 	}
 }
 
-// CreateLink creates a connection between two ports
-// Returns the link ID assigned by the daemon, or error
-func (pc *ProtocolClient) CreateLink(outputPortID, inputPortID uint32, properties map[string]string) (uint32, error) {
-	if pc == nil {
-		return 0, fmt.Errorf("ProtocolClient is nil")
-	}
-
-	pc.mu.Lock()
-	sequence := pc.nextSequence()
-	timeout := pc.requestTimeout
-	pc.mu.Unlock()
-
-	pc.logger.Logf(core.LogLevelDebug, "CreateLink: output=%d input=%d seq=%d", outputPortID, inputPortID, sequence)
-
-	// Build request
-	req := &core.LinkCreateRequest{
-		OutputPortID: outputPortID,
-		InputPortID:  inputPortID,
-		Properties:   properties,
-		Passive:      false,
-	}
-
-	// Convert to POD
-	podObj, err := req.ToPOD()
-	if err != nil {
-		return 0, fmt.Errorf("failed to convert to POD: %w", err)
-	}
-
-	// Create message frame
-	frame := core.NewMessageBuilder(pc.registryID, 4). // Method 4 = registry.bind()
-		WithSequence(sequence).
-		WithPOD(podObj).
-		Build()
-
-	if frame == nil {
-		return 0, fmt.Errorf("failed to build message frame")
-	}
-
-	// Create pending request context
-	ctx := pc.eventHandler.CreatePendingRequest(sequence)
-	if ctx == nil {
-		return 0, fmt.Errorf("failed to create pending request")
-	}
+// CreateObject asks the daemon factory to create an object of interface
+// iface and returns its proxy, bound to the new global
+// The object lives as long as the proxy unless props set object.linger
+func (pc *ProtocolClient) CreateObject(factory, iface string, version uint32, props map[string]string) (*core.Proxy, error) {
+	return pc.CreateObjectCtx(context.Background(), factory, iface, version, props)
+}
 
-	// Marshal and send message
-	data, err := frame.Marshal()
-	if err != nil {
-		return 0, fmt.Errorf("failed to marshal frame: %w", err)
+// CreateObjectCtx is CreateObject giving up when ctx is done
+func (pc *ProtocolClient) CreateObjectCtx(ctx context.Context, factory, iface string, version uint32, props map[string]string) (*core.Proxy, error) {
+	if pc == nil {
+		return nil, fmt.Errorf("ProtocolClient is nil")
 	}
-
 	if pc.connection == nil {
-		return 0, fmt.Errorf("connection is nil")
-	}
-
-	if err := pc.connection.Write(data); err != nil {
-		return 0, fmt.Errorf("failed to send message: %w", err)
+		return nil, fmt.Errorf("connection is nil")
 	}
 
-	pc.logger.Logf(core.LogLevelDebug, "CreateLink: sent message, waiting for response")
-
-	// Wait for response
-	result, err := pc.eventHandler.WaitForRequest(ctx, timeout)
-	if err != nil {
-		return 0, fmt.Errorf("request failed: %w", err)
+	proxy := pc.connection.AllocateProxy(interfaceName(iface))
+	req := &core.CreateObjectRequest{Factory: factory, Type: iface, Version: version, Props: props, NewID: proxy.ID()}
+	if _, err := pc.sendRequest(ctx, req.Message()); err != nil {
+		pc.connection.ReleaseProxy(proxy)
+		return nil, fmt.Errorf("create %s with %s: %w", iface, factory, err)
 	}
 
-	// Extract link ID from result
-	if linkID, ok := result.(uint32); ok {
-		pc.logger.Logf(core.LogLevelDebug, "CreateLink: received link ID %d", linkID)
-		return linkID, nil
+	// Core.BoundID comes before the Done the request waited for
+	if proxy.BoundID() == core.InvalidID {
+		pc.connection.ReleaseProxy(proxy)
+		return nil, core.NewProtocolErrorf("create %s with %s: no bound id for proxy %d", iface, factory, proxy.ID())
 	}
+	return proxy, nil
+}
 
-	return 0, fmt.Errorf("unexpected response type: %T", result)
+// CreateLink creates a connection between two ports
+// Returns the link ID assigned by the daemon, or error
+func (pc *ProtocolClient) CreateLink(outputPortID, inputPortID uint32, properties map[string]string) (uint32, error) {
+	return pc.CreateLinkCtx(context.Background(), outputPortID, inputPortID, properties)
 }
 
-// DestroyLink destroys an existing link
-func (pc *ProtocolClient) DestroyLink(linkID uint32) error {
+// CreateLinkCtx is CreateLink giving up when ctx is done
+// The link is made by the link-factory; properties may name the nodes of
+// the ports with link.output.node and link.input.node
+// Like pw-link, the link is created with object.linger so it outlives the
+// client and its proxy is destroyed right away; with object.linger set to
+// anything else the link lives as long as the connection
+func (pc *ProtocolClient) CreateLinkCtx(ctx context.Context, outputPortID, inputPortID uint32, properties map[string]string) (uint32, error) {
 	if pc == nil {
-		return fmt.Errorf("ProtocolClient is nil")
-	}
-
-	pc.mu.Lock()
-	sequence := pc.nextSequence()
-	timeout := pc.requestTimeout
-	pc.mu.Unlock()
-
-	pc.logger.Logf(core.LogLevelDebug, "DestroyLink: link=%d seq=%d", linkID, sequence)
-
-	// Build request
-	req := &core.LinkDestroyRequest{
-		LinkID: linkID,
-	}
-
-	// Convert to POD
-	podObj, err := req.ToPOD()
-	if err != nil {
-		return fmt.Errorf("failed to convert to POD: %w", err)
+		return 0, fmt.Errorf("ProtocolClient is nil")
 	}
 
-	// Create message frame for destroy method (method 0)
-	frame := core.NewMessageBuilder(linkID, 0).
-		WithSequence(sequence).
-		WithPOD(podObj).
-		Build()
+	pc.logger.Debugf("CreateLink: output=%d input=%d", outputPortID, inputPortID)
 
-	if frame == nil {
-		return fmt.Errorf("failed to build message frame")
+	props := maps.Clone(properties)
+	if props == nil {
+		props = make(map[string]string)
 	}
-
-	// Create pending request context
-	ctx := pc.eventHandler.CreatePendingRequest(sequence)
-	if ctx == nil {
-		return fmt.Errorf("failed to create pending request")
+	props["link.output.port"] = strconv.FormatUint(uint64(outputPortID), 10)
+	props["link.input.port"] = strconv.FormatUint(uint64(inputPortID), 10)
+	if _, ok := props["object.linger"]; !ok {
+		props["object.linger"] = "true"
 	}
 
-	// Marshal and send message
-	data, err := frame.Marshal()
+	proxy, err := pc.CreateObjectCtx(ctx, core.LinkFactory, core.TypeInterfaceLink, core.LinkVersion, props)
 	if err != nil {
-		return fmt.Errorf("failed to marshal frame: %w", err)
-	}
-
-	if pc.connection == nil {
-		return fmt.Errorf("connection is nil")
+		return 0, fmt.Errorf("request failed: %w", err)
 	}
 
-	if err := pc.connection.Write(data); err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
-	}
-
-	pc.logger.Logf(core.LogLevelDebug, "DestroyLink: sent message, waiting for response")
-
-	// Wait for response
-	_, err = pc.eventHandler.WaitForRequest(ctx, timeout)
-	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+	// The daemon owns a lingering link, the proxy is not needed anymore
+	linkID := proxy.BoundID()
+	if props["object.linger"] == "true" {
+		if err := proxy.Destroy(); err != nil {
+			pc.logger.Warnf("CreateLink: destroy proxy of link %d: %v", linkID, err)
+		}
 	}
+	pc.logger.Debugf("CreateLink: received link ID %d", linkID)
+	return linkID, nil
+}
 
-	pc.logger.Logf(core.LogLevelDebug, "DestroyLink: link destroyed successfully")
-	return nil
+// DestroyLink destroys an existing link
+func (pc *ProtocolClient) DestroyLink(linkID uint32) error {
+	return pc.DestroyLinkCtx(context.Background(), linkID)
 }
 
-// SetLinkActive sets the active state of a link
-func (pc *ProtocolClient) SetLinkActive(linkID uint32, active bool) error {
+// DestroyLinkCtx is DestroyLink giving up when ctx is done
+// The link global is destroyed with Registry.Destroy
+func (pc *ProtocolClient) DestroyLinkCtx(ctx context.Context, linkID uint32) error {
 	if pc == nil {
 		return fmt.Errorf("ProtocolClient is nil")
 	}
 
-	pc.mu.Lock()
-	sequence := pc.nextSequence()
-	timeout := pc.requestTimeout
-	pc.mu.Unlock()
-
-	pc.logger.Logf(core.LogLevelDebug, "SetLinkActive: link=%d active=%v seq=%d", linkID, active, sequence)
-
-	// Build request
-	props := make(map[string]string)
-	props["state"] = fmt.Sprintf("%v", active)
-
-	req := &core.LinkCreateRequest{
-		OutputPortID: 0, // Not used for state change
-		InputPortID:  0, // Not used for state change
-		Properties:   props,
-		Passive:      !active,
-	}
-
-	// Convert to POD
-	podObj, err := req.ToPOD()
-	if err != nil {
-		return fmt.Errorf("failed to convert to POD: %w", err)
-	}
-
-	// Create message frame (method 2 = set_param)
-	frame := core.NewMessageBuilder(linkID, 2).
-		WithSequence(sequence).
-		WithPOD(podObj).
-		Build()
-
-	if frame == nil {
-		return fmt.Errorf("failed to build message frame")
-	}
-
-	// Create pending request context
-	ctx := pc.eventHandler.CreatePendingRequest(sequence)
-	if ctx == nil {
-		return fmt.Errorf("failed to create pending request")
-	}
-
-	// Marshal and send message
-	data, err := frame.Marshal()
-	if err != nil {
-		return fmt.Errorf("failed to marshal frame: %w", err)
-	}
+	pc.logger.Debugf("DestroyLink: link=%d", linkID)
 
-	if pc.connection == nil {
-		return fmt.Errorf("connection is nil")
-	}
-
-	if err := pc.connection.Write(data); err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
-	}
-
-	pc.logger.Logf(core.LogLevelDebug, "SetLinkActive: sent message, waiting for response")
-
-	// Wait for response
-	_, err = pc.eventHandler.WaitForRequest(ctx, timeout)
-	if err != nil {
+	if _, err := pc.sendRequest(ctx, core.NewRegistryDestroyMessage(pc.registryID, linkID)); err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
 
-	pc.logger.Logf(core.LogLevelDebug, "SetLinkActive: link state updated successfully")
+	pc.logger.Debugf("DestroyLink: link destroyed successfully")
 	return nil
 }
 
@@ -318,6 +275,14 @@ func (pc *ProtocolClient) GetConnection() *core.Connection {
 	return nil
 }
 
+// Stats returns a snapshot of the requests made through this client
+func (pc *ProtocolClient) Stats() ProtocolClientStats {
+	if pc == nil {
+		return ProtocolClientStats{}
+	}
+	return pc.stats.snapshot(pc.PendingRequestCount())
+}
+
 // PendingRequestCount returns the number of pending requests
 func (pc *ProtocolClient) PendingRequestCount() int {
 	if pc != nil && pc.eventHandler != nil {
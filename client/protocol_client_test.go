@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core"
+	"github.com/vignemail1/pipewire-go/core/pwtest"
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+func TestProtocolClientCreateLink(t *testing.T) {
+	server := pwtest.NewServer(t)
+	node := server.AddNode(map[string]string{"node.name": "source"})
+	out := server.AddPort(node.ID, "out", nil)
+	in := server.AddPort(node.ID, "in", nil)
+
+	client, err := NewClient(server.Path(), verbose.NewLogger(verbose.LogLevelSilent, false))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	pc := client.protocolClient()
+
+	linkID, err := pc.CreateLinkCtx(ctx, out.ID, in.ID, map[string]string{"object.linger": "true"})
+	if err != nil {
+		t.Fatalf("CreateLink failed: %v", err)
+	}
+	link, ok := server.Global(linkID)
+	if !ok || link.Type != core.TypeInterfaceLink {
+		t.Fatalf("link %d not created: %+v", linkID, link)
+	}
+
+	var create *core.CreateObjectRequest
+	for _, req := range server.Requests() {
+		if msg := req.Message; msg.ObjectID == core.CoreID && msg.MethodID == uint32(core.CoreMethodCreateObject) {
+			if create, err = core.ParseCreateObject(msg); err != nil {
+				t.Fatalf("ParseCreateObject failed: %v", err)
+			}
+		}
+	}
+	if create == nil {
+		t.Fatal("no create_object request")
+	}
+	if create.Factory != core.LinkFactory || create.Type != core.TypeInterfaceLink || create.Version != core.LinkVersion {
+		t.Errorf("unexpected create_object %+v", create)
+	}
+	props := create.Props
+	if props["link.output.port"] != fmt.Sprint(out.ID) || props["link.input.port"] != fmt.Sprint(in.ID) ||
+		props["object.linger"] != "true" {
+		t.Errorf("unexpected link props %v", props)
+	}
+
+	if err := pc.DestroyLinkCtx(ctx, linkID); err != nil {
+		t.Fatalf("DestroyLink failed: %v", err)
+	}
+	if _, ok := server.Global(linkID); ok {
+		t.Errorf("link %d not destroyed", linkID)
+	}
+	if err := pc.DestroyLinkCtx(ctx, linkID); err == nil {
+		t.Error("destroying a removed link succeeded")
+	}
+
+	// The Core.Error of a refused link fails the request
+	server.InjectError(core.CoreID, core.CoreMethodCreateObject, syscall.EINVAL, "no such port")
+	proxies := len(client.connection.Proxies())
+	_, err = pc.CreateLinkCtx(ctx, out.ID, 9999, nil)
+	var derr *core.DaemonError
+	if !errors.As(err, &derr) || derr.Message != "no such port" {
+		t.Fatalf("expected the daemon error, got %v", err)
+	}
+	if got := len(client.connection.Proxies()); got != proxies {
+		t.Errorf("%d proxies left by a failed create, want %d", got, proxies)
+	}
+}
+
+func TestCreateLinkOutlivesClient(t *testing.T) {
+	server := pwtest.NewServer(t)
+	node := server.AddNode(map[string]string{"node.name": "source"})
+	out := server.AddPort(node.ID, "out", nil)
+	in := server.AddPort(node.ID, "in", nil)
+
+	client, err := NewClient(server.Path(), verbose.NewLogger(verbose.LogLevelSilent, false))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	output := NewPort(out.ID, "out", PortDirectionOutput, nil, client)
+	input := NewPort(in.ID, "in", PortDirectionInput, nil, client)
+
+	link, err := client.CreateLinkCtx(ctx, output, input, nil)
+	if err != nil {
+		t.Fatalf("CreateLink failed: %v", err)
+	}
+	if leaked := client.leakedProxies(); len(leaked) != 0 {
+		t.Errorf("link proxy reported as leaked: %v", leaked)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.ClientCount() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("client not disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := server.Global(link.ID()); !ok {
+		t.Errorf("link %d removed with the client that created it", link.ID())
+	}
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core"
+	"github.com/vignemail1/pipewire-go/core/pwtest"
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+func TestLeakedProxies(t *testing.T) {
+	server := pwtest.NewServer(t)
+	node := server.AddNode(nil)
+
+	client, err := NewClient(server.Path(), verbose.NewLogger(verbose.LogLevelSilent, false))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	conn := client.GetConnection()
+	proxy := conn.AllocateProxy("node")
+	bind := core.NewMessageBuilder(conn.RegistryID(), uint32(core.RegistryMethodBind)).
+		WithPOD(core.NewArgsBuilder().Int(int32(node.ID)).String(core.TypeInterfaceNode).
+			Int(pwtest.NodeVersion).Int(int32(proxy.ID())).Build()).
+		Build()
+	if _, err := conn.Send(bind); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+
+	// The registry proxy belongs to the client and is not reported
+	if leaked := client.leakedProxies(); len(leaked) != 1 || leaked[0] != proxy {
+		t.Fatalf("got leaked proxies %v, want %s", leaked, proxy)
+	}
+
+	if err := proxy.Destroy(); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if leaked := client.leakedProxies(); len(leaked) != 0 {
+		t.Errorf("destroyed proxy reported as leaked: %v", leaked)
+	}
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+	if _, ok := conn.Proxy(proxy.ID()); ok {
+		t.Error("proxy id still allocated after the daemon confirmed")
+	}
+	if _, ok := server.Global(node.ID); !ok {
+		t.Error("destroying a bound proxy removed the global")
+	}
+}
@@ -0,0 +1,305 @@
+// Package client - reconnect.go
+// Opt-in reconnect with backoff and the connection-state event stream
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core"
+)
+
+// ConnectionState is the state reported on the connection-state stream
+type ConnectionState int
+
+const (
+	ConnectionStateConnecting ConnectionState = iota
+	ConnectionStateReady
+	ConnectionStateLost
+)
+
+// String returns the string representation of ConnectionState
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionStateConnecting:
+		return "Connecting"
+	case ConnectionStateReady:
+		return "Ready"
+	case ConnectionStateLost:
+		return "Lost"
+	default:
+		return "Unknown"
+	}
+}
+
+// ConnectionStateEvent is sent on the channels returned by ConnectionStates
+type ConnectionStateEvent struct {
+	State   ConnectionState
+	Attempt int   // Reconnect attempt, 0 for the connection made by NewClient
+	Err     error // Why the connection was lost or the attempt failed
+	Time    time.Time
+}
+
+// ReconnectPolicy controls how a client re-dials a daemon that went away
+type ReconnectPolicy struct {
+	InitialDelay time.Duration // Delay before the first attempt
+	MaxDelay     time.Duration // Upper bound of the delay between attempts
+	Multiplier   float64       // Delay growth factor per attempt
+	MaxAttempts  int           // 0 retries until the client is closed
+	ReadyTimeout time.Duration // Bound for the handshake and registry resync
+}
+
+// DefaultReconnectPolicy returns a policy retrying forever,
+// starting at 500ms and backing off up to 30s
+func DefaultReconnectPolicy() *ReconnectPolicy {
+	return &ReconnectPolicy{
+		InitialDelay: 500 * time.Millisecond,
+		MaxDelay:     30 * time.Second,
+		Multiplier:   2,
+		MaxAttempts:  0,
+		ReadyTimeout: 5 * time.Second,
+	}
+}
+
+// Delay returns the wait before the given attempt, starting at 1
+func (p *ReconnectPolicy) Delay(attempt int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay = time.Duration(float64(delay) * p.Multiplier)
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// SetReconnectPolicy enables reconnecting when the daemon goes away
+// A nil policy (the default) lets the client stop with the connection
+func (c *Client) SetReconnectPolicy(policy *ReconnectPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnect = policy
+}
+
+// ConnectionStates returns a channel receiving connection state changes
+// Each call returns a new subscription, closed when the client is closed
+// Events are dropped for subscribers that do not keep up
+func (c *Client) ConnectionStates() <-chan ConnectionStateEvent {
+	ch := make(chan ConnectionStateEvent, 16)
+
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.ctx.Err() != nil {
+		close(ch)
+		return ch
+	}
+	c.stateSubs = append(c.stateSubs, ch)
+	return ch
+}
+
+// publishState sends a state change to every subscriber
+func (c *Client) publishState(state ConnectionState, attempt int, err error) {
+	event := ConnectionStateEvent{State: state, Attempt: attempt, Err: err, Time: time.Now()}
+
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	for _, ch := range c.stateSubs {
+		select {
+		case ch <- event:
+		default:
+			c.logger.Warnf("Client: Connection state subscriber full, dropping %s", state)
+		}
+	}
+}
+
+// closeStates closes every state subscription
+func (c *Client) closeStates() {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	for _, ch := range c.stateSubs {
+		close(ch)
+	}
+	c.stateSubs = nil
+}
+
+// startEventLoop runs the protocol event loop of conn until it fails or the client is closed
+func (c *Client) startEventLoop(conn *core.Connection) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- conn.StartEventLoop(c.ctx)
+	}()
+	return done
+}
+
+// attach installs the protocol handlers of the client on a new connection
+// It runs for the first connection and again after every reconnect
+func (c *Client) attach(conn *core.Connection, handler *core.EventHandler) {
+	handler.RegisterHandler(conn.RegistryID(), c.handleRegistryMessage)
+	conn.SetEventHandler(handler)
+}
+
+// handleRegistryMessage feeds Registry.Global and Registry.GlobalRemove to the registry
+func (c *Client) handleRegistryMessage(msg *core.MessageFrame) error {
+	switch core.RegistryEventType(msg.MethodID) {
+	case core.RegistryEventTypeGlobal:
+		global, err := core.ParseRegistryGlobal(msg)
+		if err != nil {
+			return err
+		}
+		return c.registry.handleGlobal(global.ID, global.Type, global.Version, global.Props)
+
+	case core.RegistryEventTypeGlobalRemove:
+		id, err := core.ParseRegistryGlobalRemove(msg)
+		if err != nil {
+			return err
+		}
+		c.registry.handleGlobalRemove(id)
+	}
+	return nil
+}
+
+// forwardRegistryEvent turns registry events into application events for
+// the listeners registered with RegisterEventListener
+func (c *Client) forwardRegistryEvent(event RegistryEvent) {
+	action := "added"
+	if event.Type == RegistryEventTypeGlobalRemove {
+		action = "removed"
+	}
+
+	appEvent := &ApplicationEvent{
+		Type:       eventTypeForGlobal(event.Object.Type),
+		ObjectID:   event.ObjectID,
+		ObjectType: event.Object.Type,
+		Data: map[string]interface{}{
+			"action":     action,
+			"synthetic":  event.Synthetic,
+			"properties": event.Object.Properties,
+		},
+	}
+	if err := c.dispatcher.Dispatch(appEvent); err != nil {
+		c.logger.Warnf("Client: Registry event dispatch failed: %v", err)
+	}
+}
+
+// eventTypeForGlobal maps a global interface type to an application event type
+func eventTypeForGlobal(objType string) EventType {
+	switch {
+	case strings.HasSuffix(objType, "Node"):
+		return EventTypeNode
+	case strings.HasSuffix(objType, "Port"):
+		return EventTypePort
+	case strings.HasSuffix(objType, "Link"):
+		return EventTypeLink
+	default:
+		return EventTypeRegistry
+	}
+}
+
+// run watches the event loop of conn and reconnects according to the
+// reconnect policy when it stops while the client is still open
+func (c *Client) run(loopDone <-chan error) {
+	for {
+		err := <-loopDone
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		c.logger.Warnf("Client: Connection lost: %v", err)
+		c.GetConnection().Close()
+		c.publishState(ConnectionStateLost, 0, err)
+
+		c.mu.RLock()
+		policy := c.reconnect
+		c.mu.RUnlock()
+		if policy == nil {
+			return
+		}
+
+		if loopDone = c.redial(policy); loopDone == nil {
+			return
+		}
+	}
+}
+
+// redial tries to connect again until it succeeds, the policy gives up
+// or the client is closed; returns the event loop of the new connection
+func (c *Client) redial(policy *ReconnectPolicy) <-chan error {
+	for attempt := 1; policy.MaxAttempts == 0 || attempt <= policy.MaxAttempts; attempt++ {
+		c.publishState(ConnectionStateConnecting, attempt, nil)
+
+		select {
+		case <-time.After(policy.Delay(attempt)):
+		case <-c.ctx.Done():
+			return nil
+		}
+
+		loopDone, err := c.reconnectOnce(policy)
+		if err == nil {
+			c.publishState(ConnectionStateReady, attempt, nil)
+			return loopDone
+		}
+		if c.ctx.Err() != nil {
+			return nil
+		}
+
+		c.logger.Warnf("Client: Reconnect attempt %d failed: %v", attempt, err)
+		c.publishState(ConnectionStateLost, attempt, err)
+	}
+
+	c.logger.Errorf("Client: Giving up after %d reconnect attempts", policy.MaxAttempts)
+	return nil
+}
+
+// reconnectOnce dials the daemon, redoes the handshake and resyncs the registry
+func (c *Client) reconnectOnce(policy *ReconnectPolicy) (<-chan error, error) {
+	conn, err := core.Dial(c.socketPath, c.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the limits set on the first connection
+	c.mu.RLock()
+	conn.SetLimits(c.connection.Limits())
+	c.mu.RUnlock()
+
+	// Globals of the new connection are compared with the known ones
+	c.registry.beginResync()
+	handler := core.NewEventHandler()
+	c.attach(conn, handler)
+	loopDone := c.startEventLoop(conn)
+
+	ctx, cancel := context.WithTimeout(c.ctx, policy.ReadyTimeout)
+	defer cancel()
+
+	fail := func(err error) (<-chan error, error) {
+		c.registry.cancelResync()
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.WaitUntilReady(ctx); err != nil {
+		return fail(fmt.Errorf("connection not ready: %w", err))
+	}
+	// Every global is announced before the Done of this round trip
+	if err := conn.Roundtrip(ctx); err != nil {
+		return fail(fmt.Errorf("registry resync: %w", err))
+	}
+
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		return fail(c.ctx.Err())
+	}
+	c.connection = conn
+	c.registryID = conn.RegistryID()
+	c.eventHandler = handler
+	c.core.setConnection(conn)
+	c.registry.setConnection(conn.RegistryID(), conn)
+	c.mu.Unlock()
+
+	removed, added := c.registry.finishResync()
+	c.logger.Infof("Client: Reconnected, registry resynced (%d removed, %d added)", removed, added)
+	return loopDone, nil
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+func TestReconnectPolicyDelay(t *testing.T) {
+	policy := &ReconnectPolicy{
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   2,
+	}
+
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		if got := policy.Delay(i + 1); got != w {
+			t.Errorf("attempt %d: got %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestRegistryResync(t *testing.T) {
+	registry := newRegistry(2, nil, verbose.NewLogger(verbose.LogLevelError, false))
+	registry.handleGlobal(30, "PipeWire:Interface:Node", 3, map[string]string{"node.name": "kept"})
+	registry.handleGlobal(31, "PipeWire:Interface:Node", 3, map[string]string{"node.name": "gone"})
+	registry.handleGlobal(32, "PipeWire:Interface:Port", 3, map[string]string{"port.name": "old"})
+
+	var events []RegistryEvent
+	registry.AddListener(func(event RegistryEvent) {
+		events = append(events, event)
+	})
+
+	// The restarted daemon reuses id 32 for another object
+	registry.beginResync()
+	registry.handleGlobal(30, "PipeWire:Interface:Node", 3, map[string]string{"node.name": "kept"})
+	registry.handleGlobal(32, "PipeWire:Interface:Link", 3, nil)
+	registry.handleGlobal(33, "PipeWire:Interface:Node", 3, map[string]string{"node.name": "new"})
+	if len(events) != 0 {
+		t.Fatalf("events emitted while resyncing: %v", events)
+	}
+
+	removed, added := registry.finishResync()
+	if removed != 2 || added != 2 {
+		t.Fatalf("got %d removed, %d added, want 2 and 2", removed, added)
+	}
+
+	seen := make(map[uint32][]RegistryEventType)
+	for i, event := range events {
+		if !event.Synthetic {
+			t.Errorf("event %d not marked synthetic", i)
+		}
+		if i < removed && event.Type != RegistryEventTypeGlobalRemove {
+			t.Errorf("event %d: removals must come first", i)
+		}
+		seen[event.ObjectID] = append(seen[event.ObjectID], event.Type)
+	}
+	if _, ok := seen[30]; ok {
+		t.Error("unchanged global 30 reported")
+	}
+	if len(seen[31]) != 1 || len(seen[32]) != 2 || len(seen[33]) != 1 {
+		t.Errorf("unexpected events: %v", seen)
+	}
+	if obj, ok := registry.GetObject(32); !ok || obj.Type != "PipeWire:Interface:Link" {
+		t.Errorf("registry not replaced: %+v", obj)
+	}
+	if registry.Count() != 3 {
+		t.Errorf("got %d objects, want 3", registry.Count())
+	}
+}
@@ -0,0 +1,130 @@
+// Package client - stats.go
+// Request and dispatch metrics, gathered by Client.Stats
+
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core"
+)
+
+// ClientStats is a snapshot of the metrics of a client
+type ClientStats struct {
+	Connection core.ConnectionStats // Traffic of the current connection
+	Requests   ProtocolClientStats  // Requests made on the current connection
+	Dispatcher DispatcherStats      // Application event delivery
+}
+
+// ProtocolClientStats is a snapshot of the requests made by a ProtocolClient
+type ProtocolClientStats struct {
+	Requests  uint64 // Requests sent
+	Succeeded uint64
+	Failed    uint64 // Rejected by the daemon or not sent
+	TimedOut  uint64 // Request timeout expired
+	Cancelled uint64 // Caller context done first
+
+	// Time until the daemon answered, with a result or an error
+	Latency core.Histogram
+
+	// Requests waiting for an answer on the connection, now and at most
+	Pending     int
+	PeakPending int
+}
+
+// requestStats accumulates the counters of a ProtocolClient
+type requestStats struct {
+	mu          sync.Mutex
+	requests    uint64
+	succeeded   uint64
+	failures    uint64
+	timeouts    uint64
+	cancels     uint64
+	peakPending int
+	latency     *core.LatencyHistogram
+}
+
+// newRequestStats creates empty counters
+func newRequestStats() *requestStats {
+	return &requestStats{latency: core.NewLatencyHistogram()}
+}
+
+// sent counts a request, pending includes it
+func (s *requestStats) sent(pending int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if pending > s.peakPending {
+		s.peakPending = pending
+	}
+}
+
+// answered counts a request the daemon answered after d
+func (s *requestStats) answered(d time.Duration, failed bool) {
+	s.latency.Observe(d)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if failed {
+		s.failures++
+	} else {
+		s.succeeded++
+	}
+}
+
+// failed counts a request that could not be sent
+func (s *requestStats) failed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+}
+
+// timedOut counts a request whose timeout expired
+func (s *requestStats) timedOut() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeouts++
+}
+
+// cancelled counts a request abandoned by its caller
+func (s *requestStats) cancelled() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancels++
+}
+
+// snapshot returns the counters with the current pending count
+func (s *requestStats) snapshot(pending int) ProtocolClientStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ProtocolClientStats{
+		Requests:    s.requests,
+		Succeeded:   s.succeeded,
+		Failed:      s.failures,
+		TimedOut:    s.timeouts,
+		Cancelled:   s.cancels,
+		Latency:     s.latency.Snapshot(),
+		Pending:     pending,
+		PeakPending: s.peakPending,
+	}
+}
+
+// Stats returns a snapshot of the client metrics
+// Connection and request counters restart when a reconnect replaces the connection
+func (c *Client) Stats() ClientStats {
+	c.mu.RLock()
+	conn, protocol, dispatcher := c.connection, c.protocol, c.dispatcher
+	c.mu.RUnlock()
+
+	var stats ClientStats
+	if conn != nil {
+		stats.Connection = conn.Stats()
+	}
+	if protocol != nil && protocol.GetConnection() == conn {
+		stats.Requests = protocol.Stats()
+	}
+	if dispatcher != nil {
+		stats.Dispatcher = dispatcher.Stats()
+	}
+	return stats
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core/pwtest"
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+func TestDispatcherStats(t *testing.T) {
+	ed := NewEventDispatcher()
+	capacity := cap(ed.eventQueue)
+
+	// Nothing drains the queue until Start
+	for i := 0; i <= capacity; i++ {
+		ed.Dispatch(&ApplicationEvent{Type: EventTypeNode, ObjectID: uint32(i)})
+	}
+	stats := ed.Stats()
+	if stats.QueueDepth != capacity || stats.PeakQueueDepth != capacity || stats.Dropped != 1 {
+		t.Fatalf("unexpected stats with a full queue: %+v", stats)
+	}
+
+	if err := ed.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer ed.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for ed.Stats().Dispatched != uint64(capacity) {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue not drained: %+v", ed.Stats())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stats := ed.Stats(); stats.QueueDepth != 0 || stats.PeakQueueDepth != capacity {
+		t.Errorf("unexpected stats after draining: %+v", stats)
+	}
+}
+
+func TestClientStats(t *testing.T) {
+	server := pwtest.NewServer(t)
+	client, err := NewClient(server.Path(), verbose.NewLogger(verbose.LogLevelSilent, false))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+
+	// A hung daemon never answers
+	server.Hang()
+	defer server.Resume()
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer reqCancel()
+	if err := client.protocolClient().DestroyLinkCtx(reqCtx, 99); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	stats := client.Stats()
+	if stats.Connection.In.Frames == 0 || stats.Connection.Out.Frames == 0 || stats.Connection.Roundtrip.Count == 0 {
+		t.Errorf("connection traffic not counted: %+v", stats.Connection)
+	}
+	req := stats.Requests
+	if req.Requests != 1 || req.Cancelled != 1 || req.Pending != 0 || req.PeakPending != 1 || req.Latency.Count != 0 {
+		t.Errorf("unexpected request stats %+v", req)
+	}
+}
@@ -3,7 +3,7 @@
 
 package client
 
-import "fmt"
+import "github.com/vignemail1/pipewire-go/core"
 
 // NodeState represents the current state of a node
 type NodeState string
@@ -24,63 +24,6 @@ const (
 	NodeDirectionDuplex   NodeDirection = "duplex"
 )
 
-// PortDirection indicates whether a port is input or output
-type PortDirection uint32
-
-const (
-	PortDirectionInput PortDirection = iota
-	PortDirectionOutput
-)
-
-func (pd PortDirection) String() string {
-	switch pd {
-	case PortDirectionInput:
-		return "Input"
-	case PortDirectionOutput:
-		return "Output"
-	default:
-		return "Unknown"
-	}
-}
-
-// PortType indicates the type of data flowing through a port
-type PortType uint32
-
-const (
-	PortTypeAudio PortType = iota
-	PortTypeMidi
-	PortTypeVideo
-	PortTypeControl
-)
-
-func (pt PortType) String() string {
-	switch pt {
-	case PortTypeAudio:
-		return "Audio"
-	case PortTypeMidi:
-		return "Midi"
-	case PortTypeVideo:
-		return "Video"
-	case PortTypeControl:
-		return "Control"
-	default:
-		return "Unknown"
-	}
-}
-
-// AudioFormat describes an audio stream format
-type AudioFormat struct {
-	Format      string // "S16LE", "S32LE", "F32LE", etc.
-	SampleRate  uint32 // 44100, 48000, 96000, etc.
-	Channels    uint32 // 1, 2, 6, 8, etc.
-	ChannelMask string // "FL,FR" for stereo, "FL,FR,FC,LFE,SL,SR" for 5.1
-}
-
-// String returns a human-readable format description
-func (af *AudioFormat) String() string {
-	return fmt.Sprintf("%s@%dHz %dch (%s)", af.Format, af.SampleRate, af.Channels, af.ChannelMask)
-}
-
 // MediaClass describes the class/category of a node
 type MediaClass string
 
@@ -101,38 +44,27 @@ const (
 	MediaClassStreamAudioCapture  MediaClass = "Stream/Audio/Capture"
 )
 
-// RegistryListener is a callback for registry events
-type RegistryListener func(*GlobalObject)
-
-// GlobalObject represents an object in the PipeWire registry
-type GlobalObject struct {
-	ID         uint32
-	Type       string // "Node", "Port", "Link", "Endpoint", etc.
-	Version    uint32
-	Properties map[string]string
-}
-
 // GetProperty retrieves a property value with a default fallback
-func (go *GlobalObject) GetProperty(key string, defaultVal string) string {
-	if val, ok := go.Properties[key]; ok {
+func (g *GlobalObject) GetProperty(key string, defaultVal string) string {
+	if val, ok := g.Properties[key]; ok {
 		return val
 	}
 	return defaultVal
 }
 
 // IsNode checks if this object is a Node
-func (go *GlobalObject) IsNode() bool {
-	return go.Type == "Node" || go.Type == "pw.Node"
+func (g *GlobalObject) IsNode() bool {
+	return g.Type == core.TypeInterfaceNode
 }
 
 // IsPort checks if this object is a Port
-func (go *GlobalObject) IsPort() bool {
-	return go.Type == "Port" || go.Type == "pw.Port"
+func (g *GlobalObject) IsPort() bool {
+	return g.Type == core.TypeInterfacePort
 }
 
 // IsLink checks if this object is a Link
-func (go *GlobalObject) IsLink() bool {
-	return go.Type == "Link" || go.Type == "pw.Link"
+func (g *GlobalObject) IsLink() bool {
+	return g.Type == core.TypeInterfaceLink
 }
 
 // NodeInfo contains detailed information about a node
@@ -152,51 +84,6 @@ type NodeInfo struct {
 	Channels    uint32
 }
 
-// PortInfo contains detailed information about a port
-type PortInfo struct {
-	ID         uint32
-	NodeID     uint32
-	Type       string
-	Version    uint32
-	Direction  PortDirection
-	Properties map[string]string
-	
-	// Parsed properties
-	Name   string
-	Format PortType
-}
-
-// LinkInfo contains detailed information about a link
-type LinkInfo struct {
-	ID         uint32
-	OutputNode uint32
-	OutputPort uint32
-	InputNode  uint32
-	InputPort  uint32
-	Type       string
-	Version    uint32
-	Properties map[string]string
-	
-	// Parsed properties
-	Format    *AudioFormat
-	IsPassive bool
-}
-
-// EventType represents different event types in PipeWire
-type EventType uint32
-
-const (
-	EventNodeAdded EventType = iota
-	EventNodeRemoved
-	EventPortAdded
-	EventPortRemoved
-	EventLinkAdded
-	EventLinkRemoved
-	EventNodeUpdated
-	EventPortUpdated
-	EventLinkUpdated
-)
-
 // CoreInfo contains information about the Core object
 type CoreInfo struct {
 	ID      uint32
@@ -14,6 +14,7 @@ import (
 func main() {
 	var (
 		disconnect = flag.Bool("disconnect", false, "Disconnect (remove link)")
+		remote     = flag.String("remote", "", "Remote name or socket path (default: $PIPEWIRE_REMOTE or pipewire-0)")
 	)
 	flag.Parse()
 
@@ -37,7 +38,7 @@ func main() {
 	}
 
 	// Connect to PipeWire
-	c, err := client.NewClient("pw-connect")
+	c, err := client.NewClient(*remote, nil)
 	if err != nil {
 		log.Fatalf("Failed to connect to PipeWire: %v", err)
 	}
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 
@@ -9,7 +10,12 @@ import (
 	"github.com/vignemail1/pipewire-go/client"
 )
 
+// remote is the PipeWire remote given with --remote
+var remote = flag.String("remote", "", "Remote name or socket path (default: $PIPEWIRE_REMOTE or pipewire-0)")
+
 func main() {
+	flag.Parse()
+
 	// Initialize GTK
 	app := gtk.NewApplication("", 0)
 
@@ -25,7 +31,7 @@ func main() {
 
 func onActivate(app *gtk.Application) {
 	// Connect to PipeWire
-	c, err := client.NewClient("pw-gui")
+	c, err := client.NewClient(*remote, nil)
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
@@ -15,6 +15,7 @@ import (
 func main() {
 	var (
 		asJSON = flag.Bool("json", false, "Output as JSON")
+		remote = flag.String("remote", "", "Remote name or socket path (default: $PIPEWIRE_REMOTE or pipewire-0)")
 	)
 	flag.Parse()
 
@@ -33,7 +34,7 @@ func main() {
 	}
 
 	// Connect to PipeWire
-	c, err := client.NewClient("pw-info")
+	c, err := client.NewClient(*remote, nil)
 	if err != nil {
 		log.Fatalf("Failed to connect to PipeWire: %v", err)
 	}
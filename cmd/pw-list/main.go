@@ -2,12 +2,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/vignemail1/pipewire-go/client"
 )
@@ -21,16 +23,24 @@ func main() {
 		asJSON      = flag.Bool("json", false, "Output as JSON")
 		filter      = flag.String("filter", "", "Filter by name (contains)")
 		showProps   = flag.Bool("props", false, "Show properties")
+		remote      = flag.String("remote", "", "Remote name or socket path (default: $PIPEWIRE_REMOTE or pipewire-0)")
 	)
 	flag.Parse()
 
 	// Connect to PipeWire
-	c, err := client.NewClient("pw-list")
+	c, err := client.NewClient(*remote, nil)
 	if err != nil {
 		log.Fatalf("Failed to connect to PipeWire: %v", err)
 	}
 	defer c.Disconnect()
 
+	// Wait until the initial registry burst has been received
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Roundtrip(ctx); err != nil {
+		log.Fatalf("Failed to sync with PipeWire: %v", err)
+	}
+
 	registry := c.GetRegistry()
 
 	// Determine what to list
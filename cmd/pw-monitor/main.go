@@ -13,11 +13,12 @@ import (
 func main() {
 	var (
 		followEvents = flag.Bool("follow", false, "Keep monitoring (Ctrl+C to stop)")
+		remote       = flag.String("remote", "", "Remote name or socket path (default: $PIPEWIRE_REMOTE or pipewire-0)")
 	)
 	flag.Parse()
 
 	// Connect to PipeWire
-	c, err := client.NewClient("pw-monitor")
+	c, err := client.NewClient(*remote, nil)
 	if err != nil {
 		log.Fatalf("Failed to connect to PipeWire: %v", err)
 	}
@@ -41,7 +41,7 @@ type Config struct {
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		SocketPath:       "/run/pipewire-0",
+		SocketPath:       "", // Resolved like --remote when empty
 		ConnectTimeout:   5000, // milliseconds
 		RefreshInterval:  500,  // milliseconds
 		DefaultView:      "graph",
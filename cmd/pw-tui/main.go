@@ -2,6 +2,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 
@@ -10,6 +11,9 @@ import (
 )
 
 func main() {
+	remote := flag.String("remote", "", "Remote name or socket path (default: $PIPEWIRE_REMOTE or pipewire-0)")
+	flag.Parse()
+
 	// Create TUI app
 	app, err := NewApp()
 	if err != nil {
@@ -18,7 +22,7 @@ func main() {
 	defer app.Stop()
 
 	// Connect to PipeWire
-	c, err := client.NewClient("pw-tui")
+	c, err := client.NewClient(*remote, nil)
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
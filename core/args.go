@@ -0,0 +1,403 @@
+// Package core - Method and event argument encoding
+// core/args.go
+// Arguments travel as a SPA Struct POD; these helpers build and walk them
+
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// ArgsBuilder encodes method arguments into a Struct POD
+// Nested structs are opened with BeginStruct and closed with EndStruct
+type ArgsBuilder struct {
+	structs []*spa.PODStruct // Open structs, the argument struct first
+}
+
+// NewArgsBuilder creates a builder with the outer argument struct open
+func NewArgsBuilder() *ArgsBuilder {
+	return &ArgsBuilder{structs: []*spa.PODStruct{spa.NewPODStruct()}}
+}
+
+// add appends v to the innermost open struct
+func (b *ArgsBuilder) add(v spa.PODValue) *ArgsBuilder {
+	b.structs[len(b.structs)-1].Append(v)
+	return b
+}
+
+// None appends a None POD (a NULL string or object on the wire)
+func (b *ArgsBuilder) None() *ArgsBuilder {
+	return b.add(spa.NewPODNone())
+}
+
+// Bool appends a Bool POD
+func (b *ArgsBuilder) Bool(v bool) *ArgsBuilder {
+	return b.add(spa.NewPODBool(v))
+}
+
+// ID appends an Id POD
+func (b *ArgsBuilder) ID(v uint32) *ArgsBuilder {
+	return b.add(spa.NewPODId(v))
+}
+
+// Int appends an Int POD
+func (b *ArgsBuilder) Int(v int32) *ArgsBuilder {
+	return b.add(spa.NewPODInt32(v))
+}
+
+// Long appends a Long POD
+func (b *ArgsBuilder) Long(v int64) *ArgsBuilder {
+	return b.add(spa.NewPODInt64(v))
+}
+
+// Float appends a Float POD
+func (b *ArgsBuilder) Float(v float32) *ArgsBuilder {
+	return b.add(spa.NewPODFloat(v))
+}
+
+// Double appends a Double POD
+func (b *ArgsBuilder) Double(v float64) *ArgsBuilder {
+	return b.add(spa.NewPODDouble(v))
+}
+
+// String appends a NUL-terminated String POD
+func (b *ArgsBuilder) String(v string) *ArgsBuilder {
+	return b.add(spa.NewPODString(v))
+}
+
+// Bytes appends a Bytes POD
+func (b *ArgsBuilder) Bytes(v []byte) *ArgsBuilder {
+	return b.add(spa.NewPODBytes(v))
+}
+
+// Fd appends an Fd POD holding an index into the message fd table
+func (b *ArgsBuilder) Fd(index int64) *ArgsBuilder {
+	return b.add(spa.NewPODFd(index))
+}
+
+// Dict appends a spa_dict: Struct{Int n_items, (String key, String value)*}
+// Keys are written in sorted order so the encoding is deterministic
+func (b *ArgsBuilder) Dict(props map[string]string) *ArgsBuilder {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b.BeginStruct().Int(int32(len(keys)))
+	for _, k := range keys {
+		b.String(k).String(props[k])
+	}
+	return b.EndStruct()
+}
+
+// IDArray appends an Array POD of Id values
+func (b *ArgsBuilder) IDArray(ids []uint32) *ArgsBuilder {
+	arr := &spa.PODArray{ChildType: spa.PODTypeID, Values: make([]spa.PODValue, 0, len(ids))}
+	for _, id := range ids {
+		arr.Values = append(arr.Values, spa.NewPODId(id))
+	}
+	return b.add(arr)
+}
+
+// Raw appends an already encoded POD
+func (b *ArgsBuilder) Raw(pod []byte) *ArgsBuilder {
+	return b.add(spa.NewPODRaw(pod))
+}
+
+// BeginStruct opens a nested Struct POD
+func (b *ArgsBuilder) BeginStruct() *ArgsBuilder {
+	st := spa.NewPODStruct()
+	b.add(st)
+	b.structs = append(b.structs, st)
+	return b
+}
+
+// EndStruct closes the innermost open Struct POD
+func (b *ArgsBuilder) EndStruct() *ArgsBuilder {
+	if len(b.structs) > 1 {
+		b.structs = b.structs[:len(b.structs)-1]
+	}
+	return b
+}
+
+// Encode returns the encoded arguments, closing any open structs
+func (b *ArgsBuilder) Encode() []byte {
+	data, err := b.Build().Marshal()
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Build returns the argument struct for MessageFrame.PODData, closing any
+// open structs
+func (b *ArgsBuilder) Build() spa.PODValue {
+	b.structs = b.structs[:1]
+	return b.structs[0]
+}
+
+// ArgsReader walks the fields of an argument Struct POD in order
+type ArgsReader struct {
+	fields spa.PODIterator
+	size   int // Struct body size, bounding the item counts it can hold
+	fds    spa.FDTable
+}
+
+// NewArgsReader returns a reader over the arguments of a message
+func NewArgsReader(msg *MessageFrame) (*ArgsReader, error) {
+	if msg == nil || msg.PODData == nil {
+		return nil, fmt.Errorf("message has no arguments")
+	}
+	data, err := msg.PODData.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	r, err := newArgsReader(data)
+	if err != nil {
+		return nil, err
+	}
+	r.fds = msg.FDs
+	return r, nil
+}
+
+// newArgsReader returns a reader over the Struct POD at the start of data
+func newArgsReader(data []byte) (*ArgsReader, error) {
+	pod, err := spa.NewPODReader(data)
+	if err != nil {
+		return nil, err
+	}
+	if pod.Type() != spa.PODTypeStruct {
+		return nil, fmt.Errorf("arguments are not a struct (type %d)", pod.Type())
+	}
+	return structReader(pod, nil)
+}
+
+// structReader returns a reader over the fields of the Struct pod
+func structReader(pod spa.PODReader, fds spa.FDTable) (*ArgsReader, error) {
+	fields, err := pod.Fields()
+	if err != nil {
+		return nil, err
+	}
+	return &ArgsReader{fields: fields, size: len(pod.Body()), fds: fds}, nil
+}
+
+// More reports whether unread fields remain
+func (r *ArgsReader) More() bool {
+	return r.fields.More()
+}
+
+// next consumes the next field
+func (r *ArgsReader) next() (spa.PODReader, error) {
+	if !r.fields.Next() {
+		if err := r.fields.Err(); err != nil {
+			return spa.PODReader{}, err
+		}
+		return spa.PODReader{}, fmt.Errorf("missing argument")
+	}
+	return r.fields.POD(), nil
+}
+
+// Skip consumes the next field without decoding it
+func (r *ArgsReader) Skip() error {
+	_, err := r.next()
+	return err
+}
+
+// Bool reads a Bool field
+func (r *ArgsReader) Bool() (bool, error) {
+	pod, err := r.next()
+	if err != nil {
+		return false, err
+	}
+	return pod.GetBool()
+}
+
+// ID reads an Id field
+func (r *ArgsReader) ID() (uint32, error) {
+	pod, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	return pod.GetID()
+}
+
+// Int reads an Int field
+func (r *ArgsReader) Int() (int32, error) {
+	pod, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	return pod.GetInt()
+}
+
+// Uint reads an Int field carrying an unsigned value (ids, flags)
+func (r *ArgsReader) Uint() (uint32, error) {
+	v, err := r.Int()
+	return uint32(v), err
+}
+
+// Long reads a Long field
+func (r *ArgsReader) Long() (int64, error) {
+	pod, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	return pod.GetLong()
+}
+
+// Float reads a Float field
+func (r *ArgsReader) Float() (float32, error) {
+	pod, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	return pod.GetFloat()
+}
+
+// Double reads a Double field
+func (r *ArgsReader) Double() (float64, error) {
+	pod, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	return pod.GetDouble()
+}
+
+// String reads a String field; a None field reads as ""
+func (r *ArgsReader) String() (string, error) {
+	pod, err := r.next()
+	if err != nil {
+		return "", err
+	}
+	if pod.Type() == spa.PODTypeNone {
+		return "", nil
+	}
+	return pod.GetString()
+}
+
+// Bytes reads a Bytes field
+func (r *ArgsReader) Bytes() ([]byte, error) {
+	pod, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	body, err := pod.GetBytes()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, body...), nil
+}
+
+// Fd reads an Fd field and resolves it through the message fd table
+// The fd stays owned by the message and is closed once it is dispatched
+func (r *ArgsReader) Fd() (int, error) {
+	_, fd, err := r.fd()
+	return fd, err
+}
+
+// TakeFd reads an Fd field like Fd and takes ownership of the fd, which
+// the caller closes
+func (r *ArgsReader) TakeFd() (int, error) {
+	index, fd, err := r.fd()
+	if err != nil || fd < 0 {
+		return fd, err
+	}
+	r.fds[index] = -1
+	return fd, nil
+}
+
+// fd reads an Fd field, returning its table index and fd
+func (r *ArgsReader) fd() (int64, int, error) {
+	pod, err := r.next()
+	if err != nil {
+		return -1, -1, err
+	}
+	index, err := pod.GetFd()
+	if err != nil {
+		return -1, -1, err
+	}
+	fd, err := r.fds.Get(index)
+	return index, fd, err
+}
+
+// Struct reads a nested Struct field and returns a reader over it
+func (r *ArgsReader) Struct() (*ArgsReader, error) {
+	pod, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	return structReader(pod, r.fds)
+}
+
+// IDArray reads an Array field of Id values
+func (r *ArgsReader) IDArray() ([]uint32, error) {
+	pod, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	childType, elems, err := pod.Array()
+	if err != nil {
+		return nil, err
+	}
+	if childType != spa.PODTypeID {
+		return nil, fmt.Errorf("expected array of Id, got child type %d", childType)
+	}
+	ids := make([]uint32, 0)
+	for elems.Next() {
+		elem := elems.POD()
+		if len(elem.Body()) != 4 {
+			return nil, fmt.Errorf("expected array of Id, got child size %d", len(elem.Body()))
+		}
+		id, _ := elem.GetID()
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Raw reads the next field and returns its complete encoding
+func (r *ArgsReader) Raw() ([]byte, error) {
+	pod, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	body := pod.Body()
+	data := make([]byte, 0, spa.AlignOffset(podHeaderSize+len(body)))
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(body)))
+	data = binary.LittleEndian.AppendUint32(data, pod.Type())
+	data = append(data, body...)
+	return append(data, make([]byte, spa.AlignPadding(len(data)))...), nil
+}
+
+// Dict reads a spa_dict encoded as Struct{Int n_items, (String key, String value)*}
+func (r *ArgsReader) Dict() (map[string]string, error) {
+	s, err := r.Struct()
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.Int()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || int(n) > s.size/podHeaderSize {
+		return nil, fmt.Errorf("invalid dict item count %d", n)
+	}
+
+	props := make(map[string]string, n)
+	for i := int32(0); i < n; i++ {
+		k, err := s.String()
+		if err != nil {
+			return nil, fmt.Errorf("dict key %d: %w", i, err)
+		}
+		v, err := s.String()
+		if err != nil {
+			return nil, fmt.Errorf("dict value %q: %w", k, err)
+		}
+		props[k] = v
+	}
+	return props, nil
+}
@@ -0,0 +1,249 @@
+// Package core - Protocol capture
+// core/capture.go
+// Records the frames sent and received on a Connection to a compact file
+//
+// File layout (little endian):
+//
+//	magic   "PWCAP" 0x00, version uint16
+//	record* timestamp int64 (unix ns), direction uint8, 3 bytes reserved,
+//	        n_fds uint32, frame size uint32, frame (header + payload)
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// CaptureVersion is the version of the capture file format
+const CaptureVersion = 1
+
+// captureMagic starts every capture file
+var captureMagic = []byte("PWCAP\x00")
+
+// captureRecordHeaderSize is the size of the fixed part of a record
+const captureRecordHeaderSize = 20
+
+// CaptureDirection tells who sent a captured frame
+type CaptureDirection uint8
+
+const (
+	CaptureSent     CaptureDirection = 0 // Client to daemon
+	CaptureReceived CaptureDirection = 1 // Daemon to client
+)
+
+// String returns the string representation of CaptureDirection
+func (d CaptureDirection) String() string {
+	switch d {
+	case CaptureSent:
+		return "sent"
+	case CaptureReceived:
+		return "received"
+	default:
+		return "unknown"
+	}
+}
+
+// CaptureRecord is one frame of a capture
+type CaptureRecord struct {
+	Time      time.Time
+	Direction CaptureDirection
+	NumFDs    uint32 // Descriptors passed with the frame, not captured themselves
+	Frame     []byte // Header followed by the payload
+}
+
+// Header returns the decoded native protocol header of the frame
+func (r *CaptureRecord) Header() (objectID, opcode uint32, size int, sequence, numFDs uint32) {
+	if len(r.Frame) < HeaderSize {
+		return 0, 0, 0, 0, 0
+	}
+	return parseHeader(r.Frame)
+}
+
+// Payload returns the POD data and footer of the frame
+func (r *CaptureRecord) Payload() []byte {
+	if len(r.Frame) < HeaderSize {
+		return nil
+	}
+	return r.Frame[HeaderSize:]
+}
+
+// Message decodes the frame
+func (r *CaptureRecord) Message() (*MessageFrame, error) {
+	msg := &MessageFrame{}
+	if err := msg.Unmarshal(r.Frame); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// String returns a one-line summary of the record
+func (r *CaptureRecord) String() string {
+	id, opcode, size, seq, fds := r.Header()
+	return fmt.Sprintf("%s %s id=%d opcode=%d seq=%d size=%d fds=%d",
+		r.Time.Format(time.RFC3339Nano), r.Direction, id, opcode, seq, size, fds)
+}
+
+// CaptureWriter writes capture records, safe for concurrent use
+type CaptureWriter struct {
+	mu  sync.Mutex
+	w   *bufio.Writer
+	err error
+}
+
+// NewCaptureWriter writes the file header to w and returns a writer for the records
+// Records are buffered; call Flush before closing w
+func NewCaptureWriter(w io.Writer) (*CaptureWriter, error) {
+	bw := bufio.NewWriter(w)
+	header := make([]byte, len(captureMagic)+2)
+	copy(header, captureMagic)
+	binary.LittleEndian.PutUint16(header[len(captureMagic):], CaptureVersion)
+	if _, err := bw.Write(header); err != nil {
+		return nil, err
+	}
+	return &CaptureWriter{w: bw}, nil
+}
+
+// WriteRecord appends a record
+// After the first error every call returns it, so a failing disk does
+// not disturb the connection being captured
+func (cw *CaptureWriter) WriteRecord(rec *CaptureRecord) error {
+	var header [captureRecordHeaderSize]byte
+	binary.LittleEndian.PutUint64(header[0:8], uint64(rec.Time.UnixNano()))
+	header[8] = byte(rec.Direction)
+	binary.LittleEndian.PutUint32(header[12:16], rec.NumFDs)
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(rec.Frame)))
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.err != nil {
+		return cw.err
+	}
+	if _, err := cw.w.Write(header[:]); err != nil {
+		cw.err = err
+		return err
+	}
+	if _, err := cw.w.Write(rec.Frame); err != nil {
+		cw.err = err
+		return err
+	}
+	return nil
+}
+
+// Flush writes buffered records to the underlying writer
+func (cw *CaptureWriter) Flush() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.err != nil {
+		return cw.err
+	}
+	cw.err = cw.w.Flush()
+	return cw.err
+}
+
+// CaptureReader reads the records of a capture file
+type CaptureReader struct {
+	r *bufio.Reader
+}
+
+// NewCaptureReader checks the file header of r and returns a reader for the records
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(captureMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("capture header: %w", err)
+	}
+	if !bytes.Equal(header[:len(captureMagic)], captureMagic) {
+		return nil, fmt.Errorf("not a capture file")
+	}
+	if version := binary.LittleEndian.Uint16(header[len(captureMagic):]); version != CaptureVersion {
+		return nil, fmt.Errorf("unsupported capture version %d", version)
+	}
+	return &CaptureReader{r: br}, nil
+}
+
+// Next returns the next record, io.EOF after the last one
+func (cr *CaptureReader) Next() (*CaptureRecord, error) {
+	var header [captureRecordHeaderSize]byte
+	if _, err := io.ReadFull(cr.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("truncated capture record")
+		}
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[16:20])
+	if size < HeaderSize || size > HeaderSize+MaxMessageSize {
+		return nil, fmt.Errorf("invalid capture record size %d", size)
+	}
+
+	rec := &CaptureRecord{
+		Time:      time.Unix(0, int64(binary.LittleEndian.Uint64(header[0:8]))),
+		Direction: CaptureDirection(header[8]),
+		NumFDs:    binary.LittleEndian.Uint32(header[12:16]),
+		Frame:     make([]byte, size),
+	}
+	if _, err := io.ReadFull(cr.r, rec.Frame); err != nil {
+		return nil, fmt.Errorf("truncated capture record: %w", err)
+	}
+	return rec, nil
+}
+
+// ReadAll returns every remaining record
+func (cr *CaptureReader) ReadAll() ([]*CaptureRecord, error) {
+	var records []*CaptureRecord
+	for {
+		rec, err := cr.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// SetCapture records every frame sent and received from now on to cw
+// Passing nil stops capturing; the caller flushes cw
+func (c *Connection) SetCapture(cw *CaptureWriter) {
+	c.capture.Store(cw)
+}
+
+// captureFrames records every frame of data, as handed to the socket, one
+// record per frame; each frame carries the descriptor count of its header
+func (c *Connection) captureFrames(direction CaptureDirection, data []byte) {
+	if c.capture.Load() == nil {
+		return
+	}
+	for len(data) >= HeaderSize {
+		size := HeaderSize + int(binary.LittleEndian.Uint32(data[4:8])&MaxPayloadSize)
+		if size > len(data) {
+			size = len(data)
+		}
+		c.captureFrame(direction, data[:size], int(binary.LittleEndian.Uint32(data[12:16])))
+		data = data[size:]
+	}
+}
+
+// captureFrame records a frame if capturing is enabled
+func (c *Connection) captureFrame(direction CaptureDirection, frame []byte, numFDs int) {
+	cw := c.capture.Load()
+	if cw == nil {
+		return
+	}
+	rec := &CaptureRecord{
+		Time:      time.Now(),
+		Direction: direction,
+		NumFDs:    uint32(numFDs),
+		Frame:     frame,
+	}
+	if err := cw.WriteRecord(rec); err != nil {
+		c.logger.Warnf("Capture: %v", err)
+	}
+}
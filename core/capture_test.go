@@ -0,0 +1,184 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+func TestCaptureRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := NewCaptureWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewCaptureWriter failed: %v", err)
+	}
+
+	hello, _ := NewHelloMessage(CoreVersion).Marshal()
+	done, _ := NewMessageBuilder(CoreID, uint32(CoreEventDone)).
+		WithPOD(NewArgsBuilder().Int(0).Int(1).Build()).Build().Marshal()
+	want := []*CaptureRecord{
+		{Time: time.Unix(0, 1000), Direction: CaptureSent, Frame: hello},
+		{Time: time.Unix(0, 2000), Direction: CaptureReceived, NumFDs: 2, Frame: done},
+	}
+	for _, rec := range want {
+		if err := cw.WriteRecord(rec); err != nil {
+			t.Fatalf("WriteRecord failed: %v", err)
+		}
+	}
+	if err := cw.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	cr, err := NewCaptureReader(&buf)
+	if err != nil {
+		t.Fatalf("NewCaptureReader failed: %v", err)
+	}
+	for i, w := range want {
+		got, err := cr.Next()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if !got.Time.Equal(w.Time) || got.Direction != w.Direction || got.NumFDs != w.NumFDs ||
+			!bytes.Equal(got.Frame, w.Frame) {
+			t.Errorf("record %d: got %v, want %v", i, got, w)
+		}
+	}
+	if _, err := cr.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	if _, err := NewCaptureReader(bytes.NewReader([]byte("not a capture"))); err == nil {
+		t.Error("expected error for a file without the capture header")
+	}
+}
+
+func TestCaptureFramesSplitsWrites(t *testing.T) {
+	var buf bytes.Buffer
+	cw, _ := NewCaptureWriter(&buf)
+	conn := &Connection{logger: verbose.NewLogger(verbose.LogLevelSilent, false)}
+	conn.SetCapture(cw)
+
+	// One write carrying a Hello and a Sync becomes two records
+	hello, _ := NewHelloMessage(CoreVersion).Marshal()
+	sync, _ := NewSyncMessage(CoreID, 7).Marshal()
+	conn.captureFrames(CaptureSent, append(append([]byte(nil), hello...), sync...))
+	cw.Flush()
+
+	cr, _ := NewCaptureReader(&buf)
+	records, _ := cr.ReadAll()
+	if len(records) != 2 || !bytes.Equal(records[0].Frame, hello) || !bytes.Equal(records[1].Frame, sync) {
+		t.Fatalf("expected the Hello and Sync as two records, got %v", records)
+	}
+}
+
+func TestCaptureAndReplaySession(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := NewCaptureWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewCaptureWriter failed: %v", err)
+	}
+
+	// Record a handshake and a round trip against a scripted daemon
+	client, daemon := newConnectionPair(t)
+	client.SetCapture(cw)
+	client.SetEventHandler(NewEventHandler())
+	ctx, cancel := context.WithCancel(context.Background())
+	go client.StartEventLoop(ctx)
+
+	seq := acceptHandshake(t, daemon)
+	sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(0).Int(int32(seq)))
+	waitReadyAndRoundtrip(t, client, func() {
+		r := expectMethod(t, daemon, CoreID, CoreMethodSync)
+		id, _ := r.Uint()
+		seq, _ := r.Uint()
+		sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(int32(id)).Int(int32(seq)))
+	})
+	cancel()
+	if err := cw.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	cr, err := NewCaptureReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewCaptureReader failed: %v", err)
+	}
+	records, _ := cr.ReadAll()
+	if len(records) == 0 || records[0].Direction != CaptureSent {
+		t.Fatalf("capture does not start with the Hello: %v", records)
+	}
+
+	// The same client behaviour against the replay succeeds without a daemon
+	cr, _ = NewCaptureReader(bytes.NewReader(buf.Bytes()))
+	replayed, replayer, err := NewReplayConnection(cr, nil)
+	if err != nil {
+		t.Fatalf("NewReplayConnection failed: %v", err)
+	}
+	defer replayer.Close()
+	defer replayed.Close()
+
+	replayed.SetEventHandler(NewEventHandler())
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go replayed.StartEventLoop(ctx)
+	waitReadyAndRoundtrip(t, replayed, func() {})
+
+	select {
+	case <-replayer.Done():
+		if err := replayer.Err(); err != nil {
+			t.Fatalf("replay failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("replay did not finish")
+	}
+}
+
+func TestReplayReportsDivergence(t *testing.T) {
+	var buf bytes.Buffer
+	cw, _ := NewCaptureWriter(&buf)
+	hello, _ := NewHelloMessage(CoreVersion).Marshal()
+	cw.WriteRecord(&CaptureRecord{Time: time.Now(), Direction: CaptureSent, Frame: hello})
+	cw.Flush()
+
+	cr, _ := NewCaptureReader(&buf)
+	conn, replayer, err := NewReplayConnection(cr, nil)
+	if err != nil {
+		t.Fatalf("NewReplayConnection failed: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Sync(CoreID); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	select {
+	case <-replayer.Done():
+		if replayer.Err() == nil {
+			t.Fatal("expected divergence error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("replay did not stop")
+	}
+}
+
+// waitReadyAndRoundtrip waits for the connect sequence, then runs a round
+// trip while answer plays the daemon side
+func waitReadyAndRoundtrip(t *testing.T, conn *Connection, answer func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := conn.WaitUntilReady(ctx); err != nil {
+		t.Fatalf("WaitUntilReady failed: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- conn.Roundtrip(ctx) }()
+	answer()
+	if err := <-result; err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+}
@@ -8,57 +8,128 @@ package core
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vignemail1/pipewire-go/verbose"
 )
 
-// ProtocolVersion defines the protocol version
-const ProtocolVersion = 3
-
-// DefaultSocketPath is the default PipeWire socket path
+// DefaultSocketPath is the legacy system socket path
+// Deprecated: Dial resolves the socket with SocketCandidates
 const DefaultSocketPath = "/run/pipewire-0"
 
+// MaxMessageSize is the largest message payload accepted from the daemon
+const MaxMessageSize = 1024 * 1024
+
+// readBufferSize is the size of the buffer the event loop reads into
+const readBufferSize = 32 * 1024
+
 // Connection represents a connection to PipeWire daemon via unix socket
 type Connection struct {
-	socket     net.Conn
-	logger     *verbose.Logger
-	buffer     *bytes.Buffer
-	timeout    time.Duration
-	connected  bool
-	readBuf    []byte
-	writeBuf   []byte
-	syncID     uint32
+	socket    *net.UnixConn
+	logger    *verbose.Logger
+	buffer    *bytes.Buffer
+	timeout   time.Duration
+	connected atomic.Bool
+	readBuf   []byte
+	oobBuf    []byte // SCM_RIGHTS control data of the last read
+	writeBuf  []byte
+	syncID    uint32
+
+	// File descriptors received as SCM_RIGHTS, not yet claimed by a frame
+	fds *fdQueue
+
+	// Connect sequence state
+	state        *ProtocolStateMachine
+	props        map[string]string // Sent with Client.UpdateProperties
+	registryID   uint32            // Proxy id the registry is bound to
+	proxies      *proxyMap         // Client-side proxy ids
+	coreInfo     *CoreInfoEvent    // Last Core.Info received
+	eventHandler *EventHandler     // Receives events for non-core objects
+
+	// Roundtrip calls waiting for Core.Done
+	syncs syncWaiters
+
+	// Writer goroutine input, closed is closed by Close
+	writes chan *writeRequest
+	closed chan struct{}
+
+	// Frame recorder set by SetCapture
+	capture atomic.Pointer[CaptureWriter]
+
+	// Type ids of both sides, used with legacy protocol peers
+	types    *TypeMap
+	legacy   atomic.Bool
+	legacyMu sync.Mutex // Held while a legacy message is written, see writeLegacyMessage
+
+	// Shared memory from Core.AddMem
+	mem *MemPool
+
+	// Traffic counters returned by Stats
+	stats *connStats
+
+	// Bounds on received frames, set by SetLimits
+	limits atomic.Pointer[Limits]
 }
 
 // Dial establishes a connection to the PipeWire daemon
-// It takes a socket path (typically "/run/pipewire-0") and creates a Unix socket
-// connection, performing the initial handshake if needed
-func Dial(socketPath string, logger *verbose.Logger) (*Connection, error) {
-	if socketPath == "" {
-		socketPath = DefaultSocketPath
-	}
-
+// remote is a remote name ("pipewire-0", "pipewire-0-manager"), a comma
+// separated list of names, or an absolute socket path; empty uses
+// $PIPEWIRE_REMOTE or the default. Candidate sockets are tried in the order
+// of SocketCandidates and the error lists every path that was tried
+func Dial(remote string, logger *verbose.Logger) (*Connection, error) {
 	if logger == nil {
 		logger = verbose.NewLogger(verbose.LogLevelInfo, false)
 	}
 
-	logger.Infof("Dialing PipeWire daemon at %s", socketPath)
+	name := RemoteName(remote)
+	candidates := SocketCandidates(remote)
+	if len(candidates) == 0 {
+		return nil, NewConnectionErrorf("no socket path for remote %q", name)
+	}
 
-	// Create the connection
-	conn, err := NewConnection(socketPath, logger)
-	if err != nil {
-		return nil, err
+	tried := make([]string, 0, len(candidates))
+	for _, socketPath := range candidates {
+		logger.Debugf("Dialing PipeWire daemon at %s", socketPath)
+
+		socket, err := dialSocket(socketPath)
+		if err != nil {
+			tried = append(tried, fmt.Sprintf("%s (%v)", socketPath, err))
+			continue
+		}
+
+		conn := NewConnectionFromSocket(socket, logger)
+
+		// Set a reasonable default timeout
+		conn.SetTimeout(5 * time.Second)
+
+		logger.Infof("Connected to PipeWire daemon at %s", socketPath)
+		return conn, nil
 	}
 
-	// Set a reasonable default timeout
-	conn.SetTimeout(5 * time.Second)
+	return nil, NewConnectionErrorf("cannot connect to remote %q, tried %s",
+		name, strings.Join(tried, ", "))
+}
 
-	logger.Infof("Successfully connected to PipeWire daemon")
-	return conn, nil
+// dialSocket connects to the unix socket at path
+func dialSocket(path string) (*net.UnixConn, error) {
+	socket, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		// Keep the cause ("no such file or directory", "connection refused")
+		var opErr *net.OpError
+		if errors.As(err, &opErr) {
+			return nil, opErr.Err
+		}
+		return nil, err
+	}
+	return socket, nil
 }
 
 // NewConnection creates a new connection to PipeWire
@@ -68,30 +139,53 @@ func NewConnection(socketPath string, logger *verbose.Logger) (*Connection, erro
 	}
 
 	// Create unix socket connection
-	socket, err := net.Dial("unix", socketPath)
+	socket, err := dialSocket(socketPath)
 	if err != nil {
-		logger.Error("Failed to connect to PipeWire socket", "path", socketPath, "error", err)
+		logger.Errorf("Failed to connect to PipeWire socket %s: %v", socketPath, err)
 		return nil, NewConnectionError(fmt.Sprintf("failed to connect to %s: %v", socketPath, err))
 	}
 
-	conn := &Connection{
-		socket:    socket,
-		logger:    logger,
-		buffer:    new(bytes.Buffer),
-		timeout:   5 * time.Second,
-		connected: true,
-		readBuf:   make([]byte, 4096),
-		writeBuf:  make([]byte, 4096),
-		syncID:    0,
+	logger.Debugf("Connected to PipeWire at %s", socketPath)
+	return NewConnectionFromSocket(socket, logger), nil
+}
+
+// NewConnectionFromSocket wraps an already connected unix socket
+// Useful for socket pairs and sockets inherited from a parent process
+func NewConnectionFromSocket(socket *net.UnixConn, logger *verbose.Logger) *Connection {
+	if logger == nil {
+		logger = verbose.NewLogger(verbose.LogLevelInfo, false)
+	}
+
+	c := &Connection{
+		socket:   socket,
+		logger:   logger,
+		buffer:   new(bytes.Buffer),
+		timeout:  5 * time.Second,
+		readBuf:  make([]byte, readBufferSize),
+		oobBuf:   make([]byte, oobBufferSize),
+		writeBuf: make([]byte, 4096),
+		syncID:   0,
+		fds:      newFDQueue(),
+		types:    NewTypeMap(),
+		state:    NewProtocolStateMachine(),
+		props:    DefaultClientProperties(),
+		proxies:  newProxyMap(),
+		stats:    newConnStats(),
+		writes:   make(chan *writeRequest, writeQueueSize),
+		closed:   make(chan struct{}),
 	}
+	c.mem = NewMemPool(logger)
+	c.SetLimits(DefaultLimits())
+	c.connected.Store(true)
+	c.registryID = c.AllocateProxy("registry").ID()
 
-	logger.Debug("Connected to PipeWire", "path", socketPath)
-	return conn, nil
+	go c.writeLoop()
+	return c
 }
 
 // IsConnected returns true if connection is active
 func (c *Connection) IsConnected() bool {
-	return c.connected
+	return c.connected.Load()
 }
 
 // SetTimeout sets the read/write timeout
@@ -99,33 +193,21 @@ func (c *Connection) SetTimeout(duration time.Duration) {
 	c.timeout = duration
 }
 
-// Write sends data to PipeWire
+// Write sends data to PipeWire through the writer goroutine
 func (c *Connection) Write(data []byte) (int, error) {
-	if !c.connected {
+	if !c.IsConnected() {
 		return 0, NewConnectionError("connection is closed")
 	}
 
-	// Set write deadline
-	if c.timeout > 0 {
-		c.socket.SetWriteDeadline(time.Now().Add(c.timeout))
-	}
-
-	n, err := c.socket.Write(data)
-	if err != nil {
-		c.logger.Error("Write error", "error", err)
-		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-			return n, NewTimeoutError("write timeout")
-		}
-		return n, NewProtocolError(fmt.Sprintf("write error: %v", err))
+	if err := c.queueWrite(context.Background(), data, nil); err != nil {
+		return 0, err
 	}
-
-	c.logger.Debug("Wrote bytes", "count", n)
-	return n, nil
+	return len(data), nil
 }
 
 // Read reads data from PipeWire
 func (c *Connection) Read(p []byte) (int, error) {
-	if !c.connected {
+	if !c.IsConnected() {
 		return 0, NewConnectionError("connection is closed")
 	}
 
@@ -134,86 +216,108 @@ func (c *Connection) Read(p []byte) (int, error) {
 		c.socket.SetReadDeadline(time.Now().Add(c.timeout))
 	}
 
-	n, err := c.socket.Read(p)
+	n, err := c.readMsg(p)
 	if err != nil {
 		if err == io.EOF {
-			c.logger.Debug("Connection closed by remote")
+			c.logger.Debugf("Connection closed by remote")
 			return 0, err
 		}
-		c.logger.Error("Read error", "error", err)
+		c.logger.Errorf("Read error: %v", err)
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 			return n, NewTimeoutError("read timeout")
 		}
 		return n, NewProtocolError(fmt.Sprintf("read error: %v", err))
 	}
 
-	c.logger.Debug("Read bytes", "count", n)
+	c.logger.Debugf("Read %d bytes", n)
 	return n, nil
 }
 
-// WriteMessage sends a protocol message
-func (c *Connection) WriteMessage(msg []byte) error {
-	if !c.connected {
+// WriteMessage sends a protocol message using native protocol framing
+func (c *Connection) WriteMessage(msg *MessageFrame) error {
+	return c.WriteMessageCtx(context.Background(), msg)
+}
+
+// WriteMessageCtx is WriteMessage giving up when ctx is done before the
+// message was queued; once queued the message is written whole
+func (c *Connection) WriteMessageCtx(ctx context.Context, msg *MessageFrame) error {
+	if !c.IsConnected() {
 		return NewConnectionError("connection is closed")
 	}
 
-	// Prefix message with length (4 bytes, little-endian)
-	length := uint32(len(msg))
-	lengthBytes := make([]byte, 4)
-	for i := 0; i < 4; i++ {
-		lengthBytes[i] = byte((length >> (uint(i) * 8)) & 0xFF)
+	if int(msg.NumFDs) != len(msg.FDs) {
+		return NewProtocolErrorf("message announces %d fds, has %d", msg.NumFDs, len(msg.FDs))
+	}
+	if len(msg.FDs) > MaxFDsPerMessage {
+		return NewProtocolErrorf("too many fds: %d (max %d)", len(msg.FDs), MaxFDsPerMessage)
+	}
+	if c.legacy.Load() {
+		return c.writeLegacyMessage(ctx, msg)
 	}
 
-	// Write length then message
-	if _, err := c.Write(lengthBytes); err != nil {
-		return err
+	data, err := msg.Marshal()
+	if err != nil {
+		return NewProtocolErrorf("failed to marshal message: %v", err)
 	}
-	if _, err := c.Write(msg); err != nil {
+
+	// The writer goroutine keeps concurrent messages whole and in order
+	if err := c.queueWrite(ctx, data, msg.FDs); err != nil {
 		return err
 	}
 
-	c.logger.Debug("Message sent", "length", length)
+	c.logger.Debugf("Message sent: id=%d opcode=%d seq=%d size=%d",
+		msg.ObjectID, msg.MethodID, msg.Sequence, len(data)-HeaderSize)
 	return nil
 }
 
-// ReadMessage reads a protocol message
-func (c *Connection) ReadMessage() ([]byte, error) {
-	if !c.connected {
+// ReadMessage reads a protocol message using native protocol framing
+func (c *Connection) ReadMessage() (*MessageFrame, error) {
+	if !c.IsConnected() {
 		return nil, NewConnectionError("connection is closed")
 	}
 
-	// Read length header (4 bytes)
-	lengthBuf := make([]byte, 4)
-	if _, err := io.ReadFull(c, lengthBuf); err != nil {
+	// Read header (16 bytes)
+	header := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(c, header); err != nil {
 		return nil, err
 	}
 
-	// Parse length
-	length := uint32(0)
-	for i := 0; i < 4; i++ {
-		length |= uint32(lengthBuf[i]) << (uint(i) * 8)
+	// Payload size is the lower 24 bits of the second header word
+	_, _, size, _, _ := parseHeader(header)
+	limits := c.Limits()
+	if HeaderSize+size > limits.frameSize() {
+		return nil, NewProtocolError(fmt.Sprintf("message too large: %d bytes", size))
 	}
 
-	if length == 0 {
-		return nil, NewProtocolError("message length is 0")
-	}
-	if length > 1024*1024 { // 1MB limit
-		return nil, NewProtocolError(fmt.Sprintf("message too large: %d bytes", length))
+	// Read payload (POD data + optional footer)
+	data := make([]byte, HeaderSize+size)
+	copy(data, header)
+	if _, err := io.ReadFull(c, data[HeaderSize:]); err != nil {
+		return nil, err
 	}
 
-	// Read message data
-	msgBuf := make([]byte, length)
-	if _, err := io.ReadFull(c, msgBuf); err != nil {
+	msg := &MessageFrame{}
+	if err := msg.UnmarshalLimits(data, limits); err != nil {
 		return nil, err
 	}
 
-	c.logger.Debug("Message received", "length", length)
-	return msgBuf, nil
+	// Claim the descriptors that arrived with this message
+	fds, err := c.fds.take(int(msg.NumFDs))
+	if err != nil {
+		return nil, NewProtocolErrorf("message %d/%d: %v", msg.ObjectID, msg.MethodID, err)
+	}
+	msg.FDs = fds
+	c.countFrame(CaptureReceived, msg.ObjectID, msg.MethodID, len(data))
+	c.captureFrame(CaptureReceived, data, len(fds))
+
+	c.logger.Debugf("Message received: id=%d opcode=%d seq=%d size=%d",
+		msg.ObjectID, msg.MethodID, msg.Sequence, size)
+	return msg, nil
 }
 
 // Flush flushes pending writes
 func (c *Connection) Flush() error {
-	if !c.connected {
+	if !c.IsConnected() {
 		return NewConnectionError("connection is closed")
 	}
 
@@ -230,13 +334,24 @@ func (c *Connection) Flush() error {
 
 // Close closes the connection
 func (c *Connection) Close() error {
-	if !c.connected {
+	if !c.connected.CompareAndSwap(true, false) {
 		return nil
 	}
 
-	c.connected = false
+	// Stop the writer goroutine and release queued writers
+	close(c.closed)
+
+	// Release Roundtrip callers, no Done will arrive anymore
+	c.syncs.failAll(NewConnectionError("connection closed"))
+
+	// Descriptors never claimed by a frame would leak otherwise
+	if n := c.fds.closeAll(); n > 0 {
+		c.logger.Debugf("Closed %d unclaimed file descriptors", n)
+	}
+	c.mem.Close()
+
 	if c.socket != nil {
-		c.logger.Debug("Closing connection")
+		c.logger.Debugf("Closing connection")
 		return c.socket.Close()
 	}
 	return nil
@@ -259,19 +374,19 @@ func (c *Connection) RemoteAddr() net.Addr {
 }
 
 // GetSyncID returns and increments the sync ID
+// It is used as the sequence number of every outgoing message
 func (c *Connection) GetSyncID() uint32 {
-	c.syncID++
-	return c.syncID
+	return atomic.AddUint32(&c.syncID, 1)
 }
 
 // SetSyncID sets the sync ID
 func (c *Connection) SetSyncID(id uint32) {
-	c.syncID = id
+	atomic.StoreUint32(&c.syncID, id)
 }
 
 // CurrentSyncID returns the current sync ID without incrementing
 func (c *Connection) CurrentSyncID() uint32 {
-	return c.syncID
+	return atomic.LoadUint32(&c.syncID)
 }
 
 // Verify implements io.Reader interface
@@ -6,12 +6,17 @@ package core
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"time"
 )
 
 // StartEventLoop begins reading messages from the daemon
-// This runs in a goroutine and continuously processes incoming messages
+// It runs the connect sequence first, then continuously processes incoming messages
+// The loop is the only reader of the socket: it blocks until data arrives,
+// decodes every complete frame of a read and delivers them in wire order.
+// Handlers run on the loop goroutine and must not wait for later events
+// (for example with Roundtrip), which could never be read while they block
 func (c *Connection) StartEventLoop(ctx context.Context) error {
 	if c == nil {
 		return fmt.Errorf("connection is nil")
@@ -19,61 +24,76 @@ func (c *Connection) StartEventLoop(ctx context.Context) error {
 
 	c.logger.Infof("Connection: Starting event loop")
 
-	// Initialize state machine
-	stateMachine := NewProtocolStateMachine()
-
 	// Transition to connected
-	if err := stateMachine.TransitionTo(StateConnected); err != nil {
+	if err := c.state.TransitionTo(StateConnected); err != nil {
 		c.logger.Errorf("Connection: Failed to transition to connected: %v", err)
 		return err
 	}
 
-	// Perform Hello handshake
-	if err := c.performHelloHandshake(stateMachine); err != nil {
-		c.logger.Errorf("Connection: Hello handshake failed: %v", err)
-		stateMachine.SetError(err)
-		return err
+	// Message buffer for assembling frames, shared with the handshake so
+	// events that arrive together with Core.Done are not lost
+	buffer := NewMessageBuffer(c.Limits().frameSize())
+
+	// The connect sequence is bounded by the connection timeout
+	if c.timeout > 0 {
+		c.socket.SetReadDeadline(time.Now().Add(c.timeout))
 	}
 
-	// Transition to ready
-	if err := stateMachine.TransitionTo(StateReady); err != nil {
-		c.logger.Errorf("Connection: Failed to transition to ready: %v", err)
+	// Cancelling ctx interrupts the blocking read
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.socket.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	// Hello / UpdateProperties / GetRegistry / Sync
+	if err := c.performHandshake(buffer); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.logger.Errorf("Connection: Handshake failed: %v", err)
+		c.state.SetError(err)
 		return err
 	}
 
-	c.logger.Infof("Connection: Event loop ready, state=%s", stateMachine.GetState())
+	// Block without deadline from now on; a cancel racing with this
+	// is caught by the ctx check below or interrupts the next read
+	c.socket.SetReadDeadline(time.Time{})
+	if ctx.Err() != nil {
+		c.logger.Infof("Connection: Event loop shutting down")
+		return ctx.Err()
+	}
 
-	// Message buffer for assembling frames
-	buffer := NewMessageBuffer(1024 * 1024) // 1MB max
+	c.logger.Infof("Connection: Event loop ready, state=%s", c.state.GetState())
 
 	// Main event loop
 	for {
-		select {
-		case <-ctx.Done():
-			c.logger.Infof("Connection: Event loop shutting down")
-			return ctx.Err()
-
-		default:
-			// Read next message from daemon
-			frame, err := c.readNextMessage(buffer)
-			if err != nil {
-				if err == context.DeadlineExceeded {
-					// Timeout, try again
-					continue
-				}
-				c.logger.Errorf("Connection: Read error: %v", err)
-				stateMachine.SetError(err)
-				return err
+		frames, err := c.readFrames(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Infof("Connection: Event loop shutting down")
+				return ctx.Err()
 			}
-
-			if frame == nil {
-				// No complete frame yet, wait a bit
-				time.Sleep(10 * time.Millisecond)
-				continue
+			if !c.IsConnected() {
+				// Fail closes the connection after recording why
+				if c.state.GetState() == StateError && c.state.GetLastError() != nil {
+					return c.state.GetLastError()
+				}
+				c.logger.Infof("Connection: Event loop stopped, connection closed")
+				return NewConnectionError("connection closed")
 			}
+			c.logger.Errorf("Connection: Read error: %v", err)
+			c.state.SetError(err)
+			c.syncs.failAll(err)
+			return err
+		}
 
-			// Process the frame
-			if err := c.processMessage(frame, stateMachine); err != nil {
+		for _, frame := range frames {
+			if err := c.processMessage(frame); err != nil {
 				c.logger.Errorf("Connection: Message processing error: %v", err)
 				// Don't stop loop on processing error, just log it
 			}
@@ -81,150 +101,167 @@ func (c *Connection) StartEventLoop(ctx context.Context) error {
 	}
 }
 
-// readNextMessage reads the next message from the socket
-func (c *Connection) readNextMessage(buffer *MessageBuffer) (*Frame, error) {
-	if c == nil || c.conn == nil {
+// readFrames blocks until at least one complete frame is buffered and
+// returns every complete frame, in the order they were received
+func (c *Connection) readFrames(buffer *MessageBuffer) ([]*Frame, error) {
+	if c == nil || c.socket == nil {
 		return nil, fmt.Errorf("connection not established")
 	}
 
-	// Set read timeout
-	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
-
-	// Read up to 4096 bytes
-	data := make([]byte, 4096)
-	n, err := c.conn.Read(data)
-	if err != nil {
-		return nil, err
-	}
+	var frames []*Frame
+	for {
+		// A single read may deliver several frames, or only part of one
+		for {
+			frame, err := c.nextBufferedFrame(buffer)
+			if err != nil {
+				return nil, err
+			}
+			if frame == nil {
+				break
+			}
+			if c.handleTypeFrame(frame) {
+				closeFDs(frame.FDs)
+				continue
+			}
+			frames = append(frames, frame)
+		}
+		if len(frames) > 0 {
+			return frames, nil
+		}
 
-	if n > 0 {
-		// Append to buffer
-		if err := buffer.Append(data[:n]); err != nil {
-			return nil, fmt.Errorf("buffer error: %w", err)
+		// Read into the connection buffer, queueing any fds passed alongside
+		n, err := c.readMsg(c.readBuf)
+		if n > 0 {
+			if err := buffer.Append(c.readBuf[:n]); err != nil {
+				return nil, WrapProtocolError(err, "buffer error")
+			}
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
+}
 
-	// Try to extract a complete frame
-	frame, err := buffer.ReadFrame()
+// nextBufferedFrame extracts a complete frame from the buffer, if any,
+// and attaches the file descriptors it announces
+func (c *Connection) nextBufferedFrame(buffer *MessageBuffer) (*Frame, error) {
+	legacy := c.legacy.Load()
+	var frame *Frame
+	var err error
+	if legacy {
+		frame, err = buffer.ReadLegacyFrame()
+	} else {
+		frame, err = buffer.ReadFrame()
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return frame, nil
-}
-
-// processMessage processes an incoming message frame
-func (c *Connection) processMessage(frame *Frame, stateMachine *ProtocolStateMachine) error {
-	if frame == nil {
-		return fmt.Errorf("frame is nil")
+	// Version 0 headers do not count fds, Fd values index the fds read
+	// with the frame, so it claims every fd queued so far
+	if frame != nil && legacy {
+		frame.NumFDs = uint32(c.fds.len())
+		binary.LittleEndian.PutUint32(frame.Header[12:16], frame.NumFDs)
 	}
 
-	// Dispatch to event handler if registered
-	if c.eventHandler != nil {
-		// Create an event from the frame
-		// This is simplified - real implementation would parse frame contents
-		event := &Event{
-			Type: EventTypeMessage,
-			Data: frame.Data,
+	// Claim the descriptors announced by the frame header
+	if frame != nil && frame.NumFDs > 0 {
+		if max := c.Limits().MaxFDs; max > 0 && frame.NumFDs > uint32(max) {
+			return nil, NewProtocolErrorf("frame %d/%d: too many fds: %d (max %d)",
+				frame.ObjectID, frame.Opcode, frame.NumFDs, max)
 		}
-
-		// Dispatch asynchronously to avoid blocking
-		go func() {
-			if err := c.eventHandler.Dispatch(frame.Header[0:4], event); err != nil {
-				c.logger.Warnf("Connection: Event dispatch error: %v", err)
-			}
-		}()
+		fds, err := c.fds.take(int(frame.NumFDs))
+		if err != nil {
+			return nil, WrapProtocolError(err, fmt.Sprintf("frame %d/%d", frame.ObjectID, frame.Opcode))
+		}
+		frame.FDs = fds
 	}
-
-	return nil
-}
-
-// performHelloHandshake performs the PipeWire Hello handshake
-func (c *Connection) performHelloHandshake(stateMachine *ProtocolStateMachine) error {
-	if c == nil {
-		return fmt.Errorf("connection is nil")
+	if frame != nil {
+		c.countFrame(CaptureReceived, frame.ObjectID, frame.Opcode, len(frame.Header)+len(frame.Data))
 	}
-
-	c.logger.Debugf("Connection: Performing Hello handshake")
-
-	// Create Hello request
-	// ObjectID=0 (core), MethodID=0 (Hello)
-	helloFrame := &MessageFrame{
-		ObjectID: 0,
-		MethodID: 0,
-		Sequence: 1,
-		Data:     nil, // No data for Hello
+	if frame != nil && c.capture.Load() != nil {
+		c.captureFrame(CaptureReceived, append(append([]byte(nil), frame.Header...), frame.Data...), len(frame.FDs))
 	}
 
-	// Marshal and send
-	data, err := helloFrame.Marshal()
-	if err != nil {
-		return fmt.Errorf("hello marshal error: %w", err)
-	}
+	return frame, nil
+}
 
-	if err := c.writeMessage(data); err != nil {
-		return fmt.Errorf("hello write error: %w", err)
+// processMessage processes an incoming message frame
+// Called from the event loop goroutine, so handlers see events in wire order
+func (c *Connection) processMessage(frame *Frame) error {
+	if frame == nil {
+		return fmt.Errorf("frame is nil")
 	}
+	// Fds no handler took, with ArgsReader.TakeFd, are closed once dispatched
+	defer closeFDs(frame.FDs)
 
-	// Transition to HelloSent
-	if err := stateMachine.TransitionTo(StateHelloSent); err != nil {
+	msg, err := c.decodeFrame(frame)
+	if err != nil {
 		return err
 	}
 
-	// Wait for Hello response (with timeout)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Read Hello response
-	buffer := NewMessageBuffer(1024 * 1024)
-	for {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("hello handshake timeout")
-		default:
-			frame, err := c.readNextMessage(buffer)
-			if err != nil {
-				return fmt.Errorf("hello response read error: %w", err)
+	if msg.ObjectID == CoreID {
+		switch EventID(msg.MethodID) {
+		case CoreEventDone:
+			// Core.Done completes pending round trips
+			if err := c.handleCoreDone(msg); err != nil {
+				return err
 			}
-
-			if frame != nil {
-				// Transition to HelloReceived
-				if err := stateMachine.TransitionTo(StateHelloReceived); err != nil {
-					return err
-				}
-
-				// Parse version from response if available
-				// This is simplified - real implementation would parse POD data
-				stateMachine.SetVersion(3, 0)
-
-				c.logger.Debugf("Connection: Hello handshake complete, version=%s", 
-					stateMachine.GetVersion())
-				return nil
+		case CoreEventError:
+			// Core.Error fails the request that caused it
+			if err := c.handleCoreError(msg); err != nil {
+				return err
+			}
+		case CoreEventPing:
+			// The daemon checks we are alive, answer right away
+			if err := c.handleCorePing(msg); err != nil {
+				return err
+			}
+		case CoreEventBoundID:
+			if err := c.handleBoundID(msg); err != nil {
+				return err
+			}
+		case CoreEventRemoveID:
+			// The daemon is done with the id, it can be reused
+			if err := c.handleRemoveID(msg); err != nil {
+				return err
+			}
+		case CoreEventAddMem:
+			if err := c.handleAddMem(msg); err != nil {
+				return err
+			}
+		case CoreEventRemoveMem:
+			if err := c.handleRemoveMem(msg); err != nil {
+				return err
 			}
-
-			time.Sleep(10 * time.Millisecond)
 		}
 	}
-}
 
-// writeMessage writes a message to the daemon
-func (c *Connection) writeMessage(data []byte) error {
-	if c == nil || c.conn == nil {
-		return fmt.Errorf("connection not established")
+	// Deliver to the handlers of the target object, if any
+	if c.eventHandler != nil && c.eventHandler.HandlerCount(msg.ObjectID) > 0 {
+		if err := c.eventHandler.Dispatch(msg); err != nil {
+			c.logger.Warnf("Connection: Event dispatch error: %v", err)
+		}
 	}
 
-	// Set write timeout
-	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return nil
+}
 
-	n, err := c.conn.Write(data)
+// handleCoreError maps a Core.Error event to the request that caused it
+func (c *Connection) handleCoreError(msg *MessageFrame) error {
+	derr, err := ParseCoreError(msg)
 	if err != nil {
-		return fmt.Errorf("write error: %w", err)
+		return err
 	}
 
-	if n != len(data) {
-		return fmt.Errorf("partial write: %d of %d bytes", n, len(data))
+	if derr.ID == CoreID && c.syncs.resolve(derr.Seq, derr) {
+		return nil
+	}
+	if c.eventHandler.RejectDaemonError(derr) {
+		return nil
 	}
 
+	c.logger.Warnf("Connection: Unmatched daemon error: %v", derr)
 	return nil
 }
 
@@ -234,50 +271,54 @@ func (c *Connection) WaitUntilReady(ctx context.Context) error {
 		return fmt.Errorf("connection is nil")
 	}
 
-	// In a real implementation, this would check the state machine
-	// For now, we'll just wait a bit and return
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(100 * time.Millisecond):
-		return nil
-	}
+	return c.state.WaitForState(ctx, StateReady)
 }
 
 // Shutdown gracefully closes the connection
 func (c *Connection) Shutdown(ctx context.Context) error {
-	if c == nil || c.conn == nil {
+	if c == nil || c.socket == nil {
 		return nil
 	}
 
 	c.logger.Infof("Connection: Shutting down")
 
+	if c.state.GetState() != StateDisconnected {
+		c.state.TransitionTo(StateDisconnected)
+	}
+
 	// Close the underlying connection
-	return c.conn.Close()
+	return c.Close()
+}
+
+// Fail puts the connection in the error state and closes it
+// The event loop returns err and pending round trips fail with it
+func (c *Connection) Fail(err error) {
+	c.logger.Errorf("Connection: %v", err)
+	c.state.SetError(err)
+	c.syncs.failAll(err)
+	c.Close()
 }
 
 // GetState returns the current connection state
 func (c *Connection) GetState() State {
-	// In a real implementation, this would return the state machine state
-	// For now, return a default
-	return StateReady
+	if c == nil || c.state == nil {
+		return StateDisconnected
+	}
+	return c.state.GetState()
 }
 
-// Event represents an incoming event from the daemon
-type Event struct {
-	Type EventType
-	Data []byte
+// StateMachine returns the protocol state machine of the connection
+func (c *Connection) StateMachine() *ProtocolStateMachine {
+	return c.state
 }
 
-// EventType represents the type of event
-type EventType int
-
-const (
-	EventTypeMessage EventType = iota
-	EventTypePort
-	EventTypeNode
-	EventTypeLink
-)
+// SetEventHandler sets the handler receiving events for non-core objects
+// Must be called before StartEventLoop
+func (c *Connection) SetEventHandler(handler *EventHandler) {
+	c.eventHandler = handler
+}
 
-// EventDispatchFunc is a function that handles events
-type EventDispatchFunc func(event *Event) error
+// EventHandler returns the handler set with SetEventHandler, or nil
+func (c *Connection) EventHandler() *EventHandler {
+	return c.eventHandler
+}
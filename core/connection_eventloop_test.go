@@ -0,0 +1,181 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventLoopDeliversInOrder(t *testing.T) {
+	client, daemon := readyPair(t)
+
+	const count = 500
+	got := make(map[uint32][]int32)
+	var mu sync.Mutex
+	done := make(chan struct{})
+
+	record := func(msg *MessageFrame) error {
+		r, err := NewArgsReader(msg)
+		if err != nil {
+			return err
+		}
+		n, err := r.Int()
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		got[msg.ObjectID] = append(got[msg.ObjectID], n)
+		if len(got[5])+len(got[6]) == count {
+			close(done)
+		}
+		mu.Unlock()
+		return nil
+	}
+	client.EventHandler().RegisterHandler(5, record)
+	client.EventHandler().RegisterHandler(6, record)
+
+	// Write the whole burst at once so reads carry many frames
+	var burst []byte
+	for i := 0; i < count; i++ {
+		msg := NewMessageBuilder(uint32(5+i%2), 0).
+			WithPOD(NewArgsBuilder().Int(int32(i)).Build()).
+			Build()
+		data, err := msg.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		burst = append(burst, data...)
+	}
+	if _, err := daemon.Write(burst); err != nil {
+		t.Fatalf("daemon write failed: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("events not delivered")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, values := range got {
+		for i := 1; i < len(values); i++ {
+			if values[i] != values[i-1]+2 {
+				t.Fatalf("object %d: event %d delivered after %d", id, values[i], values[i-1])
+			}
+		}
+	}
+}
+
+func TestEventLoopStopsOnCancel(t *testing.T) {
+	client, daemon := newConnectionPair(t)
+	client.SetTimeout(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- client.StartEventLoop(ctx) }()
+
+	seq := acceptHandshake(t, daemon)
+	sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(0).Int(int32(seq)))
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer waitCancel()
+	if err := client.WaitUntilReady(waitCtx); err != nil {
+		t.Fatalf("WaitUntilReady failed: %v", err)
+	}
+
+	// The loop is blocked in a read without deadline
+	cancel()
+	select {
+	case err := <-result:
+		if err != context.Canceled {
+			t.Fatalf("expected Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event loop did not stop after cancel")
+	}
+}
+
+func TestEventLoopAnswersPing(t *testing.T) {
+	_, daemon := readyPair(t)
+
+	sendEvent(t, daemon, CoreEventPing, NewArgsBuilder().Int(0).Int(42))
+
+	r := expectMethod(t, daemon, CoreID, CoreMethodPong)
+	id, _ := r.Uint()
+	seq, _ := r.Uint()
+	if id != 0 || seq != 42 {
+		t.Errorf("got pong(%d, %d), want pong(0, 42)", id, seq)
+	}
+}
+
+func TestFailStopsEventLoop(t *testing.T) {
+	client, daemon := newConnectionPair(t)
+
+	result := make(chan error, 1)
+	go func() { result <- client.StartEventLoop(context.Background()) }()
+
+	seq := acceptHandshake(t, daemon)
+	sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(0).Int(int32(seq)))
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer waitCancel()
+	if err := client.WaitUntilReady(waitCtx); err != nil {
+		t.Fatalf("WaitUntilReady failed: %v", err)
+	}
+
+	client.Fail(NewUnresponsiveErrorf("no pong within %v", time.Second))
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrDaemonUnresponsive) || !IsTimeout(err) {
+			t.Fatalf("expected unresponsive timeout error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event loop did not stop after Fail")
+	}
+	if client.GetState() != StateError {
+		t.Errorf("got state %s, want Error", client.GetState())
+	}
+}
+
+func TestConcurrentWritesStayWhole(t *testing.T) {
+	client, daemon := newConnectionPair(t)
+
+	const writers, perWriter = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				msg := NewMessageBuilder(uint32(w), 1).
+					WithPOD(NewArgsBuilder().Int(int32(i)).String("payload").Build()).
+					Build()
+				if err := client.WriteMessage(msg); err != nil {
+					t.Errorf("write failed: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	next := make(map[uint32]int32)
+	for i := 0; i < writers*perWriter; i++ {
+		msg, err := daemon.ReadMessage()
+		if err != nil {
+			t.Fatalf("daemon read failed: %v", err)
+		}
+		r, err := NewArgsReader(msg)
+		if err != nil {
+			t.Fatalf("message %d corrupted: %v", i, err)
+		}
+		n, _ := r.Int()
+		if n != next[msg.ObjectID] {
+			t.Fatalf("writer %d: got %d, want %d", msg.ObjectID, n, next[msg.ObjectID])
+		}
+		next[msg.ObjectID]++
+	}
+	wg.Wait()
+}
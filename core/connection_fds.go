@@ -0,0 +1,167 @@
+// Package core - File descriptor passing
+// core/connection_fds.go
+// SCM_RIGHTS ancillary data handling for memfd/eventfd based features
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MaxFDsPerMessage is the maximum number of fds libpipewire sends per message
+const MaxFDsPerMessage = 28
+
+// oobBufferSize is the control data space for MaxFDsPerMessage fds
+var oobBufferSize = syscall.CmsgSpace(MaxFDsPerMessage * 4)
+
+// fdQueue holds received file descriptors in arrival order
+// The kernel delivers fds with the bytes of the frame that announces them,
+// so frames claim fds from the front of the queue using their n_fds header
+type fdQueue struct {
+	mu  sync.Mutex
+	fds []int
+}
+
+// newFDQueue creates an empty fd queue
+func newFDQueue() *fdQueue {
+	return &fdQueue{fds: make([]int, 0, MaxFDsPerMessage)}
+}
+
+// push appends received fds to the queue
+func (q *fdQueue) push(fds ...int) {
+	if q == nil || len(fds) == 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.fds = append(q.fds, fds...)
+}
+
+// take removes and returns the first n fds
+func (q *fdQueue) take(n int) ([]int, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	if q == nil {
+		return nil, fmt.Errorf("expected %d fds, none received", n)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if n > len(q.fds) {
+		return nil, fmt.Errorf("expected %d fds, only %d received", n, len(q.fds))
+	}
+	fds := make([]int, n)
+	copy(fds, q.fds[:n])
+	q.fds = q.fds[n:]
+	return fds, nil
+}
+
+// len returns the number of unclaimed fds
+func (q *fdQueue) len() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.fds)
+}
+
+// closeAll closes every unclaimed fd and returns how many were closed
+func (q *fdQueue) closeAll() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.fds)
+	for _, fd := range q.fds {
+		syscall.Close(fd)
+	}
+	q.fds = q.fds[:0]
+	return n
+}
+
+// readMsg reads bytes from the socket and queues any fds passed alongside
+// Fds the kernel dropped for lack of control buffer space desynchronize
+// the queue from the frames, so a truncated read fails the connection
+func (c *Connection) readMsg(p []byte) (int, error) {
+	n, oobn, flags, _, err := c.socket.ReadMsgUnix(p, c.oobBuf)
+	if flags&syscall.MSG_CTRUNC != 0 && err == nil {
+		err = NewProtocolError("control data truncated, file descriptors lost")
+	}
+	if oobn > 0 {
+		fds, perr := parseUnixRights(c.oobBuf[:oobn])
+		c.fds.push(fds...)
+		if perr != nil && err == nil {
+			err = perr
+		}
+		if len(fds) > 0 {
+			c.logger.Debugf("Received %d file descriptors", len(fds))
+		}
+	}
+	return n, err
+}
+
+// writeMsg writes bytes with fds attached as SCM_RIGHTS ancillary data
+func (c *Connection) writeMsg(data []byte, fds []int) error {
+	if !c.IsConnected() {
+		return NewConnectionError("connection is closed")
+	}
+
+	if c.timeout > 0 {
+		c.socket.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+
+	n, _, err := c.socket.WriteMsgUnix(data, syscall.UnixRights(fds...), nil)
+	if err != nil {
+		c.logger.Errorf("Write error: %v", err)
+		return NewProtocolError(fmt.Sprintf("write error: %v", err))
+	}
+	if n != len(data) {
+		return NewProtocolError(fmt.Sprintf("partial write: %d of %d bytes", n, len(data)))
+	}
+
+	c.logger.Debugf("Wrote %d bytes with %d file descriptors", n, len(fds))
+	return nil
+}
+
+// closeFDs closes fds, skipping the -1 of descriptors taken by a handler
+func closeFDs(fds []int) {
+	for _, fd := range fds {
+		if fd >= 0 {
+			syscall.Close(fd)
+		}
+	}
+}
+
+// PendingFDCount returns the number of received fds not yet claimed by a frame
+func (c *Connection) PendingFDCount() int {
+	return c.fds.len()
+}
+
+// parseUnixRights extracts fds from SCM_RIGHTS control messages
+func parseUnixRights(oob []byte) ([]int, error) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, fmt.Errorf("invalid control message: %w", err)
+	}
+
+	var fds []int
+	for i := range msgs {
+		if msgs[i].Header.Level != syscall.SOL_SOCKET || msgs[i].Header.Type != syscall.SCM_RIGHTS {
+			continue
+		}
+		rights, err := syscall.ParseUnixRights(&msgs[i])
+		if err != nil {
+			return fds, fmt.Errorf("invalid SCM_RIGHTS message: %w", err)
+		}
+		fds = append(fds, rights...)
+	}
+	return fds, nil
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// fdMessageBody is Struct{Fd 0}
+var fdMessageBody = []byte{
+	0x10, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, // Struct, 16 bytes
+	0x08, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00, // Fd, 8 bytes
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // index 0
+}
+
+// newConnectionPair returns two connected Connections backed by a socketpair
+func newConnectionPair(t *testing.T) (*Connection, *Connection) {
+	t.Helper()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("socketpair: %v", err)
+	}
+
+	wrap := func(fd int, name string) *Connection {
+		f := os.NewFile(uintptr(fd), name)
+		defer f.Close()
+		fc, err := net.FileConn(f)
+		if err != nil {
+			t.Fatalf("FileConn: %v", err)
+		}
+		return NewConnectionFromSocket(fc.(*net.UnixConn), nil)
+	}
+
+	a, b := wrap(fds[0], "a"), wrap(fds[1], "b")
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	return a, b
+}
+
+func TestConnectionPassesFDs(t *testing.T) {
+	a, b := newConnectionPair(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	msg := NewMessageBuilder(0, 6).
+		WithSequence(3).
+		WithPOD(spa.NewPODRaw(fdMessageBody)).
+		Build()
+	index := msg.AddFD(int(w.Fd()))
+	if index != 0 || msg.NumFDs != 1 {
+		t.Fatalf("AddFD: index=%d NumFDs=%d", index, msg.NumFDs)
+	}
+
+	if err := a.WriteMessage(msg); err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+
+	got, err := b.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+	if got.NumFDs != 1 || len(got.FDs) != 1 {
+		t.Fatalf("expected 1 fd, got NumFDs=%d FDs=%v", got.NumFDs, got.FDs)
+	}
+	if b.PendingFDCount() != 0 {
+		t.Errorf("expected no pending fds, got %d", b.PendingFDCount())
+	}
+
+	fd, err := got.FDs.Get(0)
+	if err != nil {
+		t.Fatalf("FDs.Get failed: %v", err)
+	}
+
+	// The received fd is a new descriptor for the same pipe
+	dup := os.NewFile(uintptr(fd), "received")
+	defer dup.Close()
+	if _, err := dup.Write([]byte("ok")); err != nil {
+		t.Fatalf("write through received fd: %v", err)
+	}
+	buf := make([]byte, 2)
+	if _, err := r.Read(buf); err != nil || string(buf) != "ok" {
+		t.Fatalf("read from pipe: %q %v", buf, err)
+	}
+}
+
+func TestConnectionFDCountMismatch(t *testing.T) {
+	a, _ := newConnectionPair(t)
+
+	msg := NewMessageBuilder(0, 6).WithPOD(spa.NewPODRaw(fdMessageBody)).Build()
+	msg.NumFDs = 2
+
+	if err := a.WriteMessage(msg); err == nil {
+		t.Fatal("expected error when NumFDs does not match FDs")
+	}
+}
+
+func TestFDQueueTake(t *testing.T) {
+	q := newFDQueue()
+	q.push(10, 11, 12)
+
+	fds, err := q.take(2)
+	if err != nil {
+		t.Fatalf("take failed: %v", err)
+	}
+	if len(fds) != 2 || fds[0] != 10 || fds[1] != 11 {
+		t.Errorf("unexpected fds: %v", fds)
+	}
+	if _, err := q.take(2); err == nil {
+		t.Error("expected error when taking more fds than queued")
+	}
+	if q.len() != 1 {
+		t.Errorf("expected 1 queued fd, got %d", q.len())
+	}
+}
+
+func TestConnectionClosesUnclaimedFDs(t *testing.T) {
+	client, daemon := readyPair(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+
+	// An event for an object nobody listens to, passing the write end
+	msg := NewMessageBuilder(99, 0).WithPOD(spa.NewPODRaw(fdMessageBody)).WithFDs(int(w.Fd())).Build()
+	if err := daemon.WriteMessage(msg); err != nil {
+		t.Fatalf("daemon write failed: %v", err)
+	}
+	w.Close()
+
+	// The client drops its copy once the frame is dispatched, so the
+	// pipe reports end of file
+	r.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected io.EOF once the fd is closed, got %v", err)
+	}
+	if client.PendingFDCount() != 0 {
+		t.Errorf("expected no pending fds, got %d", client.PendingFDCount())
+	}
+}
+
+func TestReadMsgControlTruncated(t *testing.T) {
+	a, b := newConnectionPair(t)
+
+	// More fds than the control buffer holds
+	fds := make([]int, 2*MaxFDsPerMessage)
+	for i := range fds {
+		fds[i] = int(os.Stdin.Fd())
+	}
+	hello, _ := NewHelloMessage(CoreVersion).Marshal()
+	if _, _, err := a.socket.WriteMsgUnix(hello, syscall.UnixRights(fds...), nil); err != nil {
+		t.Fatalf("WriteMsgUnix failed: %v", err)
+	}
+
+	if _, err := b.Read(make([]byte, len(hello))); err == nil {
+		t.Fatal("expected error for truncated control data")
+	}
+}
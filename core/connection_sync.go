@@ -0,0 +1,147 @@
+// Package core - Sequence numbers and round trips
+// core/connection_sync.go
+// Core.Sync / Core.Done barrier used to wait until the daemon caught up
+
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// syncWaiters tracks Roundtrip calls waiting for their Core.Done
+type syncWaiters struct {
+	mu      sync.Mutex
+	waiters map[uint32]chan error
+}
+
+// add registers a waiter for seq
+func (w *syncWaiters) add(seq uint32) chan error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.waiters == nil {
+		w.waiters = make(map[uint32]chan error)
+	}
+	ch := make(chan error, 1)
+	w.waiters[seq] = ch
+	return ch
+}
+
+// remove drops the waiter for seq
+func (w *syncWaiters) remove(seq uint32) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.waiters, seq)
+}
+
+// resolve completes the waiter for seq, if any
+func (w *syncWaiters) resolve(seq uint32, err error) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ch, ok := w.waiters[seq]
+	if ok {
+		delete(w.waiters, seq)
+		ch <- err
+	}
+	return ok
+}
+
+// len returns the number of waiting calls
+func (w *syncWaiters) len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.waiters)
+}
+
+// failAll completes every waiter with err
+func (w *syncWaiters) failAll(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for seq, ch := range w.waiters {
+		ch <- err
+		delete(w.waiters, seq)
+	}
+}
+
+// Send assigns the next sequence number to msg and writes it
+// Returns the sequence number used
+func (c *Connection) Send(msg *MessageFrame) (uint32, error) {
+	return c.SendCtx(context.Background(), msg)
+}
+
+// SendCtx is Send giving up when ctx is done before msg was queued
+func (c *Connection) SendCtx(ctx context.Context, msg *MessageFrame) (uint32, error) {
+	msg.Sequence = c.GetSyncID()
+	return msg.Sequence, c.WriteMessageCtx(ctx, msg)
+}
+
+// Sync sends Core.Sync(id) using the message sequence number as the sync seq
+// The daemon answers with Core.Done(id, seq) once it processed every earlier message
+func (c *Connection) Sync(id uint32) (uint32, error) {
+	return c.SyncCtx(context.Background(), id)
+}
+
+// SyncCtx is Sync giving up when ctx is done before the message was queued
+func (c *Connection) SyncCtx(ctx context.Context, id uint32) (uint32, error) {
+	seq := c.GetSyncID()
+	msg := NewSyncMessage(id, seq)
+	msg.Sequence = seq
+	return seq, c.WriteMessageCtx(ctx, msg)
+}
+
+// Roundtrip sends Core.Sync with a fresh seq and blocks until the matching
+// Core.Done arrives, so every event the daemon emitted before it has been received
+// Requires a running event loop
+func (c *Connection) Roundtrip(ctx context.Context) error {
+	if c == nil {
+		return NewConnectionError("connection is nil")
+	}
+
+	// Register before sending so a fast Done cannot be missed
+	seq := c.GetSyncID()
+	done := c.syncs.add(seq)
+	defer c.syncs.remove(seq)
+
+	msg := NewSyncMessage(CoreID, seq)
+	msg.Sequence = seq
+	start := time.Now()
+	if err := c.WriteMessageCtx(ctx, msg); err != nil {
+		return err
+	}
+
+	c.logger.Debugf("Connection: Roundtrip waiting for done seq=%d", seq)
+
+	select {
+	case err := <-done:
+		if err == nil {
+			c.stats.roundtrip.Observe(time.Since(start))
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handleCoreDone completes the Roundtrip waiting for seq
+func (c *Connection) handleCoreDone(msg *MessageFrame) error {
+	id, seq, err := ParseCoreDone(msg)
+	if err != nil {
+		return err
+	}
+	if id == CoreID && c.syncs.resolve(seq, nil) {
+		c.logger.Debugf("Connection: Roundtrip done seq=%d", seq)
+	}
+	return nil
+}
+
+// handleCorePing answers Core.Ping(id, seq) with Core.Pong(id, seq)
+func (c *Connection) handleCorePing(msg *MessageFrame) error {
+	id, seq, err := ParseCorePing(msg)
+	if err != nil {
+		return err
+	}
+	c.logger.Debugf("Connection: Ping id=%d seq=%d", id, seq)
+	_, err = c.Send(NewPongMessage(id, seq))
+	return err
+}
@@ -0,0 +1,97 @@
+// Package core - Connection writer
+// core/connection_writer.go
+// Single writer goroutine serialising every write to the socket
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// writeQueueSize is the number of writes that can be queued before callers block
+const writeQueueSize = 64
+
+// writeRequest is one message handed to the writer goroutine
+type writeRequest struct {
+	data []byte
+	fds  []int
+	done chan error
+}
+
+// writeLoop owns the socket write side until the connection is closed
+// Messages are written in the order they were queued, fds stay attached
+// to the bytes of the message that announces them
+func (c *Connection) writeLoop() {
+	for {
+		select {
+		case req := <-c.writes:
+			req.done <- c.writeData(req.data, req.fds)
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// queueWrite hands data to the writer goroutine and waits for the result
+// ctx only bounds the wait for a queue slot, a queued message is not
+// abandoned since the writer may already own its fds
+func (c *Connection) queueWrite(ctx context.Context, data []byte, fds []int) error {
+	req := &writeRequest{data: data, fds: fds, done: make(chan error, 1)}
+
+	select {
+	case c.writes <- req:
+	case <-c.closed:
+		return NewConnectionError("connection is closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-req.done:
+		return err
+	case <-c.closed:
+		return NewConnectionError("connection is closed")
+	}
+}
+
+// writeData writes one message to the socket, called from the writer goroutine only
+func (c *Connection) writeData(data []byte, fds []int) error {
+	if len(fds) > 0 {
+		if err := c.writeMsg(data, fds); err != nil {
+			return err
+		}
+		c.recordSent(data)
+		return nil
+	}
+
+	// Set write deadline
+	if c.timeout > 0 {
+		c.socket.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+
+	n, err := c.socket.Write(data)
+	if err != nil {
+		c.logger.Errorf("Write error: %v", err)
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return NewTimeoutError("write timeout")
+		}
+		return NewProtocolError(fmt.Sprintf("write error: %v", err))
+	}
+
+	c.recordSent(data)
+	c.logger.Debugf("Wrote %d bytes", n)
+	return nil
+}
+
+// recordSent counts and captures the frames of written data
+// Legacy frames are recorded by writeLegacyMessage, in the current layout
+func (c *Connection) recordSent(data []byte) {
+	if c.legacy.Load() {
+		return
+	}
+	c.countFrames(CaptureSent, data)
+	c.captureFrames(CaptureSent, data)
+}
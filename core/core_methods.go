@@ -0,0 +1,230 @@
+// Package core - Core and Client interface messages
+// core/core_methods.go
+// Encoders for the connect sequence methods and decoders for Core events
+
+package core
+
+import "fmt"
+
+// NewHelloMessage builds Core.Hello(version)
+func NewHelloMessage(version int32) *MessageFrame {
+	return NewMessageBuilder(CoreID, uint32(CoreMethodHello)).
+		WithPOD(NewArgsBuilder().Int(version).Build()).
+		Build()
+}
+
+// NewSyncMessage builds Core.Sync(id, seq); the daemon answers with Core.Done(id, seq)
+func NewSyncMessage(id uint32, seq uint32) *MessageFrame {
+	return NewMessageBuilder(CoreID, uint32(CoreMethodSync)).
+		WithPOD(NewArgsBuilder().Int(int32(id)).Int(int32(seq)).Build()).
+		Build()
+}
+
+// NewPongMessage builds Core.Pong(id, seq), the answer to Core.Ping(id, seq)
+func NewPongMessage(id uint32, seq uint32) *MessageFrame {
+	return NewMessageBuilder(CoreID, uint32(CoreMethodPong)).
+		WithPOD(NewArgsBuilder().Int(int32(id)).Int(int32(seq)).Build()).
+		Build()
+}
+
+// NewGetRegistryMessage builds Core.GetRegistry(version, new_id)
+// newID is the client-allocated proxy id the registry is bound to
+func NewGetRegistryMessage(version int32, newID uint32) *MessageFrame {
+	return NewMessageBuilder(CoreID, uint32(CoreMethodGetRegistry)).
+		WithPOD(NewArgsBuilder().Int(version).Int(int32(newID)).Build()).
+		Build()
+}
+
+// NewDestroyMessage builds Core.Destroy(id), destroying the object behind proxy id
+// The daemon confirms with Core.RemoveID(id)
+func NewDestroyMessage(id uint32) *MessageFrame {
+	return NewMessageBuilder(CoreID, uint32(CoreMethodDestroy)).
+		WithPOD(NewArgsBuilder().Int(int32(id)).Build()).
+		Build()
+}
+
+// LinkFactory is the factory creating links with Core.CreateObject, from the
+// link.output.port and link.input.port properties (and their node ids)
+const LinkFactory = "link-factory"
+
+// CreateObjectRequest holds the arguments of Core.CreateObject
+type CreateObjectRequest struct {
+	Factory string            // Factory name, e.g. LinkFactory
+	Type    string            // Interface type of the object, e.g. TypeInterfaceLink
+	Version uint32            // Interface version of the proxy
+	Props   map[string]string // Properties handed to the factory
+	NewID   uint32            // Client-allocated proxy id of the new object
+}
+
+// Message builds Core.CreateObject(factory_name, type, version, props, new_id)
+// The daemon answers with Core.BoundID(new_id, global_id)
+func (r *CreateObjectRequest) Message() *MessageFrame {
+	return NewMessageBuilder(CoreID, uint32(CoreMethodCreateObject)).
+		WithPOD(NewArgsBuilder().
+			String(r.Factory).String(r.Type).Int(int32(r.Version)).Dict(r.Props).Int(int32(r.NewID)).
+			Build()).
+		Build()
+}
+
+// ParseCreateObject decodes Core.CreateObject(factory_name, type, version, props, new_id)
+func ParseCreateObject(msg *MessageFrame) (*CreateObjectRequest, error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	create := &CreateObjectRequest{}
+	if create.Factory, err = r.String(); err != nil {
+		return nil, fmt.Errorf("core create_object factory: %w", err)
+	}
+	if create.Type, err = r.String(); err != nil {
+		return nil, fmt.Errorf("core create_object type: %w", err)
+	}
+	if create.Version, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("core create_object version: %w", err)
+	}
+	if create.Props, err = r.Dict(); err != nil {
+		return nil, fmt.Errorf("core create_object props: %w", err)
+	}
+	if create.NewID, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("core create_object new_id: %w", err)
+	}
+	return create, nil
+}
+
+// NewUpdatePropertiesMessage builds Client.UpdateProperties(props)
+func NewUpdatePropertiesMessage(props map[string]string) *MessageFrame {
+	return NewMessageBuilder(ClientID, uint32(ClientMethodUpdateProperties)).
+		WithPOD(NewArgsBuilder().Dict(props).Build()).
+		Build()
+}
+
+// CoreInfoEvent is the decoded Core.Info event
+type CoreInfoEvent struct {
+	ID         uint32
+	Cookie     uint32
+	UserName   string
+	HostName   string
+	Version    string
+	Name       string
+	ChangeMask uint64
+	Props      map[string]string
+}
+
+// ParseCoreInfo decodes Core.Info(id, cookie, user, host, version, name, change_mask, props)
+func ParseCoreInfo(msg *MessageFrame) (*CoreInfoEvent, error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &CoreInfoEvent{}
+	if info.ID, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("core info id: %w", err)
+	}
+	if info.Cookie, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("core info cookie: %w", err)
+	}
+	if info.UserName, err = r.String(); err != nil {
+		return nil, fmt.Errorf("core info user name: %w", err)
+	}
+	if info.HostName, err = r.String(); err != nil {
+		return nil, fmt.Errorf("core info host name: %w", err)
+	}
+	if info.Version, err = r.String(); err != nil {
+		return nil, fmt.Errorf("core info version: %w", err)
+	}
+	if info.Name, err = r.String(); err != nil {
+		return nil, fmt.Errorf("core info name: %w", err)
+	}
+	mask, err := r.Long()
+	if err != nil {
+		return nil, fmt.Errorf("core info change mask: %w", err)
+	}
+	info.ChangeMask = uint64(mask)
+	if info.Props, err = r.Dict(); err != nil {
+		return nil, fmt.Errorf("core info props: %w", err)
+	}
+	return info, nil
+}
+
+// ParseCoreDone decodes Core.Done(id, seq)
+func ParseCoreDone(msg *MessageFrame) (id uint32, seq uint32, err error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	if id, err = r.Uint(); err != nil {
+		return 0, 0, fmt.Errorf("core done id: %w", err)
+	}
+	if seq, err = r.Uint(); err != nil {
+		return 0, 0, fmt.Errorf("core done seq: %w", err)
+	}
+	return id, seq, nil
+}
+
+// ParseCorePing decodes Core.Ping(id, seq)
+func ParseCorePing(msg *MessageFrame) (id uint32, seq uint32, err error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	if id, err = r.Uint(); err != nil {
+		return 0, 0, fmt.Errorf("core ping id: %w", err)
+	}
+	if seq, err = r.Uint(); err != nil {
+		return 0, 0, fmt.Errorf("core ping seq: %w", err)
+	}
+	return id, seq, nil
+}
+
+// ParseCoreBoundID decodes Core.BoundID(id, global_id)
+func ParseCoreBoundID(msg *MessageFrame) (id uint32, globalID uint32, err error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	if id, err = r.Uint(); err != nil {
+		return 0, 0, fmt.Errorf("core bound_id id: %w", err)
+	}
+	if globalID, err = r.Uint(); err != nil {
+		return 0, 0, fmt.Errorf("core bound_id global id: %w", err)
+	}
+	return id, globalID, nil
+}
+
+// ParseCoreRemoveID decodes Core.RemoveID(id)
+func ParseCoreRemoveID(msg *MessageFrame) (uint32, error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return 0, err
+	}
+	id, err := r.Uint()
+	if err != nil {
+		return 0, fmt.Errorf("core remove_id id: %w", err)
+	}
+	return id, nil
+}
+
+// ParseCoreError decodes Core.Error(id, seq, res, message) into a DaemonError
+func ParseCoreError(msg *MessageFrame) (*DaemonError, error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	derr := &DaemonError{}
+	if derr.ID, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("core error id: %w", err)
+	}
+	if derr.Seq, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("core error seq: %w", err)
+	}
+	if derr.Res, err = r.Int(); err != nil {
+		return nil, fmt.Errorf("core error res: %w", err)
+	}
+	if derr.Message, err = r.String(); err != nil {
+		return nil, fmt.Errorf("core error message: %w", err)
+	}
+	return derr, nil
+}
@@ -6,7 +6,9 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"syscall"
 )
 
 // ErrorCode represents all possible PipeWire protocol errors
@@ -97,16 +99,26 @@ func WrapError(code ErrorCode, err error) *Error {
 // ConnectionError represents a connection error
 // PROPERLY IMPLEMENTS error interface (not as field, but through Error() method)
 type ConnectionError struct {
-	*Error
+	Base *Error
 }
 
 // Ensure ConnectionError implements error interface
 var _ error = (*ConnectionError)(nil)
 
+// Error implements the error interface
+func (e *ConnectionError) Error() string {
+	return e.Base.Error()
+}
+
+// Unwrap returns the base error carrying the code
+func (e *ConnectionError) Unwrap() error {
+	return e.Base
+}
+
 // NewConnectionError creates a new connection error
 func NewConnectionError(message string) *ConnectionError {
 	return &ConnectionError{
-		Error: &Error{
+		Base: &Error{
 			Code:    ErrorCodeConnectionLost,
 			Message: message,
 			Wrapped: nil,
@@ -117,7 +129,7 @@ func NewConnectionError(message string) *ConnectionError {
 // NewConnectionErrorf creates a new connection error with formatted message
 func NewConnectionErrorf(format string, args ...interface{}) *ConnectionError {
 	return &ConnectionError{
-		Error: &Error{
+		Base: &Error{
 			Code:    ErrorCodeConnectionLost,
 			Message: fmt.Sprintf(format, args...),
 			Wrapped: nil,
@@ -128,16 +140,26 @@ func NewConnectionErrorf(format string, args ...interface{}) *ConnectionError {
 // TimeoutError represents a timeout error
 // PROPERLY IMPLEMENTS error interface
 type TimeoutError struct {
-	*Error
+	Base *Error
 }
 
 // Ensure TimeoutError implements error interface
 var _ error = (*TimeoutError)(nil)
 
+// Error implements the error interface
+func (e *TimeoutError) Error() string {
+	return e.Base.Error()
+}
+
+// Unwrap returns the base error carrying the code
+func (e *TimeoutError) Unwrap() error {
+	return e.Base
+}
+
 // NewTimeoutError creates a new timeout error
 func NewTimeoutError(message string) *TimeoutError {
 	return &TimeoutError{
-		Error: &Error{
+		Base: &Error{
 			Code:    ErrorCodeTimeout,
 			Message: message,
 			Wrapped: nil,
@@ -148,7 +170,7 @@ func NewTimeoutError(message string) *TimeoutError {
 // NewTimeoutErrorf creates a new timeout error with formatted message
 func NewTimeoutErrorf(format string, args ...interface{}) *TimeoutError {
 	return &TimeoutError{
-		Error: &Error{
+		Base: &Error{
 			Code:    ErrorCodeTimeout,
 			Message: fmt.Sprintf(format, args...),
 			Wrapped: nil,
@@ -159,16 +181,26 @@ func NewTimeoutErrorf(format string, args ...interface{}) *TimeoutError {
 // ProtocolError represents a protocol error
 // PROPERLY IMPLEMENTS error interface
 type ProtocolError struct {
-	*Error
+	Base *Error
 }
 
 // Ensure ProtocolError implements error interface
 var _ error = (*ProtocolError)(nil)
 
+// Error implements the error interface
+func (e *ProtocolError) Error() string {
+	return e.Base.Error()
+}
+
+// Unwrap returns the base error carrying the code
+func (e *ProtocolError) Unwrap() error {
+	return e.Base
+}
+
 // NewProtocolError creates a new protocol error
 func NewProtocolError(message string) *ProtocolError {
 	return &ProtocolError{
-		Error: &Error{
+		Base: &Error{
 			Code:    ErrorCodeProtocolError,
 			Message: message,
 			Wrapped: nil,
@@ -179,7 +211,7 @@ func NewProtocolError(message string) *ProtocolError {
 // NewProtocolErrorf creates a new protocol error with formatted message
 func NewProtocolErrorf(format string, args ...interface{}) *ProtocolError {
 	return &ProtocolError{
-		Error: &Error{
+		Base: &Error{
 			Code:    ErrorCodeProtocolError,
 			Message: fmt.Sprintf(format, args...),
 			Wrapped: nil,
@@ -187,18 +219,40 @@ func NewProtocolErrorf(format string, args ...interface{}) *ProtocolError {
 	}
 }
 
+// WrapProtocolError creates a protocol error caused by err
+// errors.Is and errors.As still see err, such as a spa.LimitError
+func WrapProtocolError(err error, message string) *ProtocolError {
+	return &ProtocolError{
+		Base: &Error{
+			Code:    ErrorCodeProtocolError,
+			Message: message,
+			Wrapped: err,
+		},
+	}
+}
+
 // ResourceError represents a resource error
 type ResourceError struct {
-	*Error
+	Base *Error
 }
 
 // Ensure ResourceError implements error interface
 var _ error = (*ResourceError)(nil)
 
+// Error implements the error interface
+func (e *ResourceError) Error() string {
+	return e.Base.Error()
+}
+
+// Unwrap returns the base error carrying the code
+func (e *ResourceError) Unwrap() error {
+	return e.Base
+}
+
 // NewResourceError creates a new resource error
 func NewResourceError(message string) *ResourceError {
 	return &ResourceError{
-		Error: &Error{
+		Base: &Error{
 			Code:    ErrorCodeNoMemory,
 			Message: message,
 			Wrapped: nil,
@@ -206,70 +260,198 @@ func NewResourceError(message string) *ResourceError {
 	}
 }
 
+// Sentinel errors matched by errors.Is against errors reported by the daemon
+var (
+	ErrPermission   = errors.New("permission denied")
+	ErrNotFound     = errors.New("not found")
+	ErrInvalid      = errors.New("invalid argument")
+	ErrExists       = errors.New("already exists")
+	ErrBusy         = errors.New("resource busy")
+	ErrNoMemory     = errors.New("out of memory")
+	ErrNotSupported = errors.New("not supported")
+	ErrIO           = errors.New("I/O error")
+)
+
+// ErrDaemonUnresponsive is matched by errors.Is when the daemon stopped
+// answering keepalive probes
+var ErrDaemonUnresponsive = errors.New("daemon not responding")
+
+// NewUnresponsiveErrorf creates a timeout error for a daemon that missed
+// its keepalive deadline; it matches ErrDaemonUnresponsive
+func NewUnresponsiveErrorf(format string, args ...interface{}) *TimeoutError {
+	return &TimeoutError{
+		Base: &Error{
+			Code:    ErrorCodeTimeout,
+			Message: fmt.Sprintf(format, args...),
+			Wrapped: ErrDaemonUnresponsive,
+		},
+	}
+}
+
+// errnoSentinels maps daemon errno values to sentinel errors
+var errnoSentinels = map[syscall.Errno]error{
+	syscall.EPERM:      ErrPermission,
+	syscall.EACCES:     ErrPermission,
+	syscall.ENOENT:     ErrNotFound,
+	syscall.ESRCH:      ErrNotFound,
+	syscall.EINVAL:     ErrInvalid,
+	syscall.EEXIST:     ErrExists,
+	syscall.EBUSY:      ErrBusy,
+	syscall.ENOMEM:     ErrNoMemory,
+	syscall.ENOTSUP:    ErrNotSupported,
+	syscall.ENOSYS:     ErrNotSupported,
+	syscall.EIO:        ErrIO,
+	syscall.EPIPE:      ErrIO,
+	syscall.ETIMEDOUT:  ErrIO,
+	syscall.ENOTCONN:   ErrIO,
+	syscall.ECONNRESET: ErrIO,
+}
+
+// errnoCodes maps daemon errno values to error codes
+var errnoCodes = map[syscall.Errno]ErrorCode{
+	syscall.EPERM:   ErrorCodePermissionDenied,
+	syscall.EACCES:  ErrorCodePermissionDenied,
+	syscall.ENOENT:  ErrorCodeNotFound,
+	syscall.ESRCH:   ErrorCodeNotFound,
+	syscall.EINVAL:  ErrorCodeInvalidArgument,
+	syscall.EBUSY:   ErrorCodeBusyError,
+	syscall.ENOMEM:  ErrorCodeNoMemory,
+	syscall.ENOTSUP: ErrorCodeNotSupported,
+	syscall.ENOSYS:  ErrorCodeUnimplemented,
+	syscall.EIO:     ErrorCodeIOError,
+}
+
+// DaemonError is an error reported by the daemon in a Core.Error event
+// Res is the negative errno describing the failure
+type DaemonError struct {
+	ID      uint32 // Proxy id the error relates to
+	Seq     uint32 // Sequence number of the failing request
+	Res     int32  // Negative errno
+	Message string // Human-readable message from the daemon
+}
+
+// Ensure DaemonError implements error interface
+var _ error = (*DaemonError)(nil)
+
+// Error implements the error interface
+func (e *DaemonError) Error() string {
+	return fmt.Sprintf("pipewire: %s (id=%d seq=%d: %v)", e.Message, e.ID, e.Seq, e.Errno())
+}
+
+// Errno returns the positive errno value
+func (e *DaemonError) Errno() syscall.Errno {
+	if e.Res < 0 {
+		return syscall.Errno(-e.Res)
+	}
+	return syscall.Errno(e.Res)
+}
+
+// Code returns the error code matching the errno
+func (e *DaemonError) Code() ErrorCode {
+	if code, ok := errnoCodes[e.Errno()]; ok {
+		return code
+	}
+	return ErrorCodeUnknown
+}
+
+// Is reports whether the errno matches a sentinel such as ErrPermission
+func (e *DaemonError) Is(target error) bool {
+	sentinel, ok := errnoSentinels[e.Errno()]
+	return ok && sentinel == target
+}
+
+// Unwrap returns the errno so errors.Is(err, syscall.EPERM) also works
+func (e *DaemonError) Unwrap() error {
+	return e.Errno()
+}
+
+// NewDaemonError creates an error from the fields of a Core.Error event
+func NewDaemonError(id, seq uint32, res int32, message string) *DaemonError {
+	return &DaemonError{ID: id, Seq: seq, Res: res, Message: message}
+}
+
+// VersionError reports a method or interface this library cannot use at
+// the version negotiated with the daemon
+// errors.Is(err, ErrNotSupported) matches it
+type VersionError struct {
+	Interface string // Interface type, e.g. TypeInterfaceNode
+	Method    string // Method refused, empty when the interface itself is unknown
+	Since     uint32 // Version the method needs
+	Version   uint32 // Version the proxy was bound at
+}
+
+// Ensure VersionError implements error interface
+var _ error = (*VersionError)(nil)
+
+// Error implements the error interface
+func (e *VersionError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("interface %s is not supported", e.Interface)
+	}
+	return fmt.Sprintf("%s.%s needs version %d, proxy bound at version %d",
+		e.Interface, e.Method, e.Since, e.Version)
+}
+
+// Is reports whether target is ErrNotSupported
+func (e *VersionError) Is(target error) bool {
+	return target == ErrNotSupported
+}
+
 // Helper functions for error checking
 
 // IsTimeout checks if an error is a timeout error
 func IsTimeout(err error) bool {
-	_, ok := err.(*TimeoutError)
-	return ok
+	var e *TimeoutError
+	return errors.As(err, &e)
 }
 
 // IsConnectionError checks if an error is a connection error
 func IsConnectionError(err error) bool {
-	_, ok := err.(*ConnectionError)
-	return ok
+	var e *ConnectionError
+	return errors.As(err, &e)
 }
 
 // IsProtocolError checks if an error is a protocol error
 func IsProtocolError(err error) bool {
-	_, ok := err.(*ProtocolError)
-	return ok
+	var e *ProtocolError
+	return errors.As(err, &e)
 }
 
 // IsPermissionError checks if an error is a permission error
 func IsPermissionError(err error) bool {
-	e, ok := err.(*Error)
-	return ok && e.Code == ErrorCodePermissionDenied
+	return CodeFromError(err) == ErrorCodePermissionDenied
 }
 
 // IsNotFound checks if an error is a not found error
 func IsNotFound(err error) bool {
-	e, ok := err.(*Error)
-	return ok && e.Code == ErrorCodeNotFound
+	return CodeFromError(err) == ErrorCodeNotFound
 }
 
 // IsInvalid checks if an error is an invalid error
 func IsInvalid(err error) bool {
-	e, ok := err.(*Error)
-	if !ok {
-		return false
+	switch CodeFromError(err) {
+	case ErrorCodeInvalidArgument, ErrorCodeInvalidType, ErrorCodeInvalidValue,
+		ErrorCodeInvalidFormat, ErrorCodeInvalidState:
+		return true
 	}
-	return e.Code == ErrorCodeInvalidArgument ||
-		e.Code == ErrorCodeInvalidType ||
-		e.Code == ErrorCodeInvalidValue ||
-		e.Code == ErrorCodeInvalidFormat ||
-		e.Code == ErrorCodeInvalidState
+	return false
 }
 
 // IsResourceError checks if an error is a resource error
 func IsResourceError(err error) bool {
-	_, ok := err.(*ResourceError)
-	return ok
+	var e *ResourceError
+	return errors.As(err, &e)
 }
 
 // CodeFromError extracts error code from error
 func CodeFromError(err error) ErrorCode {
-	if e, ok := err.(*Error); ok {
-		return e.Code
-	}
-	if _, ok := err.(*ConnectionError); ok {
-		return ErrorCodeConnectionLost
+	var daemonErr *DaemonError
+	if errors.As(err, &daemonErr) {
+		return daemonErr.Code()
 	}
-	if _, ok := err.(*TimeoutError); ok {
-		return ErrorCodeTimeout
-	}
-	if _, ok := err.(*ProtocolError); ok {
-		return ErrorCodeProtocolError
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
 	}
 	return ErrorCodeUnknown
 }
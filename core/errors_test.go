@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDaemonErrorIs(t *testing.T) {
+	err := fmt.Errorf("create link: %w", NewDaemonError(42, 7, -int32(syscall.EPERM), "not allowed"))
+
+	if !errors.Is(err, ErrPermission) {
+		t.Error("expected errors.Is(err, ErrPermission)")
+	}
+	if !errors.Is(err, syscall.EPERM) {
+		t.Error("expected errors.Is(err, syscall.EPERM)")
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("EPERM must not match ErrNotFound")
+	}
+	if !IsPermissionError(err) {
+		t.Error("expected IsPermissionError")
+	}
+
+	var derr *DaemonError
+	if !errors.As(err, &derr) || derr.ID != 42 || derr.Seq != 7 {
+		t.Fatalf("errors.As failed: %v", derr)
+	}
+	if derr.Errno() != syscall.EPERM {
+		t.Errorf("expected EPERM, got %v", derr.Errno())
+	}
+
+	tests := []struct {
+		errno    syscall.Errno
+		sentinel error
+	}{
+		{syscall.EACCES, ErrPermission},
+		{syscall.ENOENT, ErrNotFound},
+		{syscall.EINVAL, ErrInvalid},
+		{syscall.EEXIST, ErrExists},
+		{syscall.EBUSY, ErrBusy},
+		{syscall.ENOMEM, ErrNoMemory},
+		{syscall.ENOTSUP, ErrNotSupported},
+		{syscall.EIO, ErrIO},
+	}
+	for _, tt := range tests {
+		if err := NewDaemonError(0, 0, -int32(tt.errno), ""); !errors.Is(err, tt.sentinel) {
+			t.Errorf("%v: expected %v", tt.errno, tt.sentinel)
+		}
+	}
+}
+
+func TestTypedErrorsUnwrap(t *testing.T) {
+	err := fmt.Errorf("read: %w", NewTimeoutError("read timeout"))
+
+	if !IsTimeout(err) {
+		t.Error("expected IsTimeout through wrapping")
+	}
+	if CodeFromError(err) != ErrorCodeTimeout {
+		t.Errorf("expected timeout code, got %v", CodeFromError(err))
+	}
+	if IsConnectionError(err) {
+		t.Error("timeout must not be a connection error")
+	}
+}
+
+// daemonError sends Core.Error(id, seq, res, message) from the daemon side
+func daemonError(t *testing.T, daemon *Connection, id, seq uint32, errno syscall.Errno, message string) {
+	t.Helper()
+	sendEvent(t, daemon, CoreEventError, NewArgsBuilder().
+		Int(int32(id)).Int(int32(seq)).Int(-int32(errno)).String(message))
+}
+
+func TestCoreErrorRejectsPendingRequest(t *testing.T) {
+	client, daemon := readyPair(t)
+	handler := client.EventHandler()
+
+	seq := client.GetSyncID()
+	ctx := handler.CreatePendingRequestFor(seq, 42)
+	msg := NewMessageBuilder(42, 3).WithSequence(seq).WithPOD(NewArgsBuilder().Build()).Build()
+	if err := client.WriteMessage(msg); err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+
+	if _, err := daemon.ReadMessage(); err != nil {
+		t.Fatalf("daemon read failed: %v", err)
+	}
+	daemonError(t, daemon, 42, seq, syscall.EACCES, "access denied")
+
+	_, err := handler.WaitForRequest(ctx, 2*time.Second)
+	if !errors.Is(err, ErrPermission) {
+		t.Fatalf("expected ErrPermission, got %v", err)
+	}
+	if handler.PendingRequestCount() != 0 {
+		t.Errorf("expected no pending requests, got %d", handler.PendingRequestCount())
+	}
+}
+
+func TestCoreErrorMatchedByObject(t *testing.T) {
+	client, daemon := readyPair(t)
+	handler := client.EventHandler()
+
+	ctx := handler.CreatePendingRequestFor(client.GetSyncID(), 42)
+
+	// Sequence unknown to the client, object id matches the request
+	daemonError(t, daemon, 42, 9999, syscall.ENOENT, "no such port")
+
+	_, err := handler.WaitForRequest(ctx, 2*time.Second)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRoundtripCoreError(t *testing.T) {
+	client, daemon := readyPair(t)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- client.Roundtrip(context.Background())
+	}()
+
+	r := expectMethod(t, daemon, CoreID, CoreMethodSync)
+	r.Uint()
+	seq, _ := r.Uint()
+	daemonError(t, daemon, CoreID, seq, syscall.EINVAL, "bad sync")
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrInvalid) {
+			t.Fatalf("expected ErrInvalid, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Roundtrip did not fail on Core.Error")
+	}
+}
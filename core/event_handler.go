@@ -5,6 +5,8 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -16,6 +18,7 @@ type HandlerFunc func(*MessageFrame) error
 // RequestContext tracks a pending request and its response
 type RequestContext struct {
 	Sequence uint32
+	ObjectID uint32 // Target object, used when an error cannot be matched by sequence
 	Result   chan interface{}
 	Error    chan error
 	Timeout  time.Time
@@ -127,12 +130,18 @@ func (eh *EventHandler) DispatchToAll(frame *MessageFrame) {
 
 // CreatePendingRequest creates a new request context and registers it
 func (eh *EventHandler) CreatePendingRequest(sequence uint32) *RequestContext {
+	return eh.CreatePendingRequestFor(sequence, CoreID)
+}
+
+// CreatePendingRequestFor creates a request context for a message sent to objectID
+func (eh *EventHandler) CreatePendingRequestFor(sequence, objectID uint32) *RequestContext {
 	if eh == nil {
 		return nil
 	}
 
 	ctx := &RequestContext{
 		Sequence: sequence,
+		ObjectID: objectID,
 		Result:   make(chan interface{}, 1),
 		Error:    make(chan error, 1),
 		Timeout:  time.Now().Add(eh.requestTimeout),
@@ -145,38 +154,92 @@ func (eh *EventHandler) CreatePendingRequest(sequence uint32) *RequestContext {
 	return ctx
 }
 
+// RejectDaemonError fails the pending request a Core.Error event refers to
+// The request is matched by sequence number first, then by target object
+// (errors about the core itself are only matched by sequence)
+// Returns false if no pending request matches
+func (eh *EventHandler) RejectDaemonError(derr *DaemonError) bool {
+	if eh == nil || derr == nil {
+		return false
+	}
+
+	eh.mu.RLock()
+	ctx, ok := eh.pendingRequests[derr.Seq]
+	if !ok && derr.ID != CoreID {
+		for _, pending := range eh.pendingRequests {
+			if pending.ObjectID == derr.ID && (ctx == nil || pending.Sequence < ctx.Sequence) {
+				ctx = pending
+			}
+		}
+	}
+	eh.mu.RUnlock()
+
+	if ctx == nil {
+		return false
+	}
+
+	select {
+	case ctx.Error <- derr:
+		return true
+	default:
+		return false
+	}
+}
+
 // WaitForRequest waits for a response to a pending request
+// A zero timeout uses the handler's request timeout
 func (eh *EventHandler) WaitForRequest(ctx *RequestContext, timeout time.Duration) (interface{}, error) {
 	if eh == nil {
 		return nil, fmt.Errorf("EventHandler is nil")
 	}
-	if ctx == nil {
-		return nil, fmt.Errorf("RequestContext is nil")
-	}
 
 	actualTimeout := timeout
 	if actualTimeout == 0 {
+		eh.mu.RLock()
 		actualTimeout = eh.requestTimeout
+		eh.mu.RUnlock()
 	}
 
+	waitCtx, cancel := context.WithTimeout(context.Background(), actualTimeout)
+	defer cancel()
+
+	result, err := eh.WaitForRequestCtx(waitCtx, ctx)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return nil, NewTimeoutErrorf("request %d timeout after %v", ctx.Sequence, actualTimeout)
+	}
+	return result, err
+}
+
+// WaitForRequestCtx waits for a response to a pending request until ctx is done
+// The request is dropped whatever the outcome, so a late response or
+// Core.Error for it is ignored; on cancellation the error wraps ctx.Err()
+func (eh *EventHandler) WaitForRequestCtx(ctx context.Context, req *RequestContext) (interface{}, error) {
+	if eh == nil {
+		return nil, fmt.Errorf("EventHandler is nil")
+	}
+	if req == nil {
+		return nil, fmt.Errorf("RequestContext is nil")
+	}
+	defer eh.dropPendingRequest(req)
+
 	select {
-	case result := <-ctx.Result:
-		eh.mu.Lock()
-		delete(eh.pendingRequests, ctx.Sequence)
-		eh.mu.Unlock()
+	case result := <-req.Result:
 		return result, nil
 
-	case err := <-ctx.Error:
-		eh.mu.Lock()
-		delete(eh.pendingRequests, ctx.Sequence)
-		eh.mu.Unlock()
+	case err := <-req.Error:
 		return nil, err
 
-	case <-time.After(actualTimeout):
-		eh.mu.Lock()
-		delete(eh.pendingRequests, ctx.Sequence)
-		eh.mu.Unlock()
-		return nil, fmt.Errorf("request %d timeout after %v", ctx.Sequence, actualTimeout)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("request %d: %w", req.Sequence, ctx.Err())
+	}
+}
+
+// dropPendingRequest forgets req, unless its sequence was registered again since
+func (eh *EventHandler) dropPendingRequest(req *RequestContext) {
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+	if eh.pendingRequests[req.Sequence] == req {
+		delete(eh.pendingRequests, req.Sequence)
 	}
 }
 
@@ -11,8 +11,8 @@ import (
 	"time"
 )
 
-// EventHandler handles events from PipeWire
-type EventHandler interface {
+// DispatchHandler handles the events routed by an EventDispatcher
+type DispatchHandler interface {
 	Handle(event Event) error
 }
 
@@ -74,7 +74,7 @@ func NewBaseEvent(eventType EventType, data interface{}) *BaseEvent {
 // EventDispatcher routes events to handlers
 type EventDispatcher struct {
 	mu           sync.RWMutex
-	handlers     map[EventType][]EventHandler
+	handlers     map[EventType][]DispatchHandler
 	errorHandler ErrorEventHandler
 	queue        chan Event
 	running      bool
@@ -95,7 +95,7 @@ func NewEventDispatcher(workers int) *EventDispatcher {
 	}
 
 	return &EventDispatcher{
-		handlers:     make(map[EventType][]EventHandler),
+		handlers:     make(map[EventType][]DispatchHandler),
 		queue:        make(chan Event, 1000),
 		running:      false,
 		workers:      workers,
@@ -104,7 +104,7 @@ func NewEventDispatcher(workers int) *EventDispatcher {
 }
 
 // RegisterHandler registers a handler for an event type
-func (d *EventDispatcher) RegisterHandler(eventType EventType, handler EventHandler) {
+func (d *EventDispatcher) RegisterHandler(eventType EventType, handler DispatchHandler) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -113,14 +113,14 @@ func (d *EventDispatcher) RegisterHandler(eventType EventType, handler EventHand
 	}
 
 	if _, exists := d.handlers[eventType]; !exists {
-		d.handlers[eventType] = make([]EventHandler, 0)
+		d.handlers[eventType] = make([]DispatchHandler, 0)
 	}
 
 	d.handlers[eventType] = append(d.handlers[eventType], handler)
 }
 
 // UnregisterHandler removes a handler
-func (d *EventDispatcher) UnregisterHandler(eventType EventType, handler EventHandler) {
+func (d *EventDispatcher) UnregisterHandler(eventType EventType, handler DispatchHandler) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
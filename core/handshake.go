@@ -0,0 +1,184 @@
+// Package core - Connect sequence
+// core/handshake.go
+// Hello / UpdateProperties / GetRegistry / Sync handshake with the daemon
+
+package core
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultClientProperties returns the properties announced with
+// Client.UpdateProperties when the application does not set its own
+func DefaultClientProperties() map[string]string {
+	props := map[string]string{
+		"application.name":       filepath.Base(os.Args[0]),
+		"application.process.id": strconv.Itoa(os.Getpid()),
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		props["application.process.binary"] = filepath.Base(exe)
+	}
+	if u, err := user.Current(); err == nil {
+		props["application.process.user"] = u.Username
+	} else if name := os.Getenv("USER"); name != "" {
+		props["application.process.user"] = name
+	}
+	if host, err := os.Hostname(); err == nil {
+		props["application.process.host"] = host
+	}
+	if lang := os.Getenv("LANG"); lang != "" {
+		props["application.language"] = lang
+	}
+	return props
+}
+
+// SetProperty sets a client property sent during the connect sequence
+// Must be called before StartEventLoop
+func (c *Connection) SetProperty(key, value string) {
+	c.props[key] = value
+}
+
+// Properties returns a copy of the client properties
+func (c *Connection) Properties() map[string]string {
+	props := make(map[string]string, len(c.props))
+	for k, v := range c.props {
+		props[k] = v
+	}
+	return props
+}
+
+// RegistryID returns the proxy id the registry is bound to
+func (c *Connection) RegistryID() uint32 {
+	return c.registryID
+}
+
+// CoreInfo returns the last Core.Info received from the daemon, nil before the handshake
+func (c *Connection) CoreInfo() *CoreInfoEvent {
+	return c.coreInfo
+}
+
+// performHandshake runs the connect sequence:
+// Core.Hello, Client.UpdateProperties, Core.GetRegistry and a Core.Sync
+// whose Core.Done marks the connection ready
+// A legacy connection sends their protocol version 0 counterparts, see
+// SetLegacyProtocol
+func (c *Connection) performHandshake(buffer *MessageBuffer) error {
+	c.logger.Debugf("Connection: Sending hello, core version %d", CoreVersion)
+
+	if _, err := c.Send(NewHelloMessage(CoreVersion)); err != nil {
+		return fmt.Errorf("hello: %w", err)
+	}
+	if err := c.state.TransitionTo(StateHelloSent); err != nil {
+		return err
+	}
+
+	if _, err := c.Send(NewUpdatePropertiesMessage(c.Properties())); err != nil {
+		return fmt.Errorf("update properties: %w", err)
+	}
+	if _, err := c.Send(NewGetRegistryMessage(RegistryVersion, c.registryID)); err != nil {
+		return fmt.Errorf("get registry: %w", err)
+	}
+
+	syncSeq, err := c.Sync(CoreID)
+	if err != nil {
+		return fmt.Errorf("sync: %w", err)
+	}
+
+	for {
+		frames, err := c.readFrames(buffer)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return NewTimeoutError("connect sequence timed out")
+			}
+			return fmt.Errorf("handshake read error: %w", err)
+		}
+
+		for i, frame := range frames {
+			ready, err := c.handleHandshakeFrame(frame, syncSeq)
+			if err != nil {
+				return err
+			}
+			if ready {
+				// Events read together with Core.Done belong to the event loop
+				for _, rest := range frames[i+1:] {
+					if err := c.processMessage(rest); err != nil {
+						c.logger.Warnf("Connection: Message processing error: %v", err)
+					}
+				}
+				return nil
+			}
+		}
+	}
+}
+
+// handleHandshakeFrame processes one frame received during the connect sequence
+// Returns true once the Core.Done answering syncSeq arrived
+func (c *Connection) handleHandshakeFrame(frame *Frame, syncSeq uint32) (bool, error) {
+	// Registry globals and other events may arrive before Core.Done
+	if frame.ObjectID != CoreID {
+		if err := c.processMessage(frame); err != nil {
+			c.logger.Warnf("Connection: Message processing error: %v", err)
+		}
+		return false, nil
+	}
+
+	msg, err := c.decodeFrame(frame)
+	if err != nil {
+		return false, err
+	}
+
+	switch EventID(msg.MethodID) {
+	case CoreEventInfo:
+		info, err := ParseCoreInfo(msg)
+		if err != nil {
+			return false, err
+		}
+		c.coreInfo = info
+		c.state.SetServerID(info.ID)
+		c.state.SetServerInfo(info.Name, info.Version)
+		if c.state.GetState() == StateHelloSent {
+			if err := c.state.TransitionTo(StateHelloReceived); err != nil {
+				return false, err
+			}
+		}
+		c.logger.Debugf("Connection: Core info name=%s version=%s", info.Name, info.Version)
+
+	case CoreEventDone:
+		id, seq, err := ParseCoreDone(msg)
+		if err != nil {
+			return false, err
+		}
+		if id != CoreID || seq != syncSeq {
+			return false, nil
+		}
+		if c.state.GetState() == StateHelloSent {
+			if err := c.state.TransitionTo(StateHelloReceived); err != nil {
+				return false, err
+			}
+		}
+		if err := c.state.TransitionTo(StateReady); err != nil {
+			return false, err
+		}
+		c.logger.Debugf("Connection: Connect sequence complete, registry=%d", c.registryID)
+		return true, nil
+
+	case CoreEventError:
+		derr, err := ParseCoreError(msg)
+		if err != nil {
+			return false, err
+		}
+		return false, fmt.Errorf("daemon rejected connect sequence: %w", derr)
+
+	default:
+		if err := c.processMessage(frame); err != nil {
+			c.logger.Warnf("Connection: Message processing error: %v", err)
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,300 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// expectMethod reads the next message on the daemon side and checks its target
+func expectMethod(t *testing.T, daemon *Connection, objectID uint32, opcode MethodID) *ArgsReader {
+	t.Helper()
+
+	msg, err := daemon.ReadMessage()
+	if err != nil {
+		t.Fatalf("daemon read failed: %v", err)
+	}
+	if msg.ObjectID != objectID || msg.MethodID != uint32(opcode) {
+		t.Fatalf("expected method %d on object %d, got %d on %d",
+			opcode, objectID, msg.MethodID, msg.ObjectID)
+	}
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		t.Fatalf("invalid arguments: %v", err)
+	}
+	return r
+}
+
+// sendEvent writes a Core event from the daemon side
+func sendEvent(t *testing.T, daemon *Connection, event EventID, args *ArgsBuilder) {
+	t.Helper()
+
+	msg := NewMessageBuilder(CoreID, uint32(event)).WithPOD(args.Build()).Build()
+	if err := daemon.WriteMessage(msg); err != nil {
+		t.Fatalf("daemon write failed: %v", err)
+	}
+}
+
+// acceptHandshake plays the daemon side of the connect sequence
+// and returns the sync seq it answered
+func acceptHandshake(t *testing.T, daemon *Connection) uint32 {
+	t.Helper()
+
+	r := expectMethod(t, daemon, CoreID, CoreMethodHello)
+	if v, err := r.Int(); err != nil || v != CoreVersion {
+		t.Fatalf("hello version: %d %v", v, err)
+	}
+
+	r = expectMethod(t, daemon, ClientID, ClientMethodUpdateProperties)
+	props, err := r.Dict()
+	if err != nil {
+		t.Fatalf("update properties: %v", err)
+	}
+	if props["application.name"] == "" || props["application.process.id"] == "" {
+		t.Errorf("missing application properties: %v", props)
+	}
+
+	r = expectMethod(t, daemon, CoreID, CoreMethodGetRegistry)
+	if v, err := r.Int(); err != nil || v != RegistryVersion {
+		t.Fatalf("get registry version: %d %v", v, err)
+	}
+	if id, err := r.Uint(); err != nil || id != 2 {
+		t.Fatalf("get registry new id: %d %v", id, err)
+	}
+
+	r = expectMethod(t, daemon, CoreID, CoreMethodSync)
+	id, _ := r.Uint()
+	seq, err := r.Uint()
+	if err != nil || id != CoreID {
+		t.Fatalf("sync: id=%d %v", id, err)
+	}
+
+	sendEvent(t, daemon, CoreEventInfo, NewArgsBuilder().
+		Int(0).Int(1234).String("user").String("host").String("1.0.5").String("pipewire-0").
+		Long(0x1f).Dict(map[string]string{"core.name": "pipewire-0"}))
+	return seq
+}
+
+func TestHandshakeReady(t *testing.T) {
+	client, daemon := newConnectionPair(t)
+	client.SetProperty("application.name", "handshake-test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.StartEventLoop(ctx)
+
+	seq := acceptHandshake(t, daemon)
+
+	if got := client.GetState(); got == StateReady {
+		t.Fatalf("ready before Core.Done")
+	}
+
+	// A Done for an unrelated seq must not complete the handshake
+	sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(0).Int(int32(seq+100)))
+	sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(0).Int(int32(seq)))
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer waitCancel()
+	if err := client.WaitUntilReady(waitCtx); err != nil {
+		t.Fatalf("WaitUntilReady failed: %v", err)
+	}
+
+	if client.GetState() != StateReady {
+		t.Errorf("expected Ready, got %s", client.GetState())
+	}
+	if client.RegistryID() != 2 {
+		t.Errorf("expected registry id 2, got %d", client.RegistryID())
+	}
+	info := client.CoreInfo()
+	if info == nil || info.Version != "1.0.5" || info.Props["core.name"] != "pipewire-0" {
+		t.Errorf("unexpected core info: %+v", info)
+	}
+	if v := client.StateMachine().GetServerVersion(); v != "1.0.5" {
+		t.Errorf("expected server version 1.0.5, got %q", v)
+	}
+}
+
+func TestHandshakeRejected(t *testing.T) {
+	client, daemon := newConnectionPair(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.StartEventLoop(ctx)
+
+	seq := acceptHandshake(t, daemon)
+	sendEvent(t, daemon, CoreEventError, NewArgsBuilder().
+		Int(0).Int(int32(seq)).Int(-1).String("access denied"))
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer waitCancel()
+	if err := client.WaitUntilReady(waitCtx); err == nil {
+		t.Fatal("expected WaitUntilReady to fail")
+	}
+	if client.GetState() != StateError {
+		t.Errorf("expected Error, got %s", client.GetState())
+	}
+}
+
+func TestArgsRoundTrip(t *testing.T) {
+	b := NewArgsBuilder().
+		Int(-5).Long(1 << 40).ID(7).Bool(true).String("hi").None().
+		Dict(map[string]string{"b": "2", "a": "1"}).
+		Bytes([]byte{1, 2, 3})
+
+	r, err := newArgsReader(b.Encode())
+	if err != nil {
+		t.Fatalf("newArgsReader failed: %v", err)
+	}
+	if v, _ := r.Int(); v != -5 {
+		t.Errorf("Int: got %d", v)
+	}
+	if v, _ := r.Long(); v != 1<<40 {
+		t.Errorf("Long: got %d", v)
+	}
+	if v, _ := r.ID(); v != 7 {
+		t.Errorf("ID: got %d", v)
+	}
+	if v, _ := r.Bool(); !v {
+		t.Error("Bool: got false")
+	}
+	if v, _ := r.String(); v != "hi" {
+		t.Errorf("String: got %q", v)
+	}
+	if v, err := r.String(); err != nil || v != "" {
+		t.Errorf("None as string: %q %v", v, err)
+	}
+	if d, _ := r.Dict(); len(d) != 2 || d["a"] != "1" || d["b"] != "2" {
+		t.Errorf("Dict: got %v", d)
+	}
+	if v, _ := r.Bytes(); len(v) != 3 {
+		t.Errorf("Bytes: got %v", v)
+	}
+	if r.More() {
+		t.Error("expected end of arguments")
+	}
+	if _, err := r.Int(); err == nil {
+		t.Error("expected error reading past the end")
+	}
+}
+
+func TestHelloEncoding(t *testing.T) {
+	msg := NewHelloMessage(CoreVersion)
+	msg.Sequence = 0
+
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := []byte{
+		0x00, 0x00, 0x00, 0x00, // id 0
+		0x18, 0x00, 0x00, 0x01, // size 24, opcode 1
+		0x00, 0x00, 0x00, 0x00, // seq
+		0x00, 0x00, 0x00, 0x00, // n_fds
+		0x10, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, // Struct, 16 bytes
+		0x04, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, // Int
+		0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 4 + padding
+	}
+	if string(data) != string(want) {
+		t.Errorf("hello encoding mismatch:\n got %x\nwant %x", data, want)
+	}
+}
+
+func TestCreateObjectRequest(t *testing.T) {
+	req := &CreateObjectRequest{
+		Factory: LinkFactory,
+		Type:    TypeInterfaceLink,
+		Version: LinkVersion,
+		Props:   map[string]string{"link.output.port": "40", "link.input.port": "41"},
+		NewID:   5,
+	}
+	msg := req.Message()
+	if msg.ObjectID != CoreID || msg.MethodID != uint32(CoreMethodCreateObject) {
+		t.Fatalf("create_object sent as %d.%d", msg.ObjectID, msg.MethodID)
+	}
+
+	parsed, err := ParseCreateObject(msg)
+	if err != nil || !reflect.DeepEqual(parsed, req) {
+		t.Fatalf("ParseCreateObject: %+v %v", parsed, err)
+	}
+}
+
+// readyPair returns a client connection that completed the connect sequence
+func readyPair(t *testing.T) (*Connection, *Connection) {
+	t.Helper()
+
+	client, daemon := newConnectionPair(t)
+	client.SetEventHandler(NewEventHandler())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go client.StartEventLoop(ctx)
+
+	seq := acceptHandshake(t, daemon)
+	sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(0).Int(int32(seq)))
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer waitCancel()
+	if err := client.WaitUntilReady(waitCtx); err != nil {
+		t.Fatalf("WaitUntilReady failed: %v", err)
+	}
+	return client, daemon
+}
+
+func TestRoundtrip(t *testing.T) {
+	client, daemon := readyPair(t)
+
+	result := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		result <- client.Roundtrip(ctx)
+	}()
+
+	r := expectMethod(t, daemon, CoreID, CoreMethodSync)
+	id, _ := r.Uint()
+	seq, _ := r.Uint()
+
+	// An older done must not release the waiter
+	sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(int32(id)).Int(int32(seq-1)))
+	select {
+	case err := <-result:
+		t.Fatalf("Roundtrip returned early: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(int32(id)).Int(int32(seq)))
+	if err := <-result; err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+}
+
+func TestRoundtripContextCancel(t *testing.T) {
+	client, _ := readyPair(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Roundtrip(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRoundtripConnectionClosed(t *testing.T) {
+	client, daemon := readyPair(t)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- client.Roundtrip(context.Background())
+	}()
+
+	expectMethod(t, daemon, CoreID, CoreMethodSync)
+	daemon.Close()
+
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Fatal("expected error after daemon hung up")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Roundtrip did not return after daemon hung up")
+	}
+}
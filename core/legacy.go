@@ -0,0 +1,579 @@
+// Package core - Protocol version 0
+// core/legacy.go
+// Framing and method translation for peers speaking protocol version 0
+// (pipewire 0.2)
+//
+// Version 0 frames have an 8-byte header, id then opcode<<24|size, with no
+// seq or fd count. The Core, Client and Registry interfaces are numbered
+// differently, some methods and events take other arguments, and types are
+// referred to by ids each side announces with Core.UpdateTypes. The
+// connection converts frames at the socket so the rest of the package only
+// sees the current layout
+
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// LegacyHeaderSize is the size of the protocol version 0 message header
+const LegacyHeaderSize = 8
+
+// LegacyCoreMethod is a Core method opcode of protocol version 0 peers
+// (pipewire 0.2); the numbering differs from MethodID
+type LegacyCoreMethod uint32
+
+const (
+	LegacyCoreMethodHello        LegacyCoreMethod = 0
+	LegacyCoreMethodUpdateTypes  LegacyCoreMethod = 1
+	LegacyCoreMethodSync         LegacyCoreMethod = 2
+	LegacyCoreMethodGetRegistry  LegacyCoreMethod = 3
+	LegacyCoreMethodClientUpdate LegacyCoreMethod = 4
+	LegacyCoreMethodPermissions  LegacyCoreMethod = 5
+	LegacyCoreMethodCreateObject LegacyCoreMethod = 6
+	LegacyCoreMethodDestroy      LegacyCoreMethod = 7
+)
+
+// LegacyCoreEvent is a Core event opcode of protocol version 0 peers;
+// the numbering differs from EventID
+type LegacyCoreEvent uint32
+
+const (
+	LegacyCoreEventUpdateTypes LegacyCoreEvent = 0
+	LegacyCoreEventDone        LegacyCoreEvent = 1
+	LegacyCoreEventError       LegacyCoreEvent = 2
+	LegacyCoreEventRemoveID    LegacyCoreEvent = 3
+	LegacyCoreEventInfo        LegacyCoreEvent = 4
+)
+
+// Registry opcodes of protocol version 0 peers; there is no Registry.Destroy
+const (
+	LegacyRegistryMethodBind        MethodID = 0
+	LegacyRegistryEventGlobal       EventID  = 0
+	LegacyRegistryEventGlobalRemove EventID  = 1
+)
+
+// POD types numbered differently by protocol version 0, which has no
+// Sequence: Pointer and Fd come one earlier
+const (
+	legacyPODTypePointer uint32 = 16
+	legacyPODTypeFd      uint32 = 17
+)
+
+// legacyInterfaces are given client type ids before the connect sequence,
+// so the first Core.UpdateTypes announces them
+var legacyInterfaces = []string{
+	TypeInterfaceCore,
+	TypeInterfaceRegistry,
+	TypeInterfaceClient,
+	TypeInterfaceModule,
+	TypeInterfaceFactory,
+	TypeInterfaceDevice,
+	TypeInterfaceNode,
+	TypeInterfacePort,
+	TypeInterfaceLink,
+}
+
+// SetLegacyProtocol makes the connection speak protocol version 0
+// Must be called before StartEventLoop. Frames use the 8-byte header both
+// ways; the connect sequence, Core methods, Client.UpdateProperties and
+// Registry.Bind are sent as their version 0 counterparts, after a
+// Core.UpdateTypes announcing the client type ids they use; Core and
+// Registry events are rewritten in the current layout and the type ids of
+// the other received PODs translated to local ids. Version 0 has no
+// Core.BoundID, Core.Ping or Registry.Destroy, and the events of bound
+// objects keep their version 0 arguments
+func (c *Connection) SetLegacyProtocol(enabled bool) {
+	if enabled {
+		for _, name := range legacyInterfaces {
+			c.types.ClientID(name)
+		}
+	}
+	c.legacy.Store(enabled)
+}
+
+// ReadLegacyFrame extracts a complete protocol version 0 frame from the
+// buffer, returned with a current 16-byte header whose seq and n_fds are 0
+// Returns (nil, nil) when the buffer does not yet hold a complete frame
+func (m *MessageBuffer) ReadLegacyFrame() (*Frame, error) {
+	if len(m.buffer) < LegacyHeaderSize {
+		return nil, nil
+	}
+
+	objectID := binary.LittleEndian.Uint32(m.buffer[0:4])
+	word := binary.LittleEndian.Uint32(m.buffer[4:8])
+	size := int(word & MaxPayloadSize)
+	if HeaderSize+size > m.maxSize {
+		return nil, NewProtocolErrorf("frame too large: %d bytes (max %d)", HeaderSize+size, m.maxSize)
+	}
+	if len(m.buffer) < LegacyHeaderSize+size {
+		return nil, nil
+	}
+
+	frame := &Frame{
+		Header:    make([]byte, HeaderSize),
+		Data:      make([]byte, size),
+		Complete:  true,
+		FrameSize: HeaderSize + size,
+		ObjectID:  objectID,
+		Opcode:    word >> 24,
+	}
+	binary.LittleEndian.PutUint32(frame.Header[0:4], objectID)
+	binary.LittleEndian.PutUint32(frame.Header[4:8], word)
+	copy(frame.Data, m.buffer[LegacyHeaderSize:LegacyHeaderSize+size])
+
+	m.buffer = m.buffer[LegacyHeaderSize+size:]
+	return frame, nil
+}
+
+// handleTypeFrame converts a frame received from a protocol version 0 peer
+// Core and Registry events are renumbered and rewritten in the current
+// layout, the type ids of other frames are translated to local ids
+// Returns true when the frame was consumed here: a Core.UpdateTypes, or an
+// event that does not decode
+func (c *Connection) handleTypeFrame(frame *Frame) bool {
+	if !c.legacy.Load() {
+		return false
+	}
+
+	if err := legacyPODTypes(frame.Data, false); err != nil {
+		c.logger.Warnf("Connection: Invalid legacy frame %d/%d: %v", frame.ObjectID, frame.Opcode, err)
+		return true
+	}
+
+	switch frame.ObjectID {
+	case CoreID:
+		// The opcode is renumbered by the translation
+		event := LegacyCoreEvent(frame.Opcode)
+		if err := c.translateLegacyCoreEvent(frame); err != nil {
+			c.logger.Warnf("Connection: Invalid legacy core event %d: %v", event, err)
+			return true
+		}
+		return event == LegacyCoreEventUpdateTypes
+
+	case c.registryID:
+		if err := c.translateLegacyRegistryEvent(frame); err != nil {
+			c.logger.Warnf("Connection: Invalid legacy registry event %d: %v", frame.Opcode, err)
+			return true
+		}
+		return false
+	}
+
+	if c.types.HasRemote() {
+		if err := c.types.TranslatePOD(frame.Data); err != nil {
+			c.logger.Warnf("Connection: Type translation of %d/%d failed: %v", frame.ObjectID, frame.Opcode, err)
+		}
+	}
+	return false
+}
+
+// translateLegacyCoreEvent records a Core.UpdateTypes, and rewrites the
+// other protocol version 0 Core events as their current counterparts
+func (c *Connection) translateLegacyCoreEvent(frame *Frame) error {
+	msg, err := c.decodeFrame(frame)
+	if err != nil {
+		return err
+	}
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return err
+	}
+
+	var event EventID
+	var args *ArgsBuilder
+	switch LegacyCoreEvent(frame.Opcode) {
+	case LegacyCoreEventUpdateTypes:
+		firstID, names, err := ParseUpdateTypes(msg)
+		if err != nil {
+			return err
+		}
+		c.types.UpdateRemote(firstID, names)
+		c.logger.Debugf("Connection: Server types %d..%d updated", firstID, firstID+uint32(len(names)))
+		return nil
+
+	case LegacyCoreEventDone:
+		// done(seq)
+		seq, err := r.Int()
+		if err != nil {
+			return fmt.Errorf("done seq: %w", err)
+		}
+		event, args = CoreEventDone, NewArgsBuilder().Int(int32(CoreID)).Int(seq)
+
+	case LegacyCoreEventError:
+		// error(id, res, message), without the seq of the failing method
+		id, err := r.Int()
+		if err != nil {
+			return fmt.Errorf("error id: %w", err)
+		}
+		res, err := r.Int()
+		if err != nil {
+			return fmt.Errorf("error res: %w", err)
+		}
+		message, err := r.String()
+		if err != nil {
+			return fmt.Errorf("error message: %w", err)
+		}
+		event, args = CoreEventError, NewArgsBuilder().Int(id).Int(-1).Int(res).String(message) // seq SPA_ID_INVALID
+
+	case LegacyCoreEventRemoveID:
+		// remove_id(id)
+		id, err := r.Int()
+		if err != nil {
+			return fmt.Errorf("remove_id id: %w", err)
+		}
+		event, args = CoreEventRemoveID, NewArgsBuilder().Int(id)
+
+	case LegacyCoreEventInfo:
+		info, err := parseLegacyCoreInfo(r)
+		if err != nil {
+			return err
+		}
+		event, args = CoreEventInfo, NewArgsBuilder().
+			Int(int32(info.ID)).Int(int32(info.Cookie)).
+			String(info.UserName).String(info.HostName).String(info.Version).String(info.Name).
+			Long(int64(info.ChangeMask)).Dict(info.Props)
+
+	default:
+		return fmt.Errorf("unknown opcode")
+	}
+	return rewriteFrame(frame, uint32(event), args)
+}
+
+// translateLegacyRegistryEvent rewrites the protocol version 0
+// Registry.Global(id, parent_id, permissions, type, version, n_items,
+// (key, value)*) as Registry.Global(id, permissions, type, version, props)
+// with the type named; Registry.GlobalRemove(id) is the same in both
+func (c *Connection) translateLegacyRegistryEvent(frame *Frame) error {
+	switch EventID(frame.Opcode) {
+	case LegacyRegistryEventGlobalRemove:
+		return nil
+	case LegacyRegistryEventGlobal:
+	default:
+		return fmt.Errorf("unknown opcode")
+	}
+
+	msg, err := c.decodeFrame(frame)
+	if err != nil {
+		return err
+	}
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return err
+	}
+
+	id, err := r.Int()
+	if err != nil {
+		return fmt.Errorf("global id: %w", err)
+	}
+	if _, err := r.Int(); err != nil {
+		return fmt.Errorf("global parent_id: %w", err)
+	}
+	permissions, err := r.Int()
+	if err != nil {
+		return fmt.Errorf("global permissions: %w", err)
+	}
+	typeID, err := r.ID()
+	if err != nil {
+		return fmt.Errorf("global type: %w", err)
+	}
+	iface, ok := c.types.RemoteName(typeID)
+	if !ok {
+		return fmt.Errorf("global %d: type %d not announced", id, typeID)
+	}
+	version, err := r.Int()
+	if err != nil {
+		return fmt.Errorf("global version: %w", err)
+	}
+	props, err := readLegacyDict(r)
+	if err != nil {
+		return fmt.Errorf("global props: %w", err)
+	}
+
+	return rewriteFrame(frame, uint32(RegistryEventTypeGlobal), NewArgsBuilder().
+		Int(id).Int(permissions).String(iface).Int(version).Dict(props))
+}
+
+// rewriteFrame replaces the content of frame with event and args in the
+// current layout, keeping its object, seq and fds
+func rewriteFrame(frame *Frame, event uint32, args *ArgsBuilder) error {
+	data, err := NewMessageBuilder(frame.ObjectID, event).
+		WithSequence(frame.Sequence).
+		WithPOD(args.Build()).
+		Build().
+		Marshal()
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(data[12:16], frame.NumFDs)
+	frame.Header, frame.Data = data[:HeaderSize], data[HeaderSize:]
+	frame.Opcode, frame.FrameSize = event, len(data)
+	return nil
+}
+
+// parseLegacyCoreInfo decodes the protocol version 0 Core.Info(id,
+// change_mask, user, host, version, name, cookie, n_items, (key, value)*)
+func parseLegacyCoreInfo(r *ArgsReader) (*CoreInfoEvent, error) {
+	var err error
+	info := &CoreInfoEvent{}
+	if info.ID, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("info id: %w", err)
+	}
+	mask, err := r.Long()
+	if err != nil {
+		return nil, fmt.Errorf("info change mask: %w", err)
+	}
+	info.ChangeMask = uint64(mask)
+	for _, field := range []*string{&info.UserName, &info.HostName, &info.Version, &info.Name} {
+		if *field, err = r.String(); err != nil {
+			return nil, fmt.Errorf("info strings: %w", err)
+		}
+	}
+	if info.Cookie, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("info cookie: %w", err)
+	}
+	if info.Props, err = readLegacyDict(r); err != nil {
+		return nil, fmt.Errorf("info props: %w", err)
+	}
+	return info, nil
+}
+
+// readLegacyDict reads a protocol version 0 dict: Int n_items then the
+// (String key, String value) pairs, flattened into the arguments
+func readLegacyDict(r *ArgsReader) (map[string]string, error) {
+	n, err := r.Int()
+	if err != nil {
+		return nil, fmt.Errorf("n_items: %w", err)
+	}
+	props := make(map[string]string)
+	for i := int32(0); i < n; i++ {
+		k, err := r.String()
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		if props[k], err = r.String(); err != nil {
+			return nil, fmt.Errorf("value %q: %w", k, err)
+		}
+	}
+	return props, nil
+}
+
+// legacyDict appends props as a protocol version 0 dict, in sorted key order
+func legacyDict(b *ArgsBuilder, props map[string]string) *ArgsBuilder {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b.Int(int32(len(keys)))
+	for _, k := range keys {
+		b.String(k).String(props[k])
+	}
+	return b
+}
+
+// writeLegacyMessage is WriteMessageCtx for a protocol version 0 peer
+// Counters and capture record the message in the current layout
+func (c *Connection) writeLegacyMessage(ctx context.Context, msg *MessageFrame) error {
+	record, err := msg.Marshal()
+	if err != nil {
+		return NewProtocolErrorf("failed to marshal message: %v", err)
+	}
+
+	// Types are announced in the order the messages using them are written
+	c.legacyMu.Lock()
+	defer c.legacyMu.Unlock()
+
+	data, err := c.marshalLegacy(msg)
+	if err != nil {
+		return NewProtocolErrorf("message %d/%d: %v", msg.ObjectID, msg.MethodID, err)
+	}
+	if err := c.queueWrite(ctx, data, msg.FDs); err != nil {
+		return err
+	}
+	c.countFrames(CaptureSent, record)
+	c.captureFrames(CaptureSent, record)
+
+	c.logger.Debugf("Legacy message sent: id=%d opcode=%d size=%d",
+		msg.ObjectID, msg.MethodID, len(data))
+	return nil
+}
+
+// marshalLegacy encodes msg as protocol version 0 frames: a Core.UpdateTypes
+// for the client type ids not announced yet, then the method itself
+// Caller holds c.legacyMu
+func (c *Connection) marshalLegacy(msg *MessageFrame) ([]byte, error) {
+	objectID, opcode, pod, err := c.translateLegacyMethod(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := legacyPODTypes(pod, true); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if firstID, names := c.types.Unannounced(); len(names) > 0 {
+		update, err := NewUpdateTypesMessage(firstID, names).PODData.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		data = appendLegacyFrame(data, CoreID, uint32(LegacyCoreMethodUpdateTypes), update)
+	}
+	return appendLegacyFrame(data, objectID, opcode, pod), nil
+}
+
+// appendLegacyFrame appends a frame with the protocol version 0 header to data
+func appendLegacyFrame(data []byte, objectID, opcode uint32, pod []byte) []byte {
+	data = binary.LittleEndian.AppendUint32(data, objectID)
+	data = binary.LittleEndian.AppendUint32(data, opcode<<24|uint32(len(pod)))
+	return append(data, pod...)
+}
+
+// translateLegacyMethod returns the object, opcode and arguments of the
+// protocol version 0 counterpart of msg
+// Methods of bound objects keep their arguments, with local type ids
+// translated to client ids; the footer is dropped
+func (c *Connection) translateLegacyMethod(msg *MessageFrame) (objectID, opcode uint32, pod []byte, err error) {
+	var args *ArgsBuilder
+	objectID = msg.ObjectID
+
+	switch {
+	case msg.ObjectID == CoreID:
+		args, opcode, err = c.translateLegacyCoreMethod(msg)
+
+	case msg.ObjectID == ClientID && MethodID(msg.MethodID) == ClientMethodUpdateProperties:
+		// Core.client_update(props)
+		var r *ArgsReader
+		var props map[string]string
+		if r, err = NewArgsReader(msg); err == nil {
+			props, err = r.Dict()
+		}
+		objectID, opcode = CoreID, uint32(LegacyCoreMethodClientUpdate)
+		args = legacyDict(NewArgsBuilder(), props)
+
+	case msg.ObjectID == ClientID:
+		err = fmt.Errorf("client method %d has no protocol version 0 counterpart", msg.MethodID)
+
+	case msg.ObjectID == c.registryID && MethodID(msg.MethodID) == RegistryMethodBind:
+		// bind(id, type, version, new_id) with the type as a client id
+		var bind *RegistryBindRequest
+		if bind, err = ParseRegistryBind(msg); err == nil {
+			opcode = uint32(LegacyRegistryMethodBind)
+			args = NewArgsBuilder().Int(int32(bind.ID)).ID(c.types.ClientID(bind.Type)).
+				Int(int32(bind.Version)).Int(int32(bind.NewID))
+		}
+
+	case msg.ObjectID == c.registryID:
+		err = fmt.Errorf("registry method %d has no protocol version 0 counterpart", msg.MethodID)
+
+	default:
+		opcode = msg.MethodID
+		if msg.PODData != nil {
+			var raw []byte
+			if raw, err = msg.PODData.Marshal(); err == nil {
+				pod = append([]byte(nil), raw...)
+				err = c.types.TranslatePODToClient(pod)
+			}
+		}
+		return objectID, opcode, pod, err
+	}
+
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return objectID, opcode, args.Encode(), nil
+}
+
+// translateLegacyCoreMethod returns the arguments and opcode of the protocol
+// version 0 counterpart of a Core method
+func (c *Connection) translateLegacyCoreMethod(msg *MessageFrame) (*ArgsBuilder, uint32, error) {
+	if MethodID(msg.MethodID) == CoreMethodCreateObject {
+		// create_object(factory_name, type, version, n_items, (key, value)*, new_id)
+		req, err := ParseCreateObject(msg)
+		if err != nil {
+			return nil, 0, err
+		}
+		args := NewArgsBuilder().String(req.Factory).ID(c.types.ClientID(req.Type)).Int(int32(req.Version))
+		return legacyDict(args, req.Props).Int(int32(req.NewID)), uint32(LegacyCoreMethodCreateObject), nil
+	}
+
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch MethodID(msg.MethodID) {
+	case CoreMethodHello:
+		// hello() carries a NULL pointer in place of the version
+		return NewArgsBuilder().add(spa.NewPODPointer(0, 0)), uint32(LegacyCoreMethodHello), nil
+
+	case CoreMethodSync:
+		// sync(seq), answered with done(seq) on the core
+		if _, err := r.Int(); err != nil {
+			return nil, 0, fmt.Errorf("sync id: %w", err)
+		}
+		seq, err := r.Int()
+		if err != nil {
+			return nil, 0, fmt.Errorf("sync seq: %w", err)
+		}
+		return NewArgsBuilder().Int(seq), uint32(LegacyCoreMethodSync), nil
+
+	case CoreMethodGetRegistry:
+		// get_registry(version, new_id)
+		version, err := r.Int()
+		if err != nil {
+			return nil, 0, fmt.Errorf("get_registry version: %w", err)
+		}
+		newID, err := r.Int()
+		if err != nil {
+			return nil, 0, fmt.Errorf("get_registry new_id: %w", err)
+		}
+		return NewArgsBuilder().Int(version).Int(newID), uint32(LegacyCoreMethodGetRegistry), nil
+
+	case CoreMethodDestroy:
+		// destroy(id)
+		id, err := r.Int()
+		if err != nil {
+			return nil, 0, fmt.Errorf("destroy id: %w", err)
+		}
+		return NewArgsBuilder().Int(id), uint32(LegacyCoreMethodDestroy), nil
+	}
+	return nil, 0, fmt.Errorf("core method %d has no protocol version 0 counterpart", msg.MethodID)
+}
+
+// legacyPODTypes rewrites, in place, the type of the Pointer and Fd PODs
+// among the consecutive PODs of data, and of the Structs they contain,
+// to the protocol version 0 numbering when toLegacy is set, from it otherwise
+func legacyPODTypes(data []byte, toLegacy bool) error {
+	for len(data) > 0 {
+		size, err := podTotalSize(data)
+		if err != nil {
+			return err
+		}
+		pod := data[:size]
+
+		podType := binary.LittleEndian.Uint32(pod[4:8])
+		switch {
+		case podType == spa.PODTypeStruct:
+			bodySize := int(binary.LittleEndian.Uint32(pod[0:4]))
+			if err := legacyPODTypes(pod[podHeaderSize:podHeaderSize+bodySize], toLegacy); err != nil {
+				return err
+			}
+		case toLegacy && podType == spa.PODTypePointer:
+			binary.LittleEndian.PutUint32(pod[4:8], legacyPODTypePointer)
+		case toLegacy && podType == spa.PODTypeFd:
+			binary.LittleEndian.PutUint32(pod[4:8], legacyPODTypeFd)
+		case !toLegacy && podType == legacyPODTypePointer:
+			binary.LittleEndian.PutUint32(pod[4:8], spa.PODTypePointer)
+		case !toLegacy && podType == legacyPODTypeFd:
+			binary.LittleEndian.PutUint32(pod[4:8], spa.PODTypeFd)
+		}
+		data = data[size:]
+	}
+	return nil
+}
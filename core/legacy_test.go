@@ -0,0 +1,338 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// legacyPeer plays a protocol version 0 daemon on the raw socket
+type legacyPeer struct {
+	t    *testing.T
+	conn *net.UnixConn
+}
+
+// newLegacyPair returns a client connection in legacy mode and the peer
+// on the other end of its socketpair
+func newLegacyPair(t *testing.T) (*Connection, *legacyPeer) {
+	t.Helper()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("socketpair: %v", err)
+	}
+	wrap := func(fd int, name string) *net.UnixConn {
+		f := os.NewFile(uintptr(fd), name)
+		defer f.Close()
+		fc, err := net.FileConn(f)
+		if err != nil {
+			t.Fatalf("FileConn: %v", err)
+		}
+		return fc.(*net.UnixConn)
+	}
+
+	client := NewConnectionFromSocket(wrap(fds[0], "client"), nil)
+	client.SetLegacyProtocol(true)
+	client.SetEventHandler(NewEventHandler())
+	peer := &legacyPeer{t: t, conn: wrap(fds[1], "peer")}
+	t.Cleanup(func() {
+		client.Close()
+		peer.conn.Close()
+	})
+	return client, peer
+}
+
+// read returns the next frame, checking its 8-byte header
+func (p *legacyPeer) read(objectID, opcode uint32) []byte {
+	p.t.Helper()
+
+	p.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	header := make([]byte, LegacyHeaderSize)
+	if _, err := io.ReadFull(p.conn, header); err != nil {
+		p.t.Fatalf("peer read failed: %v", err)
+	}
+	id, word := binary.LittleEndian.Uint32(header[0:4]), binary.LittleEndian.Uint32(header[4:8])
+	payload := make([]byte, word&MaxPayloadSize)
+	if _, err := io.ReadFull(p.conn, payload); err != nil {
+		p.t.Fatalf("peer read failed: %v", err)
+	}
+	if id != objectID || word>>24 != opcode {
+		p.t.Fatalf("expected opcode %d on object %d, got %d on %d", opcode, objectID, word>>24, id)
+	}
+	return payload
+}
+
+// expect reads the next frame and returns a reader over its arguments
+func (p *legacyPeer) expect(objectID, opcode uint32) *ArgsReader {
+	p.t.Helper()
+
+	r, err := newArgsReader(p.read(objectID, opcode))
+	if err != nil {
+		p.t.Fatalf("invalid arguments: %v", err)
+	}
+	return r
+}
+
+// send writes a frame with the 8-byte header
+func (p *legacyPeer) send(objectID, opcode uint32, args *ArgsBuilder) {
+	p.t.Helper()
+	p.sendPOD(objectID, opcode, args.Encode())
+}
+
+// sendPOD writes a frame carrying pod, fds attached
+func (p *legacyPeer) sendPOD(objectID, opcode uint32, pod []byte, fds ...int) {
+	p.t.Helper()
+
+	data := appendLegacyFrame(nil, objectID, opcode, pod)
+	if _, _, err := p.conn.WriteMsgUnix(data, syscall.UnixRights(fds...), nil); err != nil {
+		p.t.Fatalf("peer write failed: %v", err)
+	}
+}
+
+// acceptLegacyHandshake plays the version 0 connect sequence and returns
+// the client type ids it announced
+func (p *legacyPeer) acceptLegacyHandshake() []string {
+	p.t.Helper()
+
+	// The client types come before any other method
+	r := p.expect(CoreID, uint32(LegacyCoreMethodUpdateTypes))
+	first, _ := r.Uint()
+	n, _ := r.Uint()
+	var types []string
+	for i := uint32(0); i < n; i++ {
+		name, err := r.String()
+		if err != nil {
+			p.t.Fatalf("update_types: %v", err)
+		}
+		types = append(types, name)
+	}
+	if first != 0 || len(types) == 0 || types[0] != TypeInterfaceCore {
+		p.t.Fatalf("unexpected update_types %d %v", first, types)
+	}
+
+	// hello(NULL), with the version 0 Pointer type
+	hello := p.read(CoreID, uint32(LegacyCoreMethodHello))
+	if len(hello) < 16 || binary.LittleEndian.Uint32(hello[12:16]) != legacyPODTypePointer {
+		p.t.Fatalf("unexpected hello %x", hello)
+	}
+
+	r = p.expect(CoreID, uint32(LegacyCoreMethodClientUpdate))
+	props, err := readLegacyDict(r)
+	if err != nil || props["application.name"] == "" {
+		p.t.Fatalf("client_update: %v %v", props, err)
+	}
+
+	r = p.expect(CoreID, uint32(LegacyCoreMethodGetRegistry))
+	r.Int()
+	if id, err := r.Uint(); err != nil || id != 2 {
+		p.t.Fatalf("get_registry new id: %d %v", id, err)
+	}
+
+	r = p.expect(CoreID, uint32(LegacyCoreMethodSync))
+	seq, err := r.Int()
+	if err != nil {
+		p.t.Fatalf("sync: %v", err)
+	}
+
+	p.send(CoreID, uint32(LegacyCoreEventUpdateTypes), NewArgsBuilder().
+		Int(10).Int(2).String(TypeInterfaceNode).String("Spa:Pod:Object:Param:Format"))
+	p.send(CoreID, uint32(LegacyCoreEventInfo), NewArgsBuilder().Int(0).Long(0xf).
+		String("user").String("host").String("0.2.7").String("pipewire-0").Int(1234).
+		Int(1).String("core.name").String("pipewire-0"))
+	p.send(2, uint32(LegacyRegistryEventGlobal), NewArgsBuilder().
+		Int(30).Int(0).Int(0x1c8).ID(10).Int(2).Int(1).String("node.name").String("sink"))
+	p.send(CoreID, uint32(LegacyCoreEventDone), NewArgsBuilder().Int(seq))
+	return types
+}
+
+// legacyReadyPair returns a legacy client that completed the connect
+// sequence, the peer, the announced client types and the registry globals
+func legacyReadyPair(t *testing.T) (*Connection, *legacyPeer, []string, chan *RegistryGlobalEvent) {
+	t.Helper()
+
+	client, peer := newLegacyPair(t)
+	globals := make(chan *RegistryGlobalEvent, 4)
+	client.EventHandler().RegisterHandler(client.RegistryID(), func(msg *MessageFrame) error {
+		if RegistryEventType(msg.MethodID) != RegistryEventTypeGlobal {
+			return nil
+		}
+		global, err := ParseRegistryGlobal(msg)
+		if err != nil {
+			return err
+		}
+		globals <- global
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go client.StartEventLoop(ctx)
+
+	types := peer.acceptLegacyHandshake()
+	waitCtx, waitCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer waitCancel()
+	if err := client.WaitUntilReady(waitCtx); err != nil {
+		t.Fatalf("WaitUntilReady failed: %v", err)
+	}
+	return client, peer, types, globals
+}
+
+func TestLegacyHandshake(t *testing.T) {
+	client, peer, _, globals := legacyReadyPair(t)
+
+	if info := client.CoreInfo(); info == nil || info.Version != "0.2.7" || info.Props["core.name"] != "pipewire-0" {
+		t.Fatalf("unexpected core info %+v", info)
+	}
+	select {
+	case g := <-globals:
+		if g.ID != 30 || g.Type != TypeInterfaceNode || g.Version != 2 || g.Props["node.name"] != "sink" {
+			t.Errorf("unexpected global %+v", g)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("global not delivered")
+	}
+
+	// Events of bound objects see server ids translated, and Fd values
+	// index the fds sent with the frame
+	const proxyID = 5
+	type event struct {
+		id uint32
+		fd int
+	}
+	events := make(chan event, 1)
+	client.EventHandler().RegisterHandler(proxyID, func(msg *MessageFrame) error {
+		r, err := NewArgsReader(msg)
+		if err != nil {
+			return err
+		}
+		id, err := r.ID()
+		if err != nil {
+			return err
+		}
+		fd, err := r.TakeFd()
+		events <- event{id, fd}
+		return err
+	})
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+	pod := NewArgsBuilder().ID(11).Fd(0).Encode()
+	binary.LittleEndian.PutUint32(pod[20:24], legacyPODTypeFd)
+	peer.sendPOD(proxyID, 0, pod, int(pw.Fd()))
+
+	select {
+	case e := <-events:
+		if e.id != 0x40003 {
+			t.Errorf("got id %#x, want %#x", e.id, 0x40003)
+		}
+		if e.fd < 0 {
+			t.Fatalf("no fd received")
+		}
+		f := os.NewFile(uintptr(e.fd), "received")
+		f.Write([]byte("x"))
+		f.Close()
+		buf := make([]byte, 1)
+		if _, err := pr.Read(buf); err != nil || buf[0] != 'x' {
+			t.Errorf("received fd is not the pipe: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("event not delivered")
+	}
+}
+
+func TestLegacyMethods(t *testing.T) {
+	client, peer, types, _ := legacyReadyPair(t)
+
+	clientID := func(name string) uint32 {
+		for i, n := range types {
+			if n == name {
+				return uint32(i)
+			}
+		}
+		t.Fatalf("type %s not announced", name)
+		return 0
+	}
+
+	// bind(id, type, version, new_id) names the type with a client id
+	bind := &RegistryBindRequest{ID: 30, Type: TypeInterfaceNode, Version: 2, NewID: 3}
+	if _, err := client.Send(bind.Message(client.RegistryID())); err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+	r := peer.expect(2, uint32(LegacyRegistryMethodBind))
+	id, _ := r.Uint()
+	typeID, _ := r.ID()
+	version, _ := r.Uint()
+	newID, err := r.Uint()
+	if err != nil || id != 30 || typeID != clientID(TypeInterfaceNode) || version != 2 || newID != 3 {
+		t.Errorf("unexpected bind %d %d %d %d: %v", id, typeID, version, newID, err)
+	}
+
+	// A type used for the first time is announced before the method
+	create := &CreateObjectRequest{Factory: "test-factory", Type: "PipeWire:Interface:Test",
+		Version: 1, Props: map[string]string{"object.linger": "true"}, NewID: 4}
+	if _, err := client.Send(create.Message()); err != nil {
+		t.Fatalf("create_object failed: %v", err)
+	}
+	r = peer.expect(CoreID, uint32(LegacyCoreMethodUpdateTypes))
+	first, _ := r.Uint()
+	n, _ := r.Uint()
+	name, _ := r.String()
+	if first != uint32(len(types)) || n != 1 || name != create.Type {
+		t.Fatalf("unexpected update_types %d %d %q", first, n, name)
+	}
+	r = peer.expect(CoreID, uint32(LegacyCoreMethodCreateObject))
+	factory, _ := r.String()
+	typeID, _ = r.ID()
+	r.Int()
+	props, _ := readLegacyDict(r)
+	newID, err = r.Uint()
+	if err != nil || factory != create.Factory || typeID != first || props["object.linger"] != "true" || newID != 4 {
+		t.Errorf("unexpected create_object %q %d %v %d: %v", factory, typeID, props, newID, err)
+	}
+
+	// Registry.Destroy does not exist in version 0
+	if _, err := client.Send(NewRegistryDestroyMessage(client.RegistryID(), 30)); err == nil {
+		t.Error("registry destroy sent to a version 0 peer")
+	}
+
+	// error(id, res, message) fails the request on the object
+	const proxyID = 5
+	req := client.EventHandler().CreatePendingRequestFor(40, proxyID)
+	peer.send(CoreID, uint32(LegacyCoreEventError), NewArgsBuilder().Int(proxyID).Int(-22).String("bad"))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = client.EventHandler().WaitForRequestCtx(ctx, req)
+	var derr *DaemonError
+	if !errors.As(err, &derr) || derr.ID != proxyID || derr.Res != -22 || derr.Message != "bad" {
+		t.Fatalf("expected the daemon error, got %v", err)
+	}
+
+	// sync(seq) and done(seq) make a round trip
+	errc := make(chan error, 1)
+	go func() { errc <- client.Roundtrip(ctx) }()
+	r = peer.expect(CoreID, uint32(LegacyCoreMethodSync))
+	seq, _ := r.Int()
+	if r.More() {
+		t.Error("sync carries more than the seq")
+	}
+	peer.send(CoreID, uint32(LegacyCoreEventDone), NewArgsBuilder().Int(seq))
+	if err := <-errc; err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+
+	// Counters see the methods in the current layout
+	stats := client.Stats()
+	if n := stats.OutByMessage[FrameKey{"core", uint32(CoreMethodGetRegistry)}].Frames; n != 1 {
+		t.Errorf("%d get_registry counted, want 1", n)
+	}
+}
@@ -0,0 +1,54 @@
+// Package core - Decoding limits
+// core/limits.go
+// Bounds on what a connection accepts from the daemon
+
+package core
+
+import (
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// Limits bounds the frames, PODs and registry a connection accepts, so a
+// broken or malicious daemon gets a ProtocolError instead of making the
+// client allocate without bounds
+// A zero MaxFrameSize keeps the 1MB default, other zero fields disable their limit
+type Limits struct {
+	MaxFrameSize int        // Header and payload of one frame
+	MaxFDs       int        // Descriptors announced by one frame
+	POD          spa.Limits // Arguments and footer of one frame
+	MaxGlobals   int        // Registry globals a client keeps track of
+}
+
+// DefaultLimits returns the limits of a new connection
+func DefaultLimits() Limits {
+	return Limits{
+		MaxFrameSize: MaxMessageSize,
+		MaxFDs:       MaxFDsPerMessage,
+		POD:          spa.DefaultLimits(),
+		MaxGlobals:   1 << 16,
+	}
+}
+
+// frameSize returns MaxFrameSize or the default when unset
+func (l Limits) frameSize() int {
+	if l.MaxFrameSize <= 0 {
+		return MaxMessageSize
+	}
+	return l.MaxFrameSize
+}
+
+// SetLimits replaces the limits of the connection
+// MaxFrameSize applies to event loops started afterwards
+func (c *Connection) SetLimits(limits Limits) {
+	c.limits.Store(&limits)
+}
+
+// Limits returns the limits of the connection
+func (c *Connection) Limits() Limits {
+	return *c.limits.Load()
+}
+
+// decodeFrame decodes a received frame within the connection limits
+func (c *Connection) decodeFrame(frame *Frame) (*MessageFrame, error) {
+	return frame.MessageLimits(c.Limits())
+}
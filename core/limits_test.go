@@ -0,0 +1,115 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// nestedArgs returns arguments holding depth nested structs
+func nestedArgs(depth int) *ArgsBuilder {
+	b := NewArgsBuilder().Int(0).Int(4)
+	for i := 0; i < depth; i++ {
+		b.BeginStruct()
+	}
+	return b.Int(1)
+}
+
+// encodeFrame marshals a Core event with args and an optional footer
+func encodeFrame(t testing.TB, args, footer *ArgsBuilder, fds ...int) []byte {
+	t.Helper()
+	b := NewMessageBuilder(CoreID, uint32(CoreEventDone)).WithPOD(args.Build()).WithFDs(fds...)
+	if footer != nil {
+		b.WithFooter(footer.Build())
+	}
+	data, err := b.Build().Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	return data
+}
+
+func TestMessageFrameLimits(t *testing.T) {
+	// Struct{Int, Int} with the generation footer Struct{Id, Struct{Long}}
+	done := encodeFrame(t, NewArgsBuilder().Int(0).Int(7),
+		NewArgsBuilder().ID(FooterOpcodeGeneration).BeginStruct().Long(42))
+	deep := encodeFrame(t, nestedArgs(40), nil)
+	twoFDs := encodeFrame(t, NewArgsBuilder(), nil, 3, 4)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		limits    func(*Limits)
+		podLimits bool // Rejected by a spa.Limits field
+	}{
+		{name: "frame size", data: done, limits: func(l *Limits) { l.MaxFrameSize = 64 }},
+		{name: "fds", data: twoFDs, limits: func(l *Limits) { l.MaxFDs = 1 }},
+		{name: "depth", data: deep, podLimits: true},
+		{name: "elements", data: done, limits: func(l *Limits) { l.POD.MaxElements = 1 }, podLimits: true},
+		{name: "values in footer", data: done, limits: func(l *Limits) { l.POD.MaxValues = 3 }, podLimits: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := DefaultLimits()
+			if tt.limits != nil {
+				tt.limits(&limits)
+			}
+			err := (&MessageFrame{}).UnmarshalLimits(tt.data, limits)
+			if !IsProtocolError(err) {
+				t.Fatalf("expected a ProtocolError, got %v", err)
+			}
+			if got := errors.Is(err, spa.ErrLimitExceeded); got != tt.podLimits {
+				t.Errorf("errors.Is(err, spa.ErrLimitExceeded) = %v for %v", got, err)
+			}
+		})
+	}
+
+	// The default limits accept them
+	for _, data := range [][]byte{done, twoFDs} {
+		if err := (&MessageFrame{}).Unmarshal(data); err != nil {
+			t.Errorf("Unmarshal failed: %v", err)
+		}
+	}
+}
+
+func TestConnectionDropsFrameOverLimits(t *testing.T) {
+	client, daemon := readyPair(t)
+
+	// The same ping answered with lax limits is dropped by the defaults
+	sendEvent(t, daemon, CoreEventPing, nestedArgs(40))
+	sendEvent(t, daemon, CoreEventPing, NewArgsBuilder().Int(0).Int(5))
+	r := expectMethod(t, daemon, CoreID, CoreMethodPong)
+	r.Uint()
+	if seq, _ := r.Uint(); seq != 5 {
+		t.Fatalf("pong for seq %d, the ping over limits was not dropped", seq)
+	}
+
+	limits := client.Limits()
+	limits.POD.MaxDepth = 64
+	client.SetLimits(limits)
+	sendEvent(t, daemon, CoreEventPing, nestedArgs(40))
+	r = expectMethod(t, daemon, CoreID, CoreMethodPong)
+	r.Uint()
+	if seq, _ := r.Uint(); seq != 4 {
+		t.Fatalf("got pong for seq %d, want 4", seq)
+	}
+}
+
+func FuzzMessageFrameUnmarshal(f *testing.F) {
+	f.Add(encodeFrame(f, NewArgsBuilder().Int(0).Int(7), NewArgsBuilder().ID(FooterOpcodeGeneration).BeginStruct().Long(42)))
+	f.Add(encodeFrame(f, NewArgsBuilder().String("pipewire-0").Dict(map[string]string{"a": "b"}).Bytes([]byte{1, 2}), nil))
+	f.Add(encodeFrame(f, nestedArgs(8), nil, 3))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var msg MessageFrame
+		if err := msg.Unmarshal(data); err != nil {
+			if !IsProtocolError(err) {
+				t.Fatalf("expected a ProtocolError, got %T: %v", err, err)
+			}
+			return
+		}
+		if _, err := msg.Marshal(); err != nil {
+			t.Fatalf("Marshal of a decoded frame failed: %v", err)
+		}
+	})
+}
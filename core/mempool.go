@@ -0,0 +1,317 @@
+// Package core - Memory pool
+// core/mempool.go
+// Shared memory announced by the daemon with Core.AddMem / Core.RemoveMem
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+// Memory block flags (PW_MEMBLOCK_FLAG_*)
+const (
+	MemFlagReadable   uint32 = 1 << 0
+	MemFlagWritable   uint32 = 1 << 1
+	MemFlagSeal       uint32 = 1 << 2
+	MemFlagMap        uint32 = 1 << 3
+	MemFlagDontClose  uint32 = 1 << 4
+	MemFlagDontNotify uint32 = 1 << 5
+)
+
+// pageSize is the alignment of mapping offsets
+var pageSize = uint64(os.Getpagesize())
+
+// MemBlock is a memory region shared by the daemon, identified by its memid
+type MemBlock struct {
+	ID    uint32
+	Type  MemType
+	Flags uint32
+
+	fd       int
+	mappings []*memMapping // Live mappings, guarded by the pool mutex
+	removed  bool          // Set by Remove, the fd closes with the last mapping
+}
+
+// memMapping is one mmap of a block, shared by the MemMaps it covers
+type memMapping struct {
+	block  *MemBlock
+	offset uint64 // Page aligned offset into the fd
+	data   []byte
+	refs   int
+}
+
+// MemMap is a reference to a range of a block, obtained from MemPool.Map
+// The bytes stay valid until Release, even if the daemon removes the block
+type MemMap struct {
+	pool    *MemPool
+	mapping *memMapping
+	data    []byte
+}
+
+// Bytes returns the mapped range
+func (m *MemMap) Bytes() []byte {
+	return m.data
+}
+
+// Release drops the reference; the memory is unmapped with the last one
+func (m *MemMap) Release() {
+	if m == nil || m.mapping == nil {
+		return
+	}
+	m.pool.release(m.mapping)
+	m.mapping = nil
+	m.data = nil
+}
+
+// MemPool tracks the memory blocks of a connection and maps them on demand
+// Safe for concurrent use
+type MemPool struct {
+	mu     sync.Mutex
+	blocks map[uint32]*MemBlock
+	logger *verbose.Logger
+}
+
+// NewMemPool creates an empty pool
+func NewMemPool(logger *verbose.Logger) *MemPool {
+	if logger == nil {
+		logger = verbose.NewLogger(verbose.LogLevelInfo, false)
+	}
+	return &MemPool{
+		blocks: make(map[uint32]*MemBlock),
+		logger: logger,
+	}
+}
+
+// Add registers a block, as announced by Core.AddMem; the pool owns fd
+// A block already registered with the same id is removed first
+func (p *MemPool) Add(id uint32, memType MemType, fd int, flags uint32) error {
+	if fd < 0 {
+		return fmt.Errorf("memid %d: invalid fd %d", id, fd)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if old, ok := p.blocks[id]; ok {
+		p.logger.Warnf("MemPool: memid %d added twice, replacing", id)
+		p.removeLocked(old)
+	}
+	p.blocks[id] = &MemBlock{ID: id, Type: memType, Flags: flags, fd: fd}
+	p.logger.Debugf("MemPool: Added memid %d type=%d fd=%d flags=%#x", id, memType, fd, flags)
+	return nil
+}
+
+// Remove forgets a block, as announced by Core.RemoveMem
+// Mappings still referenced stay valid until released
+func (p *MemPool) Remove(id uint32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	block, ok := p.blocks[id]
+	if !ok {
+		return fmt.Errorf("unknown memid %d", id)
+	}
+	p.removeLocked(block)
+	p.logger.Debugf("MemPool: Removed memid %d", id)
+	return nil
+}
+
+// Block returns the block with this memid
+func (p *MemPool) Block(id uint32) (*MemBlock, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	block, ok := p.blocks[id]
+	return block, ok
+}
+
+// Len returns the number of registered blocks
+func (p *MemPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.blocks)
+}
+
+// Blocks describes the registered blocks, ordered by memid
+func (p *MemPool) Blocks() []MemoryInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	infos := make([]MemoryInfo, 0, len(p.blocks))
+	for _, block := range p.blocks {
+		info := MemoryInfo{
+			ID:    block.ID,
+			Type:  block.Type,
+			FD:    int32(block.fd),
+			Flags: block.Flags,
+		}
+		var st syscall.Stat_t
+		if err := syscall.Fstat(block.fd, &st); err == nil {
+			info.Size = uint32(st.Size)
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// Map resolves (memid, offset, size) to bytes, mapping the block on first use
+// Mappings covering the range are shared; call Release when done
+func (p *MemPool) Map(id, offset, size uint32) (*MemMap, error) {
+	if size == 0 {
+		return nil, fmt.Errorf("memid %d: empty range", id)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	block, ok := p.blocks[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown memid %d", id)
+	}
+
+	start := uint64(offset)
+	end := start + uint64(size)
+	for _, m := range block.mappings {
+		if m.offset <= start && m.offset+uint64(len(m.data)) >= end {
+			m.refs++
+			return &MemMap{pool: p, mapping: m, data: m.data[start-m.offset : end-m.offset]}, nil
+		}
+	}
+
+	// Offsets passed to mmap must be page aligned
+	mapOffset := start &^ (pageSize - 1)
+	mapSize := (end - mapOffset + pageSize - 1) &^ (pageSize - 1)
+
+	prot := 0
+	if block.Flags&MemFlagReadable != 0 || block.Flags&MemFlagWritable == 0 {
+		prot |= syscall.PROT_READ
+	}
+	if block.Flags&MemFlagWritable != 0 {
+		prot |= syscall.PROT_WRITE
+	}
+
+	data, err := syscall.Mmap(block.fd, int64(mapOffset), int(mapSize), prot, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("memid %d: mmap %d bytes at %d: %w", id, mapSize, mapOffset, err)
+	}
+
+	m := &memMapping{block: block, offset: mapOffset, data: data, refs: 1}
+	block.mappings = append(block.mappings, m)
+	return &MemMap{pool: p, mapping: m, data: data[start-mapOffset : end-mapOffset]}, nil
+}
+
+// Close removes every block, as if the daemon removed them
+// Referenced mappings stay valid until released
+func (p *MemPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, block := range p.blocks {
+		p.removeLocked(block)
+	}
+}
+
+// release drops a reference to a mapping
+func (p *MemPool) release(m *memMapping) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m.refs--
+	if m.refs > 0 {
+		return
+	}
+
+	block := m.block
+	for i, other := range block.mappings {
+		if other == m {
+			block.mappings = append(block.mappings[:i], block.mappings[i+1:]...)
+			break
+		}
+	}
+	if err := syscall.Munmap(m.data); err != nil {
+		p.logger.Warnf("MemPool: munmap memid %d: %v", block.ID, err)
+	}
+	if block.removed && len(block.mappings) == 0 {
+		p.closeBlock(block)
+	}
+}
+
+// removeLocked drops a block from the pool, caller holds p.mu
+// The fd is closed now or, while mappings are referenced, with the last one
+func (p *MemPool) removeLocked(block *MemBlock) {
+	if p.blocks[block.ID] == block {
+		delete(p.blocks, block.ID)
+	}
+	block.removed = true
+	if len(block.mappings) == 0 {
+		p.closeBlock(block)
+	}
+}
+
+// closeBlock closes the fd of a removed block
+func (p *MemPool) closeBlock(block *MemBlock) {
+	if block.fd < 0 {
+		return
+	}
+	if block.Flags&MemFlagDontClose == 0 {
+		syscall.Close(block.fd)
+	}
+	block.fd = -1
+}
+
+// MemPool returns the shared memory announced by the daemon on this connection
+func (c *Connection) MemPool() *MemPool {
+	return c.mem
+}
+
+// handleAddMem registers the block of a Core.AddMem event
+// The pool owns the fd once added, every error path closes it
+// Core.AddMem: {Int id, Id type, Fd fd, Int flags}
+func (c *Connection) handleAddMem(msg *MessageFrame) error {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return err
+	}
+	id, err := r.Uint()
+	if err != nil {
+		return fmt.Errorf("add_mem id: %w", err)
+	}
+	memType, err := r.ID()
+	if err != nil {
+		return fmt.Errorf("add_mem type: %w", err)
+	}
+	fd, err := r.TakeFd()
+	if err != nil {
+		return fmt.Errorf("add_mem fd: %w", err)
+	}
+	flags, err := r.Uint()
+	if err != nil {
+		closeFDs([]int{fd})
+		return fmt.Errorf("add_mem flags: %w", err)
+	}
+	if err := c.mem.Add(id, MemType(memType), fd, flags); err != nil {
+		closeFDs([]int{fd})
+		return err
+	}
+	return nil
+}
+
+// handleRemoveMem forgets the block of a Core.RemoveMem event
+// Core.RemoveMem: {Int id}
+func (c *Connection) handleRemoveMem(msg *MessageFrame) error {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return err
+	}
+	id, err := r.Uint()
+	if err != nil {
+		return fmt.Errorf("remove_mem id: %w", err)
+	}
+	return c.mem.Remove(id)
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// memFile returns an fd of a temporary file holding data
+func memFile(t *testing.T, data []byte) int {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "mem")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatalf("Dup: %v", err)
+	}
+	return fd
+}
+
+// fdIsOpen reports whether fd refers to an open file
+func fdIsOpen(fd int) bool {
+	var st syscall.Stat_t
+	return syscall.Fstat(fd, &st) == nil
+}
+
+func TestMemPoolMapAndRemove(t *testing.T) {
+	data := make([]byte, 2*pageSize)
+	copy(data[pageSize+10:], "pipewire")
+	fd := memFile(t, data)
+
+	pool := NewMemPool(nil)
+	if err := pool.Add(7, MemTypeMemFd, fd, MemFlagReadable); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	m1, err := pool.Map(7, uint32(pageSize)+10, 8)
+	if err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+	if string(m1.Bytes()) != "pipewire" {
+		t.Errorf("got %q", m1.Bytes())
+	}
+
+	// A range inside an existing mapping shares it
+	m2, err := pool.Map(7, uint32(pageSize)+14, 4)
+	if err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+	if string(m2.Bytes()) != "wire" {
+		t.Errorf("got %q", m2.Bytes())
+	}
+	block, _ := pool.Block(7)
+	if len(block.mappings) != 1 {
+		t.Errorf("got %d mappings, want 1", len(block.mappings))
+	}
+
+	if infos := pool.Blocks(); len(infos) != 1 || infos[0].Size != uint32(len(data)) {
+		t.Errorf("unexpected block info %+v", infos)
+	}
+
+	// Removal waits for the last reference
+	if err := pool.Remove(7); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := pool.Map(7, 0, 1); err == nil {
+		t.Error("expected error mapping a removed block")
+	}
+	if string(m1.Bytes()) != "pipewire" || !fdIsOpen(fd) {
+		t.Fatal("referenced block released early")
+	}
+	m1.Release()
+	m2.Release()
+	if fdIsOpen(fd) {
+		t.Error("fd not closed after the last release")
+	}
+
+	if err := pool.Remove(7); err == nil {
+		t.Error("expected error removing an unknown memid")
+	}
+}
+
+func TestConnectionAddRemoveMem(t *testing.T) {
+	client, daemon := readyPair(t)
+
+	fd := memFile(t, []byte("shared"))
+	defer syscall.Close(fd)
+
+	addMem := NewMessageBuilder(CoreID, uint32(CoreEventAddMem)).
+		WithPOD(NewArgsBuilder().Int(3).ID(uint32(MemTypeMemFd)).Fd(0).Int(int32(MemFlagReadable)).Build()).
+		WithFDs(fd).
+		Build()
+	if err := daemon.WriteMessage(addMem); err != nil {
+		t.Fatalf("daemon write failed: %v", err)
+	}
+	waitFor(t, func() bool { return client.MemPool().Len() == 1 })
+
+	m, err := client.MemPool().Map(3, 0, 6)
+	if err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+	if string(m.Bytes()) != "shared" {
+		t.Errorf("got %q", m.Bytes())
+	}
+	m.Release()
+
+	sendEvent(t, daemon, CoreEventRemoveMem, NewArgsBuilder().Int(3))
+	waitFor(t, func() bool { return client.MemPool().Len() == 0 })
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConnectionAddMemClosesFDOnError(t *testing.T) {
+	_, daemon := readyPair(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+
+	// Core.AddMem without its flags field
+	addMem := NewMessageBuilder(CoreID, uint32(CoreEventAddMem)).
+		WithPOD(NewArgsBuilder().Int(3).ID(uint32(MemTypeMemFd)).Fd(0).Build()).
+		WithFDs(int(w.Fd())).
+		Build()
+	if err := daemon.WriteMessage(addMem); err != nil {
+		t.Fatalf("daemon write failed: %v", err)
+	}
+	w.Close()
+
+	r.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected io.EOF once the fd is closed, got %v", err)
+	}
+}
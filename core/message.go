@@ -1,6 +1,6 @@
 // Package core - Protocol message marshalling and unmarshalling
 // core/message.go
-// Implements PipeWire protocol message frame format with header + POD data
+// Implements the PipeWire native protocol (v3) frame format: header + POD data + optional footer
 
 package core
 
@@ -10,67 +10,222 @@ import (
 	"github.com/vignemail1/pipewire-go/spa"
 )
 
+// Native protocol framing constants
+const (
+	// HeaderSize is the size of the native protocol message header
+	HeaderSize = 16
+
+	// MaxOpcode is the largest opcode that fits in the header (8 bits)
+	MaxOpcode = 0xFF
+
+	// MaxPayloadSize is the largest payload that fits in the header (24 bits)
+	MaxPayloadSize = 0xFFFFFF
+
+	// podHeaderSize is the size of a SPA POD header (size + type)
+	podHeaderSize = 8
+)
+
+// Footer opcodes carried in the optional message footer
+const (
+	// FooterOpcodeGeneration carries the registry generation (uint64)
+	FooterOpcodeGeneration = 0
+)
+
 // MessageFrame represents a complete PipeWire protocol message
-// Format: [ObjectID (4B)] [MethodID (4B)] [Sequence (4B)] [PODData (var)]
+// Format: [id (4B)] [opcode (1B) | size (3B)] [seq (4B)] [n_fds (4B)] [PODData] [Footer]
+// The second header word packs the opcode in the upper 8 bits and the
+// payload size (POD data + footer) in the lower 24 bits.
 // This is the fundamental message unit for all client-daemon communication
 type MessageFrame struct {
 	ObjectID uint32       // ID of target object
-	MethodID uint32       // Method ID or event ID
+	MethodID uint32       // Method opcode (requests) or event opcode (events)
 	Sequence uint32       // Sequence number for request/response matching
-	PODData  spa.PODValue // POD-encoded arguments (usually PODObject)
+	NumFDs   uint32       // Number of file descriptors sent with the message
+	PODData  spa.PODValue // POD-encoded arguments (a Struct on the wire)
+	Footer   spa.PODValue // Optional footer POD, nil when absent
+
+	// FDs holds the descriptors passed with the message (SCM_RIGHTS)
+	// Fd values in PODData are indices into this table
+	FDs spa.FDTable
+}
+
+// AddFD appends a file descriptor to send with the message
+// Returns the index to encode in the Fd POD value
+func (m *MessageFrame) AddFD(fd int) int64 {
+	m.FDs = append(m.FDs, fd)
+	m.NumFDs = uint32(len(m.FDs))
+	return int64(len(m.FDs) - 1)
+}
+
+// Parser returns a POD parser over the message arguments
+// with the message fd table attached for Fd values
+func (m *MessageFrame) Parser() (*spa.PODParser, error) {
+	if m == nil || m.PODData == nil {
+		return nil, fmt.Errorf("message has no POD data")
+	}
+	data, err := m.PODData.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	p := spa.NewPODParser(data)
+	p.SetFDTable(m.FDs)
+	return p, nil
 }
 
 // Marshal converts frame to bytes with little-endian encoding
-// Returns: [objectID(4)] [methodID(4)] [sequence(4)] [podData]
+// Returns: [header(16)] [podData] [footer]
 func (m *MessageFrame) Marshal() ([]byte, error) {
 	if m == nil {
 		return nil, fmt.Errorf("MessageFrame is nil")
 	}
-
-	// Build 12-byte header
-	header := make([]byte, 12)
-	binary.LittleEndian.PutUint32(header[0:4], m.ObjectID)
-	binary.LittleEndian.PutUint32(header[4:8], m.MethodID)
-	binary.LittleEndian.PutUint32(header[8:12], m.Sequence)
+	if m.MethodID > MaxOpcode {
+		return nil, fmt.Errorf("opcode %d does not fit in header (max %d)", m.MethodID, MaxOpcode)
+	}
 
 	// Marshal POD data if present
-	var podData []byte
+	var payload []byte
 	if m.PODData != nil {
 		data, err := m.PODData.Marshal()
 		if err != nil {
 			return nil, fmt.Errorf("POD marshal failed: %w", err)
 		}
-		podData = data
+		payload = data
+	}
+
+	// Footer follows the message POD inside the same payload
+	if m.Footer != nil {
+		data, err := m.Footer.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("footer marshal failed: %w", err)
+		}
+		payload = append(payload, data...)
+	}
+
+	if len(payload) > MaxPayloadSize {
+		return nil, fmt.Errorf("payload too large: %d bytes (max %d)", len(payload), MaxPayloadSize)
 	}
 
-	// Combine header + POD
-	result := append(header, podData...)
+	// Build 16-byte header
+	result := make([]byte, HeaderSize, HeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(result[0:4], m.ObjectID)
+	binary.LittleEndian.PutUint32(result[4:8], m.MethodID<<24|uint32(len(payload)))
+	binary.LittleEndian.PutUint32(result[8:12], m.Sequence)
+	binary.LittleEndian.PutUint32(result[12:16], m.NumFDs)
+
+	// Combine header + payload
+	result = append(result, payload...)
 	return result, nil
 }
 
 // Unmarshal converts bytes to frame with little-endian decoding
+// The POD data and footer are kept as raw PODs; their layout depends on
+// the method or event and is decoded by the owner of ObjectID
+// Frames are checked against DefaultLimits
 func (m *MessageFrame) Unmarshal(data []byte) error {
+	return m.UnmarshalLimits(data, DefaultLimits())
+}
+
+// UnmarshalLimits is Unmarshal checking the frame against limits
+// Failures are ProtocolErrors; a frame over a limit also matches
+// spa.ErrLimitExceeded when the POD data is at fault
+func (m *MessageFrame) UnmarshalLimits(data []byte, limits Limits) error {
 	if m == nil {
 		return fmt.Errorf("MessageFrame is nil")
 	}
 
-	if len(data) < 12 {
-		return fmt.Errorf("message too short: %d bytes (need >= 12)", len(data))
+	if len(data) < HeaderSize {
+		return NewProtocolErrorf("message too short: %d bytes (need >= %d)", len(data), HeaderSize)
 	}
 
 	// Parse header
-	m.ObjectID = binary.LittleEndian.Uint32(data[0:4])
-	m.MethodID = binary.LittleEndian.Uint32(data[4:8])
-	m.Sequence = binary.LittleEndian.Uint32(data[8:12])
+	objectID, opcode, size, sequence, numFDs := parseHeader(data)
+	if HeaderSize+size > limits.frameSize() {
+		return NewProtocolErrorf("message too large: %d bytes (max %d)", HeaderSize+size, limits.frameSize())
+	}
+	if limits.MaxFDs > 0 && numFDs > uint32(limits.MaxFDs) {
+		return NewProtocolErrorf("too many fds: %d (max %d)", numFDs, limits.MaxFDs)
+	}
+	if len(data) < HeaderSize+size {
+		return NewProtocolErrorf("message truncated: header announces %d payload bytes, have %d",
+			size, len(data)-HeaderSize)
+	}
+
+	m.ObjectID = objectID
+	m.MethodID = opcode
+	m.Sequence = sequence
+	m.NumFDs = numFDs
+	m.PODData = nil
+	m.Footer = nil
 
-	// Parse POD data if present
-	if len(data) > 12 {
-		m.PODData = spa.NewPODBytes(data[12:])
+	// Parse POD data and footer if present
+	payload := data[HeaderSize : HeaderSize+size]
+	if len(payload) == 0 {
+		return nil
+	}
+
+	podLen, err := checkPOD(payload, limits.POD)
+	if err != nil {
+		return WrapProtocolError(err, fmt.Sprintf("message %d/%d: invalid POD data", objectID, opcode))
+	}
+	m.PODData = spa.NewPODRaw(payload[:podLen])
+
+	if rest := payload[podLen:]; len(rest) > 0 {
+		footerLen, err := checkPOD(rest, limits.POD)
+		if err != nil {
+			return WrapProtocolError(err, fmt.Sprintf("message %d/%d: invalid footer", objectID, opcode))
+		}
+		m.Footer = spa.NewPODRaw(rest[:footerLen])
 	}
 
 	return nil
 }
 
+// checkPOD walks the POD at the start of data within limits
+// and returns its encoded size
+func checkPOD(data []byte, limits spa.Limits) (int, error) {
+	size, err := podTotalSize(data)
+	if err != nil {
+		return 0, err
+	}
+	p := spa.NewPODParser(data[:size])
+	p.SetLimits(limits)
+	if err := p.SkipValue(); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// parseHeader decodes the 16-byte native protocol header
+// Caller must ensure len(data) >= HeaderSize
+func parseHeader(data []byte) (objectID, opcode uint32, size int, sequence, numFDs uint32) {
+	objectID = binary.LittleEndian.Uint32(data[0:4])
+	word := binary.LittleEndian.Uint32(data[4:8])
+	opcode = word >> 24
+	size = int(word & MaxPayloadSize)
+	sequence = binary.LittleEndian.Uint32(data[8:12])
+	numFDs = binary.LittleEndian.Uint32(data[12:16])
+	return
+}
+
+// podTotalSize returns the encoded size of the POD at the start of data,
+// including its 8-byte header and trailing padding to 8 bytes
+func podTotalSize(data []byte) (int, error) {
+	if len(data) < podHeaderSize {
+		return 0, fmt.Errorf("POD too short: %d bytes (need >= %d)", len(data), podHeaderSize)
+	}
+	bodySize := int(binary.LittleEndian.Uint32(data[0:4]))
+	if bodySize > len(data)-podHeaderSize {
+		return 0, fmt.Errorf("POD truncated: announces %d bytes, have %d",
+			bodySize, len(data)-podHeaderSize)
+	}
+	total := podHeaderSize + spa.AlignOffset(bodySize)
+	if total > len(data) {
+		// Last POD of a payload may omit its trailing padding
+		total = len(data)
+	}
+	return total, nil
+}
+
 // MessageBuilder provides fluent API for creating message frames
 type MessageBuilder struct {
 	frame *MessageFrame
@@ -104,6 +259,23 @@ func (b *MessageBuilder) WithPOD(pod spa.PODValue) *MessageBuilder {
 	return b
 }
 
+// WithFooter sets the optional footer POD
+func (b *MessageBuilder) WithFooter(footer spa.PODValue) *MessageBuilder {
+	if b != nil && b.frame != nil {
+		b.frame.Footer = footer
+	}
+	return b
+}
+
+// WithFDs sets the file descriptors sent along with the message
+func (b *MessageBuilder) WithFDs(fds ...int) *MessageBuilder {
+	if b != nil && b.frame != nil {
+		b.frame.FDs = append(spa.FDTable{}, fds...)
+		b.frame.NumFDs = uint32(len(fds))
+	}
+	return b
+}
+
 // Build returns the constructed MessageFrame
 func (b *MessageBuilder) Build() *MessageFrame {
 	if b == nil || b.frame == nil {
@@ -121,62 +293,67 @@ func (m *MessageFrame) String() string {
 	if m.PODData != nil {
 		podStr = fmt.Sprintf("%T", m.PODData)
 	}
-	return fmt.Sprintf("MessageFrame{obj:%d method:%d seq:%d pod:%s}",
-		m.ObjectID, m.MethodID, m.Sequence, podStr)
+	return fmt.Sprintf("MessageFrame{obj:%d method:%d seq:%d fds:%d pod:%s footer:%v}",
+		m.ObjectID, m.MethodID, m.Sequence, m.NumFDs, podStr, m.Footer != nil)
 }
 
 // Helper functions for extracting POD values
 
 // ExtractUint32FromPOD extracts a uint32 value from POD object
-func ExtractUint32FromPOD(obj *spa.PODObject, key string) (uint32, error) {
+func ExtractUint32FromPOD(obj *spa.PODObject, key uint32) (uint32, error) {
 	if obj == nil {
 		return 0, fmt.Errorf("POD object is nil")
 	}
 
 	val, ok := obj.Get(key)
 	if !ok {
-		return 0, fmt.Errorf("key %q not found in POD", key)
+		return 0, fmt.Errorf("key %d not found in POD", key)
 	}
 
-	if u32, ok := val.(*spa.PODUint32); ok {
-		return u32.Value, nil
+	switch v := val.(type) {
+	case *spa.PODUint32:
+		return v.Value, nil
+	case *spa.PODInt32:
+		return uint32(v.Value), nil
+	case *spa.PODId:
+		return v.Value, nil
 	}
 
-	return 0, fmt.Errorf("value at %q is not uint32, got %T", key, val)
+	return 0, fmt.Errorf("value at %d is not uint32, got %T", key, val)
 }
 
 // ExtractStringFromPOD extracts a string value from POD object
-func ExtractStringFromPOD(obj *spa.PODObject, key string) (string, error) {
+func ExtractStringFromPOD(obj *spa.PODObject, key uint32) (string, error) {
 	if obj == nil {
 		return "", fmt.Errorf("POD object is nil")
 	}
 
 	val, ok := obj.Get(key)
 	if !ok {
-		return "", fmt.Errorf("key %q not found in POD", key)
+		return "", fmt.Errorf("key %d not found in POD", key)
 	}
 
 	if str, ok := val.(*spa.PODString); ok {
 		return str.Value, nil
 	}
 
-	return "", fmt.Errorf("value at %q is not string, got %T", key, val)
+	return "", fmt.Errorf("value at %d is not string, got %T", key, val)
 }
 
 // ExtractBoolFromPOD extracts a bool value from POD object
-func ExtractBoolFromPOD(obj *spa.PODObject, key string) (bool, error) {
+func ExtractBoolFromPOD(obj *spa.PODObject, key uint32) (bool, error) {
 	if obj == nil {
 		return false, fmt.Errorf("POD object is nil")
 	}
 
 	val, ok := obj.Get(key)
 	if !ok {
-		return false, fmt.Errorf("key %q not found in POD", key)
+		return false, fmt.Errorf("key %d not found in POD", key)
 	}
 
 	if b, ok := val.(*spa.PODBool); ok {
 		return b.Value, nil
 	}
 
-	return false, fmt.Errorf("value at %q is not bool, got %T", key, val)
+	return false, fmt.Errorf("value at %d is not bool, got %T", key, val)
 }
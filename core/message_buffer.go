@@ -5,23 +5,53 @@
 package core
 
 import (
-	"encoding/binary"
 	"fmt"
 )
 
 // MessageBuffer buffers bytes from socket until complete frame received
 type MessageBuffer struct {
 	buffer  []byte
+	store   []byte // Backing array, reused once frames are consumed
 	pos     int
 	maxSize int
 }
 
 // Frame represents a complete protocol frame
 type Frame struct {
-	Header    []byte // 12 bytes (ObjectID, MethodID, Sequence)
-	Data      []byte // Variable POD data
+	Header    []byte // 16 bytes (id, opcode|size, seq, n_fds)
+	Data      []byte // Payload: POD data followed by the optional footer
 	Complete  bool
 	FrameSize int
+
+	// Decoded header fields
+	ObjectID uint32
+	Opcode   uint32
+	Sequence uint32
+	NumFDs   uint32
+
+	// File descriptors received with the frame
+	FDs []int
+}
+
+// Message decodes the frame into a MessageFrame within DefaultLimits
+func (f *Frame) Message() (*MessageFrame, error) {
+	return f.MessageLimits(DefaultLimits())
+}
+
+// MessageLimits decodes the frame into a MessageFrame within limits
+func (f *Frame) MessageLimits(limits Limits) (*MessageFrame, error) {
+	if f == nil {
+		return nil, fmt.Errorf("frame is nil")
+	}
+	msg := &MessageFrame{}
+	data := make([]byte, 0, len(f.Header)+len(f.Data))
+	data = append(data, f.Header...)
+	data = append(data, f.Data...)
+	if err := msg.UnmarshalLimits(data, limits); err != nil {
+		return nil, err
+	}
+	msg.FDs = f.FDs
+	return msg, nil
 }
 
 // NewMessageBuffer creates a new message buffer
@@ -29,8 +59,10 @@ func NewMessageBuffer(maxSize int) *MessageBuffer {
 	if maxSize <= 0 {
 		maxSize = 1024 * 1024 // 1MB default
 	}
+	store := make([]byte, maxSize)
 	return &MessageBuffer{
-		buffer:  make([]byte, 0, maxSize),
+		buffer:  store[:0],
+		store:   store,
 		pos:     0,
 		maxSize: maxSize,
 	}
@@ -48,57 +80,61 @@ func (m *MessageBuffer) Append(data []byte) error {
 			len(m.buffer), len(data), m.maxSize)
 	}
 
+	// Move the unread tail to the front instead of growing
+	if len(m.buffer)+len(data) > cap(m.buffer) {
+		n := copy(m.store, m.buffer)
+		m.buffer = m.store[:n]
+	}
+
 	m.buffer = append(m.buffer, data...)
 	return nil
 }
 
 // ReadFrame extracts a complete frame from buffer
+// Returns (nil, nil) when the buffer does not yet hold a complete frame
 func (m *MessageBuffer) ReadFrame() (*Frame, error) {
-	// Need at least 12 bytes for header
-	if len(m.buffer) < 12 {
+	// Need at least 16 bytes for header
+	if len(m.buffer) < HeaderSize {
 		return nil, nil // Not enough data yet
 	}
 
-	// Parse header (12 bytes little-endian)
-	header := m.buffer[:12]
-	objectID := binary.LittleEndian.Uint32(header[0:4])
-	methodID := binary.LittleEndian.Uint32(header[4:8])
-	sequence := binary.LittleEndian.Uint32(header[8:12])
-
-	_ = objectID  // Used by caller
-	_ = methodID  // Used by caller
-	_ = sequence  // Used by caller
+	// Parse header (16 bytes little-endian)
+	objectID, opcode, size, sequence, numFDs := parseHeader(m.buffer)
 
-	// In PipeWire, after 12-byte header comes POD data
-	// For now, we'll assume single-byte size prefix for data length
-	// This is simplified; real implementation would use POD unmarshalling
+	// A frame that can never fit would stall the stream forever
+	if HeaderSize+size > m.maxSize {
+		return nil, NewProtocolErrorf("frame too large: %d bytes (max %d)", HeaderSize+size, m.maxSize)
+	}
 
-	// Check if we have enough data
-	// Minimum frame: 12 bytes header + 0 bytes data
-	if len(m.buffer) < 12 {
+	// Wait for the whole payload
+	frameSize := HeaderSize + size
+	if len(m.buffer) < frameSize {
 		return nil, nil
 	}
 
-	// Extract the frame (assuming data is POD-encoded)
-	// For this simplified version, we'll return what we have
 	frame := &Frame{
-		Header:    make([]byte, 12),
-		Data:      make([]byte, 0),
+		Header:    make([]byte, HeaderSize),
+		Data:      make([]byte, size),
 		Complete:  true,
-		FrameSize: 12,
+		FrameSize: frameSize,
+		ObjectID:  objectID,
+		Opcode:    opcode,
+		Sequence:  sequence,
+		NumFDs:    numFDs,
 	}
 
-	copy(frame.Header, header)
+	copy(frame.Header, m.buffer[:HeaderSize])
+	copy(frame.Data, m.buffer[HeaderSize:frameSize])
 
 	// Remove consumed frame from buffer
-	m.buffer = m.buffer[12:]
+	m.buffer = m.buffer[frameSize:]
 
 	return frame, nil
 }
 
 // Reset clears the buffer
 func (m *MessageBuffer) Reset() {
-	m.buffer = m.buffer[:0]
+	m.buffer = m.store[:0]
 	m.pos = 0
 }
 
@@ -151,8 +187,7 @@ func (m *MessageBuffer) IsEmpty() bool {
 // Copy returns a deep copy of the buffer
 func (m *MessageBuffer) Copy() *MessageBuffer {
 	newBuf := NewMessageBuffer(m.maxSize)
-	newBuf.buffer = make([]byte, len(m.buffer))
-	copy(newBuf.buffer, m.buffer)
+	newBuf.buffer = newBuf.store[:copy(newBuf.store, m.buffer)]
 	return newBuf
 }
 
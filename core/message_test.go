@@ -5,6 +5,10 @@
 package core
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"syscall"
 	"testing"
 	"time"
 
@@ -52,8 +56,8 @@ func TestMessageFrameMarshal(t *testing.T) {
 				t.Errorf("Marshal() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if err == nil && len(data) < 12 {
-				t.Errorf("Marshal() returned data too short: %d bytes (expected >= 12)", len(data))
+			if err == nil && len(data) < HeaderSize {
+				t.Errorf("Marshal() returned data too short: %d bytes (expected >= %d)", len(data), HeaderSize)
 			}
 		})
 	}
@@ -92,6 +96,213 @@ func TestMessageFrameUnmarshal(t *testing.T) {
 	}
 }
 
+// Golden native protocol vectors, laid out as libpipewire writes them
+
+// goldenHelloBody is Struct{Int(3)}: the Core.Hello version argument
+var goldenHelloBody = []byte{
+	0x10, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, // struct: size=16 type=Struct
+	0x04, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, // int: size=4 type=Int
+	0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // value=3 + padding
+}
+
+// goldenHello is Core.Hello (id=0, opcode=1, seq=0, n_fds=0)
+var goldenHello = append([]byte{
+	0x00, 0x00, 0x00, 0x00, // id=0
+	0x18, 0x00, 0x00, 0x01, // opcode=1, size=24
+	0x00, 0x00, 0x00, 0x00, // seq=0
+	0x00, 0x00, 0x00, 0x00, // n_fds=0
+}, goldenHelloBody...)
+
+// goldenDoneBody is Struct{Int(0), Int(7)}: Core.Done id and seq
+var goldenDoneBody = []byte{
+	0x20, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, // struct: size=32 type=Struct
+	0x04, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, // int: size=4 type=Int
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // value=0 + padding
+	0x04, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, // int: size=4 type=Int
+	0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // value=7 + padding
+}
+
+// goldenGenerationFooter is Struct{Id(0), Struct{Long(42)}}: registry generation
+var goldenGenerationFooter = []byte{
+	0x28, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, // struct: size=40 type=Struct
+	0x04, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, // id: size=4 type=Id
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // value=0 (generation) + padding
+	0x10, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, // struct: size=16 type=Struct
+	0x08, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, // long: size=8 type=Long
+	0x2a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // value=42
+}
+
+// goldenDone is Core.Done (id=0, opcode=1, seq=9, n_fds=0) with a generation footer
+var goldenDone = append(append([]byte{
+	0x00, 0x00, 0x00, 0x00, // id=0
+	0x58, 0x00, 0x00, 0x01, // opcode=1, size=88
+	0x09, 0x00, 0x00, 0x00, // seq=9
+	0x00, 0x00, 0x00, 0x00, // n_fds=0
+}, goldenDoneBody...), goldenGenerationFooter...)
+
+// goldenAddMemHeader is the header of Core.AddMem (id=0, opcode=6, seq=3, n_fds=1)
+var goldenAddMemHeader = []byte{
+	0x00, 0x00, 0x00, 0x00, // id=0
+	0x00, 0x00, 0x00, 0x06, // opcode=6, size=0
+	0x03, 0x00, 0x00, 0x00, // seq=3
+	0x01, 0x00, 0x00, 0x00, // n_fds=1
+}
+
+// TestMessageFrameGolden checks byte-exact encoding and decoding of native frames
+func TestMessageFrameGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		frame  *MessageFrame
+		golden []byte
+		body   []byte
+		footer []byte
+	}{
+		{
+			name: "hello",
+			frame: NewMessageBuilder(0, 1).
+				WithPOD(spa.NewPODRaw(goldenHelloBody)).
+				Build(),
+			golden: goldenHello,
+			body:   goldenHelloBody,
+		},
+		{
+			name: "done with footer",
+			frame: NewMessageBuilder(0, 1).
+				WithSequence(9).
+				WithPOD(spa.NewPODRaw(goldenDoneBody)).
+				WithFooter(spa.NewPODRaw(goldenGenerationFooter)).
+				Build(),
+			golden: goldenDone,
+			body:   goldenDoneBody,
+			footer: goldenGenerationFooter,
+		},
+		{
+			name: "header only with fds",
+			frame: NewMessageBuilder(0, 6).
+				WithSequence(3).
+				WithFDs(7).
+				Build(),
+			golden: goldenAddMemHeader,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.frame.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal() failed: %v", err)
+			}
+			if !bytes.Equal(data, tt.golden) {
+				t.Errorf("Marshal() mismatch:\n got % x\nwant % x", data, tt.golden)
+			}
+
+			restored := &MessageFrame{}
+			if err := restored.Unmarshal(tt.golden); err != nil {
+				t.Fatalf("Unmarshal() failed: %v", err)
+			}
+			if restored.ObjectID != tt.frame.ObjectID || restored.MethodID != tt.frame.MethodID ||
+				restored.Sequence != tt.frame.Sequence || restored.NumFDs != tt.frame.NumFDs {
+				t.Errorf("header mismatch: got %s, want %s", restored, tt.frame)
+			}
+
+			checkRaw(t, "PODData", restored.PODData, tt.body)
+			checkRaw(t, "Footer", restored.Footer, tt.footer)
+		})
+	}
+}
+
+// checkRaw verifies that a decoded POD carries the expected raw bytes
+func checkRaw(t *testing.T, name string, pod spa.PODValue, want []byte) {
+	t.Helper()
+	if want == nil {
+		if pod != nil {
+			t.Errorf("%s: expected nil, got %v", name, pod)
+		}
+		return
+	}
+	raw, ok := pod.(*spa.PODRaw)
+	if !ok {
+		t.Fatalf("%s: expected *spa.PODRaw, got %T", name, pod)
+	}
+	if !bytes.Equal(raw.Data, want) {
+		t.Errorf("%s mismatch:\n got % x\nwant % x", name, raw.Data, want)
+	}
+}
+
+// TestMessageFrameInvalid tests rejection of frames that cannot be encoded or decoded
+func TestMessageFrameInvalid(t *testing.T) {
+	if _, err := (&MessageFrame{MethodID: MaxOpcode + 1}).Marshal(); err == nil {
+		t.Error("Expected error for opcode overflow, got nil")
+	}
+
+	// Header announces more payload than present
+	if err := (&MessageFrame{}).Unmarshal(goldenDone[:len(goldenDone)-1]); err == nil {
+		t.Error("Expected error for truncated payload, got nil")
+	}
+
+	// Payload too short to hold a POD header
+	short := append([]byte{}, goldenAddMemHeader...)
+	short[4] = 4
+	short = append(short, 0, 0, 0, 0)
+	if err := (&MessageFrame{}).Unmarshal(short); err == nil {
+		t.Error("Expected error for payload shorter than a POD header, got nil")
+	}
+}
+
+// TestMessageBufferReadFrame tests frame reassembly from a byte stream
+func TestMessageBufferReadFrame(t *testing.T) {
+	stream := append(append([]byte{}, goldenHello...), goldenDone...)
+	buffer := NewMessageBuffer(1024)
+
+	// Feed one byte at a time; frames must only appear once complete
+	var frames []*Frame
+	for i := range stream {
+		if err := buffer.Append(stream[i : i+1]); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		frame, err := buffer.ReadFrame()
+		if err != nil {
+			t.Fatalf("ReadFrame failed: %v", err)
+		}
+		if frame != nil {
+			frames = append(frames, frame)
+		}
+	}
+
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 frames, got %d", len(frames))
+	}
+	if !buffer.IsEmpty() {
+		t.Errorf("Buffer should be empty, has %d bytes", buffer.Size())
+	}
+
+	if frames[0].FrameSize != len(goldenHello) || frames[1].FrameSize != len(goldenDone) {
+		t.Errorf("Frame sizes: got %d/%d, want %d/%d",
+			frames[0].FrameSize, frames[1].FrameSize, len(goldenHello), len(goldenDone))
+	}
+	if frames[1].Opcode != 1 || frames[1].Sequence != 9 {
+		t.Errorf("Decoded header: opcode=%d seq=%d", frames[1].Opcode, frames[1].Sequence)
+	}
+
+	msg, err := frames[1].Message()
+	if err != nil {
+		t.Fatalf("Message() failed: %v", err)
+	}
+	checkRaw(t, "PODData", msg.PODData, goldenDoneBody)
+	checkRaw(t, "Footer", msg.Footer, goldenGenerationFooter)
+}
+
+// TestMessageBufferFrameTooLarge tests that oversized frames are reported, not awaited
+func TestMessageBufferFrameTooLarge(t *testing.T) {
+	buffer := NewMessageBuffer(32)
+	if err := buffer.Append(goldenDone[:HeaderSize]); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if _, err := buffer.ReadFrame(); err == nil {
+		t.Error("Expected error for frame larger than buffer, got nil")
+	}
+}
+
 // TestMessageFrameTooShort tests error handling for short data
 func TestMessageFrameTooShort(t *testing.T) {
 	frame := &MessageFrame{}
@@ -142,6 +353,9 @@ func TestEventHandlerRegister(t *testing.T) {
 	if eh.HandlerCount(42) != 1 {
 		t.Errorf("HandlerCount: got %d, want 1", eh.HandlerCount(42))
 	}
+	if called {
+		t.Error("handler called on registration")
+	}
 }
 
 // TestEventHandlerDispatch tests event dispatching to handlers
@@ -238,28 +452,36 @@ func TestPendingRequestTimeout(t *testing.T) {
 	}
 }
 
-// TestLinkCreateRequestToPOD tests request to POD conversion
-func TestLinkCreateRequestToPOD(t *testing.T) {
-	req := &LinkCreateRequest{
-		OutputPortID: 10,
-		InputPortID:  20,
-		Properties: map[string]string{
-			"passive": "false",
-		},
-		Passive: false,
-	}
+func TestWaitForRequestCtxCancel(t *testing.T) {
+	handler := NewEventHandler()
+	req := handler.CreatePendingRequestFor(7, 42)
 
-	pod, err := req.ToPOD()
-	if err != nil {
-		t.Fatalf("ToPOD failed: %v", err)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := handler.WaitForRequestCtx(ctx, req)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if handler.PendingRequestCount() != 0 {
+		t.Errorf("cancelled request still pending")
 	}
 
-	if pod == nil {
-		t.Fatal("ToPOD returned nil")
+	// The daemon answering late finds nothing to complete
+	if err := handler.ResolvePendingRequest(7, uint32(1)); err == nil {
+		t.Error("late response resolved a cancelled request")
+	}
+	if handler.RejectDaemonError(NewDaemonError(42, 7, -int32(syscall.EINVAL), "late")) {
+		t.Error("late error matched a cancelled request")
 	}
 
-	if len(pod.Values) < 4 {
-		t.Errorf("POD object too small: %d values", len(pod.Values))
+	// The request timeout is reported as a TimeoutError
+	req = handler.CreatePendingRequest(8)
+	if _, err := handler.WaitForRequest(req, 10*time.Millisecond); !IsTimeout(err) {
+		t.Errorf("expected timeout error, got %v", err)
 	}
 }
 
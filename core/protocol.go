@@ -20,62 +20,113 @@ const (
 	ObjectTypeFactory  ObjectType = "Factory"
 )
 
-// MethodID represents method IDs for protocol messages
+// Interface type names announced by Registry.Global (PW_TYPE_INTERFACE_*)
+const (
+	TypeInterfaceCore     = "PipeWire:Interface:Core"
+	TypeInterfaceRegistry = "PipeWire:Interface:Registry"
+	TypeInterfaceClient   = "PipeWire:Interface:Client"
+	TypeInterfaceModule   = "PipeWire:Interface:Module"
+	TypeInterfaceFactory  = "PipeWire:Interface:Factory"
+	TypeInterfaceDevice   = "PipeWire:Interface:Device"
+	TypeInterfaceNode     = "PipeWire:Interface:Node"
+	TypeInterfacePort     = "PipeWire:Interface:Port"
+	TypeInterfaceLink     = "PipeWire:Interface:Link"
+	TypeInterfaceMetadata = "PipeWire:Interface:Metadata"
+	TypeInterfaceProfiler = "PipeWire:Interface:Profiler"
+)
+
+// Well-known proxy ids on every connection
+const (
+	CoreID   uint32 = 0 // pw_core, created implicitly
+	ClientID uint32 = 1 // pw_client representing this connection
+
+	InvalidID uint32 = 0xffffffff // SPA_ID_INVALID
+)
+
+// Interface versions implemented by this library
+// Registry.Bind asks for the lower of these and the version of the global
+const (
+	CoreVersion     = 4
+	ClientVersion   = 3
+	RegistryVersion = 3
+	ModuleVersion   = 3
+	FactoryVersion  = 3
+	DeviceVersion   = 3
+	NodeVersion     = 3
+	PortVersion     = 3
+	LinkVersion     = 3
+	MetadataVersion = 3
+	ProfilerVersion = 3
+)
+
+// MethodID represents method opcodes for protocol messages
 type MethodID uint32
 
 const (
-	// Core methods
-	CoreMethodAddListener    MethodID = 0
-	CoreMethodRemoveListener MethodID = 1
-	CoreMethodHelloV0        MethodID = 2
-	CoreMethodSync           MethodID = 3
-	CoreMethodPing           MethodID = 4
-	CoreMethodPong           MethodID = 5
-	CoreMethodError          MethodID = 6
-	CoreMethodRemoveObject   MethodID = 7
-	CoreMethodUpdateTypes    MethodID = 8
-	CoreMethodUpdateProps    MethodID = 9
-	CoreMethodBound          MethodID = 10
-	CoreMethodAddMem         MethodID = 11
-	CoreMethodRemoveMem      MethodID = 12
-	CoreMethodBoundMeta      MethodID = 13
-	CoreMethodAddPool        MethodID = 14
-	CoreMethodRemovePool     MethodID = 15
-	CoreMethodInitialized    MethodID = 16
-
-	// Registry methods
+	// Core methods (PW_CORE_METHOD_*)
+	CoreMethodAddListener  MethodID = 0
+	CoreMethodHello        MethodID = 1
+	CoreMethodSync         MethodID = 2
+	CoreMethodPong         MethodID = 3
+	CoreMethodError        MethodID = 4
+	CoreMethodGetRegistry  MethodID = 5
+	CoreMethodCreateObject MethodID = 6
+	CoreMethodDestroy      MethodID = 7
+
+	// Client methods (PW_CLIENT_METHOD_*)
+	ClientMethodAddListener       MethodID = 0
+	ClientMethodError             MethodID = 1
+	ClientMethodUpdateProperties  MethodID = 2
+	ClientMethodGetPermissions    MethodID = 3
+	ClientMethodUpdatePermissions MethodID = 4
+
+	// Registry methods (PW_REGISTRY_METHOD_*)
 	RegistryMethodAddListener MethodID = 0
-	RegistryMethodRemoveListener MethodID = 1
-	RegistryMethodGlobal      MethodID = 2
-	RegistryMethodGlobalRemove MethodID = 3
-	RegistryMethodBind        MethodID = 4
-
-	// Node methods
-	NodeMethodAddListener    MethodID = 0
-	NodeMethodRemoveListener MethodID = 1
-	NodeMethodSetParam       MethodID = 2
-	NodeMethodSetIOConf      MethodID = 3
-)
-
-// EventType represents event types from server
-type EventType uint32
-
-const (
-	EventTypeHello         EventType = 0
-	EventTypeAddMem        EventType = 1
-	EventTypeRemoveMem     EventType = 2
-	EventTypeSync          EventType = 3
-	EventTypePing          EventType = 4
-	EventTypePong          EventType = 5
-	EventTypeError         EventType = 6
-	EventTypeRemoveObject  EventType = 7
-	EventTypeUpdateTypes   EventType = 8
-	EventTypeUpdateProps   EventType = 9
-	EventTypeBound         EventType = 10
-	EventTypeAddPool       EventType = 11
-	EventTypeRemovePool    EventType = 12
-	EventTypeBoundMeta     EventType = 13
-	EventTypeInitialized   EventType = 14
+	RegistryMethodBind        MethodID = 1
+	RegistryMethodDestroy     MethodID = 2
+
+	// Node methods (PW_NODE_METHOD_*)
+	NodeMethodAddListener     MethodID = 0
+	NodeMethodSubscribeParams MethodID = 1
+	NodeMethodEnumParams      MethodID = 2
+	NodeMethodSetParam        MethodID = 3
+	NodeMethodSendCommand     MethodID = 4
+
+	// Port methods (PW_PORT_METHOD_*)
+	PortMethodAddListener     MethodID = 0
+	PortMethodSubscribeParams MethodID = 1
+	PortMethodEnumParams      MethodID = 2
+
+	// Device methods (PW_DEVICE_METHOD_*)
+	DeviceMethodAddListener     MethodID = 0
+	DeviceMethodSubscribeParams MethodID = 1
+	DeviceMethodEnumParams      MethodID = 2
+	DeviceMethodSetParam        MethodID = 3
+
+	// Metadata methods (PW_METADATA_METHOD_*)
+	MetadataMethodAddListener MethodID = 0
+	MetadataMethodSetProperty MethodID = 1
+	MetadataMethodClear       MethodID = 2
+)
+
+// EventID represents event opcodes for protocol messages
+type EventID uint32
+
+const (
+	// Core events (PW_CORE_EVENT_*)
+	CoreEventInfo       EventID = 0
+	CoreEventDone       EventID = 1
+	CoreEventPing       EventID = 2
+	CoreEventError      EventID = 3
+	CoreEventRemoveID   EventID = 4
+	CoreEventBoundID    EventID = 5
+	CoreEventAddMem     EventID = 6
+	CoreEventRemoveMem  EventID = 7
+	CoreEventBoundProps EventID = 8
+
+	// Client events (PW_CLIENT_EVENT_*)
+	ClientEventInfo        EventID = 0
+	ClientEventPermissions EventID = 1
 )
 
 // RegistryEventType represents registry-specific events
@@ -127,14 +178,6 @@ func (m *Message) String() string {
 	return "Message{ObjectID:" + string(rune(m.ObjectID)) + "}"
 }
 
-// RequestContext holds information about a request waiting for response
-type RequestContext struct {
-	Sequence  uint32
-	Result    chan interface{}
-	Error     chan error
-	Timeout   chan struct{}
-}
-
 // ProtocolState represents the state of the protocol negotiation
 type ProtocolState uint32
 
@@ -156,14 +199,15 @@ const (
 	ProtocolFeatureRtKits
 )
 
-// MemType represents different memory types
+// MemType is the kind of memory passed with Core.AddMem (SPA_DATA_*)
 type MemType uint32
 
 const (
-	MemTypeShared  MemType = 0 // Shared memory
-	MemTypeDMABuf  MemType = 1 // DMA buffer
-	MemTypeMMAP    MemType = 2 // MMAP
-	MemTypeDMAHeap MemType = 3 // DMA heap
+	MemTypeInvalid MemType = 0
+	MemTypeMemPtr  MemType = 1 // Pointer into process memory, never passed
+	MemTypeMemFd   MemType = 2 // memfd
+	MemTypeDMABuf  MemType = 3 // DMA buffer
+	MemTypeMemID   MemType = 4 // Another memid
 )
 
 // Permission represents access permissions
@@ -5,6 +5,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -59,7 +60,12 @@ type ProtocolStateMachine struct {
 	version         ProtocolVersion
 	serverID        uint32
 	serverCapabilities []string
+	serverName      string
+	serverVersion   string
 	lastError       error
+
+	// Closed and replaced on every state change to wake waiters
+	changed chan struct{}
 }
 
 // NewProtocolStateMachine creates a new protocol state machine
@@ -70,9 +76,17 @@ func NewProtocolStateMachine() *ProtocolStateMachine {
 			Major: 3,
 			Minor: 0,
 		},
+		changed: make(chan struct{}),
 	}
 }
 
+// notify wakes goroutines blocked in WaitForState
+// Caller must hold p.mu
+func (p *ProtocolStateMachine) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
 // TransitionTo attempts to transition to a new state
 func (p *ProtocolStateMachine) TransitionTo(newState State) error {
 	p.mu.Lock()
@@ -86,6 +100,7 @@ func (p *ProtocolStateMachine) TransitionTo(newState State) error {
 	}
 
 	p.state = newState
+	p.notify()
 	return nil
 }
 
@@ -208,6 +223,7 @@ func (p *ProtocolStateMachine) SetError(err error) {
 	defer p.mu.Unlock()
 	p.lastError = err
 	p.state = StateError
+	p.notify()
 }
 
 // Reset resets the state machine to disconnected
@@ -218,4 +234,55 @@ func (p *ProtocolStateMachine) Reset() {
 	p.lastError = nil
 	p.serverID = 0
 	p.serverCapabilities = nil
+	p.serverName = ""
+	p.serverVersion = ""
+	p.notify()
+}
+
+// SetServerInfo records the daemon name and version from Core.Info
+func (p *ProtocolStateMachine) SetServerInfo(name, version string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.serverName = name
+	p.serverVersion = version
+}
+
+// GetServerName returns the daemon name reported in Core.Info
+func (p *ProtocolStateMachine) GetServerName() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.serverName
+}
+
+// GetServerVersion returns the daemon version reported in Core.Info
+func (p *ProtocolStateMachine) GetServerVersion() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.serverVersion
+}
+
+// WaitForState blocks until the state machine reaches the given state
+// Returns the last error if the connection fails first, or ctx.Err()
+func (p *ProtocolStateMachine) WaitForState(ctx context.Context, state State) error {
+	for {
+		p.mu.RLock()
+		current, changed, lastErr := p.state, p.changed, p.lastError
+		p.mu.RUnlock()
+
+		if current == state {
+			return nil
+		}
+		if current == StateError && state != StateError {
+			if lastErr == nil {
+				lastErr = fmt.Errorf("connection failed")
+			}
+			return lastErr
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
 }
@@ -12,26 +12,38 @@ import (
 
 // Proxy represents a proxy to a remote PipeWire object
 type Proxy struct {
-	mu       sync.RWMutex
-	id       uint32
-	connType string // "core", "registry", "node", etc.
-	conn     *Connection
-
-timeout  time.Duration
+	mu        sync.RWMutex
+	id        uint32
+	connType  string // "core", "registry", "node", etc.
+	conn      *Connection
+	timeout   time.Duration
 	listeners map[string][]EventListener
+
+	// Lifecycle, updated by the connection event loop
+	boundID uint32        // Global id from Core.BoundID, InvalidID until bound
+	zombie  bool          // Destroy sent, the id is released by Core.RemoveID
+	removed chan struct{} // Closed when the daemon removed the id
 }
 
 // EventListener is a callback for proxy events
 type EventListener func(data []byte) error
 
+// Proxy lifecycle events fired by the connection, with nil data
+const (
+	ProxyEventBound   = "bound"   // Core.BoundID gave the proxy its global id
+	ProxyEventRemoved = "removed" // Core.RemoveID released the proxy id
+)
+
 // NewProxy creates a new proxy
 func NewProxy(id uint32, connType string, conn *Connection) *Proxy {
 	return &Proxy{
 		id:        id,
-		connType: connType,
+		connType:  connType,
 		conn:      conn,
 		timeout:   5 * time.Second,
 		listeners: make(map[string][]EventListener),
+		boundID:   InvalidID,
+		removed:   make(chan struct{}),
 	}
 }
 
@@ -107,6 +119,87 @@ func (p *Proxy) GetListeners(eventName string) int {
 	return len(p.listeners[eventName])
 }
 
+// BoundID returns the global id the proxy is bound to, InvalidID until
+// the daemon sends Core.BoundID
+func (p *Proxy) BoundID() uint32 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.boundID
+}
+
+// Destroy asks the daemon to destroy the object behind the proxy
+// The id stays allocated until the daemon confirms with Core.RemoveID,
+// wait on Removed to know when. Destroying twice is a no-op
+func (p *Proxy) Destroy() error {
+	p.mu.Lock()
+	id, conn := p.id, p.conn
+	if p.zombie || p.isRemovedLocked() {
+		p.mu.Unlock()
+		return nil
+	}
+	if conn == nil {
+		p.mu.Unlock()
+		return fmt.Errorf("proxy %d has no connection", id)
+	}
+	if id == CoreID || id == ClientID {
+		p.mu.Unlock()
+		return fmt.Errorf("proxy %d cannot be destroyed", id)
+	}
+	p.zombie = true
+	p.mu.Unlock()
+
+	if _, err := conn.Send(NewDestroyMessage(id)); err != nil {
+		p.mu.Lock()
+		p.zombie = false
+		p.mu.Unlock()
+		return fmt.Errorf("destroy proxy %d: %w", id, err)
+	}
+	return nil
+}
+
+// IsDestroyed reports whether Destroy was called
+func (p *Proxy) IsDestroyed() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.zombie
+}
+
+// Removed is closed once the daemon released the proxy id with Core.RemoveID,
+// either to confirm Destroy or because the object went away
+func (p *Proxy) Removed() <-chan struct{} {
+	return p.removed
+}
+
+// isRemovedLocked reports whether Core.RemoveID was received, caller holds p.mu
+func (p *Proxy) isRemovedLocked() bool {
+	select {
+	case <-p.removed:
+		return true
+	default:
+		return false
+	}
+}
+
+// setBound records the global id of a Core.BoundID event
+func (p *Proxy) setBound(globalID uint32) {
+	p.mu.Lock()
+	p.boundID = globalID
+	p.mu.Unlock()
+	p.FireEvent(ProxyEventBound, nil)
+}
+
+// setRemoved marks the id as released by a Core.RemoveID event
+func (p *Proxy) setRemoved() {
+	p.mu.Lock()
+	if p.isRemovedLocked() {
+		p.mu.Unlock()
+		return
+	}
+	close(p.removed)
+	p.mu.Unlock()
+	p.FireEvent(ProxyEventRemoved, nil)
+}
+
 // String returns a string representation
 func (p *Proxy) String() string {
 	p.mu.RLock()
@@ -0,0 +1,156 @@
+// Package core - Proxy id map
+// core/proxy_map.go
+// Client-side proxy ids, reused once the daemon confirms their removal
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// proxyMap allocates proxy ids and tracks the proxies using them
+// Ids released by Core.RemoveID go to a free list and are handed out again
+// before new ones, like pw_map does on the daemon side
+type proxyMap struct {
+	mu      sync.Mutex
+	proxies map[uint32]*Proxy
+	free    []uint32 // Released ids, reused last in first out
+	next    uint32   // Lowest id never allocated
+}
+
+// newProxyMap creates a map whose first id follows the well-known ones
+func newProxyMap() *proxyMap {
+	return &proxyMap{
+		proxies: make(map[uint32]*Proxy),
+		next:    ClientID + 1,
+	}
+}
+
+// insert gives p an id and tracks it
+func (m *proxyMap) insert(p *Proxy) uint32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var id uint32
+	if n := len(m.free); n > 0 {
+		id = m.free[n-1]
+		m.free = m.free[:n-1]
+	} else {
+		id = m.next
+		m.next++
+	}
+	p.id = id
+	m.proxies[id] = p
+	return id
+}
+
+// lookup returns the proxy using id
+func (m *proxyMap) lookup(id uint32) (*Proxy, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.proxies[id]
+	return p, ok
+}
+
+// remove forgets the proxy using id and frees the id for reuse
+func (m *proxyMap) remove(id uint32) (*Proxy, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.proxies[id]
+	if !ok {
+		return nil, false
+	}
+	delete(m.proxies, id)
+	m.free = append(m.free, id)
+	return p, true
+}
+
+// len returns the number of allocated ids
+func (m *proxyMap) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.proxies)
+}
+
+// list returns the tracked proxies ordered by id
+func (m *proxyMap) list() []*Proxy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	proxies := make([]*Proxy, 0, len(m.proxies))
+	for _, p := range m.proxies {
+		proxies = append(proxies, p)
+	}
+	sort.Slice(proxies, func(i, j int) bool { return proxies[i].id < proxies[j].id })
+	return proxies
+}
+
+// AllocateProxy creates a proxy with a free id of this connection
+// Pass its ID as new_id of Registry.Bind or Core.CreateObject; the id is
+// released when the daemon sends Core.RemoveID, after Proxy.Destroy
+func (c *Connection) AllocateProxy(connType string) *Proxy {
+	p := NewProxy(InvalidID, connType, c)
+	id := c.proxies.insert(p)
+	c.logger.Debugf("Connection: Allocated proxy %d (%s)", id, connType)
+	return p
+}
+
+// ReleaseProxy frees the id of a proxy the daemon never heard of, such as
+// one whose Registry.Bind could not be sent
+func (c *Connection) ReleaseProxy(p *Proxy) {
+	id := p.ID()
+	if cur, ok := c.proxies.lookup(id); !ok || cur != p {
+		return
+	}
+	c.proxies.remove(id)
+	c.logger.Debugf("Connection: Released proxy %d", id)
+	p.setRemoved()
+}
+
+// Proxy returns the proxy allocated with id
+func (c *Connection) Proxy(id uint32) (*Proxy, bool) {
+	return c.proxies.lookup(id)
+}
+
+// Proxies returns the proxies whose id is still allocated, ordered by id
+// Proxies destroyed but not yet confirmed by the daemon are included
+func (c *Connection) Proxies() []*Proxy {
+	return c.proxies.list()
+}
+
+// handleBoundID records the global id of a Core.BoundID event
+func (c *Connection) handleBoundID(msg *MessageFrame) error {
+	id, globalID, err := ParseCoreBoundID(msg)
+	if err != nil {
+		return err
+	}
+	p, ok := c.proxies.lookup(id)
+	if !ok {
+		return fmt.Errorf("bound_id for unknown proxy %d", id)
+	}
+	c.logger.Debugf("Connection: Proxy %d bound to global %d", id, globalID)
+	p.setBound(globalID)
+	return nil
+}
+
+// handleRemoveID releases the id of a Core.RemoveID event
+// Handlers registered for the id are dropped, the id may be reused
+func (c *Connection) handleRemoveID(msg *MessageFrame) error {
+	id, err := ParseCoreRemoveID(msg)
+	if err != nil {
+		return err
+	}
+	p, ok := c.proxies.remove(id)
+	if !ok {
+		return fmt.Errorf("remove_id for unknown proxy %d", id)
+	}
+	if c.eventHandler != nil {
+		c.eventHandler.UnregisterHandler(id)
+	}
+	c.logger.Debugf("Connection: Proxy %d removed", id)
+	p.setRemoved()
+	return nil
+}
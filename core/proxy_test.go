@@ -0,0 +1,87 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProxyMapReusesFreedIDs(t *testing.T) {
+	m := newProxyMap()
+
+	a := m.insert(NewProxy(InvalidID, "node", nil))
+	b := m.insert(NewProxy(InvalidID, "node", nil))
+	if a != ClientID+1 || b != a+1 {
+		t.Fatalf("got ids %d, %d", a, b)
+	}
+
+	if _, ok := m.remove(a); !ok {
+		t.Fatal("remove failed")
+	}
+	if _, ok := m.remove(a); ok {
+		t.Fatal("removed the same id twice")
+	}
+
+	c := NewProxy(InvalidID, "link", nil)
+	if id := m.insert(c); id != a || c.ID() != a {
+		t.Errorf("freed id not reused: got %d, want %d", id, a)
+	}
+	if id := m.insert(NewProxy(InvalidID, "link", nil)); id != b+1 {
+		t.Errorf("got id %d, want %d", id, b+1)
+	}
+	if n := len(m.list()); n != 3 {
+		t.Errorf("got %d proxies, want 3", n)
+	}
+}
+
+func TestProxyLifecycle(t *testing.T) {
+	client, daemon := readyPair(t)
+
+	p := client.AllocateProxy("node")
+	if p.ID() == client.RegistryID() || p.BoundID() != InvalidID {
+		t.Fatalf("unexpected new proxy %s bound=%d", p, p.BoundID())
+	}
+
+	bound := make(chan struct{}, 1)
+	p.AddEventListener(ProxyEventBound, func([]byte) error {
+		bound <- struct{}{}
+		return nil
+	})
+	sendEvent(t, daemon, CoreEventBoundID, NewArgsBuilder().Int(int32(p.ID())).Int(42))
+	select {
+	case <-bound:
+	case <-time.After(2 * time.Second):
+		t.Fatal("bound event not fired")
+	}
+	if p.BoundID() != 42 {
+		t.Errorf("got bound id %d, want 42", p.BoundID())
+	}
+
+	if err := p.Destroy(); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	r := expectMethod(t, daemon, CoreID, CoreMethodDestroy)
+	if id, err := r.Uint(); err != nil || id != p.ID() {
+		t.Fatalf("destroy id: %d %v", id, err)
+	}
+	if err := p.Destroy(); err != nil {
+		t.Fatalf("second Destroy failed: %v", err)
+	}
+
+	// The id stays allocated until the daemon confirms
+	if _, ok := client.Proxy(p.ID()); !ok || !p.IsDestroyed() {
+		t.Fatal("proxy released before remove_id")
+	}
+
+	sendEvent(t, daemon, CoreEventRemoveID, NewArgsBuilder().Int(int32(p.ID())))
+	select {
+	case <-p.Removed():
+	case <-time.After(2 * time.Second):
+		t.Fatal("remove_id not handled")
+	}
+	if _, ok := client.Proxy(p.ID()); ok {
+		t.Error("proxy still allocated after remove_id")
+	}
+	if next := client.AllocateProxy("node"); next.ID() != p.ID() {
+		t.Errorf("got id %d, want reused %d", next.ID(), p.ID())
+	}
+}
@@ -0,0 +1,172 @@
+// Package pwtest - graph.go
+// Scripting of the object graph announced by the fake daemon
+
+package pwtest
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/vignemail1/pipewire-go/core"
+)
+
+// Interface versions announced for the scripted objects
+const (
+	NodeVersion = 3
+	PortVersion = 3
+	LinkVersion = 3
+)
+
+// Global is an object of the fake daemon's graph
+type Global struct {
+	ID          uint32
+	Type        string
+	Version     uint32
+	Permissions uint32
+	Props       map[string]string
+}
+
+// AddGlobal adds an object of any interface and announces it to every
+// client with a registry
+func (s *Server) AddGlobal(iface string, version uint32, props map[string]string) *Global {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addGlobalLocked(iface, version, props).clone()
+}
+
+// AddNode adds a node
+func (s *Server) AddNode(props map[string]string) *Global {
+	return s.AddGlobal(core.TypeInterfaceNode, NodeVersion, props)
+}
+
+// AddPort adds a port owned by nodeID; direction is "in" or "out"
+func (s *Server) AddPort(nodeID uint32, direction string, props map[string]string) *Global {
+	props = maps.Clone(props)
+	if props == nil {
+		props = make(map[string]string)
+	}
+	props["node.id"] = fmt.Sprint(nodeID)
+	props["port.direction"] = direction
+	return s.AddGlobal(core.TypeInterfacePort, PortVersion, props)
+}
+
+// AddLink adds a link from outPort to inPort, filling the node ids
+// from the ports' node.id property
+func (s *Server) AddLink(outPort, inPort uint32, props map[string]string) *Global {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	props = maps.Clone(props)
+	if props == nil {
+		props = make(map[string]string)
+	}
+	props["link.output.port"] = fmt.Sprint(outPort)
+	props["link.input.port"] = fmt.Sprint(inPort)
+	if port, ok := s.globals[outPort]; ok {
+		props["link.output.node"] = port.Props["node.id"]
+	}
+	if port, ok := s.globals[inPort]; ok {
+		props["link.input.node"] = port.Props["node.id"]
+	}
+	return s.addGlobalLocked(core.TypeInterfaceLink, LinkVersion, props).clone()
+}
+
+// RemoveGlobal removes an object and announces the removal
+// Returns false when no object has this id
+func (s *Server) RemoveGlobal(id uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.removeGlobalLocked(id)
+}
+
+// Global returns a copy of the object with this id
+func (s *Server) Global(id uint32) (*Global, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.globals[id]
+	if !ok {
+		return nil, false
+	}
+	return g.clone(), true
+}
+
+// Globals returns copies of every object, ordered by id
+func (s *Server) Globals() []*Global {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	globals := s.sortedGlobals()
+	for i, g := range globals {
+		globals[i] = g.clone()
+	}
+	return globals
+}
+
+// EmitEvent sends an event of the object globalID (such as
+// core.NodeEventTypeInfo or core.NodeEventTypeParam) to every proxy bound to it
+// Returns the number of proxies the event was sent to
+func (s *Server) EmitEvent(globalID uint32, event core.EventID, args *core.ArgsBuilder) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := 0
+	for c := range s.clients {
+		for proxyID, id := range c.proxies {
+			if id == globalID {
+				c.send(proxyID, event, args)
+				sent++
+			}
+		}
+	}
+	return sent
+}
+
+// addGlobalLocked allocates an id for a new object and announces it, caller holds s.mu
+func (s *Server) addGlobalLocked(iface string, version uint32, props map[string]string) *Global {
+	g := &Global{
+		ID:          s.nextID,
+		Type:        iface,
+		Version:     version,
+		Permissions: PermAll,
+		Props:       maps.Clone(props),
+	}
+	if g.Props == nil {
+		g.Props = make(map[string]string)
+	}
+	g.Props["object.id"] = fmt.Sprint(g.ID)
+	s.nextID++
+	s.globals[g.ID] = g
+
+	for c := range s.clients {
+		c.sendGlobal(g)
+	}
+	return g
+}
+
+// removeGlobalLocked drops an object and announces the removal, caller holds s.mu
+func (s *Server) removeGlobalLocked(id uint32) bool {
+	if _, ok := s.globals[id]; !ok || id == core.CoreID {
+		return false
+	}
+	delete(s.globals, id)
+
+	for c := range s.clients {
+		c.sendGlobalRemove(id)
+
+		// Proxies bound to the global are destroyed with it
+		for proxyID, globalID := range c.proxies {
+			if globalID == id {
+				delete(c.proxies, proxyID)
+				delete(c.created, proxyID)
+				c.send(core.CoreID, core.CoreEventRemoveID, core.NewArgsBuilder().Int(int32(proxyID)))
+			}
+		}
+	}
+	return true
+}
+
+// clone returns a copy safe to hand out of the lock
+func (g *Global) clone() *Global {
+	c := *g
+	c.Props = maps.Clone(g.Props)
+	return &c
+}
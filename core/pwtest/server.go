@@ -0,0 +1,500 @@
+// Package pwtest provides an in-process fake PipeWire daemon for tests
+// core/pwtest/server.go
+// Listens on a temporary unix socket and speaks the server side of the
+// native protocol, so clients can be tested without pipewire installed
+
+package pwtest
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/vignemail1/pipewire-go/core"
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+// PermAll grants read, write, execute and metadata permissions (PW_PERM_ALL)
+const PermAll = 0x1c8
+
+// Request is a method call received from a client
+type Request struct {
+	Client  uint32 // Global id of the client that sent it
+	Message *core.MessageFrame
+}
+
+// injectedError makes the next matching method fail with Core.Error
+type injectedError struct {
+	objectID uint32
+	opcode   core.MethodID
+	errno    syscall.Errno
+	message  string
+}
+
+// Server is a fake PipeWire daemon
+// Scripting methods (AddNode, RemoveGlobal, EmitEvent...) may be called
+// from any goroutine; events reach connected clients before they return
+type Server struct {
+	path     string
+	listener *net.UnixListener
+	logger   *verbose.Logger
+
+	mu       sync.Mutex
+	globals  map[uint32]*Global
+	nextID   uint32
+	clients  map[*serverClient]struct{}
+	errors   []*injectedError
+	requests []Request
+	closed   bool
+	hung     bool
+	resumed  *sync.Cond // Signalled when hung is cleared
+
+	wg sync.WaitGroup
+}
+
+// NewServer starts a fake daemon on a socket in a temporary directory
+// The server is closed when the test finishes
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), core.DefaultRemoteName)
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("pwtest: listen on %s: %v", path, err)
+	}
+
+	s := &Server{
+		path:     path,
+		listener: listener,
+		logger:   verbose.NewLogger(verbose.LogLevelSilent, false),
+		globals:  make(map[uint32]*Global),
+		clients:  make(map[*serverClient]struct{}),
+	}
+	s.resumed = sync.NewCond(&s.mu)
+
+	// The core is always the first global
+	s.globals[0] = &Global{
+		ID:          0,
+		Type:        core.TypeInterfaceCore,
+		Version:     core.CoreVersion,
+		Permissions: PermAll,
+		Props:       map[string]string{"core.name": core.DefaultRemoteName},
+	}
+	s.nextID = 1
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Path returns the socket path, usable as remote for core.Dial
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Dir returns the directory of the socket, usable as PIPEWIRE_RUNTIME_DIR
+func (s *Server) Dir() string {
+	return filepath.Dir(s.path)
+}
+
+// Close stops accepting clients and disconnects the connected ones
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.resumed.Broadcast()
+	s.mu.Unlock()
+
+	s.listener.Close()
+	s.DropClients()
+	s.wg.Wait()
+	os.Remove(s.path)
+}
+
+// DropClients closes every client connection, as a daemon restart would
+// The server keeps accepting new clients and keeps its graph
+func (s *Server) DropClients() {
+	s.mu.Lock()
+	clients := make([]*serverClient, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range clients {
+		c.conn.Close()
+	}
+}
+
+// ClientCount returns the number of connected clients
+func (s *Server) ClientCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// InjectError makes the next call of opcode on objectID (a client-side
+// proxy id) fail with Core.Error(-errno, message) instead of being handled
+func (s *Server) InjectError(objectID uint32, opcode core.MethodID, errno syscall.Errno, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, &injectedError{
+		objectID: objectID,
+		opcode:   opcode,
+		errno:    errno,
+		message:  message,
+	})
+}
+
+// Hang stops answering requests, like a wedged daemon; connections stay open
+// Requests received meanwhile are handled after Resume
+func (s *Server) Hang() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hung = true
+}
+
+// Resume answers requests again after Hang
+func (s *Server) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hung = false
+	s.resumed.Broadcast()
+}
+
+// Requests returns the method calls received so far, in arrival order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// acceptLoop serves clients until the listener is closed
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		socket, err := s.listener.AcceptUnix()
+		if err != nil {
+			return
+		}
+
+		conn := core.NewConnectionFromSocket(socket, s.logger)
+		conn.SetTimeout(0) // Idle clients must not time out
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		c := &serverClient{
+			server:  s,
+			conn:    conn,
+			proxies: make(map[uint32]uint32),
+			created: make(map[uint32]bool),
+		}
+		c.global = s.addGlobalLocked(core.TypeInterfaceClient, core.ClientVersion, map[string]string{})
+		s.clients[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go c.serve()
+	}
+}
+
+// sortedGlobals returns the globals ordered by id, caller holds s.mu
+func (s *Server) sortedGlobals() []*Global {
+	globals := make([]*Global, 0, len(s.globals))
+	for _, g := range s.globals {
+		globals = append(globals, g)
+	}
+	sort.Slice(globals, func(i, j int) bool { return globals[i].ID < globals[j].ID })
+	return globals
+}
+
+// takeError removes and returns the injected error matching msg, caller holds s.mu
+func (s *Server) takeError(msg *core.MessageFrame) *injectedError {
+	for i, e := range s.errors {
+		if e.objectID == msg.ObjectID && uint32(e.opcode) == msg.MethodID {
+			s.errors = append(s.errors[:i], s.errors[i+1:]...)
+			return e
+		}
+	}
+	return nil
+}
+
+// serverClient is the server side of one client connection
+// Fields are protected by the server mutex
+type serverClient struct {
+	server     *Server
+	conn       *core.Connection
+	global     *Global           // Client global of this connection
+	registryID uint32            // Registry proxy id, 0 before GetRegistry
+	proxies    map[uint32]uint32 // Client proxy id -> global id
+	created    map[uint32]bool   // Proxy ids of the objects made with CreateObject
+}
+
+// serve handles the requests of the client until it disconnects
+func (c *serverClient) serve() {
+	s := c.server
+	defer s.wg.Done()
+
+	for {
+		msg, err := c.conn.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		s.mu.Lock()
+		for s.hung && !s.closed {
+			s.resumed.Wait()
+		}
+		s.requests = append(s.requests, Request{Client: c.global.ID, Message: msg})
+		if e := s.takeError(msg); e != nil {
+			c.sendError(msg.ObjectID, msg.Sequence, e.errno, e.message)
+		} else if err := c.handle(msg); err != nil {
+			c.sendError(msg.ObjectID, msg.Sequence, syscall.EINVAL, err.Error())
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	for proxyID := range c.created {
+		c.destroyCreatedLocked(proxyID, c.proxies[proxyID])
+	}
+	s.removeGlobalLocked(c.global.ID)
+	s.mu.Unlock()
+	c.conn.Close()
+}
+
+// handle dispatches one method call, caller holds s.mu
+func (c *serverClient) handle(msg *core.MessageFrame) error {
+	r, err := core.NewArgsReader(msg)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case msg.ObjectID == core.CoreID:
+		return c.handleCore(core.MethodID(msg.MethodID), r)
+	case msg.ObjectID == core.ClientID:
+		return c.handleClient(core.MethodID(msg.MethodID), r)
+	case c.registryID != 0 && msg.ObjectID == c.registryID:
+		return c.handleRegistry(core.MethodID(msg.MethodID), r)
+	}
+
+	// Methods on bound objects are only recorded
+	if _, ok := c.proxies[msg.ObjectID]; !ok {
+		return fmt.Errorf("unknown object %d", msg.ObjectID)
+	}
+	return nil
+}
+
+// handleCore implements the Core methods
+func (c *serverClient) handleCore(opcode core.MethodID, r *core.ArgsReader) error {
+	s := c.server
+
+	switch opcode {
+	case core.CoreMethodHello:
+		c.send(core.CoreID, core.CoreEventInfo, core.NewArgsBuilder().
+			Int(0).Int(0x5eed).String("pwtest").String("localhost").String("1.0.0").
+			String(core.DefaultRemoteName).Long(0x1).
+			Dict(s.globals[0].Props))
+
+	case core.CoreMethodSync:
+		id, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		seq, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		c.send(core.CoreID, core.CoreEventDone, core.NewArgsBuilder().Int(int32(id)).Int(int32(seq)))
+
+	case core.CoreMethodPong:
+		// Recorded only
+
+	case core.CoreMethodGetRegistry:
+		if _, err := r.Int(); err != nil {
+			return err
+		}
+		newID, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		c.registryID = newID
+		for _, g := range s.sortedGlobals() {
+			c.sendGlobal(g)
+		}
+
+	case core.CoreMethodCreateObject:
+		if _, err := r.String(); err != nil {
+			return err
+		}
+		iface, err := r.String()
+		if err != nil {
+			return err
+		}
+		version, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		props, err := r.Dict()
+		if err != nil {
+			return err
+		}
+		newID, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		g := s.addGlobalLocked(iface, version, props)
+		c.proxies[newID] = g.ID
+		c.created[newID] = true
+		c.send(core.CoreID, core.CoreEventBoundID, core.NewArgsBuilder().Int(int32(newID)).Int(int32(g.ID)))
+
+	case core.CoreMethodDestroy:
+		id, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		globalID, ok := c.proxies[id]
+		if !ok {
+			return fmt.Errorf("unknown proxy %d", id)
+		}
+		// Only objects the client created go away with their proxy, unless
+		// they linger
+		delete(c.proxies, id)
+		if c.created[id] {
+			c.destroyCreatedLocked(id, globalID)
+		}
+		c.send(core.CoreID, core.CoreEventRemoveID, core.NewArgsBuilder().Int(int32(id)))
+
+	default:
+		return fmt.Errorf("unsupported core method %d", opcode)
+	}
+	return nil
+}
+
+// destroyCreatedLocked drops global globalID, made by proxy proxyID with
+// CreateObject, as the proxy goes away, unless it was created with
+// object.linger; caller holds s.mu
+func (c *serverClient) destroyCreatedLocked(proxyID, globalID uint32) {
+	s := c.server
+	delete(c.created, proxyID)
+	if g, ok := s.globals[globalID]; ok && g.Props["object.linger"] != "true" {
+		s.removeGlobalLocked(globalID)
+	}
+}
+
+// handleClient implements the Client methods
+func (c *serverClient) handleClient(opcode core.MethodID, r *core.ArgsReader) error {
+	switch opcode {
+	case core.ClientMethodUpdateProperties:
+		props, err := r.Dict()
+		if err != nil {
+			return err
+		}
+		for k, v := range props {
+			c.global.Props[k] = v
+		}
+	default:
+		return fmt.Errorf("unsupported client method %d", opcode)
+	}
+	return nil
+}
+
+// handleRegistry implements the Registry methods
+func (c *serverClient) handleRegistry(opcode core.MethodID, r *core.ArgsReader) error {
+	s := c.server
+
+	switch opcode {
+	case core.RegistryMethodBind:
+		id, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		iface, err := r.String()
+		if err != nil {
+			return err
+		}
+		version, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		newID, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		// Checked like pw_global_bind
+		g, ok := s.globals[id]
+		if !ok {
+			return fmt.Errorf("unknown global %d", id)
+		}
+		if iface != g.Type {
+			return fmt.Errorf("global %d is a %s, not a %s", id, g.Type, iface)
+		}
+		if version > g.Version {
+			return fmt.Errorf("global %d: interface version %d < %d", id, g.Version, version)
+		}
+		c.proxies[newID] = id
+		c.send(core.CoreID, core.CoreEventBoundID, core.NewArgsBuilder().Int(int32(newID)).Int(int32(id)))
+
+	case core.RegistryMethodDestroy:
+		id, err := r.Uint()
+		if err != nil {
+			return err
+		}
+		if !s.removeGlobalLocked(id) {
+			return fmt.Errorf("unknown global %d", id)
+		}
+
+	default:
+		return fmt.Errorf("unsupported registry method %d", opcode)
+	}
+	return nil
+}
+
+// send writes an event to the client, errors mean the client is going away
+func (c *serverClient) send(objectID uint32, event core.EventID, args *core.ArgsBuilder) {
+	msg := core.NewMessageBuilder(objectID, uint32(event)).WithPOD(args.Build()).Build()
+	if err := c.conn.WriteMessage(msg); err != nil {
+		c.server.logger.Debugf("pwtest: write to client %d: %v", c.global.ID, err)
+	}
+}
+
+// sendError sends Core.Error(id, seq, -errno, message)
+func (c *serverClient) sendError(id, seq uint32, errno syscall.Errno, message string) {
+	c.send(core.CoreID, core.CoreEventError, core.NewArgsBuilder().
+		Int(int32(id)).Int(int32(seq)).Int(-int32(errno)).String(message))
+}
+
+// sendGlobal announces g on the registry of the client, if bound
+func (c *serverClient) sendGlobal(g *Global) {
+	if c.registryID == 0 {
+		return
+	}
+	c.send(c.registryID, core.EventID(core.RegistryEventTypeGlobal), core.NewArgsBuilder().
+		Int(int32(g.ID)).Int(int32(g.Permissions)).String(g.Type).Int(int32(g.Version)).
+		Dict(g.Props))
+}
+
+// sendGlobalRemove announces the removal of a global on the registry of the client
+func (c *serverClient) sendGlobalRemove(id uint32) {
+	if c.registryID == 0 {
+		return
+	}
+	c.send(c.registryID, core.EventID(core.RegistryEventTypeGlobalRemove), core.NewArgsBuilder().Int(int32(id)))
+}
@@ -0,0 +1,214 @@
+package pwtest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/vignemail1/pipewire-go/core"
+)
+
+// registryLog collects the registry events received by a client
+type registryLog struct {
+	mu      sync.Mutex
+	globals map[uint32]*core.RegistryGlobalEvent
+	removed []uint32
+}
+
+func (l *registryLog) handle(msg *core.MessageFrame) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch core.RegistryEventType(msg.MethodID) {
+	case core.RegistryEventTypeGlobal:
+		global, err := core.ParseRegistryGlobal(msg)
+		if err != nil {
+			return err
+		}
+		l.globals[global.ID] = global
+	case core.RegistryEventTypeGlobalRemove:
+		id, err := core.ParseRegistryGlobalRemove(msg)
+		if err != nil {
+			return err
+		}
+		delete(l.globals, id)
+		l.removed = append(l.removed, id)
+	}
+	return nil
+}
+
+func (l *registryLog) global(id uint32) (*core.RegistryGlobalEvent, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	g, ok := l.globals[id]
+	return g, ok
+}
+
+// connect dials the server and waits until the handshake is done
+func connect(t *testing.T, s *Server) (*core.Connection, *core.EventHandler, *registryLog) {
+	t.Helper()
+
+	conn, err := core.Dial(s.Path(), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	log := &registryLog{globals: make(map[uint32]*core.RegistryGlobalEvent)}
+	handler := core.NewEventHandler()
+	handler.RegisterHandler(conn.RegistryID(), log.handle)
+	conn.SetEventHandler(handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go conn.StartEventLoop(ctx)
+
+	readyCtx, readyCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer readyCancel()
+	if err := conn.WaitUntilReady(readyCtx); err != nil {
+		t.Fatalf("connection not ready: %v", err)
+	}
+	return conn, handler, log
+}
+
+// roundtrip waits until every event sent before it has been handled
+func roundtrip(t *testing.T, conn *core.Connection) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := conn.Roundtrip(ctx); err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+}
+
+func TestServerAnnouncesGraph(t *testing.T) {
+	s := NewServer(t)
+	node := s.AddNode(map[string]string{"node.name": "sink"})
+	port := s.AddPort(node.ID, "in", nil)
+
+	conn, _, log := connect(t, s)
+	roundtrip(t, conn)
+
+	if g, ok := log.global(node.ID); !ok || g.Type != core.TypeInterfaceNode || g.Props["node.name"] != "sink" {
+		t.Fatalf("node not announced: %+v", g)
+	}
+	if g, ok := log.global(port.ID); !ok || g.Props["node.id"] != node.Props["object.id"] {
+		t.Fatalf("port not announced: %+v", g)
+	}
+	if _, ok := log.global(core.CoreID); !ok {
+		t.Fatal("core global not announced")
+	}
+
+	// Changes made after the registry was bound are sent as they happen
+	out := s.AddPort(node.ID, "out", nil)
+	link := s.AddLink(out.ID, port.ID, nil)
+	s.RemoveGlobal(out.ID)
+	roundtrip(t, conn)
+
+	if g, ok := log.global(link.ID); !ok || g.Props["link.input.node"] != node.Props["object.id"] {
+		t.Fatalf("link not announced: %+v", g)
+	}
+	if _, ok := log.global(out.ID); ok {
+		t.Fatal("removed port still announced")
+	}
+}
+
+func TestServerBindAndEmitEvent(t *testing.T) {
+	s := NewServer(t)
+	node := s.AddNode(nil)
+
+	conn, handler, _ := connect(t, s)
+
+	events := make(chan *core.MessageFrame, 1)
+	proxy := conn.AllocateProxy("node")
+	proxyID := proxy.ID()
+	handler.RegisterHandler(proxyID, func(msg *core.MessageFrame) error {
+		events <- msg
+		return nil
+	})
+
+	bind := core.NewMessageBuilder(conn.RegistryID(), uint32(core.RegistryMethodBind)).
+		WithPOD(core.NewArgsBuilder().Int(int32(node.ID)).String(core.TypeInterfaceNode).Int(NodeVersion).Int(int32(proxyID)).Build()).
+		Build()
+	if _, err := conn.Send(bind); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	roundtrip(t, conn)
+	if proxy.BoundID() != node.ID {
+		t.Fatalf("proxy bound to %d, want %d", proxy.BoundID(), node.ID)
+	}
+
+	if n := s.EmitEvent(node.ID, core.EventID(core.NodeEventTypeInfo), core.NewArgsBuilder().Int(int32(node.ID))); n != 1 {
+		t.Fatalf("event sent to %d proxies, want 1", n)
+	}
+
+	select {
+	case msg := <-events:
+		if msg.MethodID != uint32(core.NodeEventTypeInfo) {
+			t.Errorf("got event %d, want info", msg.MethodID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("event not delivered to the bound proxy")
+	}
+
+	// Removing the global destroys the proxies bound to it
+	s.RemoveGlobal(node.ID)
+	select {
+	case <-proxy.Removed():
+	case <-time.After(2 * time.Second):
+		t.Fatal("proxy id not removed with its global")
+	}
+}
+
+func TestServerInjectError(t *testing.T) {
+	s := NewServer(t)
+	conn, _, _ := connect(t, s)
+
+	s.InjectError(core.CoreID, core.CoreMethodSync, syscall.EPERM, "denied")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := conn.Roundtrip(ctx); !errors.Is(err, core.ErrPermission) {
+		t.Fatalf("expected ErrPermission, got %v", err)
+	}
+
+	// Injected errors fire once
+	roundtrip(t, conn)
+}
+
+func TestServerDropClients(t *testing.T) {
+	s := NewServer(t)
+	connect(t, s)
+
+	s.DropClients()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for s.ClientCount() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("client not dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The server still accepts clients afterwards
+	conn, _, _ := connect(t, s)
+	roundtrip(t, conn)
+}
+
+func TestServerHang(t *testing.T) {
+	s := NewServer(t)
+	conn, _, _ := connect(t, s)
+
+	s.Hang()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := conn.Roundtrip(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected no answer while hung, got %v", err)
+	}
+
+	s.Resume()
+	roundtrip(t, conn)
+}
@@ -0,0 +1,138 @@
+// Package core - Registry interface messages
+// core/registry_methods.go
+// Registry.Bind encoding with version negotiation, and decoders for
+// Registry.Global and Registry.GlobalRemove events
+
+package core
+
+import "fmt"
+
+// RegistryGlobalEvent is the decoded Registry.Global event
+type RegistryGlobalEvent struct {
+	ID          uint32
+	Permissions uint32
+	Type        string // Interface type, e.g. "PipeWire:Interface:Node"
+	Version     uint32
+	Props       map[string]string
+}
+
+// ParseRegistryGlobal decodes Registry.Global(id, permissions, type, version, props)
+func ParseRegistryGlobal(msg *MessageFrame) (*RegistryGlobalEvent, error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	global := &RegistryGlobalEvent{}
+	if global.ID, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("registry global id: %w", err)
+	}
+	if global.Permissions, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("registry global permissions: %w", err)
+	}
+	if global.Type, err = r.String(); err != nil {
+		return nil, fmt.Errorf("registry global type: %w", err)
+	}
+	if global.Version, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("registry global version: %w", err)
+	}
+	if global.Props, err = r.Dict(); err != nil {
+		return nil, fmt.Errorf("registry global props: %w", err)
+	}
+	return global, nil
+}
+
+// ParseRegistryGlobalRemove decodes Registry.GlobalRemove(id)
+func ParseRegistryGlobalRemove(msg *MessageFrame) (uint32, error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return 0, err
+	}
+	id, err := r.Uint()
+	if err != nil {
+		return 0, fmt.Errorf("registry global remove id: %w", err)
+	}
+	return id, nil
+}
+
+// supportedVersions maps the interfaces a global can be bound to with the
+// version this library implements
+var supportedVersions = map[string]uint32{
+	TypeInterfaceClient:   ClientVersion,
+	TypeInterfaceModule:   ModuleVersion,
+	TypeInterfaceFactory:  FactoryVersion,
+	TypeInterfaceDevice:   DeviceVersion,
+	TypeInterfaceNode:     NodeVersion,
+	TypeInterfacePort:     PortVersion,
+	TypeInterfaceLink:     LinkVersion,
+	TypeInterfaceMetadata: MetadataVersion,
+	TypeInterfaceProfiler: ProfilerVersion,
+}
+
+// SupportedVersion returns the version of iface implemented by this library
+// and whether globals of that interface can be bound
+func SupportedVersion(iface string) (uint32, bool) {
+	version, ok := supportedVersions[iface]
+	return version, ok
+}
+
+// NegotiateVersion returns the version to bind a global of type iface
+// advertised at version advertised: the lower of it and SupportedVersion
+// Interfaces that cannot be bound give a VersionError
+func NegotiateVersion(iface string, advertised uint32) (uint32, error) {
+	supported, ok := SupportedVersion(iface)
+	if !ok {
+		return 0, &VersionError{Interface: iface}
+	}
+	return min(advertised, supported), nil
+}
+
+// RegistryBindRequest holds the arguments of Registry.Bind
+type RegistryBindRequest struct {
+	ID      uint32 // Global id to bind
+	Type    string // Interface type of the global, e.g. TypeInterfaceNode
+	Version uint32 // Interface version of the proxy, see NegotiateVersion
+	NewID   uint32 // Client-allocated proxy id receiving the object events
+}
+
+// Message builds Registry.Bind(id, type, version, new_id) on the registry proxy registryID
+// The daemon answers with Core.BoundID(new_id, id)
+func (r *RegistryBindRequest) Message(registryID uint32) *MessageFrame {
+	return NewMessageBuilder(registryID, uint32(RegistryMethodBind)).
+		WithPOD(NewArgsBuilder().
+			Int(int32(r.ID)).String(r.Type).Int(int32(r.Version)).Int(int32(r.NewID)).
+			Build()).
+		Build()
+}
+
+// NewRegistryDestroyMessage builds Registry.Destroy(id) on the registry proxy
+// registryID, destroying the global id; the daemon announces it with
+// Registry.GlobalRemove
+func NewRegistryDestroyMessage(registryID, id uint32) *MessageFrame {
+	return NewMessageBuilder(registryID, uint32(RegistryMethodDestroy)).
+		WithPOD(NewArgsBuilder().Int(int32(id)).Build()).
+		Build()
+}
+
+// ParseRegistryBind decodes Registry.Bind(id, type, version, new_id)
+func ParseRegistryBind(msg *MessageFrame) (*RegistryBindRequest, error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	bind := &RegistryBindRequest{}
+	if bind.ID, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("registry bind id: %w", err)
+	}
+	if bind.Type, err = r.String(); err != nil {
+		return nil, fmt.Errorf("registry bind type: %w", err)
+	}
+	if bind.Version, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("registry bind version: %w", err)
+	}
+	if bind.NewID, err = r.Uint(); err != nil {
+		return nil, fmt.Errorf("registry bind new_id: %w", err)
+	}
+	return bind, nil
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRegistryGlobal(t *testing.T) {
+	msg := NewMessageBuilder(2, uint32(RegistryEventTypeGlobal)).
+		WithPOD(NewArgsBuilder().
+			Int(42).Int(0x1ff).String("PipeWire:Interface:Node").Int(3).
+			Dict(map[string]string{"node.name": "alsa_output"}).
+			Build()).
+		Build()
+
+	global, err := ParseRegistryGlobal(msg)
+	if err != nil {
+		t.Fatalf("ParseRegistryGlobal failed: %v", err)
+	}
+	if global.ID != 42 || global.Permissions != 0x1ff || global.Version != 3 {
+		t.Errorf("unexpected global: %+v", global)
+	}
+	if global.Type != "PipeWire:Interface:Node" || global.Props["node.name"] != "alsa_output" {
+		t.Errorf("unexpected global: %+v", global)
+	}
+
+	// Truncated arguments must fail instead of returning a partial global
+	msg.PODData = NewArgsBuilder().Int(42).Int(0x1ff).Build()
+	if _, err := ParseRegistryGlobal(msg); err == nil {
+		t.Error("expected error for truncated global")
+	}
+}
+
+func TestParseRegistryGlobalRemove(t *testing.T) {
+	msg := NewMessageBuilder(2, uint32(RegistryEventTypeGlobalRemove)).
+		WithPOD(NewArgsBuilder().Int(42).Build()).
+		Build()
+
+	id, err := ParseRegistryGlobalRemove(msg)
+	if err != nil || id != 42 {
+		t.Fatalf("ParseRegistryGlobalRemove: id=%d err=%v", id, err)
+	}
+}
+
+func TestRegistryBindRequest(t *testing.T) {
+	req := &RegistryBindRequest{ID: 42, Type: TypeInterfaceNode, Version: 3, NewID: 7}
+	msg := req.Message(2)
+	if msg.ObjectID != 2 || msg.MethodID != uint32(RegistryMethodBind) {
+		t.Fatalf("bind sent as %d.%d", msg.ObjectID, msg.MethodID)
+	}
+
+	// Struct{Int id, String type, Int version, Int new_id}
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		t.Fatalf("NewArgsReader failed: %v", err)
+	}
+	id, _ := r.Int()
+	iface, _ := r.String()
+	version, _ := r.Int()
+	newID, err := r.Int()
+	if err != nil || id != 42 || iface != TypeInterfaceNode || version != 3 || newID != 7 || r.More() {
+		t.Fatalf("unexpected bind arguments %d %q %d %d: %v", id, iface, version, newID, err)
+	}
+
+	parsed, err := ParseRegistryBind(msg)
+	if err != nil || *parsed != *req {
+		t.Fatalf("ParseRegistryBind: %+v %v", parsed, err)
+	}
+}
+
+func TestRegistryDestroyMessage(t *testing.T) {
+	msg := NewRegistryDestroyMessage(2, 42)
+	if msg.ObjectID != 2 || msg.MethodID != uint32(RegistryMethodDestroy) {
+		t.Fatalf("destroy sent as %d.%d", msg.ObjectID, msg.MethodID)
+	}
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		t.Fatalf("NewArgsReader failed: %v", err)
+	}
+	if id, err := r.Uint(); err != nil || id != 42 || r.More() {
+		t.Fatalf("unexpected destroy argument %d: %v", id, err)
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		iface      string
+		advertised uint32
+		want       uint32
+	}{
+		{TypeInterfaceNode, 3, NodeVersion},
+		{TypeInterfaceNode, 0, 0},
+		{TypeInterfacePort, 9, PortVersion},
+		{TypeInterfaceMetadata, 2, 2},
+		{TypeInterfaceProfiler, 3, ProfilerVersion},
+	}
+	for _, tt := range tests {
+		got, err := NegotiateVersion(tt.iface, tt.advertised)
+		if err != nil || got != tt.want {
+			t.Errorf("NegotiateVersion(%s, %d) = %d, %v, want %d", tt.iface, tt.advertised, got, err, tt.want)
+		}
+	}
+
+	// Neither unknown interfaces nor the per-connection singletons are bound
+	for _, iface := range []string{"PipeWire:Interface:Unknown", TypeInterfaceCore, TypeInterfaceRegistry} {
+		_, err := NegotiateVersion(iface, 3)
+		var versionErr *VersionError
+		if !errors.As(err, &versionErr) || !errors.Is(err, ErrNotSupported) {
+			t.Errorf("NegotiateVersion(%s): expected a VersionError, got %v", iface, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package core - Capture replay
+// core/replay.go
+// Plays the daemon side of a capture to a client connection
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/vignemail1/pipewire-go/verbose"
+)
+
+// Replayer feeds the received frames of a capture to a client as if it
+// were the daemon. Each frame the client sent in the capture is awaited
+// before the frames that followed it are played, so the client sees the
+// same order of events; the client has to send the same methods in the
+// same order for sequence numbers (Core.Done) to line up
+type Replayer struct {
+	records []*CaptureRecord
+	daemon  *Connection
+	logger  *verbose.Logger
+
+	done chan struct{}
+	mu   sync.Mutex
+	err  error
+}
+
+// NewReplayConnection returns a client connection whose daemon is a replay
+// of the capture read from cr. Use it like a dialed connection, for example
+// with client.NewClientWithConnection; the replay starts with the first
+// frame the connection sends
+func NewReplayConnection(cr *CaptureReader, logger *verbose.Logger) (*Connection, *Replayer, error) {
+	if logger == nil {
+		logger = verbose.NewLogger(verbose.LogLevelInfo, false)
+	}
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read capture: %w", err)
+	}
+
+	clientSocket, daemonSocket, err := unixSocketPair()
+	if err != nil {
+		return nil, nil, NewConnectionErrorf("replay socket pair: %v", err)
+	}
+
+	daemon := NewConnectionFromSocket(daemonSocket, logger)
+	daemon.SetTimeout(0) // The client may take its time between methods
+
+	r := &Replayer{
+		records: records,
+		daemon:  daemon,
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+	go r.run()
+
+	return NewConnectionFromSocket(clientSocket, logger), r, nil
+}
+
+// Done is closed once every record has been played or the replay failed
+func (r *Replayer) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err returns why the replay stopped early, nil while running and after a complete replay
+func (r *Replayer) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close disconnects the client, which sees the daemon going away
+func (r *Replayer) Close() error {
+	return r.daemon.Close()
+}
+
+// run plays the records in order, then keeps reading like an idle daemon
+func (r *Replayer) run() {
+	err := r.play()
+
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+	close(r.done)
+
+	if err != nil {
+		r.logger.Warnf("Replay: %v", err)
+		r.daemon.Close()
+		return
+	}
+
+	r.logger.Infof("Replay: %d records played", len(r.records))
+	for {
+		msg, err := r.daemon.ReadMessage()
+		if err != nil {
+			return
+		}
+		closeFDs(msg.FDs)
+	}
+}
+
+// play sends the received frames and checks the sent ones
+func (r *Replayer) play() error {
+	for i, rec := range r.records {
+		switch rec.Direction {
+		case CaptureSent:
+			msg, err := r.daemon.ReadMessage()
+			if err != nil {
+				if err == io.EOF {
+					return fmt.Errorf("client closed at record %d of %d", i+1, len(r.records))
+				}
+				return fmt.Errorf("record %d: %w", i+1, err)
+			}
+			closeFDs(msg.FDs)
+
+			id, opcode, _, _, _ := rec.Header()
+			if msg.ObjectID != id || msg.MethodID != opcode {
+				return fmt.Errorf("record %d: client sent %d/%d, capture has %d/%d",
+					i+1, msg.ObjectID, msg.MethodID, id, opcode)
+			}
+
+		case CaptureReceived:
+			if err := r.send(rec); err != nil {
+				return fmt.Errorf("record %d: %w", i+1, err)
+			}
+
+		default:
+			return fmt.Errorf("record %d: unknown direction %d", i+1, rec.Direction)
+		}
+	}
+	return nil
+}
+
+// send writes a captured frame to the client
+// The captured descriptors are gone, /dev/null stands in for them
+func (r *Replayer) send(rec *CaptureRecord) error {
+	if rec.NumFDs == 0 {
+		return r.daemon.queueWrite(context.Background(), rec.Frame, nil)
+	}
+
+	fds := make([]int, 0, rec.NumFDs)
+	defer func() { closeFDs(fds) }()
+	for i := uint32(0); i < rec.NumFDs; i++ {
+		fd, err := syscall.Open(os.DevNull, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			return err
+		}
+		fds = append(fds, fd)
+	}
+	return r.daemon.queueWrite(context.Background(), rec.Frame, fds)
+}
+
+// unixSocketPair returns the two ends of a connected unix stream socket pair
+func unixSocketPair() (*net.UnixConn, *net.UnixConn, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wrap := func(fd int, name string) (*net.UnixConn, error) {
+		f := os.NewFile(uintptr(fd), name)
+		defer f.Close()
+		fc, err := net.FileConn(f)
+		if err != nil {
+			return nil, err
+		}
+		return fc.(*net.UnixConn), nil
+	}
+
+	a, err := wrap(fds[0], "replay-client")
+	if err != nil {
+		syscall.Close(fds[1])
+		return nil, nil, err
+	}
+	b, err := wrap(fds[1], "replay-daemon")
+	if err != nil {
+		a.Close()
+		return nil, nil, err
+	}
+	return a, b, nil
+}
@@ -0,0 +1,84 @@
+// Package core - Socket discovery
+// core/socket.go
+// Resolves remote names to socket paths following libpipewire's lookup order
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultRemoteName is the socket name of the session daemon
+	DefaultRemoteName = "pipewire-0"
+
+	// ManagerRemoteName is the socket of the daemon granting manager
+	// permissions (session managers, patchbays)
+	ManagerRemoteName = "pipewire-0-manager"
+
+	// ManagerRemote tries the manager socket first, then the regular one,
+	// like libpipewire does for clients with the manager intention
+	ManagerRemote = ManagerRemoteName + "," + DefaultRemoteName
+
+	// SystemRuntimeDir holds the sockets of a system-wide daemon
+	SystemRuntimeDir = "/run/pipewire"
+)
+
+// RemoteName returns the remote to connect to: name when set,
+// otherwise $PIPEWIRE_REMOTE, otherwise DefaultRemoteName
+func RemoteName(name string) string {
+	if name != "" {
+		return name
+	}
+	if remote := os.Getenv("PIPEWIRE_REMOTE"); remote != "" {
+		return remote
+	}
+	return DefaultRemoteName
+}
+
+// RuntimeDir returns the directory holding the session daemon sockets:
+// $PIPEWIRE_RUNTIME_DIR, $XDG_RUNTIME_DIR or $USERPROFILE, whichever is set first
+// Returns "" when none is set
+func RuntimeDir() string {
+	for _, env := range []string{"PIPEWIRE_RUNTIME_DIR", "XDG_RUNTIME_DIR", "USERPROFILE"} {
+		if dir := os.Getenv(env); dir != "" {
+			return dir
+		}
+	}
+	return ""
+}
+
+// SocketCandidates returns the socket paths to try for a remote, in order
+// The remote (see RemoteName) is a comma separated list of names;
+// absolute names are used as is, other names are looked up in RuntimeDir
+// and then in SystemRuntimeDir
+func SocketCandidates(remote string) []string {
+	runtimeDir := RuntimeDir()
+
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	for _, name := range strings.Split(RemoteName(remote), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if filepath.IsAbs(name) {
+			add(name)
+			continue
+		}
+		if runtimeDir != "" {
+			add(filepath.Join(runtimeDir, name))
+		}
+		add(filepath.Join(SystemRuntimeDir, name))
+	}
+	return paths
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// clearRemoteEnv unsets the variables consulted by the socket lookup
+func clearRemoteEnv(t *testing.T) {
+	t.Helper()
+	for _, env := range []string{"PIPEWIRE_REMOTE", "PIPEWIRE_RUNTIME_DIR", "XDG_RUNTIME_DIR", "USERPROFILE"} {
+		t.Setenv(env, "")
+	}
+}
+
+func TestSocketCandidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote string
+		env    map[string]string
+		want   []string
+	}{
+		{
+			name: "xdg runtime dir",
+			env:  map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"},
+			want: []string{"/run/user/1000/pipewire-0", "/run/pipewire/pipewire-0"},
+		},
+		{
+			name: "pipewire runtime dir wins",
+			env: map[string]string{
+				"PIPEWIRE_RUNTIME_DIR": "/tmp/pw",
+				"XDG_RUNTIME_DIR":      "/run/user/1000",
+			},
+			want: []string{"/tmp/pw/pipewire-0", "/run/pipewire/pipewire-0"},
+		},
+		{
+			name: "remote from environment",
+			env: map[string]string{
+				"PIPEWIRE_REMOTE": "pipewire-1",
+				"XDG_RUNTIME_DIR": "/run/user/1000",
+			},
+			want: []string{"/run/user/1000/pipewire-1", "/run/pipewire/pipewire-1"},
+		},
+		{
+			name:   "explicit remote overrides environment",
+			remote: "/var/run/custom.sock",
+			env:    map[string]string{"PIPEWIRE_REMOTE": "pipewire-1"},
+			want:   []string{"/var/run/custom.sock"},
+		},
+		{
+			name:   "manager falls back to regular socket",
+			remote: ManagerRemote,
+			env:    map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"},
+			want: []string{
+				"/run/user/1000/pipewire-0-manager", "/run/pipewire/pipewire-0-manager",
+				"/run/user/1000/pipewire-0", "/run/pipewire/pipewire-0",
+			},
+		},
+		{
+			name: "no runtime dir",
+			want: []string{"/run/pipewire/pipewire-0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearRemoteEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if got := SocketCandidates(tt.remote); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialResolvesRuntimeDir(t *testing.T) {
+	clearRemoteEnv(t)
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: filepath.Join(dir, DefaultRemoteName), Net: "unix"})
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	conn, err := Dial("", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestDialReportsTriedPaths(t *testing.T) {
+	clearRemoteEnv(t)
+	dir := t.TempDir()
+	t.Setenv("PIPEWIRE_RUNTIME_DIR", dir)
+
+	_, err := Dial("pipewire-test-missing", nil)
+	if err == nil {
+		t.Fatal("expected error for missing socket")
+	}
+	if !IsConnectionError(err) {
+		t.Errorf("expected connection error, got %T", err)
+	}
+	for _, path := range SocketCandidates("pipewire-test-missing") {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("error does not mention %s: %v", path, err)
+		}
+	}
+}
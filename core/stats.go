@@ -0,0 +1,223 @@
+// Package core - Connection metrics
+// core/stats.go
+// Frame counters and latency histograms exposed through Stats snapshots
+
+package core
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the latency histograms
+var LatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// HistogramBucket counts the observations at or below UpperBound
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      uint64 // Cumulative, includes the lower buckets
+}
+
+// Histogram is a snapshot of observed durations
+// Observations above the last bucket are only in Count and Sum
+type Histogram struct {
+	Count   uint64
+	Sum     time.Duration
+	Max     time.Duration
+	Buckets []HistogramBucket
+}
+
+// Mean returns the average observation, 0 when empty
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// LatencyHistogram accumulates durations into LatencyBuckets
+// Safe for concurrent use
+type LatencyHistogram struct {
+	mu     sync.Mutex
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// NewLatencyHistogram creates an empty histogram
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{counts: make([]uint64, len(LatencyBuckets))}
+}
+
+// Observe adds one duration
+func (h *LatencyHistogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+	for i, bound := range LatencyBuckets {
+		if d <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+}
+
+// Snapshot returns the current state with cumulative buckets
+func (h *LatencyHistogram) Snapshot() Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snap := Histogram{
+		Count:   h.count,
+		Sum:     h.sum,
+		Max:     h.max,
+		Buckets: make([]HistogramBucket, len(LatencyBuckets)),
+	}
+	var total uint64
+	for i, bound := range LatencyBuckets {
+		total += h.counts[i]
+		snap.Buckets[i] = HistogramBucket{UpperBound: bound, Count: total}
+	}
+	return snap
+}
+
+// FrameKey identifies a kind of message in the frame counters
+type FrameKey struct {
+	Interface string // Type of the target proxy: "core", "client", "registry", "node"...
+	Opcode    uint32
+}
+
+// FrameCounters counts frames and their bytes, headers included
+type FrameCounters struct {
+	Frames uint64
+	Bytes  uint64
+}
+
+// ConnectionStats is a snapshot of the traffic of a connection
+type ConnectionStats struct {
+	In  FrameCounters // Received from the daemon
+	Out FrameCounters // Sent to the daemon
+
+	// Per target interface and opcode; ids without a proxy count as "unknown"
+	InByMessage  map[FrameKey]FrameCounters
+	OutByMessage map[FrameKey]FrameCounters
+
+	Roundtrip         Histogram // Latency of completed Roundtrip calls
+	PendingRoundtrips int       // Roundtrip calls waiting for Core.Done
+	Proxies           int       // Allocated proxy ids
+}
+
+// connStats accumulates the counters of a connection
+type connStats struct {
+	mu        sync.Mutex
+	in, out   FrameCounters
+	inByMsg   map[FrameKey]FrameCounters
+	outByMsg  map[FrameKey]FrameCounters
+	roundtrip *LatencyHistogram
+}
+
+// newConnStats creates empty counters
+func newConnStats() *connStats {
+	return &connStats{
+		inByMsg:   make(map[FrameKey]FrameCounters),
+		outByMsg:  make(map[FrameKey]FrameCounters),
+		roundtrip: NewLatencyHistogram(),
+	}
+}
+
+// count adds one frame of size bytes
+func (s *connStats) count(direction CaptureDirection, key FrameKey, size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total, byMsg := &s.in, s.inByMsg
+	if direction == CaptureSent {
+		total, byMsg = &s.out, s.outByMsg
+	}
+	total.Frames++
+	total.Bytes += uint64(size)
+	c := byMsg[key]
+	c.Frames++
+	c.Bytes += uint64(size)
+	byMsg[key] = c
+}
+
+// countFrame adds a frame addressed to objectID
+func (c *Connection) countFrame(direction CaptureDirection, objectID, opcode uint32, size int) {
+	c.stats.count(direction, FrameKey{Interface: c.objectInterface(objectID), Opcode: opcode}, size)
+}
+
+// countFrames adds every frame of data, as handed to the socket
+func (c *Connection) countFrames(direction CaptureDirection, data []byte) {
+	for len(data) >= HeaderSize {
+		objectID := binary.LittleEndian.Uint32(data[0:4])
+		word := binary.LittleEndian.Uint32(data[4:8])
+		size := HeaderSize + int(word&MaxPayloadSize)
+		if size > len(data) {
+			size = len(data)
+		}
+		c.countFrame(direction, objectID, word>>24, size)
+		data = data[size:]
+	}
+}
+
+// objectInterface names the type of the proxy using id
+func (c *Connection) objectInterface(id uint32) string {
+	switch id {
+	case CoreID:
+		return "core"
+	case ClientID:
+		return "client"
+	}
+	if p, ok := c.proxies.lookup(id); ok {
+		return p.Type()
+	}
+	return "unknown"
+}
+
+// Stats returns a snapshot of the connection counters
+func (c *Connection) Stats() ConnectionStats {
+	s := c.stats
+	s.mu.Lock()
+	stats := ConnectionStats{
+		In:           s.in,
+		Out:          s.out,
+		InByMessage:  make(map[FrameKey]FrameCounters, len(s.inByMsg)),
+		OutByMessage: make(map[FrameKey]FrameCounters, len(s.outByMsg)),
+	}
+	for k, v := range s.inByMsg {
+		stats.InByMessage[k] = v
+	}
+	for k, v := range s.outByMsg {
+		stats.OutByMessage[k] = v
+	}
+	s.mu.Unlock()
+
+	stats.Roundtrip = s.roundtrip.Snapshot()
+	stats.PendingRoundtrips = c.syncs.len()
+	stats.Proxies = c.proxies.len()
+	return stats
+}
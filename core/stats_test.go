@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	h := NewLatencyHistogram()
+	h.Observe(50 * time.Microsecond)
+	h.Observe(3 * time.Millisecond)
+	h.Observe(time.Minute)
+
+	snap := h.Snapshot()
+	if snap.Count != 3 || snap.Max != time.Minute {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+	if snap.Mean() != snap.Sum/3 {
+		t.Errorf("got mean %v", snap.Mean())
+	}
+	for _, b := range snap.Buckets {
+		want := uint64(0)
+		switch {
+		case b.UpperBound >= 5*time.Millisecond:
+			want = 2
+		case b.UpperBound >= 100*time.Microsecond:
+			want = 1
+		}
+		if b.Count != want {
+			t.Errorf("bucket %v: got %d, want %d", b.UpperBound, b.Count, want)
+		}
+	}
+}
+
+func TestConnectionStats(t *testing.T) {
+	client, daemon := readyPair(t)
+	before := client.Stats()
+
+	node := client.AllocateProxy("node")
+	sendEvent(t, daemon, CoreEventBoundID, NewArgsBuilder().Int(int32(node.ID())).Int(40))
+	event := NewMessageBuilder(node.ID(), uint32(NodeEventTypeInfo)).
+		WithPOD(NewArgsBuilder().Int(40).Build()).
+		Build()
+	if err := daemon.WriteMessage(event); err != nil {
+		t.Fatalf("daemon write failed: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		result <- client.Roundtrip(ctx)
+	}()
+	r := expectMethod(t, daemon, CoreID, CoreMethodSync)
+	r.Uint()
+	seq, _ := r.Uint()
+	sendEvent(t, daemon, CoreEventDone, NewArgsBuilder().Int(0).Int(int32(seq)))
+	if err := <-result; err != nil {
+		t.Fatalf("Roundtrip failed: %v", err)
+	}
+
+	stats := client.Stats()
+	if got := stats.In.Frames - before.In.Frames; got != 3 {
+		t.Errorf("got %d frames in, want 3", got)
+	}
+	if got := stats.Out.Frames - before.Out.Frames; got != 1 {
+		t.Errorf("got %d frames out, want 1", got)
+	}
+	if stats.Out.Bytes <= before.Out.Bytes {
+		t.Error("bytes out not counted")
+	}
+	if c := stats.InByMessage[FrameKey{Interface: "node", Opcode: uint32(NodeEventTypeInfo)}]; c.Frames != 1 || c.Bytes == 0 {
+		t.Errorf("node info not counted: %+v", c)
+	}
+	if c := stats.OutByMessage[FrameKey{Interface: "core", Opcode: uint32(CoreMethodSync)}]; c.Frames == 0 {
+		t.Error("core sync not counted")
+	}
+	if stats.Roundtrip.Count != before.Roundtrip.Count+1 || stats.PendingRoundtrips != 0 {
+		t.Errorf("unexpected roundtrip stats %+v pending=%d", stats.Roundtrip, stats.PendingRoundtrips)
+	}
+	if stats.Proxies != 2 {
+		t.Errorf("got %d proxies, want registry and node", stats.Proxies)
+	}
+}
@@ -0,0 +1,366 @@
+// Package core - Type map
+// core/typemap.go
+// Client and server type-id maps for legacy protocol peers (Core.UpdateTypes)
+//
+// Peers speaking protocol version 0 number SPA types dynamically and announce
+// the numbering with update_types(first_id, n_types, names...). The TypeMap
+// keeps the local numbering (the fixed SPA_TYPE_* ids of the current protocol),
+// the one announced by the server and the one this client announces, and
+// translates PODs between them
+
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// standardTypes are the type names known to every TypeMap (SPA_TYPE_INFO_*)
+var standardTypes = map[string]uint32{
+	"Spa:None":                            spa.PODTypeNone,
+	"Spa:Bool":                            spa.PODTypeBool,
+	"Spa:Id":                              spa.PODTypeID,
+	"Spa:Int":                             spa.PODTypeInt,
+	"Spa:Long":                            spa.PODTypeLong,
+	"Spa:Float":                           spa.PODTypeFloat,
+	"Spa:Double":                          spa.PODTypeDouble,
+	"Spa:String":                          spa.PODTypeString,
+	"Spa:Bytes":                           spa.PODTypeBytes,
+	"Spa:Rectangle":                       spa.PODTypeRectangle,
+	"Spa:Fraction":                        spa.PODTypeFraction,
+	"Spa:Bitmap":                          spa.PODTypeBitmap,
+	"Spa:Array":                           spa.PODTypeArray,
+	"Spa:Pod:Struct":                      spa.PODTypeStruct,
+	"Spa:Pod:Object":                      spa.PODTypeObject,
+	"Spa:Pod:Sequence":                    spa.PODTypeSequence,
+	"Spa:Pointer":                         spa.PODTypePointer,
+	"Spa:Fd":                              spa.PODTypeFd,
+	"Spa:Pod:Choice":                      spa.PODTypeChoice,
+	"Spa:Pod":                             spa.PODTypePod,
+	"Spa:Pod:Object:Param:PropInfo":       spa.TypeObjectPropInfo,
+	"Spa:Pod:Object:Param:Props":          spa.TypeObjectProps,
+	"Spa:Pod:Object:Param:Format":         spa.TypeObjectFormat,
+	"Spa:Pod:Object:Param:Buffers":        spa.TypeObjectParamBuffers,
+	"Spa:Pod:Object:Param:Meta":           spa.TypeObjectParamMeta,
+	"Spa:Pod:Object:Param:IO":             spa.TypeObjectParamIO,
+	"Spa:Pod:Object:Param:Profile":        spa.TypeObjectParamProfile,
+	"Spa:Pod:Object:Param:PortConfig":     spa.TypeObjectParamPortConfig,
+	"Spa:Pod:Object:Param:Route":          spa.TypeObjectParamRoute,
+	"Spa:Pod:Object:Profiler":             spa.TypeObjectProfiler,
+	"Spa:Pod:Object:Param:Latency":        spa.TypeObjectParamLatency,
+	"Spa:Pod:Object:Param:ProcessLatency": spa.TypeObjectParamProcessLatency,
+	"Spa:Pod:Object:Param:Tag":            spa.TypeObjectParamTag,
+}
+
+// TypeMap maps type names to ids for both sides of a connection
+// Safe for concurrent use
+type TypeMap struct {
+	mu        sync.RWMutex
+	ids       map[string]uint32 // Name -> local id
+	names     map[uint32]string // Local id -> name
+	remote    map[uint32]string // Server id -> name, from Core.UpdateTypes
+	client    []string          // Client id -> name, announced with Core.UpdateTypes
+	clientIDs map[string]uint32 // Name -> client id
+	announced int               // Client ids already announced
+}
+
+// NewTypeMap returns a map knowing the standard SPA types
+func NewTypeMap() *TypeMap {
+	m := &TypeMap{
+		ids:       make(map[string]uint32, len(standardTypes)),
+		names:     make(map[uint32]string, len(standardTypes)),
+		remote:    make(map[uint32]string),
+		clientIDs: make(map[string]uint32),
+	}
+	for name, id := range standardTypes {
+		m.ids[name] = id
+		m.names[id] = name
+	}
+	return m
+}
+
+// Register adds or replaces the local id of a type name
+func (m *TypeMap) Register(name string, id uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.ids[name]; ok {
+		delete(m.names, old)
+	}
+	m.ids[name] = id
+	m.names[id] = name
+}
+
+// Lookup returns the local id of a type name
+func (m *TypeMap) Lookup(name string) (uint32, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	id, ok := m.ids[name]
+	return id, ok
+}
+
+// Name returns the type name of a local id
+func (m *TypeMap) Name(id uint32) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name, ok := m.names[id]
+	return name, ok
+}
+
+// UpdateRemote records the server types firstID..firstID+len(names)-1
+// as announced by Core.UpdateTypes
+func (m *TypeMap) UpdateRemote(firstID uint32, names []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, name := range names {
+		m.remote[firstID+uint32(i)] = name
+	}
+}
+
+// RemoteName returns the type name the server gave to id
+func (m *TypeMap) RemoteName(id uint32) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name, ok := m.remote[id]
+	return name, ok
+}
+
+// HasRemote reports whether the server announced any type
+func (m *TypeMap) HasRemote() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.remote) > 0
+}
+
+// FromRemote translates a server type id to the local id
+// Ids the server did not announce, or with a name unknown locally, are returned unchanged
+func (m *TypeMap) FromRemote(id uint32) uint32 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fromRemoteLocked(id)
+}
+
+func (m *TypeMap) fromRemoteLocked(id uint32) uint32 {
+	if name, ok := m.remote[id]; ok {
+		if local, ok := m.ids[name]; ok {
+			return local
+		}
+	}
+	return id
+}
+
+// ClientID returns the id this client gives to a type name, allocating
+// the next one for a new name; version 0 servers store the client types in
+// an array, so the ids are dense from 0
+func (m *TypeMap) ClientID(name string) uint32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.clientIDLocked(name)
+}
+
+func (m *TypeMap) clientIDLocked(name string) uint32 {
+	if id, ok := m.clientIDs[name]; ok {
+		return id
+	}
+	id := uint32(len(m.client))
+	m.client = append(m.client, name)
+	m.clientIDs[name] = id
+	return id
+}
+
+// ToClient translates a local type id to the client id of its name
+// Ids without a local name are returned unchanged
+func (m *TypeMap) ToClient(id uint32) uint32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.toClientLocked(id)
+}
+
+func (m *TypeMap) toClientLocked(id uint32) uint32 {
+	if name, ok := m.names[id]; ok {
+		return m.clientIDLocked(name)
+	}
+	return id
+}
+
+// Unannounced returns the client ids allocated since the last call, in the
+// form Core.UpdateTypes announces them, and marks them announced
+func (m *TypeMap) Unannounced() (firstID uint32, names []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	firstID, names = uint32(m.announced), m.client[m.announced:]
+	m.announced = len(m.client)
+	return firstID, names
+}
+
+// TranslatePOD rewrites, in place, the server type ids of the PODs in
+// data to local ids: Id values, object types and ids, and property keys
+func (m *TypeMap) TranslatePOD(data []byte) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return translateChildren(data, m.fromRemoteLocked)
+}
+
+// TranslatePODToClient rewrites, in place, the local type ids of the PODs
+// in data to client ids, allocating the ids of names not used before
+func (m *TypeMap) TranslatePODToClient(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return translateChildren(data, m.toClientLocked)
+}
+
+// translatePOD rewrites the ids of one POD, header included, with mapID
+func translatePOD(pod []byte, mapID func(uint32) uint32) error {
+	if len(pod) < podHeaderSize {
+		return fmt.Errorf("POD too short: %d bytes", len(pod))
+	}
+	bodySize := int(binary.LittleEndian.Uint32(pod[0:4]))
+	podType := binary.LittleEndian.Uint32(pod[4:8])
+	if bodySize > len(pod)-podHeaderSize {
+		return fmt.Errorf("POD truncated: announces %d bytes, have %d", bodySize, len(pod)-podHeaderSize)
+	}
+	body := pod[podHeaderSize : podHeaderSize+bodySize]
+
+	switch podType {
+	case spa.PODTypeID:
+		if len(body) >= 4 {
+			translateID(body[0:4], mapID)
+		}
+
+	case spa.PODTypeStruct:
+		return translateChildren(body, mapID)
+
+	case spa.PODTypeObject:
+		// type, id, then (key, flags, value)*
+		if len(body) < 8 {
+			return fmt.Errorf("object too short: %d bytes", len(body))
+		}
+		translateID(body[0:4], mapID)
+		translateID(body[4:8], mapID)
+		for props := body[8:]; len(props) > 0; {
+			if len(props) < 8 {
+				return fmt.Errorf("object property truncated")
+			}
+			translateID(props[0:4], mapID)
+			size, err := podTotalSize(props[8:])
+			if err != nil {
+				return err
+			}
+			if err := translatePOD(props[8:8+size], mapID); err != nil {
+				return err
+			}
+			props = props[8+size:]
+		}
+
+	case spa.PODTypeArray:
+		return translateValues(body, mapID)
+
+	case spa.PODTypeChoice:
+		// choice type, flags, then an array body
+		if len(body) < 8 {
+			return fmt.Errorf("choice too short: %d bytes", len(body))
+		}
+		return translateValues(body[8:], mapID)
+
+	case spa.PODTypeSequence:
+		// unit, pad, then (offset, type, value)*
+		if len(body) < 8 {
+			return fmt.Errorf("sequence too short: %d bytes", len(body))
+		}
+		for controls := body[8:]; len(controls) > 0; {
+			if len(controls) < 8 {
+				return fmt.Errorf("sequence control truncated")
+			}
+			size, err := podTotalSize(controls[8:])
+			if err != nil {
+				return err
+			}
+			if err := translatePOD(controls[8:8+size], mapID); err != nil {
+				return err
+			}
+			controls = controls[8+size:]
+		}
+	}
+	return nil
+}
+
+// translateChildren rewrites consecutive PODs
+func translateChildren(data []byte, mapID func(uint32) uint32) error {
+	for len(data) > 0 {
+		size, err := podTotalSize(data)
+		if err != nil {
+			return err
+		}
+		if err := translatePOD(data[:size], mapID); err != nil {
+			return err
+		}
+		data = data[size:]
+	}
+	return nil
+}
+
+// translateValues rewrites the elements of an array body when they are Ids
+func translateValues(body []byte, mapID func(uint32) uint32) error {
+	if len(body) < podHeaderSize {
+		return fmt.Errorf("array too short: %d bytes", len(body))
+	}
+	childSize := int(binary.LittleEndian.Uint32(body[0:4]))
+	childType := binary.LittleEndian.Uint32(body[4:8])
+	if childType != spa.PODTypeID || childSize < 4 {
+		return nil
+	}
+	for values := body[podHeaderSize:]; len(values) >= childSize; values = values[childSize:] {
+		translateID(values[0:4], mapID)
+	}
+	return nil
+}
+
+// translateID rewrites a 4-byte id with mapID
+func translateID(b []byte, mapID func(uint32) uint32) {
+	id := binary.LittleEndian.Uint32(b)
+	binary.LittleEndian.PutUint32(b, mapID(id))
+}
+
+// NewUpdateTypesMessage creates Core.UpdateTypes(first_id, n_types, names...)
+// announcing that ids firstID.. name the given types
+func NewUpdateTypesMessage(firstID uint32, names []string) *MessageFrame {
+	args := NewArgsBuilder().Int(int32(firstID)).Int(int32(len(names)))
+	for _, name := range names {
+		args.String(name)
+	}
+	return NewMessageBuilder(CoreID, uint32(LegacyCoreMethodUpdateTypes)).WithPOD(args.Build()).Build()
+}
+
+// ParseUpdateTypes decodes a Core.UpdateTypes event
+func ParseUpdateTypes(msg *MessageFrame) (firstID uint32, names []string, err error) {
+	r, err := NewArgsReader(msg)
+	if err != nil {
+		return 0, nil, err
+	}
+	if firstID, err = r.Uint(); err != nil {
+		return 0, nil, fmt.Errorf("update_types first_id: %w", err)
+	}
+	n, err := r.Uint()
+	if err != nil {
+		return 0, nil, fmt.Errorf("update_types n_types: %w", err)
+	}
+	if int(n) > MaxMessageSize/podHeaderSize {
+		return 0, nil, fmt.Errorf("update_types announces %d types", n)
+	}
+	names = make([]string, 0, n)
+	for i := uint32(0); i < n; i++ {
+		name, err := r.String()
+		if err != nil {
+			return 0, nil, fmt.Errorf("update_types type %d: %w", i, err)
+		}
+		names = append(names, name)
+	}
+	return firstID, names, nil
+}
+
+// TypeMap returns the type ids known for both sides of the connection
+func (c *Connection) TypeMap() *TypeMap {
+	return c.types
+}
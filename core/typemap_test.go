@@ -0,0 +1,67 @@
+package core
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// objectPOD encodes Object{type, id, key: Id(value)}
+func objectPOD(objType, objID, key, value uint32) []byte {
+	pod := make([]byte, 40)
+	binary.LittleEndian.PutUint32(pod[0:4], 32)
+	binary.LittleEndian.PutUint32(pod[4:8], spa.PODTypeObject)
+	binary.LittleEndian.PutUint32(pod[8:12], objType)
+	binary.LittleEndian.PutUint32(pod[12:16], objID)
+	binary.LittleEndian.PutUint32(pod[16:20], key)
+	binary.LittleEndian.PutUint32(pod[24:28], 4)
+	binary.LittleEndian.PutUint32(pod[28:32], spa.PODTypeID)
+	binary.LittleEndian.PutUint32(pod[32:36], value)
+	return pod
+}
+
+func TestTypeMapTranslatePOD(t *testing.T) {
+	types := NewTypeMap()
+	types.Register("Spa:Enum:ParamId:EnumFormat", 3)
+	types.UpdateRemote(100, []string{"Spa:Pod:Object:Param:Format", "Spa:Enum:ParamId:EnumFormat"})
+
+	if id, ok := types.Lookup("Spa:Pod:Object:Param:Format"); !ok || id != 0x40003 {
+		t.Fatalf("Lookup: got %#x %v", id, ok)
+	}
+	if name, ok := types.RemoteName(101); !ok || name != "Spa:Enum:ParamId:EnumFormat" {
+		t.Fatalf("RemoteName: got %q %v", name, ok)
+	}
+
+	pod := objectPOD(100, 101, 102, 101)
+	if err := types.TranslatePOD(pod); err != nil {
+		t.Fatalf("TranslatePOD failed: %v", err)
+	}
+	want := objectPOD(0x40003, 3, 102, 3)
+	if string(pod) != string(want) {
+		t.Errorf("translation mismatch:\n got %x\nwant %x", pod, want)
+	}
+
+	if err := types.TranslatePOD(pod[:20]); err == nil {
+		t.Error("expected error for a truncated POD")
+	}
+
+	// Client ids are dense from 0, in the order names are first used
+	if id := types.ClientID("PipeWire:Interface:Node"); id != 0 {
+		t.Errorf("ClientID: got %d, want 0", id)
+	}
+	if err := types.TranslatePODToClient(pod); err != nil {
+		t.Fatalf("TranslatePODToClient failed: %v", err)
+	}
+	if want := objectPOD(1, 2, 102, 2); string(pod) != string(want) {
+		t.Errorf("client translation mismatch:\n got %x\nwant %x", pod, want)
+	}
+	first, names := types.Unannounced()
+	if first != 0 || len(names) != 3 || names[1] != "Spa:Pod:Object:Param:Format" || names[2] != "Spa:Enum:ParamId:EnumFormat" {
+		t.Errorf("Unannounced: got %d %v", first, names)
+	}
+	types.ClientID("Spa:Pod:Object:Param:Props")
+	if first, names := types.Unannounced(); first != 3 || len(names) != 1 {
+		t.Errorf("Unannounced after a new name: got %d %v", first, names)
+	}
+}
@@ -184,41 +184,6 @@ type MemoryInfo struct {
 	Offset    uint32
 }
 
-// ProfileInfo contains information about audio profiles
-type ProfileInfo struct {
-	Index  uint32
-	ID     string
-	Name   string
-	Info   string
-	Format *AudioFormat
-}
-
-// RouteInfo contains information about audio routes
-type RouteInfo struct {
-	Index   uint32
-	ID      string
-	Name    string
-	Info    string
-	Profile string
-}
-
-// EnumFormat represents enumeration of supported formats
-type EnumFormat struct {
-	Index     uint32
-	SampleRate uint32
-	Channels  uint32
-	Format    string
-}
-
-// ProcessLatency represents processing latency information
-type ProcessLatency struct {
-	Version    uint32
-	Direction  ParamDirection
-	MinQuantum uint32
-	MaxQuantum uint32
-	NumSamples uint32
-}
-
 // IOConf represents input/output configuration
 type IOConf struct {
 	Version    uint32
@@ -4,9 +4,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/vignemail1/pipewire-go/client"
 	"github.com/vignemail1/pipewire-go/verbose"
@@ -34,9 +36,11 @@ func main() {
 	}
 	defer c.Close()
 
-	// Ping to verify connection
-	if err := c.Ping(); err != nil {
-		log.Fatalf("Failed to ping daemon: %v", err)
+	// Wait for the daemon to answer, with the initial registry burst
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Roundtrip(ctx); err != nil {
+		log.Fatalf("Daemon not answering: %v", err)
 	}
 
 	// List devices
@@ -17,6 +17,7 @@ import (
 	"os"
 
 	"github.com/vignemail1/pipewire-go/client"
+	"github.com/vignemail1/pipewire-go/spa/param"
 	"github.com/vignemail1/pipewire-go/verbose"
 )
 
@@ -88,10 +89,8 @@ func main() {
 
 		// Try to query node parameters
 		if params, err := node.GetParams(client.ParamIDFormat); err == nil {
-			if formatInfo, ok := params.(map[string]interface{}); ok {
-				if rate, hasRate := formatInfo["rate"]; hasRate {
-					fmt.Printf("    Format Rate: %v Hz\n", rate)
-				}
+			if format, ok := params.(*param.Format); ok && format.Rate > 0 {
+				fmt.Printf("    Format Rate: %v Hz\n", format.Rate)
 			}
 		}
 
@@ -0,0 +1,225 @@
+// Package spa - POD pretty-printing
+// spa/debug.go
+// Renders PODs as spa_debug_pod does, one value per line, naming object
+// types, param ids, property keys and Id values by their C names
+
+package spa
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DumpPOD writes pod to w in the layout of spa_debug_pod:
+//
+//	Object: size 32, type SPA_TYPE_OBJECT_Props (262146), id SPA_PARAM_Props (2)
+//	  Prop: key SPA_PROP_volume (65539), flags 00000000
+//	    Float 0.500000
+func DumpPOD(w io.Writer, pod PODValue) error {
+	d := &podDumper{w: w}
+	d.value(0, pod, nil)
+	return d.err
+}
+
+// FormatPOD returns pod rendered by DumpPOD
+func FormatPOD(pod PODValue) string {
+	var b strings.Builder
+	DumpPOD(&b, pod)
+	return b.String()
+}
+
+// DumpPODBytes is DumpPOD for an encoded POD, dumping the bytes in hex when
+// they do not decode
+func DumpPODBytes(w io.Writer, data []byte) error {
+	pod, err := NewPODParser(data).ParseValue()
+	if err != nil {
+		if _, werr := fmt.Fprintf(w, "Invalid POD (%v):\n%s", err, hex.Dump(data)); werr != nil {
+			return werr
+		}
+		return err
+	}
+	return DumpPOD(w, pod)
+}
+
+// podDumper writes indented lines, keeping the first write error
+type podDumper struct {
+	w   io.Writer
+	err error
+}
+
+func (d *podDumper) line(indent int, format string, args ...any) {
+	if d.err != nil {
+		return
+	}
+	_, d.err = fmt.Fprintf(d.w, "%*s"+format+"\n", append([]any{indent, ""}, args...)...)
+}
+
+// value dumps one value; names names Id values, as given by the property
+// the value belongs to
+func (d *podDumper) value(indent int, pod PODValue, names *idTable) {
+	switch v := pod.(type) {
+	case nil:
+		d.line(indent, "<nil>")
+	case *PODNone:
+		d.line(indent, "None")
+	case *PODBool:
+		d.line(indent, "Bool %t", v.Value)
+	case *PODId:
+		d.line(indent, "Id %-8d (%s)", v.Value, idNameOr(names, v.Value))
+	case *PODInt32:
+		d.line(indent, "Int %d", v.Value)
+	case *PODUint32:
+		d.line(indent, "Int %d", int32(v.Value))
+	case *PODInt64:
+		d.line(indent, "Long %d", v.Value)
+	case *PODUint64:
+		d.line(indent, "Long %d", int64(v.Value))
+	case *PODFloat:
+		d.line(indent, "Float %f", v.Value)
+	case *PODDouble:
+		d.line(indent, "Double %f", v.Value)
+	case *PODString:
+		d.line(indent, "String %q", v.Value)
+	case *PODBytes:
+		d.line(indent, "Bytes")
+		d.hex(indent+2, v.Value)
+	case *PODRectangle:
+		d.line(indent, "Rectangle %dx%d", v.Width, v.Height)
+	case *PODFraction:
+		d.line(indent, "Fraction %d/%d", v.Num, v.Den)
+	case *PODPointer:
+		d.line(indent, "Pointer %d 0x%x", v.Type, v.Value)
+	case *PODFd:
+		d.line(indent, "Fd %d", v.Value)
+	case *PODArray:
+		d.line(indent, "Array: child.size %d, child.type %s", childSize(v.ChildType, v.Values),
+			typeTitle(childType(v.ChildType, v.Values)))
+		for _, elem := range v.Values {
+			d.value(indent+2, elem, names)
+		}
+	case *PODChoice:
+		d.line(indent, "Choice: type %s, flags %08x %d %d", idNameOr(choiceTable, uint32(v.Type)), v.Flags,
+			childSize(v.ChildType, v.Values), childType(v.ChildType, v.Values))
+		for _, alt := range v.Values {
+			d.value(indent+2, alt, names)
+		}
+	case *PODStruct:
+		d.line(indent, "Struct: size %d", bodySize(v))
+		for _, field := range v.Fields {
+			d.value(indent+2, field, nil)
+		}
+	case *PODObject:
+		d.line(indent, "Object: size %d, type %s (%d), id %s (%d)", bodySize(v),
+			idNameOr(objectTypeTable, v.Type), v.Type, idNameOr(paramTable, v.ID), v.ID)
+		keys := objectKeyTables[v.Type]
+		for _, prop := range v.Props {
+			d.line(indent+2, "Prop: key %s (%d), flags %08x", idNameOr(keys, prop.Key), prop.Key, prop.Flags)
+			d.value(indent+4, prop.Value, valueTable(v.Type, prop.Key))
+		}
+	case *PODSequence:
+		d.line(indent, "Sequence: size %d, unit %d", bodySize(v), v.Unit)
+		for _, control := range v.Controls {
+			d.line(indent+2, "Control: offset %d, type %s", control.Offset, controlTypeName(control.Type))
+			d.value(indent+4, control.Value, nil)
+		}
+	case *PODRaw:
+		d.line(indent, "%s", typeTitle(podTypeID(v)))
+		d.hex(indent+2, v.Data)
+	default:
+		d.line(indent, "%s", pod)
+	}
+}
+
+// hex dumps bytes as hex.Dump does, indented
+func (d *podDumper) hex(indent int, data []byte) {
+	for _, l := range strings.Split(strings.TrimSuffix(hex.Dump(data), "\n"), "\n") {
+		if l != "" {
+			d.line(indent, "%s", l)
+		}
+	}
+}
+
+// idNameOr returns the C name of id in names, or "unknown"
+func idNameOr(names *idTable, id uint32) string {
+	if name := names.name(id); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// typeTitle returns the name of a POD type as spa_debug_pod prints it,
+// such as "Int"
+func typeTitle(podType uint32) string {
+	name, ok := podTypeNames[podType]
+	if !ok {
+		return fmt.Sprintf("Unknown(%d)", podType)
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// controlTypeName names a sequence control type (ControlType*)
+func controlTypeName(t uint32) string {
+	switch t {
+	case ControlTypeProperties:
+		return "Properties"
+	case ControlTypeMidi:
+		return "Midi"
+	case ControlTypeOSC:
+		return "OSC"
+	case ControlTypeUMP:
+		return "UMP"
+	}
+	return fmt.Sprintf("Invalid(%d)", t)
+}
+
+// childType returns the element type of an array or choice
+func childType(declared uint32, values []PODValue) uint32 {
+	if len(values) > 0 {
+		return podTypeID(values[0])
+	}
+	return declared
+}
+
+// podTypeID returns the wire type of a value, read from the encoding of a
+// PODRaw
+func podTypeID(v PODValue) uint32 {
+	if raw, ok := v.(*PODRaw); ok {
+		if len(raw.Data) < podHeaderSize {
+			return PODTypeInvalid
+		}
+		return binary.LittleEndian.Uint32(raw.Data[4:8])
+	}
+	return v.PODType().ID()
+}
+
+// childSize returns the element size of an array or choice
+func childSize(declared uint32, values []PODValue) int {
+	if len(values) > 0 {
+		if size, err := bodySizeErr(values[0]); err == nil {
+			return size
+		}
+	}
+	size, _ := PODTypeSize(declared)
+	return max(size, 0)
+}
+
+// bodySize returns the body size of an encoded value, 0 when it does not
+// encode
+func bodySize(v PODValue) int {
+	size, _ := bodySizeErr(v)
+	return size
+}
+
+func bodySizeErr(v PODValue) (int, error) {
+	data, err := v.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < podHeaderSize {
+		return 0, fmt.Errorf("POD too short")
+	}
+	return int(binary.LittleEndian.Uint32(data[0:4])), nil
+}
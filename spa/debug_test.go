@@ -0,0 +1,87 @@
+// Package spa - Tests for POD pretty-printing
+// spa/debug_test.go
+
+package spa
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDumpPOD tests the spa_debug_pod layout and the symbolic names of a
+// format
+func TestDumpPOD(t *testing.T) {
+	var b strings.Builder
+	if err := DumpPODBytes(&b, enumFormatPOD(t)); err != nil {
+		t.Fatalf("DumpPODBytes failed: %v", err)
+	}
+	want := `Object: size 240, type SPA_TYPE_OBJECT_Format (262147), id SPA_PARAM_EnumFormat (3)
+  Prop: key SPA_FORMAT_mediaType (1), flags 00000000
+    Id 1        (SPA_MEDIA_TYPE_audio)
+  Prop: key SPA_FORMAT_mediaSubtype (2), flags 00000000
+    Id 1        (SPA_MEDIA_SUBTYPE_raw)
+  Prop: key SPA_FORMAT_AUDIO_format (65537), flags 00000000
+    Choice: type SPA_CHOICE_Enum, flags 00000000 4 3
+      Id 283      (SPA_AUDIO_FORMAT_F32_LE)
+      Id 283      (SPA_AUDIO_FORMAT_F32_LE)
+      Id 259      (SPA_AUDIO_FORMAT_S16_LE)
+  Prop: key SPA_FORMAT_AUDIO_rate (65539), flags 00000000
+    Choice: type SPA_CHOICE_Range, flags 00000000 4 4
+      Int 48000
+      Int 1
+      Int 384000
+  Prop: key SPA_FORMAT_AUDIO_channels (65540), flags 00000000
+    Int 2
+  Prop: key SPA_FORMAT_AUDIO_position (65541), flags 00000000
+    Array: child.size 4, child.type Id
+      Id 3        (SPA_AUDIO_CHANNEL_FL)
+      Id 4        (SPA_AUDIO_CHANNEL_FR)
+  Prop: key SPA_FORMAT_AUDIO_iec958Codec (65542), flags 00000001
+    String "alsa_output"
+`
+	if got := b.String(); got != want {
+		t.Errorf("dump is\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestFormatPODValues tests the lines of the other value types
+func TestFormatPODValues(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  PODValue
+		want string
+	}{
+		{"none", NewPODNone(), "None\n"},
+		{"long", NewPODInt64(-5), "Long -5\n"},
+		{"double", NewPODDouble(0.25), "Double 0.250000\n"},
+		{"unnamed id", NewPODId(7), "Id 7        (unknown)\n"},
+		{"rectangle", NewPODRectangle(1920, 1080), "Rectangle 1920x1080\n"},
+		{"fraction", NewPODFraction(30, 1), "Fraction 30/1\n"},
+		{"fd", NewPODFd(3), "Fd 3\n"},
+		{"pointer", NewPODPointer(1, 0xdead), "Pointer 1 0xdead\n"},
+		{"bytes", NewPODBytes([]byte("ab")), "Bytes\n  00000000  61 62                                             |ab|\n"},
+		{"struct", NewPODStruct(NewPODInt32(1), NewPODString("a")),
+			"Struct: size 32\n  Int 1\n  String \"a\"\n"},
+		{"sequence", NewPODSequenceBuilder().AddControl(10, ControlTypeProperties, NewPODBool(true)).Build(),
+			"Sequence: size 32, unit 0\n  Control: offset 10, type Properties\n    Bool true\n"},
+		{"unknown object", NewPODObject(1, 99), "Object: size 8, type unknown (1), id unknown (99)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatPOD(tt.pod); got != tt.want {
+				t.Errorf("FormatPOD() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDumpPODBytesInvalid tests the hex fallback for undecodable data
+func TestDumpPODBytesInvalid(t *testing.T) {
+	var b strings.Builder
+	if err := DumpPODBytes(&b, []byte{1, 2, 3}); err == nil {
+		t.Error("DumpPODBytes accepted a truncated POD")
+	}
+	if !strings.Contains(b.String(), "01 02 03") {
+		t.Errorf("dump %q lacks the hex bytes", b.String())
+	}
+}
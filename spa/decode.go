@@ -0,0 +1,282 @@
+// Package spa - Wire POD decoding
+// spa/decode.go
+// Walks PODs framed as on the wire: [size (4B)] [type (4B)] [body] [padding to 8]
+
+package spa
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// podHeaderSize is the size of a POD header (size + type)
+const podHeaderSize = 8
+
+// readPODHeader splits the POD at the start of data into its type and body
+// and returns the padded size it occupies; the last POD of a buffer may
+// omit its padding
+func readPODHeader(data []byte) (uint32, []byte, int, error) {
+	if len(data) < podHeaderSize {
+		return 0, nil, 0, fmt.Errorf("POD too short: %d bytes", len(data))
+	}
+	size := binary.LittleEndian.Uint32(data[0:4])
+	podType := binary.LittleEndian.Uint32(data[4:8])
+	if uint64(size) > uint64(len(data)-podHeaderSize) {
+		return 0, nil, 0, fmt.Errorf("POD truncated: announces %d bytes, have %d",
+			size, len(data)-podHeaderSize)
+	}
+	total := podHeaderSize + AlignOffset(int(size))
+	if total > len(data) {
+		total = len(data)
+	}
+	return podType, data[podHeaderSize : podHeaderSize+int(size)], total, nil
+}
+
+// rawPOD frames a body as a PODRaw holding the complete POD
+func rawPOD(podType uint32, body []byte) *PODRaw {
+	return &PODRaw{Data: framePOD(podType, body)}
+}
+
+// childFunc receives a child of a container with the bytes preceding its
+// POD: key and flags of a property, offset and type of a control
+type childFunc func(prefix []byte, childType uint32, child []byte) error
+
+// next reads the POD at the parser offset and advances past it
+func (p *PODParser) next(build bool) (PODValue, error) {
+	podType, body, total, err := readPODHeader(p.data[p.offset:])
+	if err != nil {
+		return nil, err
+	}
+	val, err := p.decode(podType, body, 0, build)
+	if err != nil {
+		return nil, err
+	}
+	p.offset += total
+	return val, nil
+}
+
+// decode checks one POD body against the parser limits and, when build is
+// set, converts it to a PODValue
+// Types without a value in this package are checked and kept as PODRaw
+func (p *PODParser) decode(podType uint32, body []byte, depth int, build bool) (PODValue, error) {
+	p.values++
+	if err := checkLimit("values", p.values, p.limits.MaxValues); err != nil {
+		return nil, err
+	}
+	if err := checkLimit("depth", depth, p.limits.MaxDepth); err != nil {
+		return nil, err
+	}
+
+	switch podType {
+	case PODTypeString, PODTypeBytes:
+		if err := checkLimit("string length", len(body), p.limits.MaxStringLength); err != nil {
+			return nil, err
+		}
+	case PODTypeArray, PODTypeStruct, PODTypeObject, PODTypeSequence, PODTypeChoice:
+		return p.decodeContainer(podType, body, depth, build)
+	}
+
+	if !build {
+		return nil, checkScalar(podType, body)
+	}
+	return decodeScalar(podType, body)
+}
+
+// decodeContainer walks the children of a container POD
+func (p *PODParser) decodeContainer(podType uint32, body []byte, depth int, build bool) (PODValue, error) {
+	var values []PODValue
+	var props []PODProp
+	var controls []PODControl
+	n := 0
+	err := eachChild(podType, body, func(prefix []byte, childType uint32, child []byte) error {
+		n++
+		if err := checkLimit("elements", n, p.limits.MaxElements); err != nil {
+			return err
+		}
+		val, err := p.decode(childType, child, depth+1, build)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !build:
+		case podType == PODTypeObject:
+			props = append(props, PODProp{
+				Key:   binary.LittleEndian.Uint32(prefix[0:4]),
+				Flags: binary.LittleEndian.Uint32(prefix[4:8]),
+				Value: val,
+			})
+		case podType == PODTypeSequence:
+			controls = append(controls, PODControl{
+				Offset: binary.LittleEndian.Uint32(prefix[0:4]),
+				Type:   binary.LittleEndian.Uint32(prefix[4:8]),
+				Value:  val,
+			})
+		default:
+			values = append(values, val)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !build {
+		return nil, nil
+	}
+	if values == nil {
+		values = make([]PODValue, 0)
+	}
+	switch podType {
+	case PODTypeArray:
+		return &PODArray{ChildType: binary.LittleEndian.Uint32(body[4:8]), Values: values}, nil
+	case PODTypeStruct:
+		return &PODStruct{Fields: values}, nil
+	case PODTypeChoice:
+		return &PODChoice{
+			Type:      ChoiceType(binary.LittleEndian.Uint32(body[0:4])),
+			Flags:     binary.LittleEndian.Uint32(body[4:8]),
+			ChildType: binary.LittleEndian.Uint32(body[12:16]),
+			Values:    values,
+		}, nil
+	case PODTypeObject:
+		obj := NewPODObject(binary.LittleEndian.Uint32(body[0:4]), binary.LittleEndian.Uint32(body[4:8]))
+		obj.Props = append(obj.Props, props...)
+		return obj, nil
+	default:
+		seq := NewPODSequence()
+		seq.Unit = binary.LittleEndian.Uint32(body[0:4])
+		seq.Controls = append(seq.Controls, controls...)
+		return seq, nil
+	}
+}
+
+// eachChild calls fn with the type and body of every child of a container
+func eachChild(podType uint32, body []byte, fn childFunc) error {
+	switch podType {
+	case PODTypeArray:
+		return eachArrayElement(body, fn)
+
+	case PODTypeChoice:
+		// choice type (4B), flags (4B), then an array body
+		if len(body) < 8 {
+			return fmt.Errorf("choice too short: %d bytes", len(body))
+		}
+		return eachArrayElement(body[8:], fn)
+
+	case PODTypeStruct:
+		return eachPOD(body, 0, fn)
+
+	case PODTypeObject:
+		// object type (4B), id (4B), then key (4B), flags (4B), POD per property
+		if len(body) < 8 {
+			return fmt.Errorf("object too short: %d bytes", len(body))
+		}
+		return eachPOD(body[8:], 8, fn)
+
+	case PODTypeSequence:
+		// unit (4B), pad (4B), then offset (4B), type (4B), POD per control
+		if len(body) < 8 {
+			return fmt.Errorf("sequence too short: %d bytes", len(body))
+		}
+		return eachPOD(body[8:], 8, fn)
+	}
+	return fmt.Errorf("POD type %d is not a container", podType)
+}
+
+// eachArrayElement walks an array body: a child header then packed bodies
+func eachArrayElement(body []byte, fn childFunc) error {
+	if len(body) < podHeaderSize {
+		return fmt.Errorf("array too short: %d bytes", len(body))
+	}
+	childSize := int(binary.LittleEndian.Uint32(body[0:4]))
+	childType := binary.LittleEndian.Uint32(body[4:8])
+	elems := body[podHeaderSize:]
+	if childSize == 0 {
+		if len(elems) > 0 {
+			return fmt.Errorf("array of empty children has %d bytes", len(elems))
+		}
+		return nil
+	}
+	for off := 0; off+childSize <= len(elems); off += childSize {
+		if err := fn(nil, childType, elems[off:off+childSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachPOD walks complete PODs, each preceded by prefix bytes
+func eachPOD(data []byte, prefix int, fn childFunc) error {
+	for len(data) > 0 {
+		if len(data) < prefix {
+			return fmt.Errorf("child truncated: %d bytes", len(data))
+		}
+		childType, child, total, err := readPODHeader(data[prefix:])
+		if err != nil {
+			return err
+		}
+		if err := fn(data[:prefix], childType, child); err != nil {
+			return err
+		}
+		data = data[prefix+total:]
+	}
+	return nil
+}
+
+// checkScalar checks the body of a non-container POD
+func checkScalar(podType uint32, body []byte) error {
+	if size, err := PODTypeSize(podType); err == nil && len(body) < size {
+		return fmt.Errorf("POD type %d too short: %d bytes", podType, len(body))
+	}
+	if podType == PODTypeString {
+		for _, c := range body {
+			if c == 0 {
+				return nil
+			}
+		}
+		return fmt.Errorf("string is not NUL-terminated")
+	}
+	return nil
+}
+
+// decodeScalar converts the body of a non-container POD
+func decodeScalar(podType uint32, body []byte) (PODValue, error) {
+	if err := checkScalar(podType, body); err != nil {
+		return nil, err
+	}
+
+	switch podType {
+	case PODTypeNone:
+		return NewPODNone(), nil
+	case PODTypeBool:
+		return NewPODBool(binary.LittleEndian.Uint32(body) != 0), nil
+	case PODTypeID:
+		return NewPODId(binary.LittleEndian.Uint32(body)), nil
+	case PODTypeInt:
+		return NewPODInt32(int32(binary.LittleEndian.Uint32(body))), nil
+	case PODTypeLong:
+		return NewPODInt64(int64(binary.LittleEndian.Uint64(body))), nil
+	case PODTypeFloat:
+		return NewPODFloat(math.Float32frombits(binary.LittleEndian.Uint32(body))), nil
+	case PODTypeDouble:
+		return NewPODDouble(math.Float64frombits(binary.LittleEndian.Uint64(body))), nil
+	case PODTypeString:
+		for i, c := range body {
+			if c == 0 {
+				return NewPODString(string(body[:i])), nil
+			}
+		}
+	case PODTypeBytes:
+		return NewPODBytes(body), nil
+	case PODTypeRectangle:
+		return NewPODRectangle(binary.LittleEndian.Uint32(body[0:4]), binary.LittleEndian.Uint32(body[4:8])), nil
+	case PODTypeFraction:
+		return NewPODFraction(binary.LittleEndian.Uint32(body[0:4]), binary.LittleEndian.Uint32(body[4:8])), nil
+	case PODTypePointer:
+		return NewPODPointer(binary.LittleEndian.Uint32(body[0:4]), binary.LittleEndian.Uint64(body[8:16])), nil
+	case PODTypeFd:
+		return NewPODFd(int64(binary.LittleEndian.Uint64(body))), nil
+	}
+	return rawPOD(podType, body), nil
+}
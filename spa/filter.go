@@ -0,0 +1,570 @@
+// Package spa - POD filtering and fixation
+// spa/filter.go
+// Intersects PODs as spa_pod_filter does during format negotiation, and
+// fixates the result to concrete values as spa_pod_fixate does
+
+package spa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrNoIntersection is matched by errors.Is for every FilterError
+var ErrNoIntersection = errors.New("PODs have no common value")
+
+// FilterError reports why two PODs do not intersect, naming the property
+// of the object where they first differ
+type FilterError struct {
+	ObjectType uint32 // Object type of the property, 0 outside objects
+	Key        uint32 // Property key, 0 outside objects
+	Reason     string
+}
+
+// Ensure FilterError implements error interface
+var _ error = (*FilterError)(nil)
+
+// Error implements the error interface
+func (e *FilterError) Error() string {
+	if e.ObjectType == 0 {
+		return fmt.Sprintf("filter: %s", e.Reason)
+	}
+	name := ObjectKeyName(e.ObjectType, e.Key)
+	if name == "" {
+		name = fmt.Sprintf("key %#x", e.Key)
+	}
+	return fmt.Sprintf("filter: property %s: %s", name, e.Reason)
+}
+
+// Is reports whether target is ErrNoIntersection
+func (e *FilterError) Is(target error) bool {
+	return target == ErrNoIntersection
+}
+
+// Filter intersects pod with filter as spa_pod_filter does
+//   - Objects must have the same type; properties present in both are
+//     intersected, the others are kept, unless a missing one is mandatory
+//   - Structs are intersected field by field
+//   - Values are intersected as choices, a plain value being a None choice:
+//     enums keep the common values, ranges and steps narrow to their
+//     overlap, flags keep the common bits
+//
+// A single remaining value is returned as that plain value; defaults stay
+// those of pod when they remain possible
+// A nil filter returns pod; the result may share values with its inputs
+func Filter(pod, filter PODValue) (PODValue, error) {
+	if pod == nil {
+		return nil, &FilterError{Reason: "nil POD"}
+	}
+	if filter == nil {
+		return pod, nil
+	}
+	return filterValue(pod, filter)
+}
+
+// FilterBytes is Filter on encoded PODs, returning the encoded result
+func FilterBytes(pod, filter []byte) ([]byte, error) {
+	a, err := NewPODParser(pod).ParseValue()
+	if err != nil {
+		return nil, err
+	}
+	var b PODValue
+	if filter != nil {
+		if b, err = NewPODParser(filter).ParseValue(); err != nil {
+			return nil, err
+		}
+	}
+	result, err := Filter(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return result.Marshal()
+}
+
+// Fixate replaces the choices of an object's properties by their defaults,
+// as spa_pod_fixate does, except for properties flagged PropFlagDontFixate
+// Other PODs are returned unchanged, a choice alone becomes its default
+func Fixate(pod PODValue) (PODValue, error) {
+	switch v := pod.(type) {
+	case *PODChoice:
+		if v.Default() == nil {
+			return nil, fmt.Errorf("cannot fixate an empty choice")
+		}
+		return v.Default(), nil
+	case *PODObject:
+		obj := &PODObject{Type: v.Type, ID: v.ID, Props: make([]PODProp, len(v.Props))}
+		for i, prop := range v.Props {
+			obj.Props[i] = prop
+			choice, ok := prop.Value.(*PODChoice)
+			if !ok || prop.Flags&PropFlagDontFixate != 0 {
+				continue
+			}
+			if choice.Default() == nil {
+				return nil, &FilterError{ObjectType: v.Type, Key: prop.Key, Reason: "empty choice"}
+			}
+			obj.Props[i].Value = choice.Default()
+		}
+		return obj, nil
+	}
+	return pod, nil
+}
+
+// filterValue intersects two values
+func filterValue(a, b PODValue) (PODValue, error) {
+	switch av := a.(type) {
+	case *PODObject:
+		bv, ok := b.(*PODObject)
+		if !ok {
+			return nil, &FilterError{Reason: fmt.Sprintf("object against %s", b.PODType().Name())}
+		}
+		return filterObject(av, bv)
+	case *PODStruct:
+		bv, ok := b.(*PODStruct)
+		if !ok || len(av.Fields) != len(bv.Fields) {
+			return nil, &FilterError{Reason: fmt.Sprintf("%s against %s", av, b)}
+		}
+		result := &PODStruct{Fields: make([]PODValue, len(av.Fields))}
+		for i := range av.Fields {
+			field, err := filterValue(av.Fields[i], bv.Fields[i])
+			if err != nil {
+				return nil, err
+			}
+			result.Fields[i] = field
+		}
+		return result, nil
+	}
+	return filterChoice(a, b)
+}
+
+// filterObject intersects two objects property by property
+func filterObject(a, b *PODObject) (*PODObject, error) {
+	if a.Type != b.Type {
+		return nil, &FilterError{Reason: fmt.Sprintf("object type %#x against %#x", a.Type, b.Type)}
+	}
+	result := &PODObject{Type: a.Type, ID: a.ID, Props: make([]PODProp, 0, len(a.Props))}
+	for _, pa := range a.Props {
+		pb, ok := b.Prop(pa.Key)
+		if !ok {
+			if pa.Flags&PropFlagMandatory != 0 {
+				return nil, &FilterError{ObjectType: a.Type, Key: pa.Key, Reason: "mandatory and missing from the filter"}
+			}
+			result.Props = append(result.Props, pa)
+			continue
+		}
+		val, err := filterValue(pa.Value, pb.Value)
+		if err != nil {
+			var fe *FilterError
+			if errors.As(err, &fe) && fe.ObjectType == 0 {
+				fe.ObjectType, fe.Key = a.Type, pa.Key
+			}
+			return nil, err
+		}
+		result.Props = append(result.Props, PODProp{Key: pa.Key, Flags: pa.Flags | pb.Flags, Value: val})
+	}
+	for _, pb := range b.Props {
+		if _, ok := a.Prop(pb.Key); ok {
+			continue
+		}
+		if pb.Flags&PropFlagMandatory != 0 {
+			return nil, &FilterError{ObjectType: a.Type, Key: pb.Key, Reason: "mandatory and missing from the POD"}
+		}
+		result.Props = append(result.Props, pb)
+	}
+	return result, nil
+}
+
+// ============================================================================
+// CHOICES
+// ============================================================================
+
+// choiceOf returns a value as a choice, a plain value as a None choice
+func choiceOf(v PODValue) *PODChoice {
+	if c, ok := v.(*PODChoice); ok {
+		return c
+	}
+	return &PODChoice{Type: ChoiceTypeNone, Values: []PODValue{v}}
+}
+
+// alternatives returns the values a choice allows besides its default:
+// the value of None, the bounds (and step) of Range and Step, the allowed
+// values of Enum and the mask of Flags
+func alternatives(c *PODChoice) []PODValue {
+	switch c.Type {
+	case ChoiceTypeRange, ChoiceTypeStep, ChoiceTypeEnum:
+		if len(c.Values) > 1 {
+			return c.Values[1:]
+		}
+	}
+	return c.Values[:1]
+}
+
+// filterChoice intersects two values as choices
+func filterChoice(a, b PODValue) (PODValue, error) {
+	ca, cb := choiceOf(a), choiceOf(b)
+	if len(ca.Values) == 0 || len(cb.Values) == 0 {
+		return nil, &FilterError{Reason: "empty choice"}
+	}
+	if ta, tb := ca.Values[0].PODType().ID(), cb.Values[0].PODType().ID(); ta != tb {
+		return nil, &FilterError{Reason: fmt.Sprintf("%s against %s",
+			PODTypeFromID(ta), PODTypeFromID(tb))}
+	}
+	if err := checkChoice(ca); err != nil {
+		return nil, err
+	}
+	if err := checkChoice(cb); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isSet(ca) && isSet(cb):
+		return filterSets(ca, cb)
+	case isSet(ca) && isBounded(cb):
+		return filterSetBounds(ca, cb, ca.Values[0], cb.Values[0])
+	case isBounded(ca) && isSet(cb):
+		return filterSetBounds(cb, ca, ca.Values[0], cb.Values[0])
+	case ca.Type == ChoiceTypeRange && cb.Type == ChoiceTypeRange:
+		return filterRanges(ca, cb)
+	case isBounded(ca) && isBounded(cb):
+		return filterSteps(ca, cb)
+	case ca.Type == ChoiceTypeFlags || cb.Type == ChoiceTypeFlags:
+		return filterFlags(ca, cb)
+	}
+	return nil, &FilterError{Reason: fmt.Sprintf("%s against %s is not supported", ca.Type, cb.Type)}
+}
+
+// isSet reports whether a choice lists its values: None or Enum
+func isSet(c *PODChoice) bool {
+	return c.Type == ChoiceTypeNone || c.Type == ChoiceTypeEnum
+}
+
+// isBounded reports whether a choice is a Range or a Step
+func isBounded(c *PODChoice) bool {
+	return c.Type == ChoiceTypeRange || c.Type == ChoiceTypeStep
+}
+
+// checkChoice checks that a choice has the values its type needs
+func checkChoice(c *PODChoice) error {
+	need := map[ChoiceType]int{ChoiceTypeRange: 3, ChoiceTypeStep: 4}[c.Type]
+	if len(c.Values) < need {
+		return &FilterError{Reason: fmt.Sprintf("%s choice with %d values", c.Type, len(c.Values))}
+	}
+	if isBounded(c) {
+		if _, ok := compareValues(c.Values[1], c.Values[2]); !ok {
+			return &FilterError{Reason: fmt.Sprintf("%s of unordered %s", c.Type, c.Values[0].PODType().Name())}
+		}
+	}
+	return nil
+}
+
+// filterSets keeps the values of a allowed by b, the default of a first
+// when it is one of them
+func filterSets(a, b *PODChoice) (PODValue, error) {
+	altsB := alternatives(b)
+	var common []PODValue
+	for _, v := range alternatives(a) {
+		if containsValue(altsB, v) && !containsValue(common, v) {
+			common = append(common, v)
+		}
+	}
+	if len(common) == 0 {
+		return nil, &FilterError{Reason: fmt.Sprintf("no common value in %s and %s", formatChoice(a), formatChoice(b))}
+	}
+	return setResult(common, a.Values[0], b.Values[0]), nil
+}
+
+// filterSetBounds keeps the values of set within the range or step of
+// bounds, defaulting to the first of defaults that remains
+func filterSetBounds(set, bounds *PODChoice, defaults ...PODValue) (PODValue, error) {
+	var common []PODValue
+	for _, v := range alternatives(set) {
+		if inBounds(bounds, v) && !containsValue(common, v) {
+			common = append(common, v)
+		}
+	}
+	if len(common) == 0 {
+		return nil, &FilterError{Reason: fmt.Sprintf("no value of %s in %s", formatChoice(set), formatChoice(bounds))}
+	}
+	return setResult(common, defaults...), nil
+}
+
+// setResult makes a plain value of a single value, and an enum of more
+// whose default is the first preferred default that remains
+func setResult(common []PODValue, defaults ...PODValue) PODValue {
+	if len(common) == 1 {
+		return common[0]
+	}
+	def := common[0]
+	for _, d := range defaults {
+		if containsValue(common, d) {
+			def = d
+			break
+		}
+	}
+	return NewPODChoice(ChoiceTypeEnum, append([]PODValue{def}, common...)...)
+}
+
+// filterRanges narrows two ranges to their overlap
+func filterRanges(a, b *PODChoice) (PODValue, error) {
+	lo := maxValue(a.Values[1], b.Values[1])
+	hi := minValue(a.Values[2], b.Values[2])
+	if !lessOrEqual(lo, hi) {
+		return nil, &FilterError{Reason: fmt.Sprintf("%s and %s do not overlap", formatChoice(a), formatChoice(b))}
+	}
+	if equalValues(lo, hi) {
+		return lo, nil
+	}
+	def := clampValue(a.Values[0], lo, hi)
+	return NewPODChoice(ChoiceTypeRange, def, lo, hi), nil
+}
+
+// filterSteps narrows a step by a range or a step with the same grid
+func filterSteps(a, b *PODChoice) (PODValue, error) {
+	step, other := a, b
+	if step.Type != ChoiceTypeStep {
+		step, other = b, a
+	}
+	base, ok1 := intValue(step.Values[1])
+	stride, ok2 := intValue(step.Values[3])
+	if !ok1 || !ok2 || stride <= 0 {
+		return nil, &FilterError{Reason: fmt.Sprintf("step of %s is not supported", step.Values[0].PODType().Name())}
+	}
+	if other.Type == ChoiceTypeStep {
+		otherBase, _ := intValue(other.Values[1])
+		otherStride, _ := intValue(other.Values[3])
+		if otherStride != stride || (otherBase-base)%stride != 0 {
+			return nil, &FilterError{Reason: fmt.Sprintf("steps %s and %s are not aligned", formatChoice(a), formatChoice(b))}
+		}
+	}
+
+	lo, _ := intValue(maxValue(step.Values[1], other.Values[1]))
+	hi, _ := intValue(minValue(step.Values[2], other.Values[2]))
+	if r := (lo - base) % stride; r != 0 {
+		lo += stride - r
+	}
+	hi -= (hi - base) % stride
+	if lo > hi {
+		return nil, &FilterError{Reason: fmt.Sprintf("%s and %s do not overlap", formatChoice(a), formatChoice(b))}
+	}
+	like := step.Values[0]
+	if lo+stride > hi {
+		return withInt(like, lo), nil
+	}
+	def, _ := intValue(a.Values[0])
+	def = min(max(def, lo), hi)
+	def -= (def - base) % stride
+	return NewPODChoice(ChoiceTypeStep, withInt(like, def), withInt(like, lo), withInt(like, hi), step.Values[3]), nil
+}
+
+// filterFlags keeps the bits both flags allow; a plain value must only
+// use allowed bits
+func filterFlags(a, b *PODChoice) (PODValue, error) {
+	fa, oka := intValue(a.Values[0])
+	fb, okb := intValue(b.Values[0])
+	if !oka || !okb {
+		return nil, &FilterError{Reason: fmt.Sprintf("flags of %s are not supported", a.Values[0].PODType().Name())}
+	}
+	switch {
+	case a.Type == ChoiceTypeFlags && b.Type == ChoiceTypeFlags:
+		if fa&fb == 0 {
+			return nil, &FilterError{Reason: fmt.Sprintf("flags %#x and %#x have no common bit", fa, fb)}
+		}
+		return NewPODChoice(ChoiceTypeFlags, withInt(a.Values[0], fa&fb)), nil
+	case a.Type == ChoiceTypeNone && b.Type == ChoiceTypeFlags:
+		if fa&^fb != 0 {
+			return nil, &FilterError{Reason: fmt.Sprintf("%#x has bits outside flags %#x", fa, fb)}
+		}
+		return a.Values[0], nil
+	case a.Type == ChoiceTypeFlags && b.Type == ChoiceTypeNone:
+		return filterFlags(b, a)
+	}
+	return nil, &FilterError{Reason: fmt.Sprintf("%s against %s is not supported", a.Type, b.Type)}
+}
+
+// formatChoice describes a choice for errors, such as range[48000 1 384000]
+func formatChoice(c *PODChoice) string {
+	if c.Type == ChoiceTypeNone {
+		return fmt.Sprint(c.Values[0])
+	}
+	return fmt.Sprintf("%s%v", c.Type, c.Values)
+}
+
+// ============================================================================
+// VALUES
+// ============================================================================
+
+// compareValues orders two values of the same type as spa_pod_compare_value,
+// ok is false for types without an order, such as String and Bool
+func compareValues(a, b PODValue) (c int, ok bool) {
+	switch av := a.(type) {
+	case *PODRectangle:
+		bv, ok := b.(*PODRectangle)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case av.Width == bv.Width && av.Height == bv.Height:
+			return 0, true
+		case av.Width < bv.Width || av.Height < bv.Height:
+			return -1, true
+		}
+		return 1, true
+	case *PODFraction:
+		bv, ok := b.(*PODFraction)
+		if !ok {
+			return 0, false
+		}
+		return cmp3(uint64(av.Num)*uint64(bv.Den), uint64(bv.Num)*uint64(av.Den)), true
+	case *PODFloat, *PODDouble:
+		x, _ := floatValue(a)
+		y, ok := floatValue(b)
+		if !ok {
+			return 0, false
+		}
+		return cmp3(x, y), true
+	}
+	x, ok1 := intValue(a)
+	y, ok2 := intValue(b)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	return cmp3(x, y), true
+}
+
+func cmp3[T int64 | uint64 | float64](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// lessOrEqual reports whether a <= b, in both dimensions for rectangles
+func lessOrEqual(a, b PODValue) bool {
+	if ra, ok := a.(*PODRectangle); ok {
+		rb, ok := b.(*PODRectangle)
+		return ok && ra.Width <= rb.Width && ra.Height <= rb.Height
+	}
+	c, ok := compareValues(a, b)
+	return ok && c <= 0
+}
+
+// equalValues reports whether two values are the same, by their encoding
+// for types without an order
+func equalValues(a, b PODValue) bool {
+	if a.PODType().ID() != b.PODType().ID() {
+		return false
+	}
+	if c, ok := compareValues(a, b); ok {
+		if r1, ok := a.(*PODRectangle); ok {
+			r2 := b.(*PODRectangle)
+			return *r1 == *r2
+		}
+		return c == 0
+	}
+	da, err1 := a.Marshal()
+	db, err2 := b.Marshal()
+	return err1 == nil && err2 == nil && bytes.Equal(da, db)
+}
+
+func containsValue(values []PODValue, v PODValue) bool {
+	for _, other := range values {
+		if equalValues(other, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// inBounds reports whether v is within a range, and on the grid of a step
+func inBounds(bounds *PODChoice, v PODValue) bool {
+	if !lessOrEqual(bounds.Values[1], v) || !lessOrEqual(v, bounds.Values[2]) {
+		return false
+	}
+	if bounds.Type != ChoiceTypeStep {
+		return true
+	}
+	x, ok1 := intValue(v)
+	base, ok2 := intValue(bounds.Values[1])
+	stride, ok3 := intValue(bounds.Values[3])
+	if !ok1 || !ok2 || !ok3 || stride <= 0 {
+		return true
+	}
+	return (x-base)%stride == 0
+}
+
+// maxValue returns the larger of two values, per dimension for rectangles
+func maxValue(a, b PODValue) PODValue {
+	if ra, ok := a.(*PODRectangle); ok {
+		if rb, ok := b.(*PODRectangle); ok {
+			return NewPODRectangle(max(ra.Width, rb.Width), max(ra.Height, rb.Height))
+		}
+	}
+	if c, _ := compareValues(a, b); c < 0 {
+		return b
+	}
+	return a
+}
+
+// minValue returns the smaller of two values, per dimension for rectangles
+func minValue(a, b PODValue) PODValue {
+	if ra, ok := a.(*PODRectangle); ok {
+		if rb, ok := b.(*PODRectangle); ok {
+			return NewPODRectangle(min(ra.Width, rb.Width), min(ra.Height, rb.Height))
+		}
+	}
+	if c, _ := compareValues(a, b); c > 0 {
+		return b
+	}
+	return a
+}
+
+// clampValue returns v within [lo, hi]
+func clampValue(v, lo, hi PODValue) PODValue {
+	return minValue(maxValue(v, lo), hi)
+}
+
+// intValue returns the value of an Int, Long or Id
+func intValue(v PODValue) (int64, bool) {
+	switch x := v.(type) {
+	case *PODInt32:
+		return int64(x.Value), true
+	case *PODInt64:
+		return x.Value, true
+	case *PODUint32:
+		return int64(x.Value), true
+	case *PODUint64:
+		return int64(x.Value), true
+	case *PODId:
+		return int64(x.Value), true
+	}
+	return 0, false
+}
+
+// withInt returns a value of the type of like holding x
+func withInt(like PODValue, x int64) PODValue {
+	switch like.(type) {
+	case *PODInt64:
+		return NewPODInt64(x)
+	case *PODUint64:
+		return &PODUint64{Value: uint64(x)}
+	case *PODUint32:
+		return &PODUint32{Value: uint32(x)}
+	case *PODId:
+		return NewPODId(uint32(x))
+	}
+	return NewPODInt32(int32(x))
+}
+
+// floatValue returns the value of a Float or Double
+func floatValue(v PODValue) (float64, bool) {
+	switch x := v.(type) {
+	case *PODFloat:
+		return float64(x.Value), true
+	case *PODDouble:
+		return x.Value, true
+	}
+	return 0, false
+}
@@ -0,0 +1,224 @@
+// Package spa - Tests for POD filtering and fixation
+// spa/filter_test.go
+
+package spa
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustMarshal encodes a POD for comparisons
+func mustMarshal(t *testing.T, v PODValue) string {
+	t.Helper()
+	data, err := v.Marshal()
+	if err != nil {
+		t.Fatalf("marshal %v failed: %v", v, err)
+	}
+	return string(data)
+}
+
+// TestFilterChoices tests intersecting values and choices of each type
+func TestFilterChoices(t *testing.T) {
+	i := func(v int32) PODValue { return NewPODInt32(v) }
+	enum := func(vals ...int32) PODValue {
+		return NewPODChoiceBuilder(ChoiceTypeEnum).AddInt32(vals...).Build()
+	}
+	step := func(def, lo, hi, st int32) PODValue {
+		return NewPODChoiceStep(i(def), i(lo), i(hi), i(st))
+	}
+	rng := func(def, lo, hi int32) PODValue { return NewPODChoiceRange(i(def), i(lo), i(hi)) }
+	flags := func(mask int32) PODValue { return NewPODChoice(ChoiceTypeFlags, i(mask)) }
+
+	tests := []struct {
+		name   string
+		a, b   PODValue
+		want   PODValue
+		errMsg string
+	}{
+		{"equal values", i(2), i(2), i(2), ""},
+		{"different values", i(2), i(6), nil, "no common value in int32(2) and int32(6)"},
+		{"value in enum", i(2), enum(1, 1, 2), i(2), ""},
+		{"enums keep the common values", enum(3, 1, 2, 3), enum(2, 2, 3, 4), enum(3, 2, 3), ""},
+		{"enum default from the filter", enum(1, 1, 2, 3), enum(2, 2, 3), enum(2, 2, 3), ""},
+		{"single common value", enum(1, 1, 2), enum(2, 2, 3), i(2), ""},
+		{"enum in range", enum(44100, 44100, 48000, 96000), rng(48000, 1, 50000), enum(44100, 44100, 48000), ""},
+		{"range around value", rng(48000, 1, 384000), i(44100), i(44100), ""},
+		{"value out of range", i(8), rng(2, 1, 4), nil, "no value of int32(8) in range"},
+		{"ranges overlap", rng(48000, 8000, 48000), rng(96000, 44100, 192000), rng(48000, 44100, 48000), ""},
+		{"ranges clamp the default", rng(1, 1, 10), rng(6, 5, 20), rng(5, 5, 10), ""},
+		{"ranges touch", rng(1, 1, 10), rng(10, 10, 20), i(10), ""},
+		{"ranges apart", rng(1, 1, 10), rng(11, 11, 20), nil, "do not overlap"},
+		{"value on step", i(128), step(64, 64, 1024, 64), i(128), ""},
+		{"value off step", i(100), step(64, 64, 1024, 64), nil, "no value of int32(100)"},
+		{"step in range", step(64, 64, 1024, 64), rng(100, 100, 300), step(128, 128, 256, 64), ""},
+		{"aligned steps", step(64, 0, 1024, 64), step(256, 128, 4096, 64), step(128, 128, 1024, 64), ""},
+		{"misaligned steps", step(64, 0, 1024, 64), step(32, 32, 4096, 64), nil, "not aligned"},
+		{"common flags", flags(0x6), flags(0xc), NewPODChoice(ChoiceTypeFlags, i(0x4)), ""},
+		{"value within flags", i(0x4), flags(0x6), i(0x4), ""},
+		{"value outside flags", i(0x9), flags(0x6), nil, "bits outside flags"},
+		{"mismatched types", i(2), NewPODId(2), nil, "int against id"},
+		{"strings", NewPODString("a"), NewPODString("a"), NewPODString("a"), ""},
+		{"range of strings", NewPODString("a"), NewPODChoiceRange(NewPODString("a"), NewPODString("a"), NewPODString("z")), nil, "unordered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Filter(tt.a, tt.b)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) || !errors.Is(err, ErrNoIntersection) {
+					t.Fatalf("expected error %q, got %v, %v", tt.errMsg, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Filter failed: %v", err)
+			}
+			if mustMarshal(t, got) != mustMarshal(t, tt.want) {
+				t.Errorf("got %s, want %s", describe(got), describe(tt.want))
+			}
+		})
+	}
+}
+
+// describe prints choices with their values for failures
+func describe(v PODValue) string {
+	if c, ok := v.(*PODChoice); ok {
+		return formatChoice(c)
+	}
+	return v.String()
+}
+
+// TestFilterRectangles tests ranges of video sizes
+func TestFilterRectangles(t *testing.T) {
+	a := NewPODChoiceRange(NewPODRectangle(640, 480), NewPODRectangle(1, 1), NewPODRectangle(1920, 1080))
+	b := NewPODChoiceRange(NewPODRectangle(1280, 720), NewPODRectangle(320, 720), NewPODRectangle(4096, 720))
+	got, err := Filter(a, b)
+	if err != nil {
+		t.Fatalf("Filter failed: %v", err)
+	}
+	want := NewPODChoiceRange(NewPODRectangle(640, 720), NewPODRectangle(320, 720), NewPODRectangle(1920, 720))
+	if mustMarshal(t, got) != mustMarshal(t, want) {
+		t.Errorf("got %s, want %s", describe(got), describe(want))
+	}
+	if _, err := Filter(NewPODRectangle(2000, 100), a); err == nil {
+		t.Error("expected error for a size wider than the range")
+	}
+}
+
+// TestFilterFormat tests negotiating a format between two ports
+func TestFilterFormat(t *testing.T) {
+	enumFormat, _ := NewPODParser(enumFormatPOD(t)).ParseValue()
+	filter := NewPODObjectBuilder(TypeObjectFormat, ParamEnumFormat).
+		PutID(FormatMediaType, 1).
+		PutID(FormatMediaSubtype, 1).
+		Put(FormatAudioFormat, NewPODChoiceBuilder(ChoiceTypeEnum).AddID(0x104, 0x104, 0x11b).Build()).
+		Put(FormatAudioRate, NewPODChoiceBuilder(ChoiceTypeEnum).AddInt32(44100, 44100, 48000).Build()).
+		Build()
+
+	filtered, err := Filter(enumFormat, filter)
+	if err != nil {
+		t.Fatalf("Filter failed: %v", err)
+	}
+	obj := filtered.(*PODObject)
+	if len(obj.Props) != 7 {
+		t.Fatalf("expected the 7 props of the EnumFormat, got %d", len(obj.Props))
+	}
+	format, _ := obj.Get(FormatAudioFormat)
+	rate, _ := obj.Get(FormatAudioRate)
+	if id, ok := format.(*PODId); !ok || id.Value != 0x11b {
+		t.Errorf("expected F32 as the only common format, got %s", describe(format))
+	}
+	// The EnumFormat default of 48000 stays the default
+	if want := NewPODChoice(ChoiceTypeEnum, NewPODInt32(48000), NewPODInt32(44100), NewPODInt32(48000)); mustMarshal(t, rate) != mustMarshal(t, want) {
+		t.Errorf("expected both rates, got %s", describe(rate))
+	}
+
+	fixed, err := Fixate(filtered)
+	if err != nil {
+		t.Fatalf("Fixate failed: %v", err)
+	}
+	rate, _ = fixed.(*PODObject).Get(FormatAudioRate)
+	if r, ok := rate.(*PODInt32); !ok || r.Value != 48000 {
+		t.Errorf("expected a fixed rate of 48000, got %s", describe(rate))
+	}
+
+	// FilterBytes works on the encoded PODs
+	data, err := FilterBytes(enumFormatPOD(t), mustBytes(t, filter))
+	if err != nil || string(data) != mustMarshal(t, filtered) {
+		t.Errorf("FilterBytes differs from Filter: %v", err)
+	}
+	if data, err := FilterBytes(enumFormatPOD(t), nil); err != nil || string(data) != string(enumFormatPOD(t)) {
+		t.Errorf("FilterBytes without a filter changed the POD: %v", err)
+	}
+}
+
+func mustBytes(t *testing.T, v PODValue) []byte {
+	t.Helper()
+	return []byte(mustMarshal(t, v))
+}
+
+// TestFilterErrors tests the reasons reported for incompatible objects
+func TestFilterErrors(t *testing.T) {
+	enumFormat, _ := NewPODParser(enumFormatPOD(t)).ParseValue()
+	filter := NewPODObjectBuilder(TypeObjectFormat, ParamFormat).PutInt32(FormatAudioRate, 768000).Build()
+	_, err := Filter(enumFormat, filter)
+	var fe *FilterError
+	if !errors.As(err, &fe) || fe.ObjectType != TypeObjectFormat || fe.Key != FormatAudioRate {
+		t.Fatalf("expected a FilterError on the rate, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "SPA_FORMAT_AUDIO_rate") {
+		t.Errorf("expected the key name in %q", err)
+	}
+
+	props := NewPODObjectBuilder(TypeObjectProps, ParamProps).Build()
+	if _, err := Filter(enumFormat, props); err == nil {
+		t.Error("expected error for different object types")
+	}
+
+	mandatory := NewPODObjectBuilder(TypeObjectFormat, ParamFormat).
+		PutProp(FormatAudioChannels, PropFlagMandatory, NewPODInt32(2)).Build()
+	empty := NewPODObjectBuilder(TypeObjectFormat, ParamFormat).Build()
+	if _, err := Filter(empty, mandatory); err == nil || !strings.Contains(err.Error(), "mandatory") {
+		t.Errorf("expected error for a missing mandatory prop, got %v", err)
+	}
+	if got, err := Filter(mandatory, nil); err != nil || got != mandatory {
+		t.Errorf("expected a nil filter to keep the POD: %v", err)
+	}
+
+	s1 := NewPODStruct(NewPODInt32(1), NewPODString("x"))
+	s2 := NewPODStruct(NewPODInt32(1), NewPODString("y"))
+	if _, err := Filter(s1, s2); err == nil {
+		t.Error("expected error for different struct fields")
+	}
+	if got, err := Filter(s1, s1); err != nil || !reflect.DeepEqual(got, s1) {
+		t.Errorf("expected an equal struct, got %v: %v", got, err)
+	}
+}
+
+// TestFixate tests that choices become their defaults
+func TestFixate(t *testing.T) {
+	obj := NewPODObjectBuilder(TypeObjectParamBuffers, ParamBuffers).
+		Put(ParamBuffersBuffers, NewPODChoiceRange(NewPODInt32(8), NewPODInt32(2), NewPODInt32(16))).
+		PutProp(ParamBuffersSize, PropFlagDontFixate, NewPODChoiceRange(NewPODInt32(4096), NewPODInt32(1), NewPODInt32(8192))).
+		PutInt32(ParamBuffersStride, 8).
+		Build()
+	fixed, err := Fixate(obj)
+	if err != nil {
+		t.Fatalf("Fixate failed: %v", err)
+	}
+	fo := fixed.(*PODObject)
+	if v, _ := fo.Get(ParamBuffersBuffers); v.(*PODInt32).Value != 8 {
+		t.Errorf("expected 8 buffers, got %v", v)
+	}
+	if v, _ := fo.Get(ParamBuffersSize); v.PODType().ID() != PODTypeChoice {
+		t.Errorf("expected the DontFixate choice to stay, got %v", v)
+	}
+	if v, _ := obj.Get(ParamBuffersBuffers); v.PODType().ID() != PODTypeChoice {
+		t.Error("Fixate changed its input")
+	}
+	if _, err := Fixate(&PODChoice{Type: ChoiceTypeEnum}); err == nil {
+		t.Error("expected error for an empty choice")
+	}
+}
@@ -0,0 +1,388 @@
+// Package spa - SPA type ids
+// spa/ids.go
+// Object types, param ids, object keys and enumerations as numbered by
+// libspa (spa/param/*.h), with their C names for lookups by name
+
+package spa
+
+import "sync"
+
+// ===== Object Types (SPA_TYPE_OBJECT_*) =====
+
+const (
+	TypeObjectPropInfo            uint32 = 0x40001
+	TypeObjectProps               uint32 = 0x40002
+	TypeObjectFormat              uint32 = 0x40003
+	TypeObjectParamBuffers        uint32 = 0x40004
+	TypeObjectParamMeta           uint32 = 0x40005
+	TypeObjectParamIO             uint32 = 0x40006
+	TypeObjectParamProfile        uint32 = 0x40007
+	TypeObjectParamPortConfig     uint32 = 0x40008
+	TypeObjectParamRoute          uint32 = 0x40009
+	TypeObjectProfiler            uint32 = 0x4000a
+	TypeObjectParamLatency        uint32 = 0x4000b
+	TypeObjectParamProcessLatency uint32 = 0x4000c
+	TypeObjectParamTag            uint32 = 0x4000d
+)
+
+// ===== Param Ids (SPA_PARAM_*) =====
+
+const (
+	ParamInvalid        uint32 = 0
+	ParamPropInfo       uint32 = 1
+	ParamProps          uint32 = 2
+	ParamEnumFormat     uint32 = 3
+	ParamFormat         uint32 = 4
+	ParamBuffers        uint32 = 5
+	ParamMeta           uint32 = 6
+	ParamIO             uint32 = 7
+	ParamEnumProfile    uint32 = 8
+	ParamProfile        uint32 = 9
+	ParamEnumPortConfig uint32 = 10
+	ParamPortConfig     uint32 = 11
+	ParamEnumRoute      uint32 = 12
+	ParamRoute          uint32 = 13
+	ParamControl        uint32 = 14
+	ParamLatency        uint32 = 15
+	ParamProcessLatency uint32 = 16
+	ParamTag            uint32 = 17
+)
+
+// ===== Props Keys (SPA_PROP_*) =====
+
+const (
+	PropDevice            uint32 = 0x101
+	PropDeviceName        uint32 = 0x102
+	PropDeviceFd          uint32 = 0x103
+	PropCard              uint32 = 0x104
+	PropCardName          uint32 = 0x105
+	PropMinLatency        uint32 = 0x106
+	PropMaxLatency        uint32 = 0x107
+	PropPeriods           uint32 = 0x108
+	PropPeriodSize        uint32 = 0x109
+	PropPeriodEvent       uint32 = 0x10a
+	PropLive              uint32 = 0x10b
+	PropRate              uint32 = 0x10c
+	PropQuality           uint32 = 0x10d
+	PropWaveType          uint32 = 0x10001
+	PropFrequency         uint32 = 0x10002
+	PropVolume            uint32 = 0x10003
+	PropMute              uint32 = 0x10004
+	PropPatternType       uint32 = 0x10005
+	PropDitherType        uint32 = 0x10006
+	PropTruncate          uint32 = 0x10007
+	PropChannelVolumes    uint32 = 0x10008
+	PropVolumeBase        uint32 = 0x10009
+	PropVolumeStep        uint32 = 0x1000a
+	PropChannelMap        uint32 = 0x1000b
+	PropMonitorMute       uint32 = 0x1000c
+	PropMonitorVolumes    uint32 = 0x1000d
+	PropLatencyOffsetNsec uint32 = 0x1000e
+	PropSoftMute          uint32 = 0x1000f
+	PropSoftVolumes       uint32 = 0x10010
+	PropIec958Codecs      uint32 = 0x10011
+	PropParams            uint32 = 0x80001
+)
+
+// ===== PropInfo Keys (SPA_PROP_INFO_*) =====
+
+const (
+	PropInfoID          uint32 = 1
+	PropInfoName        uint32 = 2
+	PropInfoType        uint32 = 3
+	PropInfoLabels      uint32 = 4
+	PropInfoContainer   uint32 = 5
+	PropInfoParams      uint32 = 6
+	PropInfoDescription uint32 = 7
+)
+
+// ===== Format Keys (SPA_FORMAT_*) =====
+
+const (
+	FormatMediaType         uint32 = 1
+	FormatMediaSubtype      uint32 = 2
+	FormatAudioFormat       uint32 = 0x10001
+	FormatAudioFlags        uint32 = 0x10002
+	FormatAudioRate         uint32 = 0x10003
+	FormatAudioChannels     uint32 = 0x10004
+	FormatAudioPosition     uint32 = 0x10005
+	FormatAudioIec958Codec  uint32 = 0x10006
+	FormatAudioBitorder     uint32 = 0x10007
+	FormatAudioInterleave   uint32 = 0x10008
+	FormatVideoFormat       uint32 = 0x20001
+	FormatVideoModifier     uint32 = 0x20002
+	FormatVideoSize         uint32 = 0x20003
+	FormatVideoFramerate    uint32 = 0x20004
+	FormatVideoMaxFramerate uint32 = 0x20005
+	FormatControlTypes      uint32 = 0x60001
+)
+
+// ===== Param Object Keys (SPA_PARAM_<OBJECT>_*) =====
+
+const (
+	ParamBuffersBuffers  uint32 = 1
+	ParamBuffersBlocks   uint32 = 2
+	ParamBuffersSize     uint32 = 3
+	ParamBuffersStride   uint32 = 4
+	ParamBuffersAlign    uint32 = 5
+	ParamBuffersDataType uint32 = 6
+	ParamBuffersMetaType uint32 = 7
+
+	ParamMetaType uint32 = 1
+	ParamMetaSize uint32 = 2
+
+	ParamIOID   uint32 = 1
+	ParamIOSize uint32 = 2
+
+	ParamProfileIndex       uint32 = 1
+	ParamProfileName        uint32 = 2
+	ParamProfileDescription uint32 = 3
+	ParamProfilePriority    uint32 = 4
+	ParamProfileAvailable   uint32 = 5
+	ParamProfileInfo        uint32 = 6
+	ParamProfileClasses     uint32 = 7
+	ParamProfileSave        uint32 = 8
+
+	ParamRouteIndex       uint32 = 1
+	ParamRouteDirection   uint32 = 2
+	ParamRouteDevice      uint32 = 3
+	ParamRouteName        uint32 = 4
+	ParamRouteDescription uint32 = 5
+	ParamRoutePriority    uint32 = 6
+	ParamRouteAvailable   uint32 = 7
+	ParamRouteInfo        uint32 = 8
+	ParamRouteProfiles    uint32 = 9
+	ParamRouteProps       uint32 = 10
+	ParamRouteDevices     uint32 = 11
+	ParamRouteProfile     uint32 = 12
+	ParamRouteSave        uint32 = 13
+
+	ParamLatencyDirection  uint32 = 1
+	ParamLatencyMinQuantum uint32 = 2
+	ParamLatencyMaxQuantum uint32 = 3
+	ParamLatencyMinRate    uint32 = 4
+	ParamLatencyMaxRate    uint32 = 5
+	ParamLatencyMinNs      uint32 = 6
+	ParamLatencyMaxNs      uint32 = 7
+
+	ParamProcessLatencyQuantum uint32 = 1
+	ParamProcessLatencyRate    uint32 = 2
+	ParamProcessLatencyNs      uint32 = 3
+)
+
+// ===== Name Tables =====
+
+// idTable names the ids of one SPA namespace by their C names
+type idTable struct {
+	prefix string            // Shared by the names, such as "SPA_PROP_"
+	names  map[uint32]string // Names without the prefix
+}
+
+var (
+	objectTypeTable = &idTable{"SPA_TYPE_OBJECT_", map[uint32]string{
+		TypeObjectPropInfo: "PropInfo", TypeObjectProps: "Props", TypeObjectFormat: "Format",
+		TypeObjectParamBuffers: "ParamBuffers", TypeObjectParamMeta: "ParamMeta", TypeObjectParamIO: "ParamIO",
+		TypeObjectParamProfile: "ParamProfile", TypeObjectParamPortConfig: "ParamPortConfig",
+		TypeObjectParamRoute: "ParamRoute", TypeObjectProfiler: "Profiler", TypeObjectParamLatency: "ParamLatency",
+		TypeObjectParamProcessLatency: "ParamProcessLatency", TypeObjectParamTag: "ParamTag",
+	}}
+
+	paramTable = &idTable{"SPA_PARAM_", map[uint32]string{
+		ParamInvalid: "Invalid", ParamPropInfo: "PropInfo", ParamProps: "Props", ParamEnumFormat: "EnumFormat",
+		ParamFormat: "Format", ParamBuffers: "Buffers", ParamMeta: "Meta", ParamIO: "IO",
+		ParamEnumProfile: "EnumProfile", ParamProfile: "Profile", ParamEnumPortConfig: "EnumPortConfig",
+		ParamPortConfig: "PortConfig", ParamEnumRoute: "EnumRoute", ParamRoute: "Route", ParamControl: "Control",
+		ParamLatency: "Latency", ParamProcessLatency: "ProcessLatency", ParamTag: "Tag",
+	}}
+
+	// objectKeyTables names the property keys of each object type
+	objectKeyTables = map[uint32]*idTable{
+		TypeObjectProps: {"SPA_PROP_", map[uint32]string{
+			PropDevice: "device", PropDeviceName: "deviceName", PropDeviceFd: "deviceFd", PropCard: "card",
+			PropCardName: "cardName", PropMinLatency: "minLatency", PropMaxLatency: "maxLatency",
+			PropPeriods: "periods", PropPeriodSize: "periodSize", PropPeriodEvent: "periodEvent", PropLive: "live",
+			PropRate: "rate", PropQuality: "quality", PropWaveType: "waveType", PropFrequency: "frequency",
+			PropVolume: "volume", PropMute: "mute", PropPatternType: "patternType", PropDitherType: "ditherType",
+			PropTruncate: "truncate", PropChannelVolumes: "channelVolumes", PropVolumeBase: "volumeBase",
+			PropVolumeStep: "volumeStep", PropChannelMap: "channelMap", PropMonitorMute: "monitorMute",
+			PropMonitorVolumes: "monitorVolumes", PropLatencyOffsetNsec: "latencyOffsetNsec",
+			PropSoftMute: "softMute", PropSoftVolumes: "softVolumes", PropIec958Codecs: "iec958Codecs",
+			PropParams: "params",
+		}},
+		TypeObjectPropInfo: {"SPA_PROP_INFO_", map[uint32]string{
+			PropInfoID: "id", PropInfoName: "name", PropInfoType: "type", PropInfoLabels: "labels",
+			PropInfoContainer: "container", PropInfoParams: "params", PropInfoDescription: "description",
+		}},
+		TypeObjectFormat: {"SPA_FORMAT_", map[uint32]string{
+			FormatMediaType: "mediaType", FormatMediaSubtype: "mediaSubtype",
+			FormatAudioFormat: "AUDIO_format", FormatAudioFlags: "AUDIO_flags", FormatAudioRate: "AUDIO_rate",
+			FormatAudioChannels: "AUDIO_channels", FormatAudioPosition: "AUDIO_position",
+			FormatAudioIec958Codec: "AUDIO_iec958Codec", FormatAudioBitorder: "AUDIO_bitorder",
+			FormatAudioInterleave: "AUDIO_interleave", FormatVideoFormat: "VIDEO_format",
+			FormatVideoModifier: "VIDEO_modifier", FormatVideoSize: "VIDEO_size",
+			FormatVideoFramerate: "VIDEO_framerate", FormatVideoMaxFramerate: "VIDEO_maxFramerate",
+			FormatControlTypes: "CONTROL_types",
+		}},
+		TypeObjectParamBuffers: {"SPA_PARAM_BUFFERS_", map[uint32]string{
+			ParamBuffersBuffers: "buffers", ParamBuffersBlocks: "blocks", ParamBuffersSize: "size",
+			ParamBuffersStride: "stride", ParamBuffersAlign: "align", ParamBuffersDataType: "dataType",
+			ParamBuffersMetaType: "metaType",
+		}},
+		TypeObjectParamMeta: {"SPA_PARAM_META_", map[uint32]string{
+			ParamMetaType: "type", ParamMetaSize: "size",
+		}},
+		TypeObjectParamIO: {"SPA_PARAM_IO_", map[uint32]string{
+			ParamIOID: "id", ParamIOSize: "size",
+		}},
+		TypeObjectParamProfile: {"SPA_PARAM_PROFILE_", map[uint32]string{
+			ParamProfileIndex: "index", ParamProfileName: "name", ParamProfileDescription: "description",
+			ParamProfilePriority: "priority", ParamProfileAvailable: "available", ParamProfileInfo: "info",
+			ParamProfileClasses: "classes", ParamProfileSave: "save",
+		}},
+		TypeObjectParamRoute: {"SPA_PARAM_ROUTE_", map[uint32]string{
+			ParamRouteIndex: "index", ParamRouteDirection: "direction", ParamRouteDevice: "device",
+			ParamRouteName: "name", ParamRouteDescription: "description", ParamRoutePriority: "priority",
+			ParamRouteAvailable: "available", ParamRouteInfo: "info", ParamRouteProfiles: "profiles",
+			ParamRouteProps: "props", ParamRouteDevices: "devices", ParamRouteProfile: "profile",
+			ParamRouteSave: "save",
+		}},
+		TypeObjectParamLatency: {"SPA_PARAM_LATENCY_", map[uint32]string{
+			ParamLatencyDirection: "direction", ParamLatencyMinQuantum: "minQuantum",
+			ParamLatencyMaxQuantum: "maxQuantum", ParamLatencyMinRate: "minRate", ParamLatencyMaxRate: "maxRate",
+			ParamLatencyMinNs: "minNs", ParamLatencyMaxNs: "maxNs",
+		}},
+		TypeObjectParamProcessLatency: {"SPA_PARAM_PROCESS_LATENCY_", map[uint32]string{
+			ParamProcessLatencyQuantum: "quantum", ParamProcessLatencyRate: "rate", ParamProcessLatencyNs: "ns",
+		}},
+	}
+
+	mediaTypeTable = &idTable{"SPA_MEDIA_TYPE_", map[uint32]string{
+		0: "unknown", 1: "audio", 2: "video", 3: "image", 4: "binary", 5: "stream", 6: "application",
+	}}
+
+	mediaSubtypeTable = &idTable{"SPA_MEDIA_SUBTYPE_", map[uint32]string{
+		0: "unknown", 1: "raw", 2: "dsp", 3: "iec958", 4: "dsd",
+		0x10001: "mp3", 0x10002: "aac", 0x10003: "vorbis", 0x10004: "wma", 0x10005: "ra", 0x10006: "sbc",
+		0x10007: "adpcm", 0x10008: "g723", 0x10009: "g726", 0x1000a: "g729", 0x1000b: "amr", 0x1000c: "gsm",
+		0x1000d: "alac", 0x1000e: "flac", 0x1000f: "ape", 0x10010: "opus",
+		0x20001: "h264", 0x20002: "mjpg", 0x20003: "dv", 0x20004: "mpegts", 0x20005: "h263",
+		0x20006: "mpeg1", 0x20007: "mpeg2", 0x20008: "mpeg4", 0x20009: "xvid", 0x2000a: "vc1",
+		0x2000b: "vp8", 0x2000c: "vp9", 0x2000d: "bayer",
+		0x30001: "jpeg", 0x60001: "midi",
+	}}
+
+	audioFormatTable = &idTable{"SPA_AUDIO_FORMAT_", map[uint32]string{
+		0: "UNKNOWN", 1: "ENCODED",
+		0x101: "S8", 0x102: "U8", 0x103: "S16_LE", 0x104: "S16_BE", 0x105: "U16_LE", 0x106: "U16_BE",
+		0x107: "S24_32_LE", 0x108: "S24_32_BE", 0x109: "U24_32_LE", 0x10a: "U24_32_BE",
+		0x10b: "S32_LE", 0x10c: "S32_BE", 0x10d: "U32_LE", 0x10e: "U32_BE",
+		0x10f: "S24_LE", 0x110: "S24_BE", 0x111: "U24_LE", 0x112: "U24_BE",
+		0x113: "S20_LE", 0x114: "S20_BE", 0x115: "U20_LE", 0x116: "U20_BE",
+		0x117: "S18_LE", 0x118: "S18_BE", 0x119: "U18_LE", 0x11a: "U18_BE",
+		0x11b: "F32_LE", 0x11c: "F32_BE", 0x11d: "F64_LE", 0x11e: "F64_BE", 0x11f: "ULAW", 0x120: "ALAW",
+		0x201: "U8P", 0x202: "S16P", 0x203: "S24_32P", 0x204: "S32P", 0x205: "S24P", 0x206: "F32P",
+		0x207: "F64P", 0x208: "S8P",
+	}}
+
+	audioChannelTable = &idTable{"SPA_AUDIO_CHANNEL_", map[uint32]string{
+		0: "UNKNOWN", 1: "NA", 2: "MONO", 3: "FL", 4: "FR", 5: "FC", 6: "LFE", 7: "SL", 8: "SR",
+		9: "FLC", 10: "FRC", 11: "RC", 12: "RL", 13: "RR", 14: "TC", 15: "TFL", 16: "TFC", 17: "TFR",
+		18: "TRL", 19: "TRC", 20: "TRR", 21: "RLC", 22: "RRC", 23: "FLW", 24: "FRW", 25: "LFE2",
+		26: "FLH", 27: "FCH", 28: "FRH", 29: "TFLC", 30: "TFRC", 31: "TSL", 32: "TSR", 33: "LLFE",
+		34: "RLFE", 35: "BC", 36: "BLC", 37: "BRC",
+	}}
+
+	directionTable = &idTable{"SPA_DIRECTION_", map[uint32]string{0: "INPUT", 1: "OUTPUT"}}
+
+	availabilityTable = &idTable{"SPA_PARAM_AVAILABILITY_", map[uint32]string{0: "unknown", 1: "no", 2: "yes"}}
+
+	metaTypeTable = &idTable{"SPA_META_", map[uint32]string{
+		0: "Invalid", 1: "Header", 2: "VideoCrop", 3: "VideoDamage", 4: "Bitmap", 5: "Cursor",
+		6: "Control", 7: "Busy", 8: "VideoTransform", 9: "SyncTimeline",
+	}}
+
+	ioTypeTable = &idTable{"SPA_IO_", map[uint32]string{
+		0: "Invalid", 1: "Buffers", 2: "Range", 3: "Clock", 4: "Latency", 5: "Control", 6: "Notify",
+		7: "Position", 8: "RateMatch", 9: "Memory", 10: "AsyncBuffers",
+	}}
+
+	dataTypeTable = &idTable{"SPA_DATA_", map[uint32]string{
+		0: "Invalid", 1: "MemPtr", 2: "MemFd", 3: "DmaBuf", 4: "MemId", 5: "SyncObj",
+	}}
+
+	choiceTable = &idTable{"SPA_CHOICE_", map[uint32]string{
+		uint32(ChoiceTypeNone): "None", uint32(ChoiceTypeRange): "Range", uint32(ChoiceTypeStep): "Step",
+		uint32(ChoiceTypeEnum): "Enum", uint32(ChoiceTypeFlags): "Flags",
+	}}
+)
+
+// objectValueTables names the Id values of properties, per object type
+// and key
+var objectValueTables = map[uint32]map[uint32]*idTable{
+	TypeObjectFormat: {
+		FormatMediaType: mediaTypeTable, FormatMediaSubtype: mediaSubtypeTable,
+		FormatAudioFormat: audioFormatTable, FormatAudioPosition: audioChannelTable,
+	},
+	TypeObjectProps:        {PropChannelMap: audioChannelTable},
+	TypeObjectPropInfo:     {PropInfoID: objectKeyTables[TypeObjectProps]},
+	TypeObjectParamMeta:    {ParamMetaType: metaTypeTable},
+	TypeObjectParamIO:      {ParamIOID: ioTypeTable},
+	TypeObjectParamProfile: {ParamProfileAvailable: availabilityTable},
+	TypeObjectParamRoute:   {ParamRouteDirection: directionTable, ParamRouteAvailable: availabilityTable},
+	TypeObjectParamLatency: {ParamLatencyDirection: directionTable},
+}
+
+// name returns the C name of id, or "" when unknown; a nil table knows none
+func (t *idTable) name(id uint32) string {
+	if t == nil {
+		return ""
+	}
+	if short, ok := t.names[id]; ok {
+		return t.prefix + short
+	}
+	return ""
+}
+
+// valueTable returns the table naming the Id values of a property
+func valueTable(objType, key uint32) *idTable {
+	return objectValueTables[objType][key]
+}
+
+// allIDTables lists every table searched by IDByName
+func allIDTables() []*idTable {
+	tables := []*idTable{
+		objectTypeTable, paramTable, mediaTypeTable, mediaSubtypeTable, audioFormatTable,
+		audioChannelTable, directionTable, availabilityTable, metaTypeTable, ioTypeTable,
+		dataTypeTable, choiceTable,
+	}
+	for _, t := range objectKeyTables {
+		tables = append(tables, t)
+	}
+	return tables
+}
+
+var (
+	idsByNameOnce sync.Once
+	idsByName     map[string]uint32
+)
+
+// IDByName returns the id of an SPA C name, such as "SPA_PROP_volume",
+// "SPA_PARAM_EnumFormat" or "SPA_AUDIO_FORMAT_S16_LE"
+func IDByName(name string) (uint32, bool) {
+	idsByNameOnce.Do(func() {
+		idsByName = make(map[string]uint32)
+		for _, t := range allIDTables() {
+			for id, short := range t.names {
+				idsByName[t.prefix+short] = id
+			}
+		}
+	})
+	id, ok := idsByName[name]
+	return id, ok
+}
+
+// ObjectKeyName returns the C name of a property key of an object type,
+// such as "SPA_PROP_volume", or "" when unknown
+func ObjectKeyName(objType, key uint32) string {
+	return objectKeyTables[objType].name(key)
+}
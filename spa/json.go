@@ -0,0 +1,489 @@
+// Package spa - POD JSON encoding
+// spa/json.go
+// Lossless JSON form of PODs, for logging params and writing fixtures
+
+package spa
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// PODToJSON encodes a POD as JSON. Every value is an object with a single
+// member named by its POD type:
+//
+//	{"int": 2}
+//	{"id": "SPA_MEDIA_TYPE_audio"}
+//	{"choice": {"type": "range", "flags": 0, "childType": "int", "values": [{"int": 48000}, ...]}}
+//	{"object": {"type": "SPA_TYPE_OBJECT_Format", "id": "SPA_PARAM_Format", "props": [
+//		{"key": "SPA_FORMAT_mediaType", "flags": 0, "value": {"id": "SPA_MEDIA_TYPE_audio"}}, ...]}}
+//
+// Ids, object types, param ids and property keys are given by their C name
+// when it is known and by number otherwise. Bytes are base64, and values
+// this package does not decode are kept whole as {"raw": base64}
+// PODFromJSON decodes the result to a POD with the same encoding
+func PODToJSON(pod PODValue) ([]byte, error) {
+	return podToJSON(pod, nil)
+}
+
+// PODFromJSON decodes a POD encoded by PODToJSON
+// Names and numbers are both accepted wherever an id is expected
+func PODFromJSON(data []byte) (PODValue, error) {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, err
+	}
+	if len(wrapper) != 1 {
+		return nil, fmt.Errorf("POD value must have a single member naming its type, got %d", len(wrapper))
+	}
+	for typeName, body := range wrapper {
+		val, err := podBodyFromJSON(typeName, body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", typeName, err)
+		}
+		return val, nil
+	}
+	panic("unreachable")
+}
+
+// JSON forms of the composite values
+type (
+	jsonArray struct {
+		ChildType string            `json:"childType"`
+		Values    []json.RawMessage `json:"values"`
+	}
+	jsonChoice struct {
+		Type      ChoiceType        `json:"type"`
+		Flags     uint32            `json:"flags"`
+		ChildType string            `json:"childType"`
+		Values    []json.RawMessage `json:"values"`
+	}
+	jsonObject struct {
+		Type  json.RawMessage `json:"type"`
+		ID    json.RawMessage `json:"id"`
+		Props []jsonProp      `json:"props"`
+	}
+	jsonProp struct {
+		Key   json.RawMessage `json:"key"`
+		Flags uint32          `json:"flags"`
+		Value json.RawMessage `json:"value"`
+	}
+	jsonSequence struct {
+		Unit     uint32        `json:"unit"`
+		Controls []jsonControl `json:"controls"`
+	}
+	jsonControl struct {
+		Offset uint32          `json:"offset"`
+		Type   uint32          `json:"type"`
+		Value  json.RawMessage `json:"value"`
+	}
+	jsonRectangle struct {
+		Width  uint32 `json:"width"`
+		Height uint32 `json:"height"`
+	}
+	jsonFraction struct {
+		Num uint32 `json:"num"`
+		Den uint32 `json:"den"`
+	}
+	jsonPointer struct {
+		Type  uint32 `json:"type"`
+		Value uint64 `json:"value"`
+	}
+)
+
+// ============================================================================
+// ENCODE
+// ============================================================================
+
+// podToJSON encodes one value; names names Id values, as given by the
+// property the value belongs to
+func podToJSON(pod PODValue, names *idTable) ([]byte, error) {
+	var typeName string
+	var body any
+	switch v := pod.(type) {
+	case nil:
+		return nil, fmt.Errorf("nil POD value")
+	case *PODNone:
+		typeName, body = "none", nil
+	case *PODBool:
+		typeName, body = "bool", v.Value
+	case *PODId:
+		typeName, body = "id", jsonID(names, v.Value)
+	case *PODInt32:
+		typeName, body = "int", v.Value
+	case *PODUint32:
+		typeName, body = "int", int32(v.Value)
+	case *PODInt64:
+		typeName, body = "long", v.Value
+	case *PODUint64:
+		typeName, body = "long", int64(v.Value)
+	case *PODFloat:
+		typeName, body = "float", jsonFloat(float64(v.Value), 32)
+	case *PODDouble:
+		typeName, body = "double", jsonFloat(v.Value, 64)
+	case *PODString:
+		typeName, body = "string", v.Value
+	case *PODBytes:
+		typeName, body = "bytes", base64.StdEncoding.EncodeToString(v.Value)
+	case *PODRectangle:
+		typeName, body = "rectangle", jsonRectangle{v.Width, v.Height}
+	case *PODFraction:
+		typeName, body = "fraction", jsonFraction{v.Num, v.Den}
+	case *PODPointer:
+		typeName, body = "pointer", jsonPointer{v.Type, v.Value}
+	case *PODFd:
+		typeName, body = "fd", v.Value
+	case *PODRaw:
+		typeName, body = "raw", base64.StdEncoding.EncodeToString(v.Data)
+	case *PODArray:
+		values, err := podsToJSON(v.Values, names)
+		if err != nil {
+			return nil, fmt.Errorf("array: %w", err)
+		}
+		typeName = "array"
+		body = jsonArray{ChildType: PODTypeFromID(childType(v.ChildType, v.Values)), Values: values}
+	case *PODChoice:
+		values, err := podsToJSON(v.Values, names)
+		if err != nil {
+			return nil, fmt.Errorf("choice: %w", err)
+		}
+		typeName = "choice"
+		body = jsonChoice{
+			Type:      v.Type,
+			Flags:     v.Flags,
+			ChildType: PODTypeFromID(childType(v.ChildType, v.Values)),
+			Values:    values,
+		}
+	case *PODStruct:
+		fields, err := podsToJSON(v.Fields, nil)
+		if err != nil {
+			return nil, fmt.Errorf("struct: %w", err)
+		}
+		typeName, body = "struct", fields
+	case *PODObject:
+		obj := jsonObject{
+			Type:  jsonID(objectTypeTable, v.Type),
+			ID:    jsonID(paramTable, v.ID),
+			Props: make([]jsonProp, 0, len(v.Props)),
+		}
+		keys := objectKeyTables[v.Type]
+		for _, prop := range v.Props {
+			value, err := podToJSON(prop.Value, valueTable(v.Type, prop.Key))
+			if err != nil {
+				return nil, fmt.Errorf("property %d: %w", prop.Key, err)
+			}
+			obj.Props = append(obj.Props, jsonProp{Key: jsonID(keys, prop.Key), Flags: prop.Flags, Value: value})
+		}
+		typeName, body = "object", obj
+	case *PODSequence:
+		seq := jsonSequence{Unit: v.Unit, Controls: make([]jsonControl, 0, len(v.Controls))}
+		for i, control := range v.Controls {
+			value, err := podToJSON(control.Value, nil)
+			if err != nil {
+				return nil, fmt.Errorf("control %d: %w", i, err)
+			}
+			seq.Controls = append(seq.Controls, jsonControl{Offset: control.Offset, Type: control.Type, Value: value})
+		}
+		typeName, body = "sequence", seq
+	default:
+		// Values of other packages are kept by their encoding
+		data, err := pod.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		typeName, body = "raw", base64.StdEncoding.EncodeToString(data)
+	}
+	return json.Marshal(map[string]any{typeName: body})
+}
+
+func podsToJSON(pods []PODValue, names *idTable) ([]json.RawMessage, error) {
+	out := make([]json.RawMessage, 0, len(pods))
+	for i, pod := range pods {
+		data, err := podToJSON(pod, names)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+		out = append(out, data)
+	}
+	return out, nil
+}
+
+// jsonID encodes an id by its name in names, or as a number
+func jsonID(names *idTable, id uint32) json.RawMessage {
+	if name := names.name(id); name != "" {
+		data, _ := json.Marshal(name)
+		return data
+	}
+	return json.RawMessage(strconv.FormatUint(uint64(id), 10))
+}
+
+// jsonFloat encodes a float in the shortest form reading back to the same
+// value, and the values JSON numbers cannot hold as strings
+func jsonFloat(f float64, bitSize int) any {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// ============================================================================
+// DECODE
+// ============================================================================
+
+func podBodyFromJSON(typeName string, body json.RawMessage) (PODValue, error) {
+	switch typeName {
+	case "none":
+		return NewPODNone(), nil
+	case "bool":
+		var b bool
+		if err := decodeJSON(body, &b); err != nil {
+			return nil, err
+		}
+		return NewPODBool(b), nil
+	case "id":
+		id, err := idFromJSON(body)
+		if err != nil {
+			return nil, err
+		}
+		return NewPODId(id), nil
+	case "int":
+		var i int32
+		if err := decodeJSON(body, &i); err != nil {
+			return nil, err
+		}
+		return NewPODInt32(i), nil
+	case "long":
+		var i int64
+		if err := decodeJSON(body, &i); err != nil {
+			return nil, err
+		}
+		return NewPODInt64(i), nil
+	case "float":
+		f, err := floatFromJSON(body, 32)
+		if err != nil {
+			return nil, err
+		}
+		return NewPODFloat(float32(f)), nil
+	case "double":
+		f, err := floatFromJSON(body, 64)
+		if err != nil {
+			return nil, err
+		}
+		return NewPODDouble(f), nil
+	case "string":
+		var s string
+		if err := decodeJSON(body, &s); err != nil {
+			return nil, err
+		}
+		return NewPODString(s), nil
+	case "bytes":
+		b, err := bytesFromJSON(body)
+		if err != nil {
+			return nil, err
+		}
+		return &PODBytes{Value: b}, nil
+	case "rectangle":
+		var r jsonRectangle
+		if err := decodeJSON(body, &r); err != nil {
+			return nil, err
+		}
+		return NewPODRectangle(r.Width, r.Height), nil
+	case "fraction":
+		var f jsonFraction
+		if err := decodeJSON(body, &f); err != nil {
+			return nil, err
+		}
+		return NewPODFraction(f.Num, f.Den), nil
+	case "pointer":
+		var p jsonPointer
+		if err := decodeJSON(body, &p); err != nil {
+			return nil, err
+		}
+		return NewPODPointer(p.Type, p.Value), nil
+	case "fd":
+		var fd int64
+		if err := decodeJSON(body, &fd); err != nil {
+			return nil, err
+		}
+		return NewPODFd(fd), nil
+	case "raw":
+		b, err := bytesFromJSON(body)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < podHeaderSize || int(binary.LittleEndian.Uint32(b[0:4])) > len(b)-podHeaderSize {
+			return nil, fmt.Errorf("raw POD of %d bytes is truncated", len(b))
+		}
+		return &PODRaw{Data: b}, nil
+	case "array":
+		var a jsonArray
+		if err := decodeJSON(body, &a); err != nil {
+			return nil, err
+		}
+		values, err := podsFromJSON(a.Values)
+		if err != nil {
+			return nil, err
+		}
+		child, err := childTypeFromJSON(a.ChildType, values)
+		if err != nil {
+			return nil, err
+		}
+		return &PODArray{ChildType: child, Values: values}, nil
+	case "choice":
+		var c jsonChoice
+		if err := decodeJSON(body, &c); err != nil {
+			return nil, err
+		}
+		values, err := podsFromJSON(c.Values)
+		if err != nil {
+			return nil, err
+		}
+		child, err := childTypeFromJSON(c.ChildType, values)
+		if err != nil {
+			return nil, err
+		}
+		return &PODChoice{Type: c.Type, Flags: c.Flags, ChildType: child, Values: values}, nil
+	case "struct":
+		var fields []json.RawMessage
+		if err := decodeJSON(body, &fields); err != nil {
+			return nil, err
+		}
+		values, err := podsFromJSON(fields)
+		if err != nil {
+			return nil, err
+		}
+		return NewPODStruct(values...), nil
+	case "object":
+		var o jsonObject
+		if err := decodeJSON(body, &o); err != nil {
+			return nil, err
+		}
+		objType, err := idFromJSON(o.Type)
+		if err != nil {
+			return nil, fmt.Errorf("type: %w", err)
+		}
+		id, err := idFromJSON(o.ID)
+		if err != nil {
+			return nil, fmt.Errorf("id: %w", err)
+		}
+		obj := NewPODObject(objType, id)
+		for i, p := range o.Props {
+			key, err := idFromJSON(p.Key)
+			if err != nil {
+				return nil, fmt.Errorf("property %d key: %w", i, err)
+			}
+			value, err := PODFromJSON(p.Value)
+			if err != nil {
+				return nil, fmt.Errorf("property %d: %w", i, err)
+			}
+			obj.Props = append(obj.Props, PODProp{Key: key, Flags: p.Flags, Value: value})
+		}
+		return obj, nil
+	case "sequence":
+		var s jsonSequence
+		if err := decodeJSON(body, &s); err != nil {
+			return nil, err
+		}
+		seq := NewPODSequence()
+		seq.Unit = s.Unit
+		for i, c := range s.Controls {
+			value, err := PODFromJSON(c.Value)
+			if err != nil {
+				return nil, fmt.Errorf("control %d: %w", i, err)
+			}
+			seq.Controls = append(seq.Controls, PODControl{Offset: c.Offset, Type: c.Type, Value: value})
+		}
+		return seq, nil
+	}
+	return nil, fmt.Errorf("unknown POD type")
+}
+
+func podsFromJSON(items []json.RawMessage) ([]PODValue, error) {
+	values := make([]PODValue, 0, len(items))
+	for i, item := range items {
+		val, err := PODFromJSON(item)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+		values = append(values, val)
+	}
+	return values, nil
+}
+
+// decodeJSON decodes body into v, refusing unknown members
+func decodeJSON(body json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// idFromJSON decodes an id given by C name or number
+func idFromJSON(body json.RawMessage) (uint32, error) {
+	var name string
+	if err := json.Unmarshal(body, &name); err == nil {
+		if id, ok := IDByName(name); ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("unknown id name %q", name)
+	}
+	var id uint32
+	if err := json.Unmarshal(body, &id); err != nil {
+		return 0, fmt.Errorf("id must be a name or an unsigned 32-bit number: %s", body)
+	}
+	return id, nil
+}
+
+// floatFromJSON decodes a float given as number or as one of the strings
+// jsonFloat writes
+func floatFromJSON(body json.RawMessage, bitSize int) (float64, error) {
+	var s string
+	if err := json.Unmarshal(body, &s); err == nil {
+		switch s {
+		case "NaN":
+			return math.NaN(), nil
+		case "+Inf", "Inf":
+			return math.Inf(1), nil
+		case "-Inf":
+			return math.Inf(-1), nil
+		}
+		return 0, fmt.Errorf("invalid float %q", s)
+	}
+	var n json.Number
+	if err := json.Unmarshal(body, &n); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(n.String(), bitSize)
+}
+
+func bytesFromJSON(body json.RawMessage) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(body, &s); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// childTypeFromJSON returns the element type of an array or choice, which
+// the values must all have
+func childTypeFromJSON(name string, values []PODValue) (uint32, error) {
+	child := PODTypeIDFromString(name)
+	if child == PODTypeInvalid {
+		return 0, fmt.Errorf("unknown child type %q", name)
+	}
+	for i, val := range values {
+		if got := podTypeID(val); got != child {
+			return 0, fmt.Errorf("value %d is %s, not %s", i, PODTypeFromID(got), name)
+		}
+	}
+	return child, nil
+}
@@ -0,0 +1,129 @@
+// Package spa - Tests for POD JSON encoding
+// spa/json_test.go
+
+package spa
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestPODJSONRoundTrip tests that every value type encodes back to the same
+// bytes after a trip through JSON
+func TestPODJSONRoundTrip(t *testing.T) {
+	format, err := NewPODParser(enumFormatPOD(t)).ParseValue()
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	tests := []struct {
+		name string
+		pod  PODValue
+	}{
+		{"format", format},
+		{"none", NewPODNone()},
+		{"uint32", NewPODUint32(math.MaxUint32)},
+		{"uint64", NewPODUint64(math.MaxUint64)},
+		{"float", NewPODFloat(0.1)},
+		{"nan", NewPODFloat(float32(math.NaN()))},
+		{"inf", NewPODDouble(math.Inf(-1))},
+		{"bytes", NewPODBytes([]byte{0, 1, 0xff})},
+		{"rectangle", NewPODRectangle(640, 480)},
+		{"fraction", NewPODFraction(25, 1)},
+		{"pointer", NewPODPointer(3, math.MaxUint64)},
+		{"fd", NewPODFd(-1)},
+		{"raw bitmap", NewPODRaw([]byte{2, 0, 0, 0, byte(PODTypeBitmap), 0, 0, 0, 0xaa, 0x55, 0, 0, 0, 0, 0, 0})},
+		{"empty array", &PODArray{ChildType: PODTypeFloat}},
+		{"empty choice", &PODChoice{Type: ChoiceTypeEnum, Flags: 1, ChildType: PODTypeLong}},
+		{"step", NewPODChoiceStep(NewPODDouble(1), NewPODDouble(0), NewPODDouble(2), NewPODDouble(0.5))},
+		{"struct", NewPODStruct(NewPODInt32(2), NewPODString("key"), NewPODStruct())},
+		{"sequence", NewPODSequenceBuilder().AddControl(64, ControlTypeMidi, NewPODBytes([]byte{0x90, 0x3c})).Build()},
+		{"unknown object", NewPODObjectBuilder(77, 88).PutProp(99, PropFlagHardware, NewPODId(5)).Build()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := PODToJSON(tt.pod)
+			if err != nil {
+				t.Fatalf("PODToJSON failed: %v", err)
+			}
+			got, err := PODFromJSON(data)
+			if err != nil {
+				t.Fatalf("PODFromJSON(%s) failed: %v", data, err)
+			}
+			if mustMarshal(t, got) != mustMarshal(t, tt.pod) {
+				t.Errorf("%s decodes to\n%s\nwant\n%s", data, FormatPOD(got), FormatPOD(tt.pod))
+			}
+		})
+	}
+}
+
+// TestPODToJSONNames tests that ids are written by name where known
+func TestPODToJSONNames(t *testing.T) {
+	data, err := PODToJSON(NewPODObjectBuilder(TypeObjectFormat, ParamFormat).
+		PutID(FormatMediaType, 1).
+		PutInt32(FormatAudioChannels, 2).
+		Build())
+	if err != nil {
+		t.Fatalf("PODToJSON failed: %v", err)
+	}
+	want := `{"object":{"type":"SPA_TYPE_OBJECT_Format","id":"SPA_PARAM_Format","props":[` +
+		`{"key":"SPA_FORMAT_mediaType","flags":0,"value":{"id":"SPA_MEDIA_TYPE_audio"}},` +
+		`{"key":"SPA_FORMAT_AUDIO_channels","flags":0,"value":{"int":2}}]}}`
+	if string(data) != want {
+		t.Errorf("PODToJSON() = %s, want %s", data, want)
+	}
+}
+
+// TestPODFromJSONFixture tests decoding a hand-written fixture mixing
+// names and numbers
+func TestPODFromJSONFixture(t *testing.T) {
+	fixture := `{"object": {"type": "SPA_TYPE_OBJECT_Props", "id": 2, "props": [
+		{"key": "SPA_PROP_volume", "flags": 0, "value": {"float": 0.5}},
+		{"key": 65540, "flags": 0, "value": {"bool": true}},
+		{"key": "SPA_PROP_channelVolumes", "flags": 0,
+		 "value": {"array": {"childType": "float", "values": [{"float": 1}, {"float": 0.25}]}}}
+	]}}`
+	got, err := PODFromJSON([]byte(fixture))
+	if err != nil {
+		t.Fatalf("PODFromJSON failed: %v", err)
+	}
+	want := NewPODObjectBuilder(TypeObjectProps, ParamProps).
+		PutFloat(PropVolume, 0.5).
+		PutBool(PropMute, true).
+		Put(PropChannelVolumes, NewPODArrayBuilder().AddFloat(1).AddFloat(0.25).Build()).
+		Build()
+	if mustMarshal(t, got) != mustMarshal(t, want) {
+		t.Errorf("fixture decodes to\n%s\nwant\n%s", FormatPOD(got), FormatPOD(want))
+	}
+}
+
+// TestPODFromJSONErrors tests rejecting malformed JSON
+func TestPODFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		json   string
+		errMsg string
+	}{
+		{"not an object", `[1]`, "cannot unmarshal"},
+		{"two members", `{"int": 1, "long": 2}`, "single member"},
+		{"unknown type", `{"vector": 1}`, "unknown POD type"},
+		{"int overflow", `{"int": 4294967296}`, "int"},
+		{"unknown id name", `{"id": "SPA_NOPE"}`, "unknown id name"},
+		{"negative id", `{"id": -1}`, "unsigned 32-bit"},
+		{"bad float", `{"float": "big"}`, "invalid float"},
+		{"bad base64", `{"bytes": "!!"}`, "illegal base64"},
+		{"truncated raw", `{"raw": "AQAAAA=="}`, "truncated"},
+		{"unknown member", `{"fraction": {"num": 1, "den": 2, "x": 3}}`, "unknown field"},
+		{"mixed array", `{"array": {"childType": "int", "values": [{"long": 1}]}}`, "value 0 is long, not int"},
+		{"unknown child type", `{"choice": {"type": "enum", "flags": 0, "childType": "blob", "values": []}}`, "unknown child type"},
+		{"bad prop", `{"object": {"type": 1, "id": 2, "props": [{"key": 1, "flags": 0, "value": {}}]}}`, "property 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PODFromJSON([]byte(tt.json))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("PODFromJSON(%s) error = %v, want %q", tt.json, err, tt.errMsg)
+			}
+		})
+	}
+}
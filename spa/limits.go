@@ -0,0 +1,62 @@
+// Package spa - Decoding limits
+// spa/limits.go
+// Bounds applied by PODParser to PODs received from an untrusted peer
+
+package spa
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Limits bounds the PODs a parser accepts
+// A zero field disables that limit
+type Limits struct {
+	MaxDepth        int // Containers nested inside the outer POD
+	MaxElements     int // Children of one array, struct, object, choice or sequence
+	MaxStringLength int // Bytes of one string or bytes value
+	MaxValues       int // PODs decoded by one parser, nested ones included
+}
+
+// DefaultLimits returns the limits of NewPODParser
+// They accept anything libpipewire sends while keeping a parser well below
+// the memory a malicious peer could otherwise make it allocate
+func DefaultLimits() Limits {
+	return Limits{
+		MaxDepth:        32,
+		MaxElements:     1 << 16,
+		MaxStringLength: 1 << 20,
+		MaxValues:       1 << 18,
+	}
+}
+
+// ErrLimitExceeded is matched by errors.Is for every LimitError
+var ErrLimitExceeded = errors.New("POD limit exceeded")
+
+// LimitError reports a POD rejected by one of the Limits
+type LimitError struct {
+	Limit string // "depth", "elements", "string length" or "values"
+	Value int
+	Max   int
+}
+
+// Ensure LimitError implements error interface
+var _ error = (*LimitError)(nil)
+
+// Error implements the error interface
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("POD %s %d exceeds limit %d", e.Limit, e.Value, e.Max)
+}
+
+// Is reports whether target is ErrLimitExceeded
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// checkLimit returns a LimitError when value is above a non-zero max
+func checkLimit(limit string, value, max int) error {
+	if max > 0 && value > max {
+		return &LimitError{Limit: limit, Value: value, Max: max}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package spa - Tests for wire POD decoding and limits
+// spa/limits_test.go
+
+package spa
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// pod frames a body with a POD header and padding
+func pod(podType uint32, body []byte) []byte {
+	return framePOD(podType, body)
+}
+
+// intPOD encodes an Int POD
+func intPOD(v int32) []byte {
+	return pod(PODTypeInt, binary.LittleEndian.AppendUint32(nil, uint32(v)))
+}
+
+// structPOD encodes a Struct POD of fields
+func structPOD(fields ...[]byte) []byte {
+	var body []byte
+	for _, f := range fields {
+		body = append(body, f...)
+	}
+	return pod(PODTypeStruct, body)
+}
+
+// intArray encodes an Array POD of Int elements
+func intArray(values ...int32) []byte {
+	body := binary.LittleEndian.AppendUint32(nil, 4)
+	body = binary.LittleEndian.AppendUint32(body, PODTypeInt)
+	for _, v := range values {
+		body = binary.LittleEndian.AppendUint32(body, uint32(v))
+	}
+	return pod(PODTypeArray, body)
+}
+
+// nested encodes depth structs around an Int
+func nested(depth int) []byte {
+	data := intPOD(1)
+	for i := 0; i < depth; i++ {
+		data = structPOD(data)
+	}
+	return data
+}
+
+// TestParseValue tests decoding of wire PODs
+func TestParseValue(t *testing.T) {
+	data := append(append(intPOD(-3), pod(PODTypeString, []byte("node\x00"))...), intArray(1, 2, 3)...)
+	p := NewPODParser(data)
+
+	v, err := p.ParseValue()
+	if i, ok := v.(*PODInt32); err != nil || !ok || i.Value != -3 {
+		t.Fatalf("expected int32(-3), got %v %v", v, err)
+	}
+	v, err = p.ParseValue()
+	if s, ok := v.(*PODString); err != nil || !ok || s.Value != "node" {
+		t.Fatalf("expected string(node), got %v %v", v, err)
+	}
+	v, err = p.ParseValue()
+	a, ok := v.(*PODArray)
+	if err != nil || !ok || len(a.Values) != 3 || a.Values[2].(*PODInt32).Value != 3 {
+		t.Fatalf("expected array of 3 ints, got %v %v", v, err)
+	}
+	if p.Offset() != len(data) {
+		t.Errorf("offset %d, want %d", p.Offset(), len(data))
+	}
+
+	// Types without a value are checked and kept whole
+	b := pod(PODTypeBitmap, []byte{1, 2, 3})
+	v, err = NewPODParser(b).ParseValue()
+	if raw, ok := v.(*PODRaw); err != nil || !ok || string(raw.Data) != string(b) {
+		t.Errorf("expected the bitmap as raw, got %v %v", v, err)
+	}
+}
+
+// TestParseValueLimits tests that each limit is enforced
+func TestParseValueLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		limits Limits
+		limit  string
+	}{
+		{"depth", nested(4), Limits{MaxDepth: 3}, "depth"},
+		{"elements", intArray(1, 2, 3), Limits{MaxElements: 2}, "elements"},
+		{"struct fields", structPOD(intPOD(1), intPOD(2)), Limits{MaxElements: 1}, "elements"},
+		{"string", pod(PODTypeString, []byte("abcdef\x00")), Limits{MaxStringLength: 4}, "string length"},
+		{"bytes", pod(PODTypeBytes, []byte("abcdef")), Limits{MaxStringLength: 4}, "string length"},
+		{"values", structPOD(intPOD(1), intArray(1, 2)), Limits{MaxValues: 3}, "values"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPODParser(tt.data)
+			if err := p.SkipValue(); err != nil {
+				t.Fatalf("default limits rejected the POD: %v", err)
+			}
+
+			p = NewPODParser(tt.data)
+			p.SetLimits(tt.limits)
+			_, err := p.ParseValue()
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != tt.limit || !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("expected %s LimitError, got %v", tt.limit, err)
+			}
+			if p.Offset() != 0 {
+				t.Errorf("offset moved to %d on error", p.Offset())
+			}
+		})
+	}
+
+	// Unmarshal goes through the parser and its default limits
+	huge := intArray(make([]int32, DefaultLimits().MaxElements+1)...)
+	if err := (&PODArray{}).Unmarshal(huge); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected a limit error for a huge array, got %v", err)
+	}
+}
+
+// TestParseValueMalformed tests rejection of PODs that do not fit their data
+func TestParseValueMalformed(t *testing.T) {
+	tests := map[string][]byte{
+		"short header":     {1, 0, 0},
+		"truncated body":   intPOD(1)[:10],
+		"short int":        pod(PODTypeInt, []byte{1, 2}),
+		"unterminated":     pod(PODTypeString, []byte("abc")),
+		"zero size array":  pod(PODTypeArray, []byte{0, 0, 0, 0, 4, 0, 0, 0, 1}),
+		"truncated field":  pod(PODTypeStruct, intPOD(1)[:10]),
+		"short object":     pod(PODTypeObject, []byte{1, 2}),
+		"truncated choice": pod(PODTypeChoice, []byte{1, 0, 0, 0, 0, 0, 0, 0, 4}),
+	}
+	for name, data := range tests {
+		if _, err := NewPODParser(data).ParseValue(); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+	if _, err := NewPODParser([]byte{0, 0, 0, 0}).ReadString(); err == nil {
+		t.Error("expected error for a string without terminator length")
+	}
+}
+
+// FuzzPODParserParseValue checks that no input panics or escapes the limits
+func FuzzPODParserParseValue(f *testing.F) {
+	f.Add(intPOD(7))
+	f.Add(intArray(1, 2, 3))
+	f.Add(nested(3))
+	f.Add(structPOD(pod(PODTypeString, []byte("key\x00")), pod(PODTypeBytes, []byte{1, 2, 3})))
+	f.Add(pod(PODTypeObject, append([]byte{2, 0, 4, 0, 3, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0}, intPOD(5)...)))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p := NewPODParser(data)
+		p.SetLimits(Limits{MaxDepth: 8, MaxElements: 64, MaxStringLength: 256, MaxValues: 512})
+		for p.Offset() < len(data) {
+			if _, err := p.ParseValue(); err != nil {
+				return
+			}
+		}
+		if p.values > 512 {
+			t.Fatalf("decoded %d values past the limit", p.values)
+		}
+	})
+}
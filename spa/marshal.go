@@ -0,0 +1,593 @@
+// Package spa - Struct tag based POD marshalling
+// spa/marshal.go
+// Maps Go structs to Object PODs through `pod` struct tags:
+//
+//	type Props struct {
+//		_      struct{} `pod:"object=SPA_TYPE_OBJECT_Props,id=SPA_PARAM_Props"`
+//		Volume float32  `pod:"key=SPA_PROP_volume,choice"`
+//		Mute   *bool    `pod:"key=SPA_PROP_mute"`
+//	}
+//
+// Tag options of a field:
+//   - key=NAME: property key, an SPA C name or a number (required)
+//   - id: encode uint32 values, or the elements of a slice, as Id
+//   - choice: encode a None choice; decoding takes the default of a choice
+//   - choice=range|step|enum|flags: encode a slice as a choice of that type,
+//     the default first; decoding fills the slice with the choice values
+//   - omitempty: omit zero values; nil pointers and interfaces always are
+//
+// Go types map to POD types as follows: bool to Bool, int8 to int32 to Int,
+// int64 to Long, uint8 to uint32 to Int (Id with the id option), uint64 to
+// Long, float32 to Float, float64 to Double, string to String, []byte to
+// Bytes, PODFraction and PODRectangle to Fraction and Rectangle, slices and
+// arrays to Array, tagged structs to Object and PODValue fields verbatim;
+// types implementing Marshaler and Unmarshaler encode themselves
+
+package spa
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Marshal encodes v, a tagged struct or a pointer to one, as an Object POD
+func Marshal(v any) ([]byte, error) {
+	obj, err := MarshalPOD(v)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Marshal()
+}
+
+// MarshalPOD is Marshal returning the Object before encoding
+func MarshalPOD(v any) (*PODObject, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("spa: cannot marshal a nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("spa: cannot marshal %s, want a struct", rv.Type())
+	}
+	return marshalStruct(rv)
+}
+
+// Unmarshal decodes an Object POD into v, a pointer to a tagged struct
+// Properties without a field are ignored, fields without a property keep
+// their value
+func Unmarshal(data []byte, v any) error {
+	val, err := NewPODParser(data).ParseValue()
+	if err != nil {
+		return err
+	}
+	return UnmarshalPOD(val, v)
+}
+
+// UnmarshalPOD is Unmarshal from a decoded POD
+func UnmarshalPOD(val PODValue, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("spa: cannot unmarshal into %T, want a non-nil pointer", v)
+	}
+	return unmarshalValue(val, rv.Elem(), fieldOptions{})
+}
+
+// Marshaler is implemented by field types encoding themselves as a POD,
+// such as a property that is either a value or a choice of values
+type Marshaler interface {
+	MarshalPOD() (PODValue, error)
+}
+
+// Unmarshaler is implemented by field types decoding themselves from a POD
+type Unmarshaler interface {
+	UnmarshalPOD(val PODValue) error
+}
+
+// ============================================================================
+// STRUCT FIELDS
+// ============================================================================
+
+// fieldOptions are the options of a `pod` tag
+type fieldOptions struct {
+	id        bool
+	choice    bool
+	choiceOf  ChoiceType
+	omitEmpty bool
+}
+
+// structField is a tagged field of a struct
+type structField struct {
+	index []int
+	name  string
+	key   uint32
+	opts  fieldOptions
+}
+
+// structInfo is the object layout of a struct type
+type structInfo struct {
+	objType    uint32
+	id         uint32
+	hasObjType bool
+	fields     []structField
+}
+
+var structInfos sync.Map // reflect.Type -> *structInfo or error
+
+// getStructInfo parses and caches the tags of a struct type
+func getStructInfo(t reflect.Type) (*structInfo, error) {
+	if cached, ok := structInfos.Load(t); ok {
+		if err, ok := cached.(error); ok {
+			return nil, err
+		}
+		return cached.(*structInfo), nil
+	}
+	info, err := parseStructInfo(t)
+	if err != nil {
+		structInfos.Store(t, err)
+		return nil, err
+	}
+	structInfos.Store(t, info)
+	return info, nil
+}
+
+func parseStructInfo(t reflect.Type) (*structInfo, error) {
+	info := &structInfo{}
+	keys := make(map[uint32]string)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("pod")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		if f.Name == "_" {
+			if err := parseObjectTag(tag, info); err != nil {
+				return nil, fmt.Errorf("spa: %s: %w", t, err)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			return nil, fmt.Errorf("spa: %s.%s: tagged field is not exported", t, f.Name)
+		}
+
+		field := structField{index: f.Index, name: f.Name}
+		hasKey := false
+		for _, opt := range strings.Split(tag, ",") {
+			name, value, _ := strings.Cut(opt, "=")
+			switch name {
+			case "key":
+				key, err := parseID(value)
+				if err != nil {
+					return nil, fmt.Errorf("spa: %s.%s: %w", t, f.Name, err)
+				}
+				field.key, hasKey = key, true
+			case "id":
+				field.opts.id = true
+			case "choice":
+				field.opts.choice = true
+				if value != "" {
+					choiceType, err := parseChoiceType(value)
+					if err != nil {
+						return nil, fmt.Errorf("spa: %s.%s: %w", t, f.Name, err)
+					}
+					field.opts.choiceOf = choiceType
+				}
+			case "omitempty":
+				field.opts.omitEmpty = true
+			default:
+				return nil, fmt.Errorf("spa: %s.%s: unknown tag option %q", t, f.Name, opt)
+			}
+		}
+		if !hasKey {
+			return nil, fmt.Errorf("spa: %s.%s: tag has no key", t, f.Name)
+		}
+		if other, dup := keys[field.key]; dup {
+			return nil, fmt.Errorf("spa: %s.%s: key %d already used by %s", t, f.Name, field.key, other)
+		}
+		keys[field.key] = f.Name
+		info.fields = append(info.fields, field)
+	}
+	return info, nil
+}
+
+// parseObjectTag parses the object=,id= tag of a blank field
+func parseObjectTag(tag string, info *structInfo) error {
+	for _, opt := range strings.Split(tag, ",") {
+		name, value, _ := strings.Cut(opt, "=")
+		id, err := parseID(value)
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "object":
+			info.objType, info.hasObjType = id, true
+		case "id":
+			info.id = id
+		default:
+			return fmt.Errorf("unknown object tag option %q", opt)
+		}
+	}
+	return nil
+}
+
+// parseID parses an SPA C name or a number
+func parseID(s string) (uint32, error) {
+	if id, ok := IDByName(s); ok {
+		return id, nil
+	}
+	id, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unknown id %q", s)
+	}
+	return uint32(id), nil
+}
+
+func parseChoiceType(s string) (ChoiceType, error) {
+	for choiceType, name := range choiceTypeNames {
+		if name == s {
+			return choiceType, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown choice type %q", s)
+}
+
+// ============================================================================
+// MARSHAL
+// ============================================================================
+
+var (
+	podValueType    = reflect.TypeOf((*PODValue)(nil)).Elem()
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	fractionType    = reflect.TypeOf(PODFraction{})
+	rectangleType   = reflect.TypeOf(PODRectangle{})
+)
+
+func marshalStruct(rv reflect.Value) (*PODObject, error) {
+	info, err := getStructInfo(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	obj := NewPODObject(info.objType, info.id)
+	for _, f := range info.fields {
+		fv := rv.FieldByIndex(f.index)
+		if f.opts.omitEmpty && fv.IsZero() {
+			continue
+		}
+		val, err := marshalValue(fv, f.opts)
+		if err != nil {
+			return nil, fmt.Errorf("spa: field %s: %w", f.name, err)
+		}
+		if val != nil {
+			obj.Props = append(obj.Props, PODProp{Key: f.key, Value: val})
+		}
+	}
+	return obj, nil
+}
+
+// marshalValue encodes a field value, nil for absent pointers and interfaces
+func marshalValue(rv reflect.Value, opts fieldOptions) (PODValue, error) {
+	if opts.choice {
+		if opts.choiceOf == ChoiceTypeNone {
+			val, err := marshalPlain(rv, opts)
+			if val == nil || err != nil {
+				return nil, err
+			}
+			return NewPODChoice(ChoiceTypeNone, val), nil
+		}
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, fmt.Errorf("%s choice of %s, want a slice", opts.choiceOf, rv.Type())
+		}
+		values, err := marshalElements(rv, opts)
+		if err != nil {
+			return nil, err
+		}
+		return NewPODChoice(opts.choiceOf, values...), nil
+	}
+	return marshalPlain(rv, opts)
+}
+
+func marshalPlain(rv reflect.Value, opts fieldOptions) (PODValue, error) {
+	if rv.Type().Implements(podValueType) {
+		if (rv.Kind() == reflect.Interface || rv.Kind() == reflect.Pointer) && rv.IsNil() {
+			return nil, nil
+		}
+		return rv.Interface().(PODValue), nil
+	}
+	if rv.Type().Implements(marshalerType) {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil, nil
+		}
+		return rv.Interface().(Marshaler).MarshalPOD()
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return marshalPlain(rv.Elem(), opts)
+	case reflect.Bool:
+		return NewPODBool(rv.Bool()), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int:
+		i := rv.Int()
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return nil, fmt.Errorf("%d overflows an Int", i)
+		}
+		return NewPODInt32(int32(i)), nil
+	case reflect.Int64:
+		return NewPODInt64(rv.Int()), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint:
+		u := rv.Uint()
+		if u > math.MaxUint32 {
+			return nil, fmt.Errorf("%d overflows an Int", u)
+		}
+		if opts.id {
+			return NewPODId(uint32(u)), nil
+		}
+		return NewPODInt32(int32(uint32(u))), nil
+	case reflect.Uint64:
+		return NewPODInt64(int64(rv.Uint())), nil
+	case reflect.Float32:
+		return NewPODFloat(float32(rv.Float())), nil
+	case reflect.Float64:
+		return NewPODDouble(rv.Float()), nil
+	case reflect.String:
+		return NewPODString(rv.String()), nil
+	case reflect.Struct:
+		switch rv.Type() {
+		case fractionType:
+			f := rv.Interface().(PODFraction)
+			return &f, nil
+		case rectangleType:
+			r := rv.Interface().(PODRectangle)
+			return &r, nil
+		}
+		return marshalStruct(rv)
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return NewPODBytes(rv.Bytes()), nil
+		}
+		values, err := marshalElements(rv, opts)
+		if err != nil {
+			return nil, err
+		}
+		arr := &PODArray{ChildType: childTypeOf(rv.Type().Elem(), opts), Values: values}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", rv.Type())
+}
+
+// marshalElements encodes the elements of a slice or array
+func marshalElements(rv reflect.Value, opts fieldOptions) ([]PODValue, error) {
+	values := make([]PODValue, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		val, err := marshalPlain(rv.Index(i), opts)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		if val == nil {
+			return nil, fmt.Errorf("element %d is nil", i)
+		}
+		values = append(values, val)
+	}
+	return values, nil
+}
+
+// childTypeOf returns the POD type of the elements of an empty array
+func childTypeOf(t reflect.Type, opts fieldOptions) uint32 {
+	switch t.Kind() {
+	case reflect.Bool:
+		return PODTypeBool
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int:
+		return PODTypeInt
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint:
+		if opts.id {
+			return PODTypeID
+		}
+		return PODTypeInt
+	case reflect.Int64, reflect.Uint64:
+		return PODTypeLong
+	case reflect.Float32:
+		return PODTypeFloat
+	case reflect.Float64:
+		return PODTypeDouble
+	}
+	switch t {
+	case fractionType:
+		return PODTypeFraction
+	case rectangleType:
+		return PODTypeRectangle
+	}
+	return PODTypeNone
+}
+
+// ============================================================================
+// UNMARSHAL
+// ============================================================================
+
+func unmarshalStruct(obj *PODObject, rv reflect.Value) error {
+	info, err := getStructInfo(rv.Type())
+	if err != nil {
+		return err
+	}
+	if info.hasObjType && obj.Type != info.objType {
+		return fmt.Errorf("spa: object type %#x, %s wants %#x", obj.Type, rv.Type(), info.objType)
+	}
+	for _, f := range info.fields {
+		val, ok := obj.Get(f.key)
+		if !ok {
+			continue
+		}
+		if err := unmarshalValue(val, rv.FieldByIndex(f.index), f.opts); err != nil {
+			return fmt.Errorf("spa: field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// unmarshalValue decodes val into rv
+// A choice fills a slice with its values and any other type with its default
+func unmarshalValue(val PODValue, rv reflect.Value, opts fieldOptions) error {
+	if rv.Type().Implements(podValueType) || rv.Type() == podValueType {
+		v := reflect.ValueOf(val)
+		if !v.Type().AssignableTo(rv.Type()) {
+			return fmt.Errorf("cannot decode %s into %s", val.PODType().Name(), rv.Type())
+		}
+		rv.Set(v)
+		return nil
+	}
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshalValue(val, rv.Elem(), opts)
+	}
+	if rv.CanAddr() && rv.Addr().Type().Implements(unmarshalerType) {
+		return rv.Addr().Interface().(Unmarshaler).UnmarshalPOD(val)
+	}
+
+	if choice, ok := val.(*PODChoice); ok {
+		isBytes := rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8
+		if rv.Kind() == reflect.Slice && !isBytes {
+			return unmarshalElements(choice.Values, rv, opts)
+		}
+		if choice.Default() == nil {
+			return fmt.Errorf("empty choice")
+		}
+		val = choice.Default()
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("cannot decode %s into %s", val.PODType().Name(), rv.Type())
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		b, ok := val.(*PODBool)
+		if !ok {
+			return mismatch()
+		}
+		rv.SetBool(b.Value)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int, reflect.Int64:
+		i, ok := podInt(val)
+		if !ok {
+			return mismatch()
+		}
+		if rv.OverflowInt(i) {
+			return fmt.Errorf("%d overflows %s", i, rv.Type())
+		}
+		rv.SetInt(i)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		i, ok := podInt(val)
+		if !ok {
+			return mismatch()
+		}
+		u := uint64(i)
+		if _, isInt := val.(*PODInt32); isInt {
+			u = uint64(uint32(i))
+		}
+		if rv.OverflowUint(u) {
+			return fmt.Errorf("%d overflows %s", u, rv.Type())
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		switch f := val.(type) {
+		case *PODFloat:
+			rv.SetFloat(float64(f.Value))
+		case *PODDouble:
+			rv.SetFloat(f.Value)
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		s, ok := val.(*PODString)
+		if !ok {
+			return mismatch()
+		}
+		rv.SetString(s.Value)
+	case reflect.Struct:
+		switch rv.Type() {
+		case fractionType:
+			f, ok := val.(*PODFraction)
+			if !ok {
+				return mismatch()
+			}
+			rv.Set(reflect.ValueOf(*f))
+		case rectangleType:
+			r, ok := val.(*PODRectangle)
+			if !ok {
+				return mismatch()
+			}
+			rv.Set(reflect.ValueOf(*r))
+		default:
+			obj, ok := val.(*PODObject)
+			if !ok {
+				return mismatch()
+			}
+			return unmarshalStruct(obj, rv)
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b, ok := val.(*PODBytes)
+			if !ok {
+				return mismatch()
+			}
+			if rv.Kind() == reflect.Array {
+				reflect.Copy(rv, reflect.ValueOf(b.Value))
+				return nil
+			}
+			rv.SetBytes(append([]byte{}, b.Value...))
+			return nil
+		}
+		switch container := val.(type) {
+		case *PODArray:
+			return unmarshalElements(container.Values, rv, opts)
+		case *PODStruct:
+			return unmarshalElements(container.Fields, rv, opts)
+		}
+		return mismatch()
+	default:
+		return fmt.Errorf("unsupported type %s", rv.Type())
+	}
+	return nil
+}
+
+// unmarshalElements decodes values into a slice, or an array of that length
+func unmarshalElements(values []PODValue, rv reflect.Value, opts fieldOptions) error {
+	if rv.Kind() == reflect.Array {
+		if rv.Len() != len(values) {
+			return fmt.Errorf("%d values for %s", len(values), rv.Type())
+		}
+	} else {
+		rv.Set(reflect.MakeSlice(rv.Type(), len(values), len(values)))
+	}
+	for i, val := range values {
+		if err := unmarshalValue(val, rv.Index(i), opts); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// podInt returns the value of an integer or Id POD
+func podInt(val PODValue) (int64, bool) {
+	switch v := val.(type) {
+	case *PODInt32:
+		return int64(v.Value), true
+	case *PODId:
+		return int64(v.Value), true
+	case *PODInt64:
+		return v.Value, true
+	case *PODUint32:
+		return int64(v.Value), true
+	case *PODUint64:
+		return int64(v.Value), true
+	}
+	return 0, false
+}
@@ -0,0 +1,166 @@
+// Package spa - Tests for struct tag POD marshalling
+// spa/marshal_test.go
+
+package spa
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type testChannelProps struct {
+	_        struct{}  `pod:"object=SPA_TYPE_OBJECT_Props,id=SPA_PARAM_Props"`
+	Volume   float32   `pod:"key=SPA_PROP_volume,choice"`
+	Mute     *bool     `pod:"key=SPA_PROP_mute"`
+	Volumes  []float32 `pod:"key=SPA_PROP_channelVolumes,omitempty"`
+	Channels []uint32  `pod:"key=SPA_PROP_channelMap,id,omitempty"`
+	Ignored  string
+}
+
+type testFormat struct {
+	_         struct{}    `pod:"object=SPA_TYPE_OBJECT_Format,id=SPA_PARAM_EnumFormat"`
+	MediaType uint32      `pod:"key=SPA_FORMAT_mediaType,id"`
+	Subtype   uint32      `pod:"key=SPA_FORMAT_mediaSubtype,id"`
+	Formats   []uint32    `pod:"key=SPA_FORMAT_AUDIO_format,id,choice=enum"`
+	Rate      []int32     `pod:"key=SPA_FORMAT_AUDIO_rate,choice=range"`
+	Channels  int         `pod:"key=SPA_FORMAT_AUDIO_channels"`
+	Position  []uint32    `pod:"key=SPA_FORMAT_AUDIO_position,id"`
+	Framerate PODFraction `pod:"key=0x20004,omitempty"`
+	Extra     PODValue    `pod:"key=0x99"`
+}
+
+// TestMarshal tests that tagged structs encode like the builders
+func TestMarshal(t *testing.T) {
+	mute := true
+	data, err := Marshal(&testChannelProps{Volume: 0.5, Mute: &mute, Ignored: "x"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want, _ := NewPODObjectBuilder(TypeObjectProps, ParamProps).
+		Put(PropVolume, NewPODChoice(ChoiceTypeNone, NewPODFloat(0.5))).
+		PutBool(PropMute, true).
+		Build().Marshal()
+	if !bytes.Equal(data, want) {
+		t.Errorf("unexpected encoding\ngot  %x\nwant %x", data, want)
+	}
+
+	// Choices, id arrays and ints match the EnumFormat a node sends
+	data, err = Marshal(testFormat{
+		MediaType: 1,
+		Subtype:   1,
+		Formats:   []uint32{0x11b, 0x11b, 0x103},
+		Rate:      []int32{48000, 1, 384000},
+		Channels:  2,
+		Position:  []uint32{3, 4},
+	})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	obj, _ := NewPODParser(data).ParseValue()
+	want, _ = NewPODObjectBuilder(TypeObjectFormat, ParamEnumFormat).
+		PutID(FormatMediaType, 1).
+		PutID(FormatMediaSubtype, 1).
+		Put(FormatAudioFormat, NewPODChoiceBuilder(ChoiceTypeEnum).AddID(0x11b, 0x11b, 0x103).Build()).
+		Put(FormatAudioRate, NewPODChoiceRange(NewPODInt32(48000), NewPODInt32(1), NewPODInt32(384000))).
+		PutInt32(FormatAudioChannels, 2).
+		Put(FormatAudioPosition, NewPODArrayBuilder().AddID(3).AddID(4).Build()).
+		Build().Marshal()
+	if !bytes.Equal(data, want) {
+		t.Errorf("unexpected format encoding %v\ngot  %x\nwant %x", obj, data, want)
+	}
+}
+
+// TestUnmarshal tests decoding objects into tagged structs
+func TestUnmarshal(t *testing.T) {
+	var format testFormat
+	if err := Unmarshal(enumFormatPOD(t), &format); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if format.MediaType != 1 || format.Channels != 2 {
+		t.Errorf("unexpected scalars %+v", format)
+	}
+	if !reflect.DeepEqual(format.Formats, []uint32{0x11b, 0x11b, 0x103}) {
+		t.Errorf("unexpected formats %x", format.Formats)
+	}
+	if !reflect.DeepEqual(format.Rate, []int32{48000, 1, 384000}) {
+		t.Errorf("unexpected rate %v", format.Rate)
+	}
+	if !reflect.DeepEqual(format.Position, []uint32{3, 4}) {
+		t.Errorf("unexpected position %v", format.Position)
+	}
+
+	// Scalars take the default of a choice, pointers are allocated
+	data, _ := NewPODObjectBuilder(TypeObjectProps, ParamProps).
+		Put(PropVolume, NewPODChoiceRange(NewPODFloat(0.25), NewPODFloat(0), NewPODFloat(1))).
+		PutBool(PropMute, true).
+		Put(PropChannelVolumes, NewPODArrayBuilder().AddFloat(0.1).AddFloat(0.2).Build()).
+		Build().Marshal()
+	var props testChannelProps
+	if err := Unmarshal(data, &props); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if props.Volume != 0.25 || props.Mute == nil || !*props.Mute || len(props.Volumes) != 2 {
+		t.Errorf("unexpected props %+v", props)
+	}
+
+	// Round trip through PODValue fields
+	format.Extra = NewPODString("extra")
+	data, err := Marshal(&format)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var again testFormat
+	if err := Unmarshal(data, &again); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if s, ok := again.Extra.(*PODString); !ok || s.Value != "extra" {
+		t.Errorf("unexpected extra %v", again.Extra)
+	}
+}
+
+// TestMarshalErrors tests rejected structs and mismatched objects
+func TestMarshalErrors(t *testing.T) {
+	type noKey struct {
+		A int32 `pod:"id"`
+	}
+	type badKey struct {
+		A int32 `pod:"key=SPA_PROP_nope"`
+	}
+	type dupKey struct {
+		A int32 `pod:"key=1"`
+		B int32 `pod:"key=1"`
+	}
+	type badType struct {
+		A map[string]int `pod:"key=1"`
+	}
+	type overflow struct {
+		A int `pod:"key=1"`
+	}
+	for name, v := range map[string]any{
+		"no key":   noKey{},
+		"bad key":  badKey{},
+		"dup key":  dupKey{},
+		"bad type": badType{A: map[string]int{}},
+		"overflow": overflow{A: 1 << 40},
+		"not obj":  42,
+	} {
+		if _, err := Marshal(v); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	// Object types must match, values must fit
+	data, _ := NewPODObjectBuilder(TypeObjectFormat, ParamFormat).Build().Marshal()
+	var props testChannelProps
+	if err := Unmarshal(data, &props); err == nil {
+		t.Error("expected error for a Format decoded as Props")
+	}
+	data, _ = NewPODObjectBuilder(TypeObjectProps, ParamProps).PutString(PropVolume, "loud").Build().Marshal()
+	if err := Unmarshal(data, &props); err == nil {
+		t.Error("expected error for a String decoded as float32")
+	}
+	if err := Unmarshal(data, props); err == nil {
+		t.Error("expected error for a non-pointer")
+	}
+}
@@ -0,0 +1,138 @@
+// Package param - Audio formats
+// spa/param/format.go
+// EnumFormat and Format params of raw audio, and the SPA_AUDIO_FORMAT_* ids
+// their sample formats use
+
+package param
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// Sample formats (SPA_AUDIO_FORMAT_*), the common ones of the full list
+// that spa.IDByName knows
+const (
+	AudioFormatUnknown  uint32 = 0
+	AudioFormatS8       uint32 = 0x101
+	AudioFormatU8       uint32 = 0x102
+	AudioFormatS16LE    uint32 = 0x103
+	AudioFormatS16BE    uint32 = 0x104
+	AudioFormatS24_32LE uint32 = 0x107
+	AudioFormatS32LE    uint32 = 0x10b
+	AudioFormatS32BE    uint32 = 0x10c
+	AudioFormatS24LE    uint32 = 0x10f
+	AudioFormatS24BE    uint32 = 0x110
+	AudioFormatF32LE    uint32 = 0x11b
+	AudioFormatF32BE    uint32 = 0x11c
+	AudioFormatF64LE    uint32 = 0x11d
+	AudioFormatF64BE    uint32 = 0x11e
+	AudioFormatS16P     uint32 = 0x202
+	AudioFormatS32P     uint32 = 0x204
+	AudioFormatF32P     uint32 = 0x206
+	AudioFormatF64P     uint32 = 0x207
+)
+
+// ParseAudioFormat returns the id of a sample format named as in node
+// properties ("S16LE", "F32P"), as in C without prefix ("S16_LE"), or
+// without endianness for little endian ("F32")
+func ParseAudioFormat(name string) (uint32, bool) {
+	name = strings.ToUpper(name)
+	candidates := []string{name}
+	for _, suffix := range []string{"LE", "BE"} {
+		if strings.HasSuffix(name, suffix) && !strings.HasSuffix(name, "_"+suffix) {
+			candidates = append(candidates, strings.TrimSuffix(name, suffix)+"_"+suffix)
+		}
+	}
+	candidates = append(candidates, name+"_LE")
+	for _, c := range candidates {
+		if id, ok := spa.IDByName("SPA_AUDIO_FORMAT_" + c); ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// Format is the negotiated format of a node or port, for raw audio
+// (SPA_TYPE_OBJECT_Format)
+type Format struct {
+	_            struct{} `pod:"object=SPA_TYPE_OBJECT_Format,id=SPA_PARAM_Format"`
+	MediaType    uint32   `pod:"key=SPA_FORMAT_mediaType,id"`          // MediaTypeAudio
+	MediaSubtype uint32   `pod:"key=SPA_FORMAT_mediaSubtype,id"`       // MediaSubtypeRaw
+	Format       uint32   `pod:"key=SPA_FORMAT_AUDIO_format,id"`       // AudioFormat*
+	Flags        uint32   `pod:"key=SPA_FORMAT_AUDIO_flags,omitempty"` // SPA_AUDIO_FLAG_*
+	Rate         uint32   `pod:"key=SPA_FORMAT_AUDIO_rate"`
+	Channels     uint32   `pod:"key=SPA_FORMAT_AUDIO_channels"`
+	Position     []uint32 `pod:"key=SPA_FORMAT_AUDIO_position,id,omitempty"` // SPA_AUDIO_CHANNEL_* per channel
+}
+
+// NewAudioFormat returns a raw audio Format
+func NewAudioFormat(format, rate, channels uint32, position ...uint32) *Format {
+	return &Format{
+		MediaType:    MediaTypeAudio,
+		MediaSubtype: MediaSubtypeRaw,
+		Format:       format,
+		Rate:         rate,
+		Channels:     channels,
+		Position:     position,
+	}
+}
+
+func (f *Format) checkAudioRaw() error {
+	return checkAudioRaw(f.MediaType, f.MediaSubtype)
+}
+
+// EnumFormat is a format a node or port supports, for raw audio
+// (SPA_TYPE_OBJECT_Format); properties are fixed or choices
+type EnumFormat struct {
+	_            struct{}  `pod:"object=SPA_TYPE_OBJECT_Format,id=SPA_PARAM_EnumFormat"`
+	MediaType    uint32    `pod:"key=SPA_FORMAT_mediaType,id"`
+	MediaSubtype uint32    `pod:"key=SPA_FORMAT_mediaSubtype,id"`
+	Format       IDChoice  `pod:"key=SPA_FORMAT_AUDIO_format,omitempty"`
+	Flags        IntChoice `pod:"key=SPA_FORMAT_AUDIO_flags,omitempty"`
+	Rate         IntChoice `pod:"key=SPA_FORMAT_AUDIO_rate,omitempty"`
+	Channels     IntChoice `pod:"key=SPA_FORMAT_AUDIO_channels,omitempty"`
+	Position     []uint32  `pod:"key=SPA_FORMAT_AUDIO_position,id,omitempty"`
+}
+
+func (f *EnumFormat) checkAudioRaw() error {
+	return checkAudioRaw(f.MediaType, f.MediaSubtype)
+}
+
+// checkAudioRaw refuses formats other than raw audio, whose keys differ
+func checkAudioRaw(mediaType, mediaSubtype uint32) error {
+	if mediaType != MediaTypeAudio || mediaSubtype != MediaSubtypeRaw {
+		return fmt.Errorf("unsupported format %d/%d, only audio/raw is", mediaType, mediaSubtype)
+	}
+	return nil
+}
+
+// Negotiate intersects the formats two ports support and fixates the
+// result, giving the format a link between them would use
+// Incompatible formats give a *spa.FilterError naming the property without
+// a common value
+func Negotiate(a, b *EnumFormat) (*Format, error) {
+	pa, err := spa.MarshalPOD(a)
+	if err != nil {
+		return nil, err
+	}
+	pb, err := spa.MarshalPOD(b)
+	if err != nil {
+		return nil, err
+	}
+	filtered, err := spa.Filter(pa, pb)
+	if err != nil {
+		return nil, err
+	}
+	fixed, err := spa.Fixate(filtered)
+	if err != nil {
+		return nil, err
+	}
+	format := &Format{}
+	if err := spa.UnmarshalPOD(fixed, format); err != nil {
+		return nil, err
+	}
+	return format, format.checkAudioRaw()
+}
@@ -0,0 +1,216 @@
+// Package param - Typed SPA params
+// spa/param/param.go
+// Structs for the standard params of nodes, ports and devices, laid out as
+// libspa encodes them (spa/param/*.h), with decoders and encoders built on
+// the struct tags of spa.Marshal
+
+package param
+
+import (
+	"fmt"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// Decode decodes the encoded param id, as received in a Param event, into
+// its typed struct: *EnumFormat, *Format, *Props, *PropInfo, *Buffers,
+// *Meta, *IO, *Profile, *Route, *Latency or *ProcessLatency
+// EnumProfile and EnumRoute decode to *Profile and *Route
+func Decode(id uint32, data []byte) (any, error) {
+	val, err := spa.NewPODParser(data).ParseValue()
+	if err != nil {
+		return nil, err
+	}
+	return DecodePOD(id, val)
+}
+
+// DecodePOD is Decode from a decoded POD
+func DecodePOD(id uint32, val spa.PODValue) (any, error) {
+	newParam, ok := paramTypes[id]
+	if !ok {
+		return nil, fmt.Errorf("param: unsupported param id %d", id)
+	}
+	p := newParam()
+	if err := spa.UnmarshalPOD(val, p); err != nil {
+		return nil, fmt.Errorf("param %d: %w", id, err)
+	}
+	if f, ok := p.(interface{ checkAudioRaw() error }); ok {
+		if err := f.checkAudioRaw(); err != nil {
+			return nil, fmt.Errorf("param %d: %w", id, err)
+		}
+	}
+	return p, nil
+}
+
+// Encode encodes v, one of the typed params, for SetParam or as a filter
+// of EnumParams; Profile and Route encode with the ids of the active
+// Profile and Route params
+func Encode(v any) ([]byte, error) {
+	return spa.Marshal(v)
+}
+
+// paramTypes allocates the typed struct of each param id
+var paramTypes = map[uint32]func() any{
+	spa.ParamEnumFormat:     func() any { return new(EnumFormat) },
+	spa.ParamFormat:         func() any { return new(Format) },
+	spa.ParamProps:          func() any { return new(Props) },
+	spa.ParamPropInfo:       func() any { return new(PropInfo) },
+	spa.ParamBuffers:        func() any { return new(Buffers) },
+	spa.ParamMeta:           func() any { return new(Meta) },
+	spa.ParamIO:             func() any { return new(IO) },
+	spa.ParamEnumProfile:    func() any { return new(Profile) },
+	spa.ParamProfile:        func() any { return new(Profile) },
+	spa.ParamEnumRoute:      func() any { return new(Route) },
+	spa.ParamRoute:          func() any { return new(Route) },
+	spa.ParamLatency:        func() any { return new(Latency) },
+	spa.ParamProcessLatency: func() any { return new(ProcessLatency) },
+}
+
+// ===== Wire Enumerations =====
+
+// Media types and subtypes (SPA_MEDIA_TYPE_*, SPA_MEDIA_SUBTYPE_*)
+const (
+	MediaTypeAudio     uint32 = 1
+	MediaTypeVideo     uint32 = 2
+	MediaSubtypeRaw    uint32 = 1
+	MediaSubtypeDSP    uint32 = 2
+	MediaSubtypeIEC958 uint32 = 3
+)
+
+// Directions of ports and routes (SPA_DIRECTION_*)
+const (
+	DirectionInput  uint32 = 0
+	DirectionOutput uint32 = 1
+)
+
+// Availability of profiles and routes (SPA_PARAM_AVAILABILITY_*)
+const (
+	AvailabilityUnknown uint32 = 0
+	AvailabilityNo      uint32 = 1
+	AvailabilityYes     uint32 = 2
+)
+
+// ===== Props =====
+
+// Props holds the adjustable properties of a node or route
+// (SPA_TYPE_OBJECT_Props); nil and empty fields are absent, so a Props
+// setting only some of them changes only those
+type Props struct {
+	_                 struct{}       `pod:"object=SPA_TYPE_OBJECT_Props,id=SPA_PARAM_Props"`
+	Volume            *float32       `pod:"key=SPA_PROP_volume"`
+	Mute              *bool          `pod:"key=SPA_PROP_mute"`
+	ChannelVolumes    []float32      `pod:"key=SPA_PROP_channelVolumes,omitempty"`
+	VolumeBase        *float32       `pod:"key=SPA_PROP_volumeBase"`
+	VolumeStep        *float32       `pod:"key=SPA_PROP_volumeStep"`
+	ChannelMap        []uint32       `pod:"key=SPA_PROP_channelMap,id,omitempty"`
+	MonitorMute       *bool          `pod:"key=SPA_PROP_monitorMute"`
+	MonitorVolumes    []float32      `pod:"key=SPA_PROP_monitorVolumes,omitempty"`
+	LatencyOffsetNsec *int64         `pod:"key=SPA_PROP_latencyOffsetNsec"`
+	SoftMute          *bool          `pod:"key=SPA_PROP_softMute"`
+	SoftVolumes       []float32      `pod:"key=SPA_PROP_softVolumes,omitempty"`
+	Params            *spa.PODStruct `pod:"key=SPA_PROP_params"` // Key and value pairs of plugin parameters
+}
+
+// PropInfo describes one property a node accepts in its Props
+// (SPA_TYPE_OBJECT_PropInfo)
+type PropInfo struct {
+	_           struct{}       `pod:"object=SPA_TYPE_OBJECT_PropInfo,id=SPA_PARAM_PropInfo"`
+	ID          uint32         `pod:"key=SPA_PROP_INFO_id,id,omitempty"`        // SPA_PROP_* key
+	Name        string         `pod:"key=SPA_PROP_INFO_name,omitempty"`         // Name of a plugin parameter
+	Type        spa.PODValue   `pod:"key=SPA_PROP_INFO_type"`                   // Default value, a Choice for ranges
+	Labels      *spa.PODStruct `pod:"key=SPA_PROP_INFO_labels"`                 // Value and label pairs of an enum
+	Container   uint32         `pod:"key=SPA_PROP_INFO_container,id,omitempty"` // PODType* of the container, Array
+	Params      bool           `pod:"key=SPA_PROP_INFO_params,omitempty"`       // Set in SPA_PROP_params
+	Description string         `pod:"key=SPA_PROP_INFO_description,omitempty"`
+}
+
+// ===== Buffers, Meta and IO =====
+
+// Buffers is the buffer layout a port accepts (SPA_TYPE_OBJECT_ParamBuffers)
+// Each field may be a choice during negotiation
+type Buffers struct {
+	_        struct{}  `pod:"object=SPA_TYPE_OBJECT_ParamBuffers,id=SPA_PARAM_Buffers"`
+	Buffers  IntChoice `pod:"key=SPA_PARAM_BUFFERS_buffers,omitempty"`  // Number of buffers
+	Blocks   IntChoice `pod:"key=SPA_PARAM_BUFFERS_blocks,omitempty"`   // Data blocks per buffer
+	Size     IntChoice `pod:"key=SPA_PARAM_BUFFERS_size,omitempty"`     // Bytes per data block
+	Stride   IntChoice `pod:"key=SPA_PARAM_BUFFERS_stride,omitempty"`   // Bytes per frame
+	Align    IntChoice `pod:"key=SPA_PARAM_BUFFERS_align,omitempty"`    // Memory alignment
+	DataType IntChoice `pod:"key=SPA_PARAM_BUFFERS_dataType,omitempty"` // Bit mask of SPA_DATA_* types
+	MetaType IntChoice `pod:"key=SPA_PARAM_BUFFERS_metaType,omitempty"` // Bit mask of required SPA_META_* types
+}
+
+// Meta is a metadata area a port adds to buffers (SPA_TYPE_OBJECT_ParamMeta)
+type Meta struct {
+	_    struct{}  `pod:"object=SPA_TYPE_OBJECT_ParamMeta,id=SPA_PARAM_Meta"`
+	Type uint32    `pod:"key=SPA_PARAM_META_type,id"` // SPA_META_*
+	Size IntChoice `pod:"key=SPA_PARAM_META_size,omitempty"`
+}
+
+// IO is an io area of a node or port (SPA_TYPE_OBJECT_ParamIO)
+type IO struct {
+	_    struct{} `pod:"object=SPA_TYPE_OBJECT_ParamIO,id=SPA_PARAM_IO"`
+	ID   uint32   `pod:"key=SPA_PARAM_IO_id,id"` // SPA_IO_*
+	Size int32    `pod:"key=SPA_PARAM_IO_size"`
+}
+
+// ===== Profiles and Routes =====
+
+// Profile is a profile of a device, from EnumProfile or the active one from
+// Profile (SPA_TYPE_OBJECT_ParamProfile)
+// Selecting a profile only needs Index, and Save to remember it
+type Profile struct {
+	_           struct{}       `pod:"object=SPA_TYPE_OBJECT_ParamProfile,id=SPA_PARAM_Profile"`
+	Index       int32          `pod:"key=SPA_PARAM_PROFILE_index"`
+	Name        string         `pod:"key=SPA_PARAM_PROFILE_name,omitempty"`
+	Description string         `pod:"key=SPA_PARAM_PROFILE_description,omitempty"`
+	Priority    int32          `pod:"key=SPA_PARAM_PROFILE_priority,omitempty"`
+	Available   uint32         `pod:"key=SPA_PARAM_PROFILE_available,id,omitempty"` // Availability*
+	Info        Dict           `pod:"key=SPA_PARAM_PROFILE_info,omitempty"`
+	Classes     *spa.PODStruct `pod:"key=SPA_PARAM_PROFILE_classes"` // Node counts per media class
+	Save        bool           `pod:"key=SPA_PARAM_PROFILE_save,omitempty"`
+}
+
+// Route is a route of a device, a physical input or output such as
+// headphones, from EnumRoute or an active one from Route
+// (SPA_TYPE_OBJECT_ParamRoute)
+// Setting a route needs Index, Device, and Props to change its volume
+type Route struct {
+	_           struct{} `pod:"object=SPA_TYPE_OBJECT_ParamRoute,id=SPA_PARAM_Route"`
+	Index       int32    `pod:"key=SPA_PARAM_ROUTE_index"`
+	Direction   uint32   `pod:"key=SPA_PARAM_ROUTE_direction,id"` // Direction*
+	Device      int32    `pod:"key=SPA_PARAM_ROUTE_device"`       // Device of an active route
+	Name        string   `pod:"key=SPA_PARAM_ROUTE_name,omitempty"`
+	Description string   `pod:"key=SPA_PARAM_ROUTE_description,omitempty"`
+	Priority    int32    `pod:"key=SPA_PARAM_ROUTE_priority,omitempty"`
+	Available   uint32   `pod:"key=SPA_PARAM_ROUTE_available,id,omitempty"` // Availability*
+	Info        Dict     `pod:"key=SPA_PARAM_ROUTE_info,omitempty"`
+	Profiles    []int32  `pod:"key=SPA_PARAM_ROUTE_profiles,omitempty"` // Profiles the route belongs to
+	Props       *Props   `pod:"key=SPA_PARAM_ROUTE_props"`
+	Devices     []int32  `pod:"key=SPA_PARAM_ROUTE_devices,omitempty"` // Devices the route applies to
+	Profile     int32    `pod:"key=SPA_PARAM_ROUTE_profile,omitempty"` // Profile of an active route
+	Save        bool     `pod:"key=SPA_PARAM_ROUTE_save,omitempty"`
+}
+
+// ===== Latency =====
+
+// Latency is the latency of the graph upstream or downstream of a port
+// (SPA_TYPE_OBJECT_ParamLatency), in quantums, samples and nanoseconds
+type Latency struct {
+	_          struct{} `pod:"object=SPA_TYPE_OBJECT_ParamLatency,id=SPA_PARAM_Latency"`
+	Direction  uint32   `pod:"key=SPA_PARAM_LATENCY_direction,id"` // Direction*
+	MinQuantum float32  `pod:"key=SPA_PARAM_LATENCY_minQuantum"`
+	MaxQuantum float32  `pod:"key=SPA_PARAM_LATENCY_maxQuantum"`
+	MinRate    int32    `pod:"key=SPA_PARAM_LATENCY_minRate"`
+	MaxRate    int32    `pod:"key=SPA_PARAM_LATENCY_maxRate"`
+	MinNs      int64    `pod:"key=SPA_PARAM_LATENCY_minNs"`
+	MaxNs      int64    `pod:"key=SPA_PARAM_LATENCY_maxNs"`
+}
+
+// ProcessLatency is the latency a node adds while processing
+// (SPA_TYPE_OBJECT_ParamProcessLatency)
+type ProcessLatency struct {
+	_       struct{} `pod:"object=SPA_TYPE_OBJECT_ParamProcessLatency,id=SPA_PARAM_ProcessLatency"`
+	Quantum float32  `pod:"key=SPA_PARAM_PROCESS_LATENCY_quantum"`
+	Rate    int32    `pod:"key=SPA_PARAM_PROCESS_LATENCY_rate"`
+	Ns      int64    `pod:"key=SPA_PARAM_PROCESS_LATENCY_ns"`
+}
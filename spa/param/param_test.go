@@ -0,0 +1,193 @@
+// Package param - Tests for typed params
+// spa/param/param_test.go
+
+package param
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// TestDecodeEnumFormat tests an EnumFormat as a node sends it
+func TestDecodeEnumFormat(t *testing.T) {
+	data, _ := spa.NewPODObjectBuilder(spa.TypeObjectFormat, spa.ParamEnumFormat).
+		PutID(spa.FormatMediaType, MediaTypeAudio).
+		PutID(spa.FormatMediaSubtype, MediaSubtypeRaw).
+		Put(spa.FormatAudioFormat, spa.NewPODChoiceBuilder(spa.ChoiceTypeEnum).
+			AddID(AudioFormatF32P, AudioFormatF32P, AudioFormatS16LE).Build()).
+		Put(spa.FormatAudioRate, spa.NewPODChoiceRange(spa.NewPODInt32(48000), spa.NewPODInt32(1), spa.NewPODInt32(384000))).
+		PutInt32(spa.FormatAudioChannels, 2).
+		Put(spa.FormatAudioPosition, spa.NewPODArrayBuilder().AddID(3).AddID(4).Build()).
+		Build().Marshal()
+
+	p, err := Decode(spa.ParamEnumFormat, data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	f, ok := p.(*EnumFormat)
+	if !ok {
+		t.Fatalf("expected *EnumFormat, got %T", p)
+	}
+	if !reflect.DeepEqual(f.Format, IDEnum(AudioFormatF32P, AudioFormatF32P, AudioFormatS16LE)) {
+		t.Errorf("unexpected format %v", f.Format)
+	}
+	if !reflect.DeepEqual(f.Rate, IntRange(48000, 1, 384000)) || f.Rate.Default() != 48000 {
+		t.Errorf("unexpected rate %v", f.Rate)
+	}
+	if !f.Channels.IsFixed() || f.Channels.Default() != 2 || !reflect.DeepEqual(f.Position, []uint32{3, 4}) {
+		t.Errorf("unexpected channels %v at %v", f.Channels, f.Position)
+	}
+
+	// Encoding gives back the same bytes
+	again, err := Encode(f)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Errorf("re-encoded EnumFormat differs\ngot  %x\nwant %x", again, data)
+	}
+}
+
+// TestFormat tests the negotiated format and sample format names
+func TestFormat(t *testing.T) {
+	data, err := Encode(NewAudioFormat(AudioFormatS16LE, 44100, 2, 3, 4))
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want, _ := spa.NewPODObjectBuilder(spa.TypeObjectFormat, spa.ParamFormat).
+		PutID(spa.FormatMediaType, 1).
+		PutID(spa.FormatMediaSubtype, 1).
+		PutID(spa.FormatAudioFormat, 0x103).
+		PutInt32(spa.FormatAudioRate, 44100).
+		PutInt32(spa.FormatAudioChannels, 2).
+		Put(spa.FormatAudioPosition, spa.NewPODArrayBuilder().AddID(3).AddID(4).Build()).
+		Build().Marshal()
+	if !bytes.Equal(data, want) {
+		t.Errorf("unexpected Format\ngot  %x\nwant %x", data, want)
+	}
+	p, err := Decode(spa.ParamFormat, data)
+	if f, ok := p.(*Format); err != nil || !ok || f.Rate != 44100 || f.Format != AudioFormatS16LE {
+		t.Errorf("unexpected decoded format %+v: %v", p, err)
+	}
+
+	// Video formats are refused rather than half decoded
+	video, _ := spa.NewPODObjectBuilder(spa.TypeObjectFormat, spa.ParamFormat).
+		PutID(spa.FormatMediaType, MediaTypeVideo).
+		PutID(spa.FormatMediaSubtype, MediaSubtypeRaw).
+		Build().Marshal()
+	if _, err := Decode(spa.ParamFormat, video); err == nil {
+		t.Error("expected error for a video format")
+	}
+
+	for name, want := range map[string]uint32{
+		"S16LE": AudioFormatS16LE, "s16_le": AudioFormatS16LE, "F32": AudioFormatF32LE,
+		"F32P": AudioFormatF32P, "S24_32LE": AudioFormatS24_32LE, "F64BE": AudioFormatF64BE,
+	} {
+		if id, ok := ParseAudioFormat(name); !ok || id != want {
+			t.Errorf("ParseAudioFormat(%q) = %#x, %v, want %#x", name, id, ok, want)
+		}
+	}
+	if _, ok := ParseAudioFormat("S17"); ok {
+		t.Error("parsed an unknown sample format")
+	}
+}
+
+// TestRoundTrip tests that every param decodes what it encodes
+func TestRoundTrip(t *testing.T) {
+	volume, mute, offset := float32(0.5), true, int64(-1000)
+	// Decoded choices know their child type
+	volumeRange := spa.NewPODChoiceRange(spa.NewPODFloat(1), spa.NewPODFloat(0), spa.NewPODFloat(10))
+	volumeRange.ChildType = spa.PODTypeFloat
+	props := &Props{Volume: &volume, Mute: &mute, ChannelVolumes: []float32{0.5, 0.25},
+		ChannelMap: []uint32{3, 4}, LatencyOffsetNsec: &offset}
+
+	for _, tt := range []struct {
+		id uint32
+		p  any
+	}{
+		{spa.ParamProps, props},
+		{spa.ParamPropInfo, &PropInfo{ID: spa.PropVolume, Description: "Volume", Type: volumeRange}},
+		{spa.ParamBuffers, &Buffers{Buffers: IntRange(8, 2, 16), Blocks: FixedInt(1),
+			Size: IntStep(4096, 64, 8192, 64), Stride: FixedInt(8), DataType: IntFlags(1<<2, 1<<2, 1<<3)}},
+		{spa.ParamMeta, &Meta{Type: 1, Size: FixedInt(32)}},
+		{spa.ParamIO, &IO{ID: 1, Size: 8}},
+		{spa.ParamProfile, &Profile{Index: 1, Name: "output:analog-stereo", Description: "Analog Stereo",
+			Priority: 6500, Available: AvailabilityYes, Info: Dict{"card.profile.devices": "1"}, Save: true}},
+		{spa.ParamRoute, &Route{Index: 2, Direction: DirectionOutput, Device: 4, Name: "analog-output-headphones",
+			Available: AvailabilityNo, Profiles: []int32{1, 3}, Props: props, Devices: []int32{4}, Profile: 1}},
+		{spa.ParamLatency, &Latency{Direction: DirectionInput, MinQuantum: 1, MaxQuantum: 1, MinRate: 256, MaxRate: 256}},
+		{spa.ParamProcessLatency, &ProcessLatency{Ns: 5000000}},
+	} {
+		data, err := Encode(tt.p)
+		if err != nil {
+			t.Errorf("%T: Encode failed: %v", tt.p, err)
+			continue
+		}
+		got, err := Decode(tt.id, data)
+		if err != nil {
+			t.Errorf("%T: Decode failed: %v", tt.p, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.p) {
+			t.Errorf("%T: round trip changed\ngot  %+v\nwant %+v", tt.p, got, tt.p)
+		}
+	}
+}
+
+// TestDecodeErrors tests unknown ids and mismatched objects
+func TestDecodeErrors(t *testing.T) {
+	data, _ := Encode(&IO{ID: 1, Size: 8})
+	if _, err := Decode(spa.ParamControl, data); err == nil {
+		t.Error("expected error for an unsupported param id")
+	}
+	if _, err := Decode(spa.ParamMeta, data); err == nil {
+		t.Error("expected error for an IO object decoded as Meta")
+	}
+
+	// A dictionary claiming more entries than it holds
+	bad := spa.NewPODStruct(spa.NewPODInt32(2), spa.NewPODString("k"), spa.NewPODString("v"))
+	var d Dict
+	if err := d.UnmarshalPOD(bad); err == nil {
+		t.Error("expected error for a short dictionary")
+	}
+}
+
+// TestNegotiate tests picking the format two ports agree on
+func TestNegotiate(t *testing.T) {
+	source := &EnumFormat{
+		MediaType:    MediaTypeAudio,
+		MediaSubtype: MediaSubtypeRaw,
+		Format:       IDEnum(AudioFormatF32P, AudioFormatF32P, AudioFormatS16LE, AudioFormatS32LE),
+		Rate:         IntRange(48000, 1, 384000),
+		Channels:     IntRange(2, 1, 64),
+	}
+	sink := &EnumFormat{
+		MediaType:    MediaTypeAudio,
+		MediaSubtype: MediaSubtypeRaw,
+		Format:       IDEnum(AudioFormatS32LE, AudioFormatS16LE, AudioFormatS32LE),
+		Rate:         IntEnum(44100, 44100, 48000),
+		Channels:     FixedInt(2),
+		Position:     []uint32{3, 4},
+	}
+	format, err := Negotiate(source, sink)
+	if err != nil {
+		t.Fatalf("Negotiate failed: %v", err)
+	}
+	// The source default F32P is not common, the sink default S32LE is
+	want := NewAudioFormat(AudioFormatS32LE, 48000, 2, 3, 4)
+	if !reflect.DeepEqual(format, want) {
+		t.Errorf("negotiated %+v, want %+v", format, want)
+	}
+
+	// Without a common rate the error names the property
+	sink.Rate = FixedInt(768000)
+	_, err = Negotiate(source, sink)
+	var fe *spa.FilterError
+	if !errors.As(err, &fe) || fe.Key != spa.FormatAudioRate {
+		t.Errorf("expected a FilterError on the rate, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Package param - Property values shared by params
+// spa/param/values.go
+// Properties holding either a fixed value or a choice of values, and the
+// dictionaries params carry as structs
+
+package param
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vignemail1/pipewire-go/spa"
+)
+
+// IntChoice is an Int property, fixed or a choice such as a range of rates
+// Values holds the default first, then the choice alternatives: min and max
+// for a range, min, max and step for a step, the allowed values for an enum
+// or flags
+type IntChoice struct {
+	Type   spa.ChoiceType
+	Values []int32
+}
+
+// FixedInt returns a fixed Int
+func FixedInt(val int32) IntChoice {
+	return IntChoice{Type: spa.ChoiceTypeNone, Values: []int32{val}}
+}
+
+// IntRange returns a range of Ints
+func IntRange(def, min, max int32) IntChoice {
+	return IntChoice{Type: spa.ChoiceTypeRange, Values: []int32{def, min, max}}
+}
+
+// IntStep returns a range of Ints in steps
+func IntStep(def, min, max, step int32) IntChoice {
+	return IntChoice{Type: spa.ChoiceTypeStep, Values: []int32{def, min, max, step}}
+}
+
+// IntEnum returns an enumeration of Ints
+func IntEnum(def int32, alts ...int32) IntChoice {
+	return IntChoice{Type: spa.ChoiceTypeEnum, Values: append([]int32{def}, alts...)}
+}
+
+// IntFlags returns a choice of Int flags
+func IntFlags(def int32, flags ...int32) IntChoice {
+	return IntChoice{Type: spa.ChoiceTypeFlags, Values: append([]int32{def}, flags...)}
+}
+
+// Default returns the default value, 0 when there is none
+func (c IntChoice) Default() int32 {
+	if len(c.Values) == 0 {
+		return 0
+	}
+	return c.Values[0]
+}
+
+// IsFixed reports whether the property has a single value
+func (c IntChoice) IsFixed() bool {
+	return c.Type == spa.ChoiceTypeNone
+}
+
+// MarshalPOD encodes a fixed value as an Int and others as a Choice
+func (c IntChoice) MarshalPOD() (spa.PODValue, error) {
+	if len(c.Values) == 0 {
+		return nil, fmt.Errorf("%s choice has no values", c.Type)
+	}
+	values := make([]spa.PODValue, len(c.Values))
+	for i, v := range c.Values {
+		values[i] = spa.NewPODInt32(v)
+	}
+	if c.IsFixed() {
+		return values[0], nil
+	}
+	return spa.NewPODChoice(c.Type, values...), nil
+}
+
+// UnmarshalPOD decodes an Int or a Choice of Ints
+func (c *IntChoice) UnmarshalPOD(val spa.PODValue) error {
+	choiceType, values := choiceValues(val)
+	ints := make([]int32, len(values))
+	for i, v := range values {
+		iv, ok := v.(*spa.PODInt32)
+		if !ok {
+			return fmt.Errorf("expected Int, got %s", v.PODType().Name())
+		}
+		ints[i] = iv.Value
+	}
+	c.Type, c.Values = choiceType, ints
+	return nil
+}
+
+func (c IntChoice) String() string {
+	return formatChoice(c.Type, c.Values)
+}
+
+// IDChoice is an Id property, fixed or a choice such as an enumeration of
+// sample formats; see IntChoice for the layout of Values
+type IDChoice struct {
+	Type   spa.ChoiceType
+	Values []uint32
+}
+
+// FixedID returns a fixed Id
+func FixedID(val uint32) IDChoice {
+	return IDChoice{Type: spa.ChoiceTypeNone, Values: []uint32{val}}
+}
+
+// IDEnum returns an enumeration of Ids
+func IDEnum(def uint32, alts ...uint32) IDChoice {
+	return IDChoice{Type: spa.ChoiceTypeEnum, Values: append([]uint32{def}, alts...)}
+}
+
+// Default returns the default value, 0 when there is none
+func (c IDChoice) Default() uint32 {
+	if len(c.Values) == 0 {
+		return 0
+	}
+	return c.Values[0]
+}
+
+// IsFixed reports whether the property has a single value
+func (c IDChoice) IsFixed() bool {
+	return c.Type == spa.ChoiceTypeNone
+}
+
+// MarshalPOD encodes a fixed value as an Id and others as a Choice
+func (c IDChoice) MarshalPOD() (spa.PODValue, error) {
+	if len(c.Values) == 0 {
+		return nil, fmt.Errorf("%s choice has no values", c.Type)
+	}
+	values := make([]spa.PODValue, len(c.Values))
+	for i, v := range c.Values {
+		values[i] = spa.NewPODId(v)
+	}
+	if c.IsFixed() {
+		return values[0], nil
+	}
+	return spa.NewPODChoice(c.Type, values...), nil
+}
+
+// UnmarshalPOD decodes an Id or a Choice of Ids
+func (c *IDChoice) UnmarshalPOD(val spa.PODValue) error {
+	choiceType, values := choiceValues(val)
+	ids := make([]uint32, len(values))
+	for i, v := range values {
+		id, ok := v.(*spa.PODId)
+		if !ok {
+			return fmt.Errorf("expected Id, got %s", v.PODType().Name())
+		}
+		ids[i] = id.Value
+	}
+	c.Type, c.Values = choiceType, ids
+	return nil
+}
+
+func (c IDChoice) String() string {
+	return formatChoice(c.Type, c.Values)
+}
+
+// choiceValues returns the type and values of a choice, a single value for
+// anything else
+func choiceValues(val spa.PODValue) (spa.ChoiceType, []spa.PODValue) {
+	if choice, ok := val.(*spa.PODChoice); ok {
+		return choice.Type, choice.Values
+	}
+	return spa.ChoiceTypeNone, []spa.PODValue{val}
+}
+
+func formatChoice[T int32 | uint32](choiceType spa.ChoiceType, values []T) string {
+	if choiceType == spa.ChoiceTypeNone && len(values) == 1 {
+		return fmt.Sprint(values[0])
+	}
+	return fmt.Sprintf("%s%v", choiceType, values)
+}
+
+// Dict is a dictionary carried as a Struct: the number of entries as an Int,
+// then each key and value as Strings, as in the info of profiles and routes
+type Dict map[string]string
+
+// MarshalPOD encodes the entries sorted by key
+func (d Dict) MarshalPOD() (spa.PODValue, error) {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]spa.PODValue, 0, 1+2*len(d))
+	fields = append(fields, spa.NewPODInt32(int32(len(d))))
+	for _, k := range keys {
+		fields = append(fields, spa.NewPODString(k), spa.NewPODString(d[k]))
+	}
+	return spa.NewPODStruct(fields...), nil
+}
+
+// UnmarshalPOD decodes a dictionary Struct
+func (d *Dict) UnmarshalPOD(val spa.PODValue) error {
+	s, ok := val.(*spa.PODStruct)
+	if !ok || len(s.Fields) == 0 {
+		return fmt.Errorf("expected a dictionary Struct, got %s", val.PODType().Name())
+	}
+	n, ok := s.Fields[0].(*spa.PODInt32)
+	if !ok || n.Value < 0 || int(n.Value) > (len(s.Fields)-1)/2 {
+		return fmt.Errorf("dictionary of %d fields has a bad entry count", len(s.Fields))
+	}
+	dict := make(Dict, n.Value)
+	for i := 0; i < int(n.Value); i++ {
+		k, kok := s.Fields[1+2*i].(*spa.PODString)
+		v, vok := s.Fields[2+2*i].(*spa.PODString)
+		if !kok || !vok {
+			return fmt.Errorf("dictionary entry %d is not a pair of Strings", i)
+		}
+		dict[k.Value] = v.Value
+	}
+	*d = dict
+	return nil
+}
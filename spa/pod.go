@@ -1,6 +1,7 @@
 // Package spa - POD (Plain Old Data) marshaling and unmarshaling
 // spa/pod.go
-// Complete implementation with proper alignment and endianness
+// Values marshal to complete PODs laid out like spa_pod_builder does:
+// [size (4B)] [type (4B)] [body] [padding to 8], little-endian
 
 package spa
 
@@ -8,6 +9,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"sort"
 )
 
 // ============================================================================
@@ -15,8 +17,9 @@ import (
 // ============================================================================
 
 // PODValue represents any POD value (marshals/unmarshals itself)
+// Marshal returns the complete padded POD and Unmarshal expects one
 type PODValue interface {
-	Type() PODType
+	PODType() PODType
 	Marshal() ([]byte, error)
 	Unmarshal([]byte) error
 	String() string
@@ -37,119 +40,102 @@ type BasePODType struct {
 func (t *BasePODType) ID() uint32   { return t.id }
 func (t *BasePODType) Name() string { return t.name }
 
-// ============================================================================
-// PRIMITIVE POD TYPES
-// ============================================================================
-
-// PODInt8 - 8-bit signed integer
-type PODInt8 struct{ Value int8 }
-
-func NewPODInt8(val int8) *PODInt8 { return &PODInt8{Value: val} }
-func (v *PODInt8) Type() PODType   { return &BasePODType{id: PODTypeInt, name: "int8"} }
-func (v *PODInt8) String() string  { return fmt.Sprintf("int8(%d)", v.Value) }
-func (v *PODInt8) Marshal() ([]byte, error) {
-	return []byte{byte(v.Value)}, nil
+// framePOD lays out a POD: header, body and padding to 8 bytes
+func framePOD(podType uint32, body []byte) []byte {
+	data := make([]byte, podHeaderSize, podHeaderSize+AlignOffset(len(body)))
+	binary.LittleEndian.PutUint32(data[0:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(data[4:8], podType)
+	data = append(data, body...)
+	return append(data, make([]byte, AlignPadding(len(body)))...)
 }
-func (v *PODInt8) Unmarshal(data []byte) error {
-	if len(data) < 1 {
-		return fmt.Errorf("insufficient data for int8")
+
+// appendPadded appends a marshalled value and pads it to 8 bytes, for
+// values such as PODRaw whose encoding may lack the trailing padding
+func appendPadded(data []byte, v PODValue) ([]byte, error) {
+	pod, err := v.Marshal()
+	if err != nil {
+		return nil, err
 	}
-	v.Value = int8(data[0])
-	return nil
+	data = append(data, pod...)
+	return append(data, make([]byte, AlignPadding(len(pod)))...), nil
 }
 
-// PODInt16 - 16-bit signed integer
-type PODInt16 struct{ Value int16 }
-
-func NewPODInt16(val int16) *PODInt16 { return &PODInt16{Value: val} }
-func (v *PODInt16) Type() PODType     { return &BasePODType{id: PODTypeInt, name: "int16"} }
-func (v *PODInt16) String() string    { return fmt.Sprintf("int16(%d)", v.Value) }
-func (v *PODInt16) Marshal() ([]byte, error) {
-	b := make([]byte, 2)
-	binary.LittleEndian.PutUint16(b, uint16(v.Value))
-	return b, nil
-}
-func (v *PODInt16) Unmarshal(data []byte) error {
-	if len(data) < 2 {
-		return fmt.Errorf("insufficient data for int16")
+// unmarshalPOD decodes the complete POD data into v, which must be the
+// type the POD decodes to
+func unmarshalPOD[T any](data []byte, v *T) error {
+	val, err := NewPODParser(data).ParseValue()
+	if err != nil {
+		return err
+	}
+	decoded, ok := any(val).(*T)
+	if !ok {
+		return fmt.Errorf("expected %T, got %s POD", v, val.PODType().Name())
 	}
-	v.Value = int16(binary.LittleEndian.Uint16(data))
+	*v = *decoded
 	return nil
 }
 
-// PODInt32 - 32-bit signed integer
+// ============================================================================
+// PRIMITIVE POD TYPES
+// ============================================================================
+
+// PODInt32 - Int, 32-bit signed integer
 type PODInt32 struct{ Value int32 }
 
 func NewPODInt32(val int32) *PODInt32 { return &PODInt32{Value: val} }
-func (v *PODInt32) Type() PODType     { return &BasePODType{id: PODTypeInt, name: "int32"} }
+func (v *PODInt32) PODType() PODType  { return &BasePODType{id: PODTypeInt, name: "int32"} }
 func (v *PODInt32) String() string    { return fmt.Sprintf("int32(%d)", v.Value) }
 func (v *PODInt32) Marshal() ([]byte, error) {
-	b := make([]byte, 4)
-	binary.LittleEndian.PutUint32(b, uint32(v.Value))
-	return b, nil
-}
-func (v *PODInt32) Unmarshal(data []byte) error {
-	if len(data) < 4 {
-		return fmt.Errorf("insufficient data for int32")
-	}
-	v.Value = int32(binary.LittleEndian.Uint32(data))
-	return nil
+	return framePOD(PODTypeInt, binary.LittleEndian.AppendUint32(nil, uint32(v.Value))), nil
 }
+func (v *PODInt32) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
 
-// PODInt64 - 64-bit signed integer
+// PODInt64 - Long, 64-bit signed integer
 type PODInt64 struct{ Value int64 }
 
 func NewPODInt64(val int64) *PODInt64 { return &PODInt64{Value: val} }
-func (v *PODInt64) Type() PODType     { return &BasePODType{id: PODTypeInt64, name: "int64"} }
+func (v *PODInt64) PODType() PODType  { return &BasePODType{id: PODTypeLong, name: "int64"} }
 func (v *PODInt64) String() string    { return fmt.Sprintf("int64(%d)", v.Value) }
 func (v *PODInt64) Marshal() ([]byte, error) {
-	b := make([]byte, 8)
-	binary.LittleEndian.PutUint64(b, uint64(v.Value))
-	return b, nil
-}
-func (v *PODInt64) Unmarshal(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("insufficient data for int64")
-	}
-	v.Value = int64(binary.LittleEndian.Uint64(data))
-	return nil
+	return framePOD(PODTypeLong, binary.LittleEndian.AppendUint64(nil, uint64(v.Value))), nil
 }
+func (v *PODInt64) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
 
 // PODUint32 - 32-bit unsigned integer
+// SPA has no unsigned type, it travels as an Int and decodes as a PODInt32
 type PODUint32 struct{ Value uint32 }
 
 func NewPODUint32(val uint32) *PODUint32 { return &PODUint32{Value: val} }
-func (v *PODUint32) Type() PODType       { return &BasePODType{id: PODTypeUint32, name: "uint32"} }
+func (v *PODUint32) PODType() PODType    { return &BasePODType{id: PODTypeInt, name: "uint32"} }
 func (v *PODUint32) String() string      { return fmt.Sprintf("uint32(%d)", v.Value) }
 func (v *PODUint32) Marshal() ([]byte, error) {
-	b := make([]byte, 4)
-	binary.LittleEndian.PutUint32(b, v.Value)
-	return b, nil
+	return framePOD(PODTypeInt, binary.LittleEndian.AppendUint32(nil, v.Value)), nil
 }
 func (v *PODUint32) Unmarshal(data []byte) error {
-	if len(data) < 4 {
-		return fmt.Errorf("insufficient data for uint32")
+	var i PODInt32
+	if err := unmarshalPOD(data, &i); err != nil {
+		return err
 	}
-	v.Value = binary.LittleEndian.Uint32(data)
+	v.Value = uint32(i.Value)
 	return nil
 }
 
 // PODUint64 - 64-bit unsigned integer
+// SPA has no unsigned type, it travels as a Long and decodes as a PODInt64
 type PODUint64 struct{ Value uint64 }
 
 func NewPODUint64(val uint64) *PODUint64 { return &PODUint64{Value: val} }
-func (v *PODUint64) Type() PODType       { return &BasePODType{id: PODTypeInt64, name: "uint64"} }
+func (v *PODUint64) PODType() PODType    { return &BasePODType{id: PODTypeLong, name: "uint64"} }
 func (v *PODUint64) String() string      { return fmt.Sprintf("uint64(%d)", v.Value) }
 func (v *PODUint64) Marshal() ([]byte, error) {
-	b := make([]byte, 8)
-	binary.LittleEndian.PutUint64(b, v.Value)
-	return b, nil
+	return framePOD(PODTypeLong, binary.LittleEndian.AppendUint64(nil, v.Value)), nil
 }
 func (v *PODUint64) Unmarshal(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("insufficient data for uint64")
+	var i PODInt64
+	if err := unmarshalPOD(data, &i); err != nil {
+		return err
 	}
-	v.Value = binary.LittleEndian.Uint64(data)
+	v.Value = uint64(i.Value)
 	return nil
 }
 
@@ -157,45 +143,29 @@ func (v *PODUint64) Unmarshal(data []byte) error {
 type PODFloat struct{ Value float32 }
 
 func NewPODFloat(val float32) *PODFloat { return &PODFloat{Value: val} }
-func (v *PODFloat) Type() PODType       { return &BasePODType{id: PODTypeFloat, name: "float"} }
+func (v *PODFloat) PODType() PODType    { return &BasePODType{id: PODTypeFloat, name: "float"} }
 func (v *PODFloat) String() string      { return fmt.Sprintf("float(%f)", v.Value) }
 func (v *PODFloat) Marshal() ([]byte, error) {
-	b := make([]byte, 4)
-	binary.LittleEndian.PutUint32(b, math.Float32bits(v.Value))
-	return b, nil
-}
-func (v *PODFloat) Unmarshal(data []byte) error {
-	if len(data) < 4 {
-		return fmt.Errorf("insufficient data for float")
-	}
-	v.Value = math.Float32frombits(binary.LittleEndian.Uint32(data))
-	return nil
+	return framePOD(PODTypeFloat, binary.LittleEndian.AppendUint32(nil, math.Float32bits(v.Value))), nil
 }
+func (v *PODFloat) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
 
 // PODDouble - 64-bit floating point
 type PODDouble struct{ Value float64 }
 
 func NewPODDouble(val float64) *PODDouble { return &PODDouble{Value: val} }
-func (v *PODDouble) Type() PODType        { return &BasePODType{id: PODTypeDouble, name: "double"} }
+func (v *PODDouble) PODType() PODType     { return &BasePODType{id: PODTypeDouble, name: "double"} }
 func (v *PODDouble) String() string       { return fmt.Sprintf("double(%f)", v.Value) }
 func (v *PODDouble) Marshal() ([]byte, error) {
-	b := make([]byte, 8)
-	binary.LittleEndian.PutUint64(b, math.Float64bits(v.Value))
-	return b, nil
-}
-func (v *PODDouble) Unmarshal(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("insufficient data for double")
-	}
-	v.Value = math.Float64frombits(binary.LittleEndian.Uint64(data))
-	return nil
+	return framePOD(PODTypeDouble, binary.LittleEndian.AppendUint64(nil, math.Float64bits(v.Value))), nil
 }
+func (v *PODDouble) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
 
-// PODBool - Boolean value
+// PODBool - Boolean value, a 32-bit integer on the wire
 type PODBool struct{ Value bool }
 
-func NewPODBool(val bool) *PODBool { return &PODBool{Value: val} }
-func (v *PODBool) Type() PODType   { return &BasePODType{id: PODTypeBool, name: "bool"} }
+func NewPODBool(val bool) *PODBool  { return &PODBool{Value: val} }
+func (v *PODBool) PODType() PODType { return &BasePODType{id: PODTypeBool, name: "bool"} }
 func (v *PODBool) String() string {
 	if v.Value {
 		return "bool(true)"
@@ -203,86 +173,63 @@ func (v *PODBool) String() string {
 	return "bool(false)"
 }
 func (v *PODBool) Marshal() ([]byte, error) {
+	var b uint32
 	if v.Value {
-		return []byte{1}, nil
-	}
-	return []byte{0}, nil
-}
-func (v *PODBool) Unmarshal(data []byte) error {
-	if len(data) < 1 {
-		return fmt.Errorf("insufficient data for bool")
+		b = 1
 	}
-	v.Value = data[0] != 0
-	return nil
+	return framePOD(PODTypeBool, binary.LittleEndian.AppendUint32(nil, b)), nil
 }
+func (v *PODBool) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
 
-// PODId - Object ID reference
+// PODId - Id from an enumeration, such as a SPA_PARAM_* or SPA_AUDIO_FORMAT_*
 type PODId struct{ Value uint32 }
 
-func NewPODId(val uint32) *PODId { return &PODId{Value: val} }
-func (v *PODId) Type() PODType   { return &BasePODType{id: PODTypeID, name: "id"} }
-func (v *PODId) String() string  { return fmt.Sprintf("id(%d)", v.Value) }
+func NewPODId(val uint32) *PODId  { return &PODId{Value: val} }
+func (v *PODId) PODType() PODType { return &BasePODType{id: PODTypeID, name: "id"} }
+func (v *PODId) String() string   { return fmt.Sprintf("id(%d)", v.Value) }
 func (v *PODId) Marshal() ([]byte, error) {
-	b := make([]byte, 4)
-	binary.LittleEndian.PutUint32(b, v.Value)
-	return b, nil
-}
-func (v *PODId) Unmarshal(data []byte) error {
-	if len(data) < 4 {
-		return fmt.Errorf("insufficient data for id")
-	}
-	v.Value = binary.LittleEndian.Uint32(data)
-	return nil
+	return framePOD(PODTypeID, binary.LittleEndian.AppendUint32(nil, v.Value)), nil
 }
+func (v *PODId) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
 
-// PODString - String value
+// PODString - String value, NUL-terminated on the wire
 type PODString struct{ Value string }
 
 func NewPODString(val string) *PODString { return &PODString{Value: val} }
-func (v *PODString) Type() PODType       { return &BasePODType{id: PODTypeString, name: "string"} }
+func (v *PODString) PODType() PODType    { return &BasePODType{id: PODTypeString, name: "string"} }
 func (v *PODString) String() string      { return fmt.Sprintf("string(%q)", v.Value) }
 func (v *PODString) Marshal() ([]byte, error) {
-	data := []byte(v.Value)
-	// String format: length (4 bytes) + data + null terminator
-	b := make([]byte, 4+len(data)+1)
-	binary.LittleEndian.PutUint32(b, uint32(len(data)+1))
-	copy(b[4:], data)
-	b[4+len(data)] = 0 // null terminator
-	return b, nil
-}
-func (v *PODString) Unmarshal(data []byte) error {
-	if len(data) < 4 {
-		return fmt.Errorf("insufficient data for string length")
-	}
-	length := binary.LittleEndian.Uint32(data)
-	if len(data) < 4+int(length) {
-		return fmt.Errorf("insufficient data for string content")
-	}
-	v.Value = string(data[4 : 4+length-1]) // -1 to exclude null terminator
-	return nil
+	body := make([]byte, len(v.Value)+1)
+	copy(body, v.Value)
+	return framePOD(PODTypeString, body), nil
 }
+func (v *PODString) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
 
 // PODBytes - Binary data
 type PODBytes struct{ Value []byte }
 
 func NewPODBytes(val []byte) *PODBytes { return &PODBytes{Value: append([]byte{}, val...)} }
-func (v *PODBytes) Type() PODType      { return &BasePODType{id: PODTypeBytes, name: "bytes"} }
+func (v *PODBytes) PODType() PODType   { return &BasePODType{id: PODTypeBytes, name: "bytes"} }
 func (v *PODBytes) String() string     { return fmt.Sprintf("bytes(%d bytes)", len(v.Value)) }
 func (v *PODBytes) Marshal() ([]byte, error) {
-	b := make([]byte, 4+len(v.Value))
-	binary.LittleEndian.PutUint32(b, uint32(len(v.Value)))
-	copy(b[4:], v.Value)
-	return b, nil
+	return framePOD(PODTypeBytes, v.Value), nil
 }
-func (v *PODBytes) Unmarshal(data []byte) error {
-	if len(data) < 4 {
-		return fmt.Errorf("insufficient data for bytes length")
-	}
-	length := binary.LittleEndian.Uint32(data)
-	if len(data) < 4+int(length) {
-		return fmt.Errorf("insufficient data for bytes content")
-	}
-	v.Value = append([]byte{}, data[4:4+length]...)
+func (v *PODBytes) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
+
+// PODRaw - Pre-encoded POD passed through verbatim
+// Used by the protocol layer to carry message bodies whose layout is only
+// known to the method or event that owns them, and by the parser for
+// PODs without a value type in this package
+type PODRaw struct{ Data []byte }
+
+func NewPODRaw(data []byte) *PODRaw { return &PODRaw{Data: append([]byte{}, data...)} }
+func (v *PODRaw) PODType() PODType  { return &BasePODType{id: PODTypeInvalid, name: "raw"} }
+func (v *PODRaw) String() string    { return fmt.Sprintf("raw(%d bytes)", len(v.Data)) }
+func (v *PODRaw) Marshal() ([]byte, error) {
+	return append([]byte{}, v.Data...), nil
+}
+func (v *PODRaw) Unmarshal(data []byte) error {
+	v.Data = append([]byte{}, data...)
 	return nil
 }
 
@@ -293,65 +240,107 @@ type PODFraction struct {
 }
 
 func NewPODFraction(num, den uint32) *PODFraction { return &PODFraction{Num: num, Den: den} }
-func (v *PODFraction) Type() PODType              { return &BasePODType{id: PODTypeFraction, name: "fraction"} }
+func (v *PODFraction) PODType() PODType           { return &BasePODType{id: PODTypeFraction, name: "fraction"} }
 func (v *PODFraction) String() string             { return fmt.Sprintf("fraction(%d/%d)", v.Num, v.Den) }
 func (v *PODFraction) Marshal() ([]byte, error) {
-	b := make([]byte, 8)
-	binary.LittleEndian.PutUint32(b[0:4], v.Num)
-	binary.LittleEndian.PutUint32(b[4:8], v.Den)
-	return b, nil
+	body := binary.LittleEndian.AppendUint32(nil, v.Num)
+	return framePOD(PODTypeFraction, binary.LittleEndian.AppendUint32(body, v.Den)), nil
 }
-func (v *PODFraction) Unmarshal(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("insufficient data for fraction")
+func (v *PODFraction) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
+
+// Value returns the fraction as a float, 0 when the denominator is 0
+func (v *PODFraction) Value() float64 {
+	if v.Den == 0 {
+		return 0
 	}
-	v.Num = binary.LittleEndian.Uint32(data[0:4])
-	v.Den = binary.LittleEndian.Uint32(data[4:8])
-	return nil
+	return float64(v.Num) / float64(v.Den)
 }
 
-// PODRectangle - Rectangle (x, y, width, height)
+// PODRectangle - Rectangle (width x height), such as a video size
 type PODRectangle struct {
-	X int32
-	Y int32
-	W int32
-	H int32
+	Width  uint32
+	Height uint32
 }
 
-func NewPODRectangle(x, y, w, h int32) *PODRectangle { return &PODRectangle{X: x, Y: y, W: w, H: h} }
-func (v *PODRectangle) Type() PODType                 { return &BasePODType{id: PODTypeRectangle, name: "rectangle"} }
-func (v *PODRectangle) String() string                { return fmt.Sprintf("rect(%d,%d %dx%d)", v.X, v.Y, v.W, v.H) }
+func NewPODRectangle(width, height uint32) *PODRectangle {
+	return &PODRectangle{Width: width, Height: height}
+}
+func (v *PODRectangle) PODType() PODType {
+	return &BasePODType{id: PODTypeRectangle, name: "rectangle"}
+}
+func (v *PODRectangle) String() string { return fmt.Sprintf("rectangle(%dx%d)", v.Width, v.Height) }
 func (v *PODRectangle) Marshal() ([]byte, error) {
-	b := make([]byte, 16)
-	binary.LittleEndian.PutUint32(b[0:4], uint32(v.X))
-	binary.LittleEndian.PutUint32(b[4:8], uint32(v.Y))
-	binary.LittleEndian.PutUint32(b[8:12], uint32(v.W))
-	binary.LittleEndian.PutUint32(b[12:16], uint32(v.H))
-	return b, nil
-}
-func (v *PODRectangle) Unmarshal(data []byte) error {
-	if len(data) < 16 {
-		return fmt.Errorf("insufficient data for rectangle")
-	}
-	v.X = int32(binary.LittleEndian.Uint32(data[0:4]))
-	v.Y = int32(binary.LittleEndian.Uint32(data[4:8]))
-	v.W = int32(binary.LittleEndian.Uint32(data[8:12]))
-	v.H = int32(binary.LittleEndian.Uint32(data[12:16]))
-	return nil
+	body := binary.LittleEndian.AppendUint32(nil, v.Width)
+	return framePOD(PODTypeRectangle, binary.LittleEndian.AppendUint32(body, v.Height)), nil
+}
+func (v *PODRectangle) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
+
+// Area returns width times height
+func (v *PODRectangle) Area() int64 {
+	return int64(v.Width) * int64(v.Height)
+}
+
+// PODNone - None, a value without body
+type PODNone struct{}
+
+func NewPODNone() *PODNone                  { return &PODNone{} }
+func (v *PODNone) PODType() PODType         { return &BasePODType{id: PODTypeNone, name: "none"} }
+func (v *PODNone) String() string           { return "none" }
+func (v *PODNone) Marshal() ([]byte, error) { return framePOD(PODTypeNone, nil), nil }
+func (v *PODNone) Unmarshal(data []byte) error {
+	return unmarshalPOD(data, v)
+}
+
+// PODPointer - Pointer of a type (SPA_TYPE_POINTER_*), only meaningful
+// within the process that built it
+type PODPointer struct {
+	Type  uint32
+	Value uint64
+}
+
+func NewPODPointer(ptrType uint32, val uint64) *PODPointer {
+	return &PODPointer{Type: ptrType, Value: val}
+}
+func (v *PODPointer) PODType() PODType { return &BasePODType{id: PODTypePointer, name: "pointer"} }
+func (v *PODPointer) String() string   { return fmt.Sprintf("pointer(%d, 0x%x)", v.Type, v.Value) }
+func (v *PODPointer) Marshal() ([]byte, error) {
+	body := binary.LittleEndian.AppendUint32(nil, v.Type)
+	body = binary.LittleEndian.AppendUint32(body, 0)
+	return framePOD(PODTypePointer, binary.LittleEndian.AppendUint64(body, v.Value)), nil
+}
+func (v *PODPointer) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
+
+// PODFd - Fd, an index into the FDTable of the message carrying it
+type PODFd struct{ Value int64 }
+
+func NewPODFd(index int64) *PODFd { return &PODFd{Value: index} }
+func (v *PODFd) PODType() PODType { return &BasePODType{id: PODTypeFd, name: "fd"} }
+func (v *PODFd) String() string   { return fmt.Sprintf("fd(%d)", v.Value) }
+func (v *PODFd) Marshal() ([]byte, error) {
+	return framePOD(PODTypeFd, binary.LittleEndian.AppendUint64(nil, uint64(v.Value))), nil
+}
+func (v *PODFd) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
+
+// Resolve returns the file descriptor of the index in fds
+func (v *PODFd) Resolve(fds FDTable) (int, error) {
+	return fds.Get(v.Value)
 }
 
 // ============================================================================
 // COMPOSITE POD TYPES
 // ============================================================================
 
-// PODArray - Array of POD values
+// PODArray - Array of values of one fixed-size type, packed without headers
+// ChildType gives the element type of an empty array; otherwise it is taken
+// from the values, which must all encode to the same type and size
 type PODArray struct {
-	Values []PODValue
+	ChildType uint32
+	Values    []PODValue
 }
 
-func NewPODArray() *PODArray { return &PODArray{Values: make([]PODValue, 0)} }
-func (v *PODArray) Type() PODType { return &BasePODType{id: PODTypeArray, name: "array"} }
-func (v *PODArray) String() string { return fmt.Sprintf("array(%d items)", len(v.Values)) }
+func NewPODArray() *PODArray         { return &PODArray{Values: make([]PODValue, 0)} }
+func (v *PODArray) PODType() PODType { return &BasePODType{id: PODTypeArray, name: "array"} }
+func (v *PODArray) String() string   { return fmt.Sprintf("array(%d items)", len(v.Values)) }
 func (v *PODArray) Append(val PODValue) error {
 	if val == nil {
 		return fmt.Errorf("cannot append nil value")
@@ -360,99 +349,244 @@ func (v *PODArray) Append(val PODValue) error {
 	return nil
 }
 func (v *PODArray) Marshal() ([]byte, error) {
-	w := NewPODWriter()
-	if err := w.WriteUint32(uint32(len(v.Values))); err != nil {
+	body, err := appendArrayBody(nil, v.ChildType, v.Values)
+	if err != nil {
 		return nil, err
 	}
-	for _, item := range v.Values {
+	return framePOD(PODTypeArray, body), nil
+}
+func (v *PODArray) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
+
+// appendArrayBody appends the body of an array, shared by choices: a child
+// header then the packed bodies of values
+func appendArrayBody(dst []byte, childType uint32, values []PODValue) ([]byte, error) {
+	childSize, _ := PODTypeSize(childType)
+	elems := make([]byte, 0, len(values)*8)
+	for i, item := range values {
 		data, err := item.Marshal()
 		if err != nil {
 			return nil, err
 		}
-		if err := w.WriteBytes(data); err != nil {
+		podType, body, _, err := readPODHeader(data)
+		if err != nil {
 			return nil, err
 		}
+		if i == 0 {
+			childType, childSize = podType, len(body)
+		} else if podType != childType || len(body) != childSize {
+			return nil, fmt.Errorf("element %d is a %s of %d bytes, want %s of %d bytes",
+				i, PODTypeFromID(podType), len(body), PODTypeFromID(childType), childSize)
+		}
+		elems = append(elems, body...)
+	}
+	if childSize < 0 {
+		childSize = 0
 	}
-	return w.Bytes(), nil
+
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(childSize))
+	dst = binary.LittleEndian.AppendUint32(dst, childType)
+	return append(dst, elems...), nil
 }
-func (v *PODArray) Unmarshal(data []byte) error {
-	p := NewPODParser(data)
-	length, err := p.ReadUint32()
+
+// PODStruct - Struct of fields of any type, each a complete POD
+// Method arguments and events travel as structs
+type PODStruct struct {
+	Fields []PODValue
+}
+
+func NewPODStruct(fields ...PODValue) *PODStruct {
+	return &PODStruct{Fields: append(make([]PODValue, 0, len(fields)), fields...)}
+}
+func (v *PODStruct) PODType() PODType { return &BasePODType{id: PODTypeStruct, name: "struct"} }
+func (v *PODStruct) String() string   { return fmt.Sprintf("struct(%d fields)", len(v.Fields)) }
+func (v *PODStruct) Append(val PODValue) error {
+	if val == nil {
+		return fmt.Errorf("cannot append nil value")
+	}
+	v.Fields = append(v.Fields, val)
+	return nil
+}
+func (v *PODStruct) Marshal() ([]byte, error) {
+	body := make([]byte, 0, len(v.Fields)*16)
+	for i, field := range v.Fields {
+		if field == nil {
+			return nil, fmt.Errorf("field %d has no value", i)
+		}
+		var err error
+		if body, err = appendPadded(body, field); err != nil {
+			return nil, fmt.Errorf("field %d: %w", i, err)
+		}
+	}
+	return framePOD(PODTypeStruct, body), nil
+}
+func (v *PODStruct) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
+
+// PODChoice - Choice of values for a property, as in EnumFormat and PropInfo
+// Values holds the default then the alternatives the choice type defines:
+// min and max for a range, min, max and step for a step, the allowed
+// values for an enum and the allowed flags for flags
+type PODChoice struct {
+	Type      ChoiceType
+	Flags     uint32
+	ChildType uint32 // Element type of an empty choice
+	Values    []PODValue
+}
+
+func NewPODChoice(choiceType ChoiceType, values ...PODValue) *PODChoice {
+	return &PODChoice{Type: choiceType, Values: append(make([]PODValue, 0, len(values)), values...)}
+}
+func (v *PODChoice) PODType() PODType { return &BasePODType{id: PODTypeChoice, name: "choice"} }
+func (v *PODChoice) String() string {
+	return fmt.Sprintf("choice(%s, %d values)", v.Type, len(v.Values))
+}
+
+// Default returns the default value, nil for an empty choice
+func (v *PODChoice) Default() PODValue {
+	if len(v.Values) == 0 {
+		return nil
+	}
+	return v.Values[0]
+}
+
+func (v *PODChoice) Marshal() ([]byte, error) {
+	body := binary.LittleEndian.AppendUint32(nil, uint32(v.Type))
+	body = binary.LittleEndian.AppendUint32(body, v.Flags)
+	body, err := appendArrayBody(body, v.ChildType, v.Values)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	v.Values = make([]PODValue, 0, length)
-	for i := uint32(0); i < length; i++ {
-		val, err := p.ParseValue()
-		if err != nil {
-			return err
+	return framePOD(PODTypeChoice, body), nil
+}
+func (v *PODChoice) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
+
+// Control types of a sequence (SPA_CONTROL_*)
+const (
+	ControlTypeInvalid    uint32 = 0
+	ControlTypeProperties uint32 = 1 // Props object
+	ControlTypeMidi       uint32 = 2 // MIDI 1.0 bytes
+	ControlTypeOSC        uint32 = 3 // Open Sound Control bytes
+	ControlTypeUMP        uint32 = 4 // MIDI 2.0 universal MIDI packets
+)
+
+// PODControl - Timed control of a sequence
+type PODControl struct {
+	Offset uint32 // Offset in samples from the start of the cycle
+	Type   uint32 // ControlType*
+	Value  PODValue
+}
+
+// PODSequence - Sequence of timed controls, such as MIDI events
+type PODSequence struct {
+	Unit     uint32
+	Controls []PODControl
+}
+
+func NewPODSequence() *PODSequence { return &PODSequence{Controls: make([]PODControl, 0)} }
+func (v *PODSequence) PODType() PODType {
+	return &BasePODType{id: PODTypeSequence, name: "sequence"}
+}
+func (v *PODSequence) String() string {
+	return fmt.Sprintf("sequence(%d controls)", len(v.Controls))
+}
+func (v *PODSequence) Marshal() ([]byte, error) {
+	body := binary.LittleEndian.AppendUint32(make([]byte, 0, 8+len(v.Controls)*24), v.Unit)
+	body = binary.LittleEndian.AppendUint32(body, 0)
+	for i, control := range v.Controls {
+		if control.Value == nil {
+			return nil, fmt.Errorf("control %d has no value", i)
+		}
+		body = binary.LittleEndian.AppendUint32(body, control.Offset)
+		body = binary.LittleEndian.AppendUint32(body, control.Type)
+		var err error
+		if body, err = appendPadded(body, control.Value); err != nil {
+			return nil, fmt.Errorf("control %d: %w", i, err)
 		}
-		v.Values = append(v.Values, val)
 	}
-	return nil
+	return framePOD(PODTypeSequence, body), nil
 }
+func (v *PODSequence) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
+
+// Property flags of an object property (SPA_POD_PROP_FLAG_*)
+const (
+	PropFlagReadOnly   uint32 = 1 << 0 // Read-only
+	PropFlagHardware   uint32 = 1 << 1 // Hardware parameter
+	PropFlagHintDict   uint32 = 1 << 2 // Value is a Struct dictionary hint
+	PropFlagMandatory  uint32 = 1 << 3 // Must be kept when filtering
+	PropFlagDontFixate uint32 = 1 << 4 // Choice is kept when fixating
+)
 
-// PODObject - Object with key-value pairs
+// PODProp - Property of an object
+// Key is from the id space of the object type, such as SPA_PROP_* for Props
+type PODProp struct {
+	Key   uint32
+	Flags uint32 // PropFlag* bits
+	Value PODValue
+}
+
+// PODObject - Object of a type (SPA_TYPE_OBJECT_*) and id, such as the
+// SPA_PARAM_* of a param, holding properties in order
 type PODObject struct {
-	Fields map[string]PODValue
+	Type  uint32
+	ID    uint32
+	Props []PODProp
 }
 
-func NewPODObject() *PODObject { return &PODObject{Fields: make(map[string]PODValue)} }
-func (v *PODObject) Type() PODType { return &BasePODType{id: PODTypeObject, name: "object"} }
-func (v *PODObject) String() string { return fmt.Sprintf("object(%d fields)", len(v.Fields)) }
-func (v *PODObject) Set(key string, val PODValue) error {
-	if key == "" {
-		return fmt.Errorf("key cannot be empty")
-	}
+func NewPODObject(objType, id uint32) *PODObject {
+	return &PODObject{Type: objType, ID: id, Props: make([]PODProp, 0)}
+}
+func (v *PODObject) PODType() PODType { return &BasePODType{id: PODTypeObject, name: "object"} }
+func (v *PODObject) String() string {
+	return fmt.Sprintf("object(type=%d id=%d, %d props)", v.Type, v.ID, len(v.Props))
+}
+
+// Set sets the value of key, appending the property when absent
+func (v *PODObject) Set(key uint32, val PODValue) error {
 	if val == nil {
 		return fmt.Errorf("value cannot be nil")
 	}
-	v.Fields[key] = val
+	if prop, ok := v.Prop(key); ok {
+		prop.Value = val
+		return nil
+	}
+	v.Props = append(v.Props, PODProp{Key: key, Value: val})
 	return nil
 }
-func (v *PODObject) Get(key string) (PODValue, bool) {
-	val, ok := v.Fields[key]
-	return val, ok
-}
-func (v *PODObject) Marshal() ([]byte, error) {
-	w := NewPODWriter()
-	if err := w.WriteUint32(uint32(len(v.Fields))); err != nil {
-		return nil, err
+
+// Get returns the value of key
+func (v *PODObject) Get(key uint32) (PODValue, bool) {
+	if prop, ok := v.Prop(key); ok {
+		return prop.Value, true
 	}
-	for k, val := range v.Fields {
-		if err := w.WriteString(k); err != nil {
-			return nil, err
-		}
-		data, err := val.Marshal()
-		if err != nil {
-			return nil, err
-		}
-		if err := w.WriteBytes(data); err != nil {
-			return nil, err
+	return nil, false
+}
+
+// Prop returns the property with key, to read or change its flags
+func (v *PODObject) Prop(key uint32) (*PODProp, bool) {
+	for i := range v.Props {
+		if v.Props[i].Key == key {
+			return &v.Props[i], true
 		}
 	}
-	return w.Bytes(), nil
+	return nil, false
 }
-func (v *PODObject) Unmarshal(data []byte) error {
-	p := NewPODParser(data)
-	length, err := p.ReadUint32()
-	if err != nil {
-		return err
-	}
-	v.Fields = make(map[string]PODValue)
-	for i := uint32(0); i < length; i++ {
-		key, err := p.ReadString()
-		if err != nil {
-			return err
+
+func (v *PODObject) Marshal() ([]byte, error) {
+	body := binary.LittleEndian.AppendUint32(make([]byte, 0, 8+len(v.Props)*24), v.Type)
+	body = binary.LittleEndian.AppendUint32(body, v.ID)
+	for _, prop := range v.Props {
+		if prop.Value == nil {
+			return nil, fmt.Errorf("property %d has no value", prop.Key)
 		}
-		val, err := p.ParseValue()
-		if err != nil {
-			return err
+		body = binary.LittleEndian.AppendUint32(body, prop.Key)
+		body = binary.LittleEndian.AppendUint32(body, prop.Flags)
+		var err error
+		if body, err = appendPadded(body, prop.Value); err != nil {
+			return nil, fmt.Errorf("property %d: %w", prop.Key, err)
 		}
-		v.Fields[key] = val
 	}
-	return nil
+	return framePOD(PODTypeObject, body), nil
 }
+func (v *PODObject) Unmarshal(data []byte) error { return unmarshalPOD(data, v) }
 
 // ============================================================================
 // BUILDERS - Fluent API for constructing POD values
@@ -478,6 +612,9 @@ func (b *PODArrayBuilder) AddInt32(val int32) *PODArrayBuilder {
 func (b *PODArrayBuilder) AddUint32(val uint32) *PODArrayBuilder {
 	return b.Add(NewPODUint32(val))
 }
+func (b *PODArrayBuilder) AddID(val uint32) *PODArrayBuilder {
+	return b.Add(NewPODId(val))
+}
 func (b *PODArrayBuilder) AddFloat(val float32) *PODArrayBuilder {
 	return b.Add(NewPODFloat(val))
 }
@@ -496,34 +633,179 @@ type PODObjectBuilder struct {
 	object *PODObject
 }
 
-func NewPODObjectBuilder() *PODObjectBuilder {
-	return &PODObjectBuilder{object: NewPODObject()}
+func NewPODObjectBuilder(objType, id uint32) *PODObjectBuilder {
+	return &PODObjectBuilder{object: NewPODObject(objType, id)}
+}
+func (b *PODObjectBuilder) Put(key uint32, val PODValue) *PODObjectBuilder {
+	if val != nil {
+		b.object.Set(key, val)
+	}
+	return b
 }
-func (b *PODObjectBuilder) Put(key string, val PODValue) *PODObjectBuilder {
-	if key != "" && val != nil {
+func (b *PODObjectBuilder) PutProp(key, flags uint32, val PODValue) *PODObjectBuilder {
+	if val != nil {
 		b.object.Set(key, val)
+		prop, _ := b.object.Prop(key)
+		prop.Flags = flags
 	}
 	return b
 }
-func (b *PODObjectBuilder) PutInt32(key string, val int32) *PODObjectBuilder {
+func (b *PODObjectBuilder) PutInt32(key uint32, val int32) *PODObjectBuilder {
 	return b.Put(key, NewPODInt32(val))
 }
-func (b *PODObjectBuilder) PutUint32(key string, val uint32) *PODObjectBuilder {
+func (b *PODObjectBuilder) PutUint32(key uint32, val uint32) *PODObjectBuilder {
 	return b.Put(key, NewPODUint32(val))
 }
-func (b *PODObjectBuilder) PutFloat(key string, val float32) *PODObjectBuilder {
+func (b *PODObjectBuilder) PutID(key uint32, val uint32) *PODObjectBuilder {
+	return b.Put(key, NewPODId(val))
+}
+func (b *PODObjectBuilder) PutFloat(key uint32, val float32) *PODObjectBuilder {
 	return b.Put(key, NewPODFloat(val))
 }
-func (b *PODObjectBuilder) PutString(key string, val string) *PODObjectBuilder {
+func (b *PODObjectBuilder) PutString(key uint32, val string) *PODObjectBuilder {
 	return b.Put(key, NewPODString(val))
 }
-func (b *PODObjectBuilder) PutBool(key string, val bool) *PODObjectBuilder {
+func (b *PODObjectBuilder) PutBool(key uint32, val bool) *PODObjectBuilder {
 	return b.Put(key, NewPODBool(val))
 }
 func (b *PODObjectBuilder) Build() *PODObject {
 	return b.object
 }
 
+// PODStructBuilder - Fluent builder for structs
+type PODStructBuilder struct {
+	st *PODStruct
+}
+
+func NewPODStructBuilder() *PODStructBuilder {
+	return &PODStructBuilder{st: NewPODStruct()}
+}
+func (b *PODStructBuilder) Add(val PODValue) *PODStructBuilder {
+	if val != nil {
+		b.st.Append(val)
+	}
+	return b
+}
+func (b *PODStructBuilder) AddNone() *PODStructBuilder {
+	return b.Add(NewPODNone())
+}
+func (b *PODStructBuilder) AddInt32(val int32) *PODStructBuilder {
+	return b.Add(NewPODInt32(val))
+}
+func (b *PODStructBuilder) AddInt64(val int64) *PODStructBuilder {
+	return b.Add(NewPODInt64(val))
+}
+func (b *PODStructBuilder) AddUint32(val uint32) *PODStructBuilder {
+	return b.Add(NewPODUint32(val))
+}
+func (b *PODStructBuilder) AddID(val uint32) *PODStructBuilder {
+	return b.Add(NewPODId(val))
+}
+func (b *PODStructBuilder) AddFloat(val float32) *PODStructBuilder {
+	return b.Add(NewPODFloat(val))
+}
+func (b *PODStructBuilder) AddString(val string) *PODStructBuilder {
+	return b.Add(NewPODString(val))
+}
+func (b *PODStructBuilder) AddBool(val bool) *PODStructBuilder {
+	return b.Add(NewPODBool(val))
+}
+func (b *PODStructBuilder) AddFd(index int64) *PODStructBuilder {
+	return b.Add(NewPODFd(index))
+}
+func (b *PODStructBuilder) Build() *PODStruct {
+	return b.st
+}
+
+// PODChoiceBuilder - Fluent builder for choices, default value first
+type PODChoiceBuilder struct {
+	choice *PODChoice
+}
+
+func NewPODChoiceBuilder(choiceType ChoiceType) *PODChoiceBuilder {
+	return &PODChoiceBuilder{choice: NewPODChoice(choiceType)}
+}
+func (b *PODChoiceBuilder) Flags(flags uint32) *PODChoiceBuilder {
+	b.choice.Flags = flags
+	return b
+}
+func (b *PODChoiceBuilder) Add(val PODValue) *PODChoiceBuilder {
+	if val != nil {
+		b.choice.Values = append(b.choice.Values, val)
+	}
+	return b
+}
+func (b *PODChoiceBuilder) AddInt32(vals ...int32) *PODChoiceBuilder {
+	for _, val := range vals {
+		b.Add(NewPODInt32(val))
+	}
+	return b
+}
+func (b *PODChoiceBuilder) AddID(vals ...uint32) *PODChoiceBuilder {
+	for _, val := range vals {
+		b.Add(NewPODId(val))
+	}
+	return b
+}
+func (b *PODChoiceBuilder) AddFloat(vals ...float32) *PODChoiceBuilder {
+	for _, val := range vals {
+		b.Add(NewPODFloat(val))
+	}
+	return b
+}
+func (b *PODChoiceBuilder) AddDouble(vals ...float64) *PODChoiceBuilder {
+	for _, val := range vals {
+		b.Add(NewPODDouble(val))
+	}
+	return b
+}
+func (b *PODChoiceBuilder) AddBool(vals ...bool) *PODChoiceBuilder {
+	for _, val := range vals {
+		b.Add(NewPODBool(val))
+	}
+	return b
+}
+func (b *PODChoiceBuilder) Build() *PODChoice {
+	return b.choice
+}
+
+// NewPODChoiceRange builds a range choice of def within [lo, hi]
+func NewPODChoiceRange(def, lo, hi PODValue) *PODChoice {
+	return NewPODChoice(ChoiceTypeRange, def, lo, hi)
+}
+
+// NewPODChoiceStep builds a step choice of def within [lo, hi] by step
+func NewPODChoiceStep(def, lo, hi, step PODValue) *PODChoice {
+	return NewPODChoice(ChoiceTypeStep, def, lo, hi, step)
+}
+
+// NewPODChoiceEnum builds an enum choice of def among alternatives
+func NewPODChoiceEnum(def PODValue, alternatives ...PODValue) *PODChoice {
+	return NewPODChoice(ChoiceTypeEnum, append([]PODValue{def}, alternatives...)...)
+}
+
+// PODSequenceBuilder - Fluent builder for sequences
+type PODSequenceBuilder struct {
+	seq *PODSequence
+}
+
+func NewPODSequenceBuilder() *PODSequenceBuilder {
+	return &PODSequenceBuilder{seq: NewPODSequence()}
+}
+func (b *PODSequenceBuilder) Unit(unit uint32) *PODSequenceBuilder {
+	b.seq.Unit = unit
+	return b
+}
+func (b *PODSequenceBuilder) AddControl(offset, controlType uint32, val PODValue) *PODSequenceBuilder {
+	if val != nil {
+		b.seq.Controls = append(b.seq.Controls, PODControl{Offset: offset, Type: controlType, Value: val})
+	}
+	return b
+}
+func (b *PODSequenceBuilder) Build() *PODSequence {
+	return b.seq
+}
+
 // ============================================================================
 // PARSER - Unmarshalling POD binary data
 // ============================================================================
@@ -532,26 +814,43 @@ func (b *PODObjectBuilder) Build() *PODObject {
 type PODParser struct {
 	data   []byte
 	offset int
+	fds    FDTable
+	limits Limits
+	values int // PODs decoded so far, checked against limits.MaxValues
 }
 
 func NewPODParser(data []byte) *PODParser {
-	return &PODParser{data: data, offset: 0}
+	return &PODParser{data: data, offset: 0, limits: DefaultLimits()}
 }
 
 func (p *PODParser) Offset() int { return p.offset }
 
-func (p *PODParser) ParseValue() (PODValue, error) {
-	if p.offset+4 > len(p.data) {
-		return nil, fmt.Errorf("insufficient data for type header")
-	}
+// SetLimits replaces the DefaultLimits the parser enforces
+func (p *PODParser) SetLimits(limits Limits) { p.limits = limits }
+
+// Limits returns the limits the parser enforces
+func (p *PODParser) Limits() Limits { return p.limits }
 
-	// Peek at type ID (not part of payload, inferred from context)
-	// For now, try to auto-detect based on alignment heuristics
-	// In production, caller must specify type or use framed POD
+// SetFDTable attaches the file descriptors received with the message
+// Fd values in the POD are indices into this table
+func (p *PODParser) SetFDTable(fds FDTable) { p.fds = fds }
+
+// FDTable returns the file descriptor table attached to the parser
+func (p *PODParser) FDTable() FDTable { return p.fds }
+
+// ParseValue decodes the framed POD at the offset and moves past it
+// Nested PODs are checked against the parser limits; a violation returns
+// a *LimitError. Structs, objects, choices, sequences and types without a
+// value in this package are returned as PODRaw holding the complete POD
+func (p *PODParser) ParseValue() (PODValue, error) {
+	return p.next(true)
+}
 
-	// For basic parsing, we'll return common types
-	// This is a limitation - proper POD requires type information from protocol
-	return nil, fmt.Errorf("ParseValue requires explicit type context - use ParseInt32, ParseString, etc.")
+// SkipValue checks the framed POD at the offset like ParseValue, without
+// converting it, and moves past it
+func (p *PODParser) SkipValue() error {
+	_, err := p.next(false)
+	return err
 }
 
 func (p *PODParser) ReadByte() (byte, error) {
@@ -627,6 +926,12 @@ func (p *PODParser) ReadString() (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if length == 0 {
+		return "", fmt.Errorf("string length must include the NUL terminator")
+	}
+	if err := checkLimit("string length", int(length), p.limits.MaxStringLength); err != nil {
+		return "", err
+	}
 	if p.offset+int(length) > len(p.data) {
 		return "", fmt.Errorf("insufficient data for string")
 	}
@@ -641,6 +946,9 @@ func (p *PODParser) ReadBytes() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkLimit("string length", int(length), p.limits.MaxStringLength); err != nil {
+		return nil, err
+	}
 	if p.offset+int(length) > len(p.data) {
 		return nil, fmt.Errorf("insufficient data for bytes")
 	}
@@ -649,6 +957,40 @@ func (p *PODParser) ReadBytes() ([]byte, error) {
 	return data, nil
 }
 
+// ReadFd reads an Fd value (int64 index) and resolves it through the fd table
+func (p *PODParser) ReadFd() (int, error) {
+	index, err := p.ReadInt64()
+	if err != nil {
+		return -1, err
+	}
+	return p.fds.Get(index)
+}
+
+// ============================================================================
+// FD TABLE - File descriptors passed alongside a message
+// ============================================================================
+
+// FDTable holds the file descriptors received with a single message
+// Fd PODs do not carry descriptors themselves, only an index into this table
+type FDTable []int
+
+// Get resolves an Fd POD index to a file descriptor
+// Negative indices mean "no fd" and resolve to -1
+func (t FDTable) Get(index int64) (int, error) {
+	if index < 0 {
+		return -1, nil
+	}
+	if index >= int64(len(t)) {
+		return -1, fmt.Errorf("fd index %d out of range (%d fds)", index, len(t))
+	}
+	return t[index], nil
+}
+
+// Len returns the number of file descriptors in the table
+func (t FDTable) Len() int {
+	return len(t)
+}
+
 // ============================================================================
 // WRITER - Marshalling POD data to bytes
 // ============================================================================
@@ -747,11 +1089,11 @@ func (w *PODWriter) Len() int {
 // HELPER FUNCTIONS
 // ============================================================================
 
-// ParseObject extracts a map[string]interface{} from POD Object
-func ParseObject(obj *PODObject) map[string]interface{} {
-	result := make(map[string]interface{})
-	for k, v := range obj.Fields {
-		result[k] = podValueToInterface(v)
+// ParseObject extracts a map[uint32]interface{} from POD Object, by property key
+func ParseObject(obj *PODObject) map[uint32]interface{} {
+	result := make(map[uint32]interface{}, len(obj.Props))
+	for _, prop := range obj.Props {
+		result[prop.Key] = podValueToInterface(prop.Value)
 	}
 	return result
 }
@@ -767,6 +1109,8 @@ func podValueToInterface(v PODValue) interface{} {
 		return val.Value
 	case *PODUint64:
 		return val.Value
+	case *PODId:
+		return val.Value
 	case *PODFloat:
 		return val.Value
 	case *PODDouble:
@@ -783,6 +1127,15 @@ func podValueToInterface(v PODValue) interface{} {
 			arr[i] = podValueToInterface(item)
 		}
 		return arr
+	case *PODStruct:
+		fields := make([]interface{}, len(val.Fields))
+		for i, field := range val.Fields {
+			fields[i] = podValueToInterface(field)
+		}
+		return fields
+	case *PODChoice:
+		// The default is the value in effect
+		return podValueToInterface(val.Default())
 	case *PODObject:
 		return ParseObject(val)
 	default:
@@ -790,13 +1143,20 @@ func podValueToInterface(v PODValue) interface{} {
 	}
 }
 
-// BuildObject creates a PODObject from map[string]interface{}
-func BuildObject(data map[string]interface{}) (*PODObject, error) {
-	obj := NewPODObject()
-	for k, v := range data {
-		podVal, err := interfaceToPODValue(v)
+// BuildObject creates a PODObject from map[uint32]interface{}, with the
+// properties in key order as libspa expects
+func BuildObject(objType, id uint32, data map[uint32]interface{}) (*PODObject, error) {
+	keys := make([]uint32, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	obj := NewPODObject(objType, id)
+	for _, k := range keys {
+		podVal, err := interfaceToPODValue(data[k])
 		if err != nil {
-			return nil, fmt.Errorf("invalid value for key %q: %w", k, err)
+			return nil, fmt.Errorf("invalid value for key %d: %w", k, err)
 		}
 		obj.Set(k, podVal)
 	}
@@ -4,9 +4,22 @@
 package spa
 
 import (
+	"bytes"
+	"encoding/hex"
+	"strings"
 	"testing"
 )
 
+// unhex decodes a hex dump, ignoring spaces and newlines
+func unhex(t *testing.T, dump string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(strings.Join(strings.Fields(dump), ""))
+	if err != nil {
+		t.Fatalf("bad hex dump: %v", err)
+	}
+	return data
+}
+
 // TestPODInt32 tests 32-bit integer marshalling
 func TestPODInt32(t *testing.T) {
 	// Create
@@ -20,8 +33,8 @@ func TestPODInt32(t *testing.T) {
 	if err != nil {
 		t.Fatalf("marshal failed: %v", err)
 	}
-	if len(data) != 4 {
-		t.Errorf("expected 4 bytes, got %d", len(data))
+	if len(data) != 16 {
+		t.Errorf("expected 16 bytes, got %d", len(data))
 	}
 
 	// Unmarshal
@@ -81,9 +94,9 @@ func TestPODString(t *testing.T) {
 		t.Fatalf("marshal failed: %v", err)
 	}
 
-	// Verify format: [length(4 bytes) + data + null]
-	if len(data) != 4+5+1 { // length + "hello" + null
-		t.Errorf("expected 10 bytes, got %d", len(data))
+	// Verify format: [header(8 bytes) + data + null + padding]
+	if len(data) != 8+8 { // header + "hello" + null + 2 bytes padding
+		t.Errorf("expected 16 bytes, got %d", len(data))
 	}
 
 	restored := &PODString{}
@@ -119,57 +132,88 @@ func TestPODArrayBuilder(t *testing.T) {
 	arr := NewPODArrayBuilder().
 		AddInt32(10).
 		AddInt32(20).
-		AddString("test").
-		AddBool(true).
+		AddInt32(30).
 		Build()
 
-	if len(arr.Values) != 4 {
-		t.Errorf("expected 4 values, got %d", len(arr.Values))
+	if len(arr.Values) != 3 {
+		t.Errorf("expected 3 values, got %d", len(arr.Values))
 	}
 
-	// Verify types
-	if _, ok := arr.Values[0].(*PODInt32); !ok {
-		t.Errorf("expected PODInt32 at [0]")
+	data, err := arr.Marshal()
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
 	}
-	if _, ok := arr.Values[1].(*PODInt32); !ok {
-		t.Errorf("expected PODInt32 at [1]")
+	restored := &PODArray{}
+	if err := restored.Unmarshal(data); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
 	}
-	if _, ok := arr.Values[2].(*PODString); !ok {
-		t.Errorf("expected PODString at [2]")
+	if restored.ChildType != PODTypeInt || len(restored.Values) != 3 || restored.Values[2].(*PODInt32).Value != 30 {
+		t.Errorf("unexpected array %v", restored.Values)
+	}
+
+	// Elements are packed without headers, so they must share type and size
+	mixed := NewPODArrayBuilder().AddInt32(1).AddString("test").Build()
+	if _, err := mixed.Marshal(); err == nil {
+		t.Error("expected error for mixed array elements")
+	}
+
+	// An empty array still announces its child type
+	data, err = (&PODArray{ChildType: PODTypeID}).Marshal()
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
 	}
-	if _, ok := arr.Values[3].(*PODBool); !ok {
-		t.Errorf("expected PODBool at [3]")
+	if want := unhex(t, "08000000 0d000000 04000000 03000000"); !bytes.Equal(data, want) {
+		t.Errorf("empty array encoded as %x, want %x", data, want)
 	}
 }
 
 // TestPODObjectBuilder tests fluent object construction
 func TestPODObjectBuilder(t *testing.T) {
-	obj := NewPODObjectBuilder().
-		PutString("name", "Alice").
-		PutInt32("age", 30).
-		PutFloat("height", 1.75).
-		PutBool("active", true).
+	obj := NewPODObjectBuilder(0x40002, 2).
+		PutString(1, "Alice").
+		PutInt32(2, 30).
+		PutFloat(3, 1.75).
+		PutProp(4, PropFlagReadOnly, NewPODBool(true)).
+		PutInt32(2, 31).
 		Build()
 
-	if len(obj.Fields) != 4 {
-		t.Errorf("expected 4 fields, got %d", len(obj.Fields))
+	if len(obj.Props) != 4 {
+		t.Errorf("expected 4 props, got %d", len(obj.Props))
 	}
 
 	// Verify values
-	if name, ok := obj.Get("name"); ok {
+	if name, ok := obj.Get(1); ok {
 		if s, ok := name.(*PODString); ok && s.Value != "Alice" {
 			t.Errorf("expected name='Alice', got %q", s.Value)
 		}
 	} else {
-		t.Errorf("field 'name' not found")
+		t.Errorf("prop 1 not found")
 	}
 
-	if age, ok := obj.Get("age"); ok {
-		if i, ok := age.(*PODInt32); ok && i.Value != 30 {
-			t.Errorf("expected age=30, got %d", i.Value)
+	if age, ok := obj.Get(2); ok {
+		if i, ok := age.(*PODInt32); ok && i.Value != 31 {
+			t.Errorf("expected age=31, got %d", i.Value)
 		}
 	} else {
-		t.Errorf("field 'age' not found")
+		t.Errorf("prop 2 not found")
+	}
+
+	data, err := obj.Marshal()
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	restored := &PODObject{}
+	if err := restored.Unmarshal(data); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if restored.Type != 0x40002 || restored.ID != 2 || len(restored.Props) != 4 {
+		t.Fatalf("unexpected object %v", restored)
+	}
+	if prop, ok := restored.Prop(4); !ok || prop.Flags != PropFlagReadOnly {
+		t.Errorf("expected read-only prop 4, got %+v", prop)
+	}
+	if s, ok := restored.Props[0].Value.(*PODString); !ok || s.Value != "Alice" {
+		t.Errorf("expected props in order, got %v", restored.Props[0].Value)
 	}
 }
 
@@ -192,7 +236,6 @@ func TestPODFraction(t *testing.T) {
 	}
 
 	// Test Value()
-	expected := 22.0 / 7.0
 	if restored.Value() < 3.14 || restored.Value() > 3.15 {
 		t.Errorf("expected ~3.14, got %f", restored.Value())
 	}
@@ -200,7 +243,7 @@ func TestPODFraction(t *testing.T) {
 
 // TestPODRectangle tests rectangle type
 func TestPODRectangle(t *testing.T) {
-	val := NewPODRectangle(10, 20, 100, 200)
+	val := NewPODRectangle(100, 200)
 	data, err := val.Marshal()
 	if err != nil {
 		t.Fatalf("marshal failed: %v", err)
@@ -212,9 +255,8 @@ func TestPODRectangle(t *testing.T) {
 		t.Fatalf("unmarshal failed: %v", err)
 	}
 
-	if restored.X != 10 || restored.Y != 20 || restored.W != 100 || restored.H != 200 {
-		t.Errorf("expected (10,20,100,200), got (%d,%d,%d,%d)",
-			restored.X, restored.Y, restored.W, restored.H)
+	if restored.Width != 100 || restored.Height != 200 {
+		t.Errorf("expected 100x200, got %dx%d", restored.Width, restored.Height)
 	}
 
 	// Test Area
@@ -326,11 +368,14 @@ func TestPODTypeSize(t *testing.T) {
 		size   int
 	}{
 		{PODTypeInt, 4},
-		{PODTypeInt64, 8},
+		{PODTypeLong, 8},
 		{PODTypeFloat, 4},
 		{PODTypeDouble, 8},
 		{PODTypeFraction, 8},
-		{PODTypeRectangle, 16},
+		{PODTypeRectangle, 8},
+		{PODTypeBool, 4},
+		{PODTypeID, 4},
+		{PODTypePointer, 16},
 		{PODTypeString, -1}, // variable
 		{PODTypeArray, -1},  // variable
 	}
@@ -349,15 +394,246 @@ func TestEndianness(t *testing.T) {
 	val := NewPODUint32(0x12345678)
 	data, _ := val.Marshal()
 
-	// Should be: 78 56 34 12 (little-endian)
+	// Should be: 78 56 34 12 (little-endian), after the header
 	expected := []byte{0x78, 0x56, 0x34, 0x12}
 	for i, b := range expected {
-		if data[i] != b {
-			t.Errorf("byte[%d]: expected 0x%02X, got 0x%02X", i, b, data[i])
+		if data[8+i] != b {
+			t.Errorf("byte[%d]: expected 0x%02X, got 0x%02X", i, b, data[8+i])
 		}
 	}
 }
 
+// TestPODVectors checks values against the bytes spa_pod_builder produces
+func TestPODVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		val  PODValue
+		dump string
+	}{
+		{"bool", NewPODBool(true), "04000000 02000000 01000000 00000000"},
+		{"id", NewPODId(0x103), "04000000 03000000 03010000 00000000"},
+		{"int", NewPODInt32(-1), "04000000 04000000 ffffffff 00000000"},
+		{"long", NewPODInt64(1 << 32), "08000000 05000000 00000000 01000000"},
+		{"float", NewPODFloat(0.5), "04000000 06000000 0000003f 00000000"},
+		{"double", NewPODDouble(1), "08000000 07000000 00000000 0000f03f"},
+		{"string", NewPODString("hello"), "06000000 08000000 68656c6c 6f000000"},
+		{"empty string", NewPODString(""), "01000000 08000000 00000000 00000000"},
+		{"bytes", NewPODBytes([]byte{1, 2, 3}), "03000000 09000000 01020300 00000000"},
+		{"rectangle", NewPODRectangle(1920, 1080), "08000000 0a000000 80070000 38040000"},
+		{"fraction", NewPODFraction(25, 1), "08000000 0b000000 19000000 01000000"},
+		{"array", NewPODArrayBuilder().AddInt32(1).AddInt32(2).AddInt32(3).Build(),
+			"14000000 0d000000 04000000 04000000 01000000 02000000 03000000 00000000"},
+		{"none", NewPODNone(), "00000000 01000000"},
+		{"pointer", NewPODPointer(1, 0x1000), "10000000 11000000 01000000 00000000 00100000 00000000"},
+		{"fd", NewPODFd(2), "08000000 12000000 02000000 00000000"},
+		{"struct", NewPODStructBuilder().AddInt32(1).AddString("a").Build(),
+			"20000000 0e000000 04000000 04000000 01000000 00000000 02000000 08000000 61000000 00000000"},
+		{"empty struct", NewPODStruct(), "00000000 0e000000"},
+		{"range", NewPODChoiceRange(NewPODInt32(48000), NewPODInt32(1), NewPODInt32(384000)),
+			"1c000000 13000000 01000000 00000000 04000000 04000000 80bb0000 01000000 00dc0500 00000000"},
+		{"enum", NewPODChoiceBuilder(ChoiceTypeEnum).AddID(0x11b, 0x11b, 0x103).Build(),
+			"1c000000 13000000 03000000 00000000 04000000 03000000 1b010000 1b010000 03010000 00000000"},
+		{"sequence", NewPODSequenceBuilder().AddControl(10, ControlTypeMidi, NewPODBytes([]byte{0x90, 0x3c, 0x7f})).Build(),
+			"20000000 10000000 00000000 00000000 0a000000 02000000 03000000 09000000 903c7f00 00000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := unhex(t, tt.dump)
+			data, err := tt.val.Marshal()
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Fatalf("encoded as\n%x\nwant\n%x", data, want)
+			}
+			val, err := NewPODParser(want).ParseValue()
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			if again, _ := val.Marshal(); !bytes.Equal(again, want) {
+				t.Errorf("round trip gave\n%x\nwant\n%x", again, want)
+			}
+		})
+	}
+}
+
+// TestPODObjectVectors checks objects against spa_debug_pod dumps of params
+func TestPODObjectVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  *PODObject
+		dump string
+	}{
+		{
+			// Object: size 56, type Spa:Pod:Object:Param:Props (262146), id Spa:Enum:ParamId:Props (2)
+			//   Prop: key Spa:Pod:Object:Param:Props:volume (65539), flags 00000000
+			//     Float 0.500000
+			//   Prop: key Spa:Pod:Object:Param:Props:mute (65540), flags 00000000
+			//     Bool true
+			"props",
+			NewPODObjectBuilder(0x40002, 2).
+				PutFloat(0x10003, 0.5).
+				PutBool(0x10004, true).
+				Build(),
+			`38000000 0f000000 02000400 02000000
+			 03000100 00000000 04000000 06000000 0000003f 00000000
+			 04000100 00000000 04000000 02000000 01000000 00000000`,
+		},
+		{
+			// Object: size 160, type Spa:Pod:Object:Param:Format (262147), id Spa:Enum:ParamId:Format (4)
+			//   Prop: key Spa:Pod:Object:Param:Format:mediaType (1), flags 00000000
+			//     Id 1        (Spa:Enum:MediaType:audio)
+			//   Prop: key Spa:Pod:Object:Param:Format:mediaSubtype (2), flags 00000000
+			//     Id 1        (Spa:Enum:MediaSubtype:raw)
+			//   Prop: key Spa:Pod:Object:Param:Format:Audio:format (65537), flags 00000000
+			//     Id 259      (Spa:Enum:AudioFormat:S16LE)
+			//   Prop: key Spa:Pod:Object:Param:Format:Audio:rate (65539), flags 00000000
+			//     Int 48000
+			//   Prop: key Spa:Pod:Object:Param:Format:Audio:channels (65540), flags 00000000
+			//     Int 2
+			//   Prop: key Spa:Pod:Object:Param:Format:Audio:position (65541), flags 00000000
+			//     Array: child.size 4, child.type Spa:Id
+			//       Id 3        (Spa:Enum:AudioChannel:FL)
+			//       Id 4        (Spa:Enum:AudioChannel:FR)
+			"audio format",
+			NewPODObjectBuilder(0x40003, 4).
+				PutID(1, 1).
+				PutID(2, 1).
+				PutID(0x10001, 0x103).
+				PutInt32(0x10003, 48000).
+				PutInt32(0x10004, 2).
+				Put(0x10005, NewPODArrayBuilder().AddID(3).AddID(4).Build()).
+				Build(),
+			`a0000000 0f000000 03000400 04000000
+			 01000000 00000000 04000000 03000000 01000000 00000000
+			 02000000 00000000 04000000 03000000 01000000 00000000
+			 01000100 00000000 04000000 03000000 03010000 00000000
+			 03000100 00000000 04000000 04000000 80bb0000 00000000
+			 04000100 00000000 04000000 04000000 02000000 00000000
+			 05000100 00000000 10000000 0d000000 04000000 03000000 03000000 04000000`,
+		},
+		{
+			// Object: size 32, type Spa:Pod:Object:Param:Props (262146), id Spa:Enum:ParamId:Props (2)
+			//   Prop: key Spa:Pod:Object:Param:Props:device (257), flags 00000001
+			//     String "hw:0"
+			"read-only string",
+			NewPODObjectBuilder(0x40002, 2).
+				PutProp(0x101, PropFlagReadOnly, NewPODString("hw:0")).
+				Build(),
+			`20000000 0f000000 02000400 02000000
+			 01010000 01000000 05000000 08000000 68773a30 00000000`,
+		},
+		{
+			// Object: size 176, type Spa:Pod:Object:Param:Format (262147), id Spa:Enum:ParamId:EnumFormat (3)
+			//   Prop: key Spa:Pod:Object:Param:Format:mediaType (1), flags 00000000
+			//     Id 1        (Spa:Enum:MediaType:audio)
+			//   Prop: key Spa:Pod:Object:Param:Format:mediaSubtype (2), flags 00000000
+			//     Id 1        (Spa:Enum:MediaSubtype:raw)
+			//   Prop: key Spa:Pod:Object:Param:Format:Audio:format (65537), flags 00000000
+			//     Choice: type Spa:Enum:Choice:Enum, flags 00000000 28 4
+			//       Id 283      (Spa:Enum:AudioFormat:F32LE)
+			//       Id 283      (Spa:Enum:AudioFormat:F32LE)
+			//       Id 259      (Spa:Enum:AudioFormat:S16LE)
+			//   Prop: key Spa:Pod:Object:Param:Format:Audio:rate (65539), flags 00000000
+			//     Choice: type Spa:Enum:Choice:Range, flags 00000000 28 4
+			//       Int 48000
+			//       Int 1
+			//       Int 384000
+			//   Prop: key Spa:Pod:Object:Param:Format:Audio:channels (65540), flags 00000000
+			//     Int 2
+			"enum format",
+			NewPODObjectBuilder(0x40003, 3).
+				PutID(1, 1).
+				PutID(2, 1).
+				Put(0x10001, NewPODChoiceBuilder(ChoiceTypeEnum).AddID(0x11b, 0x11b, 0x103).Build()).
+				Put(0x10003, NewPODChoiceRange(NewPODInt32(48000), NewPODInt32(1), NewPODInt32(384000))).
+				PutInt32(0x10004, 2).
+				Build(),
+			`b0000000 0f000000 03000400 03000000
+			 01000000 00000000 04000000 03000000 01000000 00000000
+			 02000000 00000000 04000000 03000000 01000000 00000000
+			 01000100 00000000 1c000000 13000000 03000000 00000000
+			 04000000 03000000 1b010000 1b010000 03010000 00000000
+			 03000100 00000000 1c000000 13000000 01000000 00000000
+			 04000000 04000000 80bb0000 01000000 00dc0500 00000000
+			 04000100 00000000 04000000 04000000 02000000 00000000`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := unhex(t, tt.dump)
+			data, err := tt.obj.Marshal()
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Fatalf("encoded as\n%x\nwant\n%x", data, want)
+			}
+
+			restored := &PODObject{}
+			if err := restored.Unmarshal(want); err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			if restored.Type != tt.obj.Type || restored.ID != tt.obj.ID || len(restored.Props) != len(tt.obj.Props) {
+				t.Fatalf("decoded %v, want %v", restored, tt.obj)
+			}
+			for i, prop := range restored.Props {
+				if prop.Key != tt.obj.Props[i].Key || prop.Flags != tt.obj.Props[i].Flags {
+					t.Errorf("prop %d decoded as key %d flags %d", i, prop.Key, prop.Flags)
+				}
+			}
+			if again, _ := restored.Marshal(); !bytes.Equal(again, want) {
+				t.Errorf("round trip gave\n%x\nwant\n%x", again, want)
+			}
+		})
+	}
+}
+
+// TestPODChoice tests decoding choices and their defaults
+func TestPODChoice(t *testing.T) {
+	choice := NewPODChoiceStep(NewPODInt32(1024), NewPODInt32(32), NewPODInt32(8192), NewPODInt32(32))
+	data, err := choice.Marshal()
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	restored := &PODChoice{}
+	if err := restored.Unmarshal(data); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if restored.Type != ChoiceTypeStep || restored.ChildType != PODTypeInt || len(restored.Values) != 4 {
+		t.Fatalf("unexpected choice %v", restored)
+	}
+	if def, ok := restored.Default().(*PODInt32); !ok || def.Value != 1024 {
+		t.Errorf("expected default 1024, got %v", restored.Default())
+	}
+
+	// Choice values are packed like array elements
+	mixed := NewPODChoiceEnum(NewPODInt32(1), NewPODFloat(1))
+	if _, err := mixed.Marshal(); err == nil {
+		t.Error("expected error for mixed choice values")
+	}
+
+	var ct ChoiceType
+	if err := ct.UnmarshalJSON([]byte(`"flags"`)); err != nil || ct != ChoiceTypeFlags {
+		t.Errorf("expected flags, got %v %v", ct, err)
+	}
+}
+
+// TestPODFd tests resolving fds through the message table
+func TestPODFd(t *testing.T) {
+	fds := FDTable{7, 9}
+	if fd, err := NewPODFd(1).Resolve(fds); err != nil || fd != 9 {
+		t.Errorf("expected fd 9, got %d %v", fd, err)
+	}
+	if fd, err := NewPODFd(-1).Resolve(fds); err != nil || fd != -1 {
+		t.Errorf("expected no fd, got %d %v", fd, err)
+	}
+	if _, err := NewPODFd(2).Resolve(fds); err == nil {
+		t.Error("expected error for an fd index out of range")
+	}
+}
+
 // BenchmarkPODMarshal benchmarks marshalling
 func BenchmarkPODMarshal(b *testing.B) {
 	val := NewPODString("benchmark test string")
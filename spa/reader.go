@@ -0,0 +1,319 @@
+// Package spa - Zero-copy POD reading
+// spa/reader.go
+// Walks PODs in place: reading scalars and iterating containers does not
+// allocate, only converting to a PODValue or a Go string does
+
+package spa
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// PODReader reads one POD in place
+// Readers alias the bytes they were made from, which must not change while
+// they are in use; the zero PODReader holds no POD
+type PODReader struct {
+	podType uint32
+	body    []byte
+}
+
+// NewPODReader reads the framed POD at the start of data
+func NewPODReader(data []byte) (PODReader, error) {
+	podType, body, _, err := readPODHeader(data)
+	if err != nil {
+		return PODReader{}, err
+	}
+	return PODReader{podType: podType, body: body}, nil
+}
+
+// Type returns the SPA type of the POD (PODType*)
+func (r PODReader) Type() uint32 { return r.podType }
+
+// Body returns the body of the POD, without header or padding
+func (r PODReader) Body() []byte { return r.body }
+
+// Value converts the POD to a PODValue, checking it against DefaultLimits
+func (r PODReader) Value() (PODValue, error) {
+	return NewPODParser(nil).decode(r.podType, r.body, 0, true)
+}
+
+// fixed returns the body of a POD of podType at least size bytes long
+func (r PODReader) fixed(podType uint32, size int) ([]byte, error) {
+	if r.podType != podType {
+		return nil, fmt.Errorf("POD is %s, not %s", PODTypeFromID(r.podType), PODTypeFromID(podType))
+	}
+	if len(r.body) < size {
+		return nil, fmt.Errorf("%s POD too short: %d bytes", PODTypeFromID(podType), len(r.body))
+	}
+	return r.body, nil
+}
+
+// GetBool reads a Bool
+func (r PODReader) GetBool() (bool, error) {
+	body, err := r.fixed(PODTypeBool, 4)
+	if err != nil {
+		return false, err
+	}
+	return binary.LittleEndian.Uint32(body) != 0, nil
+}
+
+// GetID reads an Id
+func (r PODReader) GetID() (uint32, error) {
+	body, err := r.fixed(PODTypeID, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(body), nil
+}
+
+// GetInt reads an Int
+func (r PODReader) GetInt() (int32, error) {
+	body, err := r.fixed(PODTypeInt, 4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(body)), nil
+}
+
+// GetLong reads a Long
+func (r PODReader) GetLong() (int64, error) {
+	body, err := r.fixed(PODTypeLong, 8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(body)), nil
+}
+
+// GetFloat reads a Float
+func (r PODReader) GetFloat() (float32, error) {
+	body, err := r.fixed(PODTypeFloat, 4)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(body)), nil
+}
+
+// GetDouble reads a Double
+func (r PODReader) GetDouble() (float64, error) {
+	body, err := r.fixed(PODTypeDouble, 8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(body)), nil
+}
+
+// GetStringBytes reads a String in place, without its NUL terminator
+func (r PODReader) GetStringBytes() ([]byte, error) {
+	body, err := r.fixed(PODTypeString, 1)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range body {
+		if c == 0 {
+			return body[:i:i], nil
+		}
+	}
+	return nil, fmt.Errorf("string is not NUL-terminated")
+}
+
+// GetString reads a String into a Go string
+func (r PODReader) GetString() (string, error) {
+	s, err := r.GetStringBytes()
+	return string(s), err
+}
+
+// GetBytes reads Bytes in place
+func (r PODReader) GetBytes() ([]byte, error) {
+	return r.fixed(PODTypeBytes, 0)
+}
+
+// GetRectangle reads a Rectangle
+func (r PODReader) GetRectangle() (width, height uint32, err error) {
+	body, err := r.fixed(PODTypeRectangle, 8)
+	if err != nil {
+		return 0, 0, err
+	}
+	return binary.LittleEndian.Uint32(body[0:4]), binary.LittleEndian.Uint32(body[4:8]), nil
+}
+
+// GetFraction reads a Fraction
+func (r PODReader) GetFraction() (num, den uint32, err error) {
+	body, err := r.fixed(PODTypeFraction, 8)
+	if err != nil {
+		return 0, 0, err
+	}
+	return binary.LittleEndian.Uint32(body[0:4]), binary.LittleEndian.Uint32(body[4:8]), nil
+}
+
+// GetFd reads an Fd, the index of a descriptor in the FDTable of the message
+func (r PODReader) GetFd() (int64, error) {
+	body, err := r.fixed(PODTypeFd, 8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(body)), nil
+}
+
+// GetPointer reads a Pointer
+func (r PODReader) GetPointer() (ptrType uint32, value uint64, err error) {
+	body, err := r.fixed(PODTypePointer, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	return binary.LittleEndian.Uint32(body[0:4]), binary.LittleEndian.Uint64(body[8:16]), nil
+}
+
+// Fields iterates the fields of a Struct
+func (r PODReader) Fields() (PODIterator, error) {
+	body, err := r.fixed(PODTypeStruct, 0)
+	if err != nil {
+		return PODIterator{}, err
+	}
+	return PODIterator{data: body}, nil
+}
+
+// Object returns the type and id of an Object and iterates its properties
+func (r PODReader) Object() (objType, id uint32, props PODIterator, err error) {
+	body, err := r.fixed(PODTypeObject, 8)
+	if err != nil {
+		return 0, 0, PODIterator{}, err
+	}
+	props = PODIterator{data: body[8:], prefix: 8}
+	return binary.LittleEndian.Uint32(body[0:4]), binary.LittleEndian.Uint32(body[4:8]), props, nil
+}
+
+// Prop finds the property with key in an Object
+func (r PODReader) Prop(key uint32) (PODReader, bool) {
+	_, _, props, err := r.Object()
+	if err != nil {
+		return PODReader{}, false
+	}
+	for props.Next() {
+		if props.Key() == key {
+			return props.POD(), true
+		}
+	}
+	return PODReader{}, false
+}
+
+// Array returns the element type of an Array and iterates its elements
+func (r PODReader) Array() (childType uint32, elems PODIterator, err error) {
+	body, err := r.fixed(PODTypeArray, podHeaderSize)
+	if err != nil {
+		return 0, PODIterator{}, err
+	}
+	elems, err = packedIterator(body)
+	return elems.childType, elems, err
+}
+
+// Choice returns the type and flags of a Choice and iterates its values,
+// the default first
+func (r PODReader) Choice() (choiceType ChoiceType, flags uint32, values PODIterator, err error) {
+	body, err := r.fixed(PODTypeChoice, 8+podHeaderSize)
+	if err != nil {
+		return 0, 0, PODIterator{}, err
+	}
+	values, err = packedIterator(body[8:])
+	return ChoiceType(binary.LittleEndian.Uint32(body[0:4])), binary.LittleEndian.Uint32(body[4:8]), values, err
+}
+
+// Sequence returns the unit of a Sequence and iterates its controls
+func (r PODReader) Sequence() (unit uint32, controls PODIterator, err error) {
+	body, err := r.fixed(PODTypeSequence, 8)
+	if err != nil {
+		return 0, PODIterator{}, err
+	}
+	return binary.LittleEndian.Uint32(body[0:4]), PODIterator{data: body[8:], prefix: 8}, nil
+}
+
+// packedIterator iterates an array body: a child header then packed bodies
+func packedIterator(body []byte) (PODIterator, error) {
+	childSize := int(binary.LittleEndian.Uint32(body[0:4]))
+	childType := binary.LittleEndian.Uint32(body[4:8])
+	elems := body[podHeaderSize:]
+	if childSize == 0 && len(elems) > 0 {
+		return PODIterator{}, fmt.Errorf("array of empty children has %d bytes", len(elems))
+	}
+	return PODIterator{data: elems, childType: childType, childSize: childSize, packed: true}, nil
+}
+
+// PODIterator walks the children of a container in place
+//
+//	for it.Next() {
+//		pod := it.POD()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type PODIterator struct {
+	data      []byte // Children not yet visited
+	prefix    int    // Bytes before each child POD: key and flags, offset and type
+	packed    bool   // Children are array elements without headers
+	childType uint32
+	childSize int
+
+	cur PODReader
+	pre []byte
+	err error
+}
+
+// Next moves to the next child, false at the end or on malformed data
+func (it *PODIterator) Next() bool {
+	if it.err != nil || len(it.data) == 0 {
+		return false
+	}
+	if it.packed {
+		if len(it.data) < it.childSize {
+			it.data = nil
+			return false
+		}
+		it.cur = PODReader{podType: it.childType, body: it.data[:it.childSize]}
+		it.data = it.data[it.childSize:]
+		return true
+	}
+
+	if len(it.data) < it.prefix {
+		it.err = fmt.Errorf("child truncated: %d bytes", len(it.data))
+		return false
+	}
+	podType, body, total, err := readPODHeader(it.data[it.prefix:])
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.pre = it.data[:it.prefix]
+	it.cur = PODReader{podType: podType, body: body}
+	it.data = it.data[it.prefix+total:]
+	return true
+}
+
+// More reports whether children remain to be visited
+func (it *PODIterator) More() bool { return it.err == nil && len(it.data) > 0 }
+
+// POD returns the current child
+func (it *PODIterator) POD() PODReader { return it.cur }
+
+// Err returns the error that ended the iteration, if any
+func (it *PODIterator) Err() error { return it.err }
+
+// Key returns the key of the current object property
+func (it *PODIterator) Key() uint32 { return it.prefixWord(0) }
+
+// Flags returns the flags of the current object property (PropFlag*)
+func (it *PODIterator) Flags() uint32 { return it.prefixWord(1) }
+
+// Offset returns the offset of the current sequence control
+func (it *PODIterator) Offset() uint32 { return it.prefixWord(0) }
+
+// ControlType returns the type of the current sequence control (ControlType*)
+func (it *PODIterator) ControlType() uint32 { return it.prefixWord(1) }
+
+// prefixWord returns the i-th word preceding the current child, 0 when
+// the container has no prefix
+func (it *PODIterator) prefixWord(i int) uint32 {
+	if len(it.pre) < 4*(i+1) {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(it.pre[4*i:])
+}
@@ -0,0 +1,229 @@
+// Package spa - Tests for zero-copy POD reading
+// spa/reader_test.go
+
+package spa
+
+import (
+	"testing"
+)
+
+// enumFormatPOD encodes an EnumFormat param as the daemon sends it
+func enumFormatPOD(tb testing.TB) []byte {
+	tb.Helper()
+	data, err := NewPODObjectBuilder(0x40003, 3).
+		PutID(1, 1).
+		PutID(2, 1).
+		Put(0x10001, NewPODChoiceBuilder(ChoiceTypeEnum).AddID(0x11b, 0x11b, 0x103).Build()).
+		Put(0x10003, NewPODChoiceRange(NewPODInt32(48000), NewPODInt32(1), NewPODInt32(384000))).
+		PutInt32(0x10004, 2).
+		Put(0x10005, NewPODArrayBuilder().AddID(3).AddID(4).Build()).
+		PutProp(0x10006, PropFlagReadOnly, NewPODString("alsa_output")).
+		Build().Marshal()
+	if err != nil {
+		tb.Fatalf("marshal failed: %v", err)
+	}
+	return data
+}
+
+// walkEnumFormat reads every property of an EnumFormat in place
+func walkEnumFormat(data []byte) (sum int64, err error) {
+	r, err := NewPODReader(data)
+	if err != nil {
+		return 0, err
+	}
+	_, _, props, err := r.Object()
+	if err != nil {
+		return 0, err
+	}
+	for props.Next() {
+		pod := props.POD()
+		var values PODIterator
+		switch pod.Type() {
+		case PODTypeChoice:
+			_, _, values, err = pod.Choice()
+		case PODTypeArray:
+			_, values, err = pod.Array()
+		default:
+			sum += int64(len(pod.Body()))
+		}
+		if err != nil {
+			return 0, err
+		}
+		for values.Next() {
+			v := values.POD()
+			switch v.Type() {
+			case PODTypeID:
+				id, _ := v.GetID()
+				sum += int64(id)
+			case PODTypeInt:
+				i, _ := v.GetInt()
+				sum += int64(i)
+			}
+		}
+	}
+	return sum, props.Err()
+}
+
+// TestPODReader tests reading values and containers in place
+func TestPODReader(t *testing.T) {
+	r, err := NewPODReader(enumFormatPOD(t))
+	if err != nil {
+		t.Fatalf("NewPODReader failed: %v", err)
+	}
+	objType, id, props, err := r.Object()
+	if err != nil || objType != 0x40003 || id != 3 {
+		t.Fatalf("unexpected object %x %d: %v", objType, id, err)
+	}
+	n := 0
+	for props.Next() {
+		n++
+	}
+	if props.Err() != nil || n != 7 {
+		t.Errorf("iterated %d props: %v", n, props.Err())
+	}
+
+	format, ok := r.Prop(0x10001)
+	if !ok {
+		t.Fatal("format prop not found")
+	}
+	choiceType, _, values, err := format.Choice()
+	if err != nil || choiceType != ChoiceTypeEnum {
+		t.Fatalf("unexpected choice %v: %v", choiceType, err)
+	}
+	var ids []uint32
+	for values.Next() {
+		id, err := values.POD().GetID()
+		if err != nil {
+			t.Fatalf("GetID failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != 3 || ids[0] != 0x11b || ids[2] != 0x103 {
+		t.Errorf("unexpected choice values %x", ids)
+	}
+
+	position, _ := r.Prop(0x10005)
+	childType, elems, err := position.Array()
+	if err != nil || childType != PODTypeID || !elems.Next() {
+		t.Fatalf("unexpected array of %d: %v", childType, err)
+	}
+	if ch, _ := elems.POD().GetID(); ch != 3 {
+		t.Errorf("expected channel 3, got %d", ch)
+	}
+
+	// Props keep their flags, strings can be read without copying
+	_, _, props, _ = r.Object()
+	for props.Next() {
+		if props.Key() != 0x10006 {
+			continue
+		}
+		s, err := props.POD().GetStringBytes()
+		if err != nil || string(s) != "alsa_output" || props.Flags() != PropFlagReadOnly {
+			t.Errorf("unexpected string prop %q flags %d: %v", s, props.Flags(), err)
+		}
+	}
+
+	// Reading as the wrong type fails
+	channels, _ := r.Prop(0x10004)
+	if _, err := channels.GetID(); err == nil {
+		t.Error("expected error reading an Int as an Id")
+	}
+	if i, err := channels.GetInt(); err != nil || i != 2 {
+		t.Errorf("expected 2 channels, got %d: %v", i, err)
+	}
+	if _, ok := r.Prop(0x99); ok {
+		t.Error("found a missing prop")
+	}
+
+	// Value converts on request
+	v, err := format.Value()
+	if c, ok := v.(*PODChoice); err != nil || !ok || len(c.Values) != 3 {
+		t.Errorf("expected a choice value, got %v %v", v, err)
+	}
+}
+
+// TestPODReaderContainers tests structs and sequences
+func TestPODReaderContainers(t *testing.T) {
+	data, _ := NewPODStructBuilder().AddInt64(-5).AddFd(1).AddString("x").Build().Marshal()
+	r, err := NewPODReader(data)
+	if err != nil {
+		t.Fatalf("NewPODReader failed: %v", err)
+	}
+	fields, err := r.Fields()
+	if err != nil {
+		t.Fatalf("Fields failed: %v", err)
+	}
+	fields.Next()
+	if l, err := fields.POD().GetLong(); err != nil || l != -5 {
+		t.Errorf("expected -5, got %d: %v", l, err)
+	}
+	fields.Next()
+	if fd, err := fields.POD().GetFd(); err != nil || fd != 1 {
+		t.Errorf("expected fd 1, got %d: %v", fd, err)
+	}
+	if !fields.More() || !fields.Next() {
+		t.Fatal("expected a third field")
+	}
+	if s, err := fields.POD().GetString(); err != nil || s != "x" {
+		t.Errorf("expected x, got %q: %v", s, err)
+	}
+	if fields.More() || fields.Next() || fields.Err() != nil {
+		t.Errorf("expected the end of the struct: %v", fields.Err())
+	}
+
+	data, _ = NewPODSequenceBuilder().AddControl(64, ControlTypeMidi, NewPODBytes([]byte{0x90})).Build().Marshal()
+	r, _ = NewPODReader(data)
+	_, controls, err := r.Sequence()
+	if err != nil || !controls.Next() {
+		t.Fatalf("expected a control: %v", err)
+	}
+	if controls.Offset() != 64 || controls.ControlType() != ControlTypeMidi {
+		t.Errorf("unexpected control at %d of type %d", controls.Offset(), controls.ControlType())
+	}
+
+	// A truncated control ends the iteration with an error
+	truncated := append([]byte{}, data...)
+	truncated[0] = 0x18
+	r, _ = NewPODReader(truncated)
+	_, controls, _ = r.Sequence()
+	for controls.Next() {
+	}
+	if controls.Err() == nil {
+		t.Error("expected error for a truncated control")
+	}
+}
+
+// TestPODReaderAllocs checks that walking a POD does not allocate
+func TestPODReaderAllocs(t *testing.T) {
+	data := enumFormatPOD(t)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := walkEnumFormat(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("walking an EnumFormat allocated %.0f times", allocs)
+	}
+}
+
+// BenchmarkPODParserEnumFormat decodes an EnumFormat into PODValues
+func BenchmarkPODParserEnumFormat(b *testing.B) {
+	data := enumFormatPOD(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewPODParser(data).ParseValue(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPODReaderEnumFormat walks the same EnumFormat in place
+func BenchmarkPODReaderEnumFormat(b *testing.B) {
+	data := enumFormatPOD(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := walkEnumFormat(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -11,47 +11,57 @@ import (
 
 // ===== POD Type Constants =====
 
+// SPA_TYPE_* ids carried in the type field of a POD header
 const (
-	PODTypeInvalid uint32 = 0
-	PODTypeNone    uint32 = 1
-	PODTypeBool    uint32 = 2
-	PODTypeInt     uint32 = 3
-	PODTypeInt64   uint32 = 4
-	PODTypeUint32  uint32 = 5
-	PODTypeString  uint32 = 6
-	PODTypeBytes   uint32 = 7
-	PODTypeFd      uint32 = 8
-	PODTypeArray   uint32 = 9
-	PODTypeObject  uint32 = 10
-	PODTypeFraction uint32 = 11
-	PODTypeRectangle uint32 = 12
-	PODTypeID      uint32 = 13
-	// NEW: Floating point types
-	PODTypeFloat   uint32 = 14
-	PODTypeDouble  uint32 = 15
+	PODTypeInvalid   uint32 = 0
+	PODTypeNone      uint32 = 1
+	PODTypeBool      uint32 = 2
+	PODTypeID        uint32 = 3
+	PODTypeInt       uint32 = 4
+	PODTypeLong      uint32 = 5
+	PODTypeFloat     uint32 = 6
+	PODTypeDouble    uint32 = 7
+	PODTypeString    uint32 = 8
+	PODTypeBytes     uint32 = 9
+	PODTypeRectangle uint32 = 10
+	PODTypeFraction  uint32 = 11
+	PODTypeBitmap    uint32 = 12
+	PODTypeArray     uint32 = 13
+	PODTypeStruct    uint32 = 14
+	PODTypeObject    uint32 = 15
+	PODTypeSequence  uint32 = 16
+	PODTypePointer   uint32 = 17
+	PODTypeFd        uint32 = 18
+	PODTypeChoice    uint32 = 19
+	PODTypePod       uint32 = 20
 )
 
 // ===== Choice Type =====
 
-type ChoiceType int
+// ChoiceType is the kind of a Choice POD (SPA_CHOICE_*)
+type ChoiceType uint32
 
 const (
-	ChoiceTypeEnum ChoiceType = iota
-	ChoiceTypeRange
-	ChoiceTypeStep
+	ChoiceTypeNone  ChoiceType = iota // Only the default value
+	ChoiceTypeRange                   // Default, min, max
+	ChoiceTypeStep                    // Default, min, max, step
+	ChoiceTypeEnum                    // Default, then allowed values
+	ChoiceTypeFlags                   // Default, then allowed flags
 )
 
+var choiceTypeNames = map[ChoiceType]string{
+	ChoiceTypeNone:  "none",
+	ChoiceTypeRange: "range",
+	ChoiceTypeStep:  "step",
+	ChoiceTypeEnum:  "enum",
+	ChoiceTypeFlags: "flags",
+}
+
 func (c ChoiceType) String() string {
-	switch c {
-	case ChoiceTypeEnum:
-		return "enum"
-	case ChoiceTypeRange:
-		return "range"
-	case ChoiceTypeStep:
-		return "step"
-	default:
-		return "unknown"
+	if name, ok := choiceTypeNames[c]; ok {
+		return name
 	}
+	return "unknown"
 }
 
 func (c ChoiceType) MarshalJSON() ([]byte, error) {
@@ -64,18 +74,13 @@ func (c *ChoiceType) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	switch s {
-	case "enum":
-		*c = ChoiceTypeEnum
-	case "range":
-		*c = ChoiceTypeRange
-	case "step":
-		*c = ChoiceTypeStep
-	default:
-		return fmt.Errorf("unknown choice type: %s", s)
+	for choiceType, name := range choiceTypeNames {
+		if name == s {
+			*c = choiceType
+			return nil
+		}
 	}
-
-	return nil
+	return fmt.Errorf("unknown choice type: %s", s)
 }
 
 // ===== Object Type =====
@@ -375,33 +380,46 @@ func AlignOffset(offset int) int {
 	return offset + AlignPadding(offset)
 }
 
-// PODTypeSize returns the size in bytes for a POD type
+// podTypeNames maps the POD type ids to their SPA names in lower case
+var podTypeNames = map[uint32]string{
+	PODTypeInvalid:   "invalid",
+	PODTypeNone:      "none",
+	PODTypeBool:      "bool",
+	PODTypeID:        "id",
+	PODTypeInt:       "int",
+	PODTypeLong:      "long",
+	PODTypeFloat:     "float",
+	PODTypeDouble:    "double",
+	PODTypeString:    "string",
+	PODTypeBytes:     "bytes",
+	PODTypeRectangle: "rectangle",
+	PODTypeFraction:  "fraction",
+	PODTypeBitmap:    "bitmap",
+	PODTypeArray:     "array",
+	PODTypeStruct:    "struct",
+	PODTypeObject:    "object",
+	PODTypeSequence:  "sequence",
+	PODTypePointer:   "pointer",
+	PODTypeFd:        "fd",
+	PODTypeChoice:    "choice",
+	PODTypePod:       "pod",
+}
+
+// PODTypeSize returns the body size in bytes of a POD type, -1 for the
+// variable length ones
 func PODTypeSize(typeID uint32) (int, error) {
 	switch typeID {
-	case PODTypeInvalid:
+	case PODTypeInvalid, PODTypeNone:
 		return 0, nil
-	case PODTypeNone:
-		return 0, nil
-	case PODTypeBool:
-		return 1, nil
-	case PODTypeInt:
+	case PODTypeBool, PODTypeID, PODTypeInt, PODTypeFloat:
 		return 4, nil
-	case PODTypeInt64:
+	case PODTypeLong, PODTypeDouble, PODTypeRectangle, PODTypeFraction, PODTypeFd:
 		return 8, nil
-	case PODTypeUint32:
-		return 4, nil
-	case PODTypeFloat:
-		return 4, nil
-	case PODTypeDouble:
-		return 8, nil
-	case PODTypeString, PODTypeBytes, PODTypeArray, PODTypeObject:
+	case PODTypePointer:
+		return 16, nil // type, padding, 64-bit pointer
+	case PODTypeString, PODTypeBytes, PODTypeBitmap, PODTypeArray, PODTypeStruct,
+		PODTypeObject, PODTypeSequence, PODTypeChoice, PODTypePod:
 		return -1, nil // variable length
-	case PODTypeFraction:
-		return 8, nil
-	case PODTypeRectangle:
-		return 16, nil
-	case PODTypeID:
-		return 4, nil
 	default:
 		return -1, fmt.Errorf("unknown POD type: %d", typeID)
 	}
@@ -409,80 +427,18 @@ func PODTypeSize(typeID uint32) (int, error) {
 
 // PODTypeFromID converts a type ID to string
 func PODTypeFromID(id uint32) string {
-	switch id {
-	case PODTypeInvalid:
-		return "invalid"
-	case PODTypeNone:
-		return "none"
-	case PODTypeBool:
-		return "bool"
-	case PODTypeInt:
-		return "int"
-	case PODTypeInt64:
-		return "int64"
-	case PODTypeUint32:
-		return "uint32"
-	case PODTypeString:
-		return "string"
-	case PODTypeBytes:
-		return "bytes"
-	case PODTypeFd:
-		return "fd"
-	case PODTypeArray:
-		return "array"
-	case PODTypeObject:
-		return "object"
-	case PODTypeFraction:
-		return "fraction"
-	case PODTypeRectangle:
-		return "rectangle"
-	case PODTypeID:
-		return "id"
-	case PODTypeFloat:
-		return "float"
-	case PODTypeDouble:
-		return "double"
-	default:
-		return fmt.Sprintf("unknown(%d)", id)
+	if name, ok := podTypeNames[id]; ok {
+		return name
 	}
+	return fmt.Sprintf("unknown(%d)", id)
 }
 
 // PODTypeIDFromString converts a string to type ID
 func PODTypeIDFromString(s string) uint32 {
-	switch s {
-	case "invalid":
-		return PODTypeInvalid
-	case "none":
-		return PODTypeNone
-	case "bool":
-		return PODTypeBool
-	case "int":
-		return PODTypeInt
-	case "int64":
-		return PODTypeInt64
-	case "uint32":
-		return PODTypeUint32
-	case "string":
-		return PODTypeString
-	case "bytes":
-		return PODTypeBytes
-	case "fd":
-		return PODTypeFd
-	case "array":
-		return PODTypeArray
-	case "object":
-		return PODTypeObject
-	case "fraction":
-		return PODTypeFraction
-	case "rectangle":
-		return PODTypeRectangle
-	case "id":
-		return PODTypeID
-	case "float":
-		return PODTypeFloat
-	case "double":
-		return PODTypeDouble
-	default:
-		return PODTypeInvalid
+	for id, name := range podTypeNames {
+		if name == s {
+			return id
+		}
 	}
+	return PODTypeInvalid
 }
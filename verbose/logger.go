@@ -3,11 +3,13 @@
 package verbose
 
 import (
-	"encoding/hex"
+	"encoding/binary"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/vignemail1/pipewire-go/spa"
 )
 
 // LogLevel defines the verbosity level
@@ -180,8 +182,17 @@ func (l *Logger) dumpBinaryInternal(label string, data []byte, offset, length in
 	fmt.Printf("[DEBUG] %s\n", strings.Repeat("─", 60))
 }
 
-// DumpPOD logs a POD structure in a human-readable format
+// DumpPOD logs the body of a POD of type podType in a human-readable
+// format, rendered as spa_debug_pod does
 func (l *Logger) DumpPOD(label string, podType uint32, podData []byte) {
+	pod := make([]byte, 8, 8+len(podData))
+	binary.LittleEndian.PutUint32(pod[0:4], uint32(len(podData)))
+	binary.LittleEndian.PutUint32(pod[4:8], podType)
+	l.DumpPODBytes(label, append(pod, podData...))
+}
+
+// DumpPODBytes logs a complete encoded POD, header included, as DumpPOD does
+func (l *Logger) DumpPODBytes(label string, pod []byte) {
 	if !l.IsVerbose(LogLevelDebug) {
 		return
 	}
@@ -189,10 +200,12 @@ func (l *Logger) DumpPOD(label string, podType uint32, podData []byte) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
+	var dump strings.Builder
+	spa.DumpPODBytes(&dump, pod)
 	fmt.Printf("[DEBUG] POD: %s\n", label)
-	fmt.Printf("[DEBUG]   Type: 0x%02x (%s)\n", podType, typeNameString(podType))
-	fmt.Printf("[DEBUG]   Size: %d bytes\n", len(podData))
-	fmt.Printf("[DEBUG]   Data: %s\n", hex.EncodeToString(podData[:min(len(podData), 64)]))
+	for _, line := range strings.Split(strings.TrimSuffix(dump.String(), "\n"), "\n") {
+		fmt.Printf("[DEBUG]   %s\n", line)
+	}
 }
 
 // OnSend registers a callback for outgoing messages
@@ -249,43 +262,6 @@ func (l *Logger) ReportError(err error, context string) {
 	}
 }
 
-// typeNameString returns human-readable POD type name
-func typeNameString(podType uint32) string {
-	typeNames := map[uint32]string{
-		0x00: "None",
-		0x01: "Bool",
-		0x02: "Id",
-		0x03: "Int",
-		0x04: "Long",
-		0x05: "Float",
-		0x06: "Double",
-		0x07: "String",
-		0x08: "Bytes",
-		0x09: "Rectangle",
-		0x0a: "Fraction",
-		0x0b: "Bitmap",
-		0x0c: "Array",
-		0x0d: "Struct",
-		0x0e: "Object",
-		0x0f: "Choice",
-		0x10: "Pointer",
-		0x11: "Fd",
-		0x12: "Sequence",
-	}
-
-	if name, ok := typeNames[podType]; ok {
-		return name
-	}
-	return "Unknown"
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 // MessageDumper provides structured logging of PipeWire messages
 type MessageDumper struct {
 	logger *Logger